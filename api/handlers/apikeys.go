@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/api/responses"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/apikey"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	log "github.com/sirupsen/logrus"
+)
+
+// APIKeyPayloadCtxKey is a key used for the APIKey payload object in the context
+type APIKeyPayloadCtxKey struct{}
+
+// APIKeysRepo repository
+type APIKeysRepo interface {
+	CreateAPIKey(context.Context, models.APIKey) (models.APIKey, error)
+	GetAPIKeys(context.Context) ([]models.APIKey, error)
+	RotateAPIKey(ctx context.Context, id uint64, prefix string, hash string) (models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id uint64) error
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (models.APIKey, error)
+	TouchAPIKey(ctx context.Context, id uint64, usedAt time.Time) error
+}
+
+// apiKeys handler, for managing the API keys of the tenant.
+type apiKeys struct {
+	apiKeysRepo APIKeysRepo
+}
+
+// NewAPIKeys returns a new API keys handler with the given repository.
+func NewAPIKeys(a APIKeysRepo) *apiKeys {
+	return &apiKeys{apiKeysRepo: a}
+}
+
+// HandleAPIKeyCreate handles POST requests to create an API key.
+// The key is returned only in this response, it cannot be retrieved later.
+func (h *apiKeys) HandleAPIKeyCreate(w http.ResponseWriter, r *http.Request) {
+	data := r.Context().Value(APIKeyPayloadCtxKey{}).(requests.APIKeyPayload)
+
+	secret, prefix, err := apikey.Generate()
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Error("Cannot generate API key")
+		render.Render(w, r, &responses.ErrResponse{Message: "Cannot create API key", HTTPStatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	key := data.ToAPIKey()
+	key.Prefix, key.Hash = prefix, apikey.Hash(secret)
+
+	key, err = h.apiKeysRepo.CreateAPIKey(r.Context(), key)
+	if err != nil {
+		render.Render(w, r, responses.NewStorageErrResponse(err, "Cannot create API key"))
+		return
+	}
+
+	render.Render(w, r, &responses.APIKeyResponse{APIKey: &key, Key: secret, HTTPStatusCode: http.StatusCreated})
+}
+
+// HandleAPIKeyGetAll handles GET requests to display all API keys of the tenant.
+func (h *apiKeys) HandleAPIKeyGetAll(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.apiKeysRepo.GetAPIKeys(r.Context())
+	if err != nil {
+		render.Render(w, r, responses.NewStorageErrResponse(err, "Cannot retrieve API keys"))
+		return
+	}
+
+	render.Render(w, r, &responses.APIKeysResponse{APIKeys: keys, HTTPStatusCode: http.StatusOK})
+}
+
+// HandleAPIKeyRotate handles POST requests to replace the API key with a new one.
+// The name, scopes and expiry are kept, the previous key stops working immediately.
+func (h *apiKeys) HandleAPIKeyRotate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		render.Render(w, r, &responses.ErrResponse{Message: "Invalid ID", HTTPStatusCode: http.StatusBadRequest})
+		return
+	}
+
+	secret, prefix, err := apikey.Generate()
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Error("Cannot generate API key")
+		render.Render(w, r, &responses.ErrResponse{Message: "Cannot rotate API key", HTTPStatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	key, err := h.apiKeysRepo.RotateAPIKey(r.Context(), id, prefix, apikey.Hash(secret))
+	if err != nil {
+		render.Render(w, r, apiKeyErrResponse(err, "Cannot rotate API key"))
+		return
+	}
+
+	render.Render(w, r, &responses.APIKeyResponse{APIKey: &key, Key: secret, HTTPStatusCode: http.StatusOK})
+}
+
+// HandleAPIKeyRevoke handles DELETE requests to revoke the API key.
+// Revoked keys are kept, so they are still listed.
+func (h *apiKeys) HandleAPIKeyRevoke(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		render.Render(w, r, &responses.ErrResponse{Message: "Invalid ID", HTTPStatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if err := h.apiKeysRepo.RevokeAPIKey(r.Context(), id); err != nil {
+		render.Render(w, r, apiKeyErrResponse(err, "Cannot revoke API key"))
+		return
+	}
+
+	render.Render(w, r, &responses.ErrResponse{Message: "API key revoked", HTTPStatusCode: http.StatusOK})
+}
+
+// apiKeyErrResponse maps the API keys repository error to the response.
+func apiKeyErrResponse(err error, msg string) render.Renderer {
+	if errors.Is(err, models.ErrAPIKeyNotFound) {
+		return &responses.ErrResponse{Message: "API key not found", HTTPStatusCode: http.StatusNotFound}
+	}
+	return responses.NewStorageErrResponse(err, msg)
+}
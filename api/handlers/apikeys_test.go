@@ -0,0 +1,195 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/api/server"
+	"github.com/brokeyourbike/xm-golang-exercise/mocks"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/apikey"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// generatedKey matches the keys generated by the handlers.
+var generatedKey = regexp.MustCompile(`xm_[0-9a-f]{12}_[0-9a-f]{64}`)
+
+func TestAPIKeys(t *testing.T) {
+	createdAt := time.Date(2022, 4, 1, 10, 0, 0, 0, time.UTC)
+	key := models.APIKey{ID: 2, Name: "billing", Prefix: "0123456789ab", Hash: "hash", Scopes: models.Scopes{"companies:read"}, CreatedAt: createdAt}
+	payload := requests.APIKeyPayload{Name: "billing", Scopes: []string{"companies:read", "companies:read"}}
+
+	// hash is the hash of the key passed to the repository
+	var hash string
+
+	cases := map[string]struct {
+		method     string
+		path       string
+		statusCode int
+		response   string
+		payload    bool
+		setupMock  func(apiKeysRepo *mocks.APIKeysRepo)
+	}{
+		"list API keys": {
+			method:     http.MethodGet,
+			path:       "/api-keys",
+			statusCode: http.StatusOK,
+			response:   `{"api_keys":[{"id":2,"name":"billing","prefix":"0123456789ab","scopes":["companies:read"],"expires_at":null,"last_used_at":null,"revoked_at":null,"created_at":"2022-04-01T10:00:00Z"}]}`,
+			setupMock: func(apiKeysRepo *mocks.APIKeysRepo) {
+				apiKeysRepo.On("GetAPIKeys", mock.Anything).Return([]models.APIKey{key}, nil)
+			},
+		},
+		"cannot list API keys": {
+			method:     http.MethodGet,
+			path:       "/api-keys",
+			statusCode: http.StatusInternalServerError,
+			response:   `{"message":"Cannot retrieve API keys"}`,
+			setupMock: func(apiKeysRepo *mocks.APIKeysRepo) {
+				apiKeysRepo.On("GetAPIKeys", mock.Anything).Return([]models.APIKey{}, errors.New("err"))
+			},
+		},
+		"create API key": {
+			method:     http.MethodPost,
+			path:       "/api-keys",
+			statusCode: http.StatusCreated,
+			response:   `{"id":2,"name":"billing","prefix":"0123456789ab","scopes":["companies:read"],"expires_at":null,"last_used_at":null,"revoked_at":null,"created_at":"2022-04-01T10:00:00Z","key":"KEY"}`,
+			payload:    true,
+			setupMock: func(apiKeysRepo *mocks.APIKeysRepo) {
+				apiKeysRepo.On("CreateAPIKey", mock.Anything, mock.MatchedBy(func(k models.APIKey) bool {
+					hash = k.Hash
+					return k.Name == "billing" && len(k.Scopes) == 1 && len(k.Prefix) == 12
+				})).Return(key, nil)
+			},
+		},
+		"cannot create API key": {
+			method:     http.MethodPost,
+			path:       "/api-keys",
+			statusCode: http.StatusInternalServerError,
+			response:   `{"message":"Cannot create API key"}`,
+			payload:    true,
+			setupMock: func(apiKeysRepo *mocks.APIKeysRepo) {
+				apiKeysRepo.On("CreateAPIKey", mock.Anything, mock.Anything).Return(models.APIKey{}, errors.New("err"))
+			},
+		},
+		"rotate API key": {
+			method:     http.MethodPost,
+			path:       "/api-keys/2/rotate",
+			statusCode: http.StatusOK,
+			response:   `{"id":2,"name":"billing","prefix":"0123456789ab","scopes":["companies:read"],"expires_at":null,"last_used_at":null,"revoked_at":null,"created_at":"2022-04-01T10:00:00Z","key":"KEY"}`,
+			setupMock: func(apiKeysRepo *mocks.APIKeysRepo) {
+				apiKeysRepo.On("RotateAPIKey", mock.Anything, uint64(2), mock.AnythingOfType("string"), mock.MatchedBy(func(h string) bool {
+					hash = h
+					return true
+				})).Return(key, nil)
+			},
+		},
+		"rotate missing API key": {
+			method:     http.MethodPost,
+			path:       "/api-keys/2/rotate",
+			statusCode: http.StatusNotFound,
+			response:   `{"message":"API key not found"}`,
+			setupMock: func(apiKeysRepo *mocks.APIKeysRepo) {
+				apiKeysRepo.On("RotateAPIKey", mock.Anything, uint64(2), mock.Anything, mock.Anything).Return(models.APIKey{}, models.ErrAPIKeyNotFound)
+			},
+		},
+		"revoke API key": {
+			method:     http.MethodDelete,
+			path:       "/api-keys/2",
+			statusCode: http.StatusOK,
+			response:   `{"message":"API key revoked"}`,
+			setupMock: func(apiKeysRepo *mocks.APIKeysRepo) {
+				apiKeysRepo.On("RevokeAPIKey", mock.Anything, uint64(2)).Return(nil)
+			},
+		},
+		"revoke missing API key": {
+			method:     http.MethodDelete,
+			path:       "/api-keys/2",
+			statusCode: http.StatusNotFound,
+			response:   `{"message":"API key not found"}`,
+			setupMock: func(apiKeysRepo *mocks.APIKeysRepo) {
+				apiKeysRepo.On("RevokeAPIKey", mock.Anything, uint64(2)).Return(models.ErrAPIKeyNotFound)
+			},
+		},
+		"cannot revoke API key": {
+			method:     http.MethodDelete,
+			path:       "/api-keys/2",
+			statusCode: http.StatusInternalServerError,
+			response:   `{"message":"Cannot revoke API key"}`,
+			setupMock: func(apiKeysRepo *mocks.APIKeysRepo) {
+				apiKeysRepo.On("RevokeAPIKey", mock.Anything, uint64(2)).Return(errors.New("err"))
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			hash = ""
+
+			apiKeysRepo := new(mocks.APIKeysRepo)
+			c.setupMock(apiKeysRepo)
+
+			req := httptest.NewRequest(c.method, c.path, nil)
+			ctx := req.Context()
+			if c.payload {
+				ctx = context.WithValue(ctx, handlers.APIKeyPayloadCtxKey{}, payload)
+			}
+
+			w := httptest.NewRecorder()
+
+			mw := dymmyMw{}
+
+			srv := server.NewServer(chi.NewMux(), server.Handlers{
+				Companies: handlers.NewCompanies(new(mocks.CompaniesRepo)),
+				Contacts:  handlers.NewContacts(new(mocks.ContactsRepo)),
+				Webhooks:  handlers.NewWebhooks(new(mocks.WebhooksRepo)),
+				APIKeys:   handlers.NewAPIKeys(apiKeysRepo),
+				Stats:     handlers.NewStats(nil),
+			}, server.Middlewares{
+				Company: mw,
+				Trashed: mw,
+				IfMatch: mw,
+				Payload: mw,
+				Patch:   mw,
+				Search:  mw,
+				Bulk:    mw,
+				Import:  mw,
+				Export:  mw,
+				Contact: mw,
+				Key:     mw,
+				Ip:      mw,
+				IpLimit: mw,
+				APIKey:  mw,
+				Auth:    mw,
+				Limit:   mw,
+				Policy:  mw,
+				Admin:   mw,
+				Audit:   mw,
+				Tenant:  mw,
+			})
+			srv.ServeHTTP(w, req.WithContext(ctx))
+
+			body := strings.Trim(w.Body.String(), "\n")
+
+			// the generated key is returned once, and only its hash is stored
+			if secret := generatedKey.FindString(body); secret != "" {
+				assert.True(t, apikey.Verify(secret, hash))
+				body = strings.ReplaceAll(body, secret, "KEY")
+			}
+
+			assert.Equal(t, c.statusCode, w.Result().StatusCode)
+			assert.Equal(t, c.response, body)
+
+			apiKeysRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/api/responses"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/go-chi/render"
+)
+
+// errBulkFailed is returned from the transaction to roll back the atomic bulk request.
+var errBulkFailed = errors.New("bulk operation failed")
+
+// HandleCompanyBulk handles POST requests to create, update and delete multiple companies.
+// In the atomic mode either all operations are applied or none of them,
+// operations not applied because of the failure of another one are reported with 424.
+// In the best-effort mode every operation is applied independently.
+func (c *companies) HandleCompanyBulk(w http.ResponseWriter, r *http.Request) {
+	data := r.Context().Value(BulkPayloadCtxKey{}).(requests.BulkPayload)
+
+	results := make([]responses.BulkResult, len(data.Operations))
+
+	if !data.Atomic() {
+		for i, op := range data.Operations {
+			results[i] = applyBulk(r.Context(), c.companiesRepo, i, op)
+		}
+
+		render.Render(w, r, responses.NewBulkResponse(data.Mode, results, http.StatusOK))
+		return
+	}
+
+	if data.Invalid() {
+		for i, op := range data.Operations {
+			if len(op.Errors) != 0 {
+				results[i] = applyBulk(r.Context(), c.companiesRepo, i, op)
+			} else {
+				results[i] = notApplied(i, op)
+			}
+		}
+
+		render.Render(w, r, responses.NewBulkResponse(data.Mode, results, http.StatusBadRequest))
+		return
+	}
+
+	failed := -1
+	err := c.companiesRepo.Transaction(r.Context(), func(tx CompaniesRepo) error {
+		for i, op := range data.Operations {
+			results[i] = applyBulk(r.Context(), tx, i, op)
+			if results[i].Error != nil {
+				failed = i
+				return errBulkFailed
+			}
+		}
+		return nil
+	})
+
+	if err != nil && failed == -1 {
+		render.Render(w, r, responses.NewStorageErrResponse(err, "Cannot apply operations"))
+		return
+	}
+
+	status := http.StatusOK
+	if err != nil {
+		status = results[failed].Status
+		for i, op := range data.Operations {
+			if i != failed {
+				results[i] = notApplied(i, op)
+			}
+		}
+	}
+
+	render.Render(w, r, responses.NewBulkResponse(data.Mode, results, status))
+}
+
+// applyBulk applies a single bulk operation using the given repository.
+// Failures are reported the same as for the single-item requests.
+func applyBulk(ctx context.Context, repo CompaniesRepo, i int, op requests.BulkOperation) responses.BulkResult {
+	result := responses.BulkResult{Index: i, Op: op.Op}
+
+	fail := func(e *responses.ErrResponse) responses.BulkResult {
+		result.Status = e.HTTPStatusCode
+		result.Error = e
+		return result
+	}
+
+	if len(op.Errors) != 0 {
+		return fail(&responses.ErrResponse{Message: "Invalid request data", Errors: op.Errors, HTTPStatusCode: http.StatusBadRequest})
+	}
+
+	if op.Op == requests.BulkOpCreate {
+		company, err := repo.Create(ctx, op.Data.ToCompany())
+		if err != nil {
+			return fail(errResponse(err, "Company cannot be created"))
+		}
+
+		result.Status, result.Company, result.Version = http.StatusCreated, &company, company.Version
+		return result
+	}
+
+	existing, err := repo.Get(ctx, op.ID)
+	if err != nil {
+		return fail(errResponse(err, "Cannot retrieve company"))
+	}
+
+	if existing.Version != op.Version {
+		return fail(errResponse(models.ErrCompanyModified, ""))
+	}
+
+	if op.Op == requests.BulkOpDelete {
+		if err := repo.Delete(ctx, existing); err != nil {
+			return fail(errResponse(err, "Cannot remove company"))
+		}
+
+		result.Status = http.StatusOK
+		return result
+	}
+
+	company := op.Data.ToCompany()
+	company.ID = existing.ID
+	company.Version = existing.Version
+
+	company, _, err = repo.Update(ctx, company)
+	if err != nil {
+		return fail(errResponse(err, "Cannot update company"))
+	}
+
+	result.Status, result.Company, result.Version = http.StatusOK, &company, company.Version
+	return result
+}
+
+// notApplied returns the result of the operation skipped in the atomic mode.
+func notApplied(i int, op requests.BulkOperation) responses.BulkResult {
+	return responses.BulkResult{
+		Index:  i,
+		Op:     op.Op,
+		Status: http.StatusFailedDependency,
+		Error:  &responses.ErrResponse{Message: "Operation not applied", HTTPStatusCode: http.StatusFailedDependency},
+	}
+}
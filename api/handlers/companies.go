@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
 
 	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
@@ -15,13 +17,39 @@ type CompanyCtxKey struct{}
 // CompanyPayloadCtxKey is a key used for the Company payload object in the context
 type CompanyPayloadCtxKey struct{}
 
+// CompaniesQueryCtxKey is a key used for the Companies query object in the context
+type CompaniesQueryCtxKey struct{}
+
+// SearchQueryCtxKey is a key used for the Search query object in the context
+type SearchQueryCtxKey struct{}
+
+// BulkPayloadCtxKey is a key used for the Bulk payload object in the context
+type BulkPayloadCtxKey struct{}
+
+// CompanyImportCtxKey is a key used for the Company import object in the context
+type CompanyImportCtxKey struct{}
+
+// ExportQueryCtxKey is a key used for the Export query object in the context
+type ExportQueryCtxKey struct{}
+
 // CompaniesRepo repository defines functions used for companies manipulation.
 type CompaniesRepo interface {
-	Create(models.Company) (models.Company, error)
-	GetAll(requests.CompanyPayload) ([]models.Company, error)
-	Get(uint64) (models.Company, error)
-	Delete(uint64) error
-	Update(models.Company) error
+	Create(context.Context, models.Company) (models.Company, error)
+	GetAll(context.Context, requests.CompaniesQuery) (models.CompaniesPage, error)
+	GetAllTrashed(context.Context, requests.CompaniesQuery) (models.CompaniesPage, error)
+	Get(context.Context, uint64) (models.Company, error)
+	GetTrashed(context.Context, uint64) (models.Company, error)
+	GetByCode(ctx context.Context, code string, country string) (models.Company, error)
+	Delete(context.Context, models.Company) error
+	Restore(context.Context, uint64) error
+	Update(context.Context, models.Company) (models.Company, models.Changes, error)
+	History(context.Context, uint64) ([]models.AuditRecord, error)
+	VerifyAudit(context.Context) (bool, error)
+	Children(context.Context, uint64) ([]models.Company, error)
+	Ancestors(context.Context, uint64) ([]models.Company, error)
+	Descendants(context.Context, uint64) ([]models.Company, error)
+	Search(context.Context, requests.SearchQuery) (models.SearchPage, error)
+	Transaction(context.Context, func(CompaniesRepo) error) error
 }
 
 // companies handler, for getting and updating companies.
@@ -38,9 +66,9 @@ func NewCompanies(c CompaniesRepo) *companies {
 func (c *companies) HandleCompanyCreate(w http.ResponseWriter, r *http.Request) {
 	data := r.Context().Value(CompanyPayloadCtxKey{}).(requests.CompanyPayload)
 
-	company, err := c.companiesRepo.Create(data.ToCompany())
+	company, err := c.companiesRepo.Create(r.Context(), data.ToCompany())
 	if err != nil {
-		render.Render(w, r, &responses.ErrResponse{Message: "Company cannot be created", HTTPStatusCode: http.StatusInternalServerError})
+		render.Render(w, r, errResponse(err, "Company cannot be created"))
 		return
 	}
 
@@ -54,30 +82,96 @@ func (c *companies) HandleCompanyGetOne(w http.ResponseWriter, r *http.Request)
 	render.Render(w, r, &responses.CompanyResponse{Company: &company, HTTPStatusCode: http.StatusOK})
 }
 
+// HandleCompanyHistory handles GET requests to display the change history of single company.
+// The records of the company are chained with the records of other companies,
+// so the whole audit chain is verified.
+func (c *companies) HandleCompanyHistory(w http.ResponseWriter, r *http.Request) {
+	company := r.Context().Value(CompanyCtxKey{}).(models.Company)
+
+	records, err := c.companiesRepo.History(r.Context(), company.ID)
+	if err != nil {
+		render.Render(w, r, responses.NewStorageErrResponse(err, "Cannot retrieve company history"))
+		return
+	}
+
+	verified, err := c.companiesRepo.VerifyAudit(r.Context())
+	if err != nil {
+		render.Render(w, r, responses.NewStorageErrResponse(err, "Cannot retrieve company history"))
+		return
+	}
+
+	render.Render(w, r, &responses.HistoryResponse{
+		History:        records,
+		Verified:       verified,
+		HTTPStatusCode: http.StatusOK,
+	})
+}
+
 // HandleCompanyGetAll handles GET requests to return multiple companies.
-// It's possible to filter the companies by passing CompanyPayload names fields as query params.
+// It's possible to filter the companies by passing `field` or `field[op]` query params,
+// sort them by passing sort query param, and paginate them by passing limit and cursor query params.
 func (c *companies) HandleCompanyGetAll(w http.ResponseWriter, r *http.Request) {
-	data := r.Context().Value(CompanyPayloadCtxKey{}).(requests.CompanyPayload)
+	data := r.Context().Value(CompaniesQueryCtxKey{}).(requests.CompaniesQuery)
+
+	page, err := c.companiesRepo.GetAll(r.Context(), data)
+	if err != nil {
+		render.Render(w, r, responses.NewStorageErrResponse(err, "Cannot retrieve companies"))
+		return
+	}
+
+	render.Render(w, r, &responses.CompaniesResponse{
+		Companies:      page.Companies,
+		NextCursor:     page.NextCursor,
+		HasMore:        page.HasMore,
+		HTTPStatusCode: http.StatusOK,
+	})
+}
+
+// HandleCompanySearch handles GET requests to search companies by the text query.
+func (c *companies) HandleCompanySearch(w http.ResponseWriter, r *http.Request) {
+	data := r.Context().Value(SearchQueryCtxKey{}).(requests.SearchQuery)
+
+	page, err := c.companiesRepo.Search(r.Context(), data)
+	if err != nil {
+		render.Render(w, r, responses.NewStorageErrResponse(err, "Cannot search companies"))
+		return
+	}
+
+	render.Render(w, r, &responses.SearchResponse{
+		Results:        page.Results,
+		NextCursor:     page.NextCursor,
+		HasMore:        page.HasMore,
+		HTTPStatusCode: http.StatusOK,
+	})
+}
 
-	companies, err := c.companiesRepo.GetAll(data)
+// HandleCompanyGetTrash handles GET requests to return multiple soft-deleted companies.
+// It accepts the same query params as HandleCompanyGetAll.
+func (c *companies) HandleCompanyGetTrash(w http.ResponseWriter, r *http.Request) {
+	data := r.Context().Value(CompaniesQueryCtxKey{}).(requests.CompaniesQuery)
+
+	page, err := c.companiesRepo.GetAllTrashed(r.Context(), data)
 	if err != nil {
-		render.Render(w, r, &responses.ErrResponse{Message: "Cannot retrieve companies", HTTPStatusCode: http.StatusInternalServerError})
+		render.Render(w, r, responses.NewStorageErrResponse(err, "Cannot retrieve companies"))
 		return
 	}
 
-	render.Render(w, r, &responses.CompaniesResponse{Companies: companies, HTTPStatusCode: http.StatusOK})
+	render.Render(w, r, responses.NewTrashResponse(page, http.StatusOK))
 }
 
-// HandleCompanyUpdate handles PUT requests to update companies.
+// HandleCompanyUpdate handles PUT and PATCH requests to update companies.
+// The company is updated only if it was not modified since it was loaded.
 func (c *companies) HandleCompanyUpdate(w http.ResponseWriter, r *http.Request) {
 	data := r.Context().Value(CompanyPayloadCtxKey{}).(requests.CompanyPayload)
 	company := r.Context().Value(CompanyCtxKey{}).(models.Company)
 
 	new := data.ToCompany()
 	new.ID = company.ID
+	new.Version = company.Version
 
-	if err := c.companiesRepo.Update(new); err != nil {
-		render.Render(w, r, &responses.ErrResponse{Message: "Cannot update company", HTTPStatusCode: http.StatusInternalServerError})
+	new, _, err := c.companiesRepo.Update(r.Context(), new)
+	if err != nil {
+		render.Render(w, r, errResponse(err, "Cannot update company"))
 		return
 	}
 
@@ -85,13 +179,78 @@ func (c *companies) HandleCompanyUpdate(w http.ResponseWriter, r *http.Request)
 }
 
 // HandleCompanyDelete handles DELETE requests to delete companies.
+// The company is deleted only if it was not modified since it was loaded.
+// A company with subsidiaries is not deleted, unless the children query param is `reparent`,
+// then the subsidiaries are moved to the parent of the deleted company.
 func (c *companies) HandleCompanyDelete(w http.ResponseWriter, r *http.Request) {
 	company := r.Context().Value(CompanyCtxKey{}).(models.Company)
 
-	if err := c.companiesRepo.Delete(company.ID); err != nil {
-		render.Render(w, r, &responses.ErrResponse{Message: "Cannot remove company", HTTPStatusCode: http.StatusInternalServerError})
+	var err error
+
+	switch r.URL.Query().Get("children") {
+	case "", "reject":
+		err = c.companiesRepo.Delete(r.Context(), company)
+	case "reparent":
+		err = deleteReparenting(r, c.companiesRepo, company)
+	default:
+		render.Render(w, r, &responses.ErrResponse{
+			Message:        "Invalid query params",
+			Errors:         []string{"children must be reject or reparent"},
+			HTTPStatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	if err != nil {
+		render.Render(w, r, errResponse(err, "Cannot remove company"))
 		return
 	}
 
 	render.Render(w, r, &responses.ErrResponse{Message: "Company removed", HTTPStatusCode: http.StatusOK})
 }
+
+// HandleCompanyRestore handles POST requests to restore soft-deleted companies.
+// The restored company is loaded again, because restoring it can clear its parent.
+func (c *companies) HandleCompanyRestore(w http.ResponseWriter, r *http.Request) {
+	company := r.Context().Value(CompanyCtxKey{}).(models.Company)
+
+	if err := c.companiesRepo.Restore(r.Context(), company.ID); err != nil {
+		render.Render(w, r, responses.NewStorageErrResponse(err, "Cannot restore company"))
+		return
+	}
+
+	restored, err := c.companiesRepo.Get(r.Context(), company.ID)
+	if err != nil {
+		render.Render(w, r, errResponse(err, "Cannot retrieve company"))
+		return
+	}
+
+	render.Render(w, r, &responses.CompanyResponse{Company: &restored, HTTPStatusCode: http.StatusOK})
+}
+
+// errResponse creates the response for the error returned by the repository.
+// Unexpected errors are reported with the given message.
+func errResponse(err error, message string) *responses.ErrResponse {
+	var conflict *models.ConflictError
+
+	switch {
+	case errors.Is(err, models.ErrCompanyNotFound):
+		return &responses.ErrResponse{Message: "Company not found", HTTPStatusCode: http.StatusNotFound}
+	case errors.Is(err, models.ErrCompanyModified):
+		return &responses.ErrResponse{Message: "Company was modified", HTTPStatusCode: http.StatusPreconditionFailed}
+	case errors.As(err, &conflict):
+		return responses.NewConflictResponse(conflict)
+	case errors.Is(err, models.ErrParentNotFound):
+		return &responses.ErrResponse{Message: "Parent company not found", HTTPStatusCode: http.StatusUnprocessableEntity}
+	case errors.Is(err, models.ErrCompanyCycle):
+		return &responses.ErrResponse{Message: "Invalid parent company", Errors: []string{err.Error()}, HTTPStatusCode: http.StatusUnprocessableEntity}
+	case errors.Is(err, models.ErrCompanyHasChildren):
+		return &responses.ErrResponse{
+			Message:        "Company has subsidiaries",
+			Errors:         []string{"delete the subsidiaries first, or pass children=reparent to move them to the parent company"},
+			HTTPStatusCode: http.StatusConflict,
+		}
+	}
+
+	return responses.NewStorageErrResponse(err, message)
+}
@@ -1,4 +1,4 @@
-package handlers
+package handlers_test
 
 import (
 	"context"
@@ -7,7 +7,9 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
 	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
 	"github.com/brokeyourbike/xm-golang-exercise/api/server"
 	"github.com/brokeyourbike/xm-golang-exercise/mocks"
@@ -15,6 +17,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
 type dymmyMw struct{}
@@ -31,6 +34,8 @@ func TestCompanies(t *testing.T) {
 		path       string
 		statusCode int
 		response   string
+		etag       string
+		filename   string
 		setupCtx   func(*http.Request) context.Context
 		setupMock  func(companiesRepo *mocks.CompaniesRepo)
 	}{
@@ -39,24 +44,91 @@ func TestCompanies(t *testing.T) {
 			path:       "/companies/30",
 			statusCode: http.StatusOK,
 			response:   `{"id":30,"name":"tes","code":"tt","country":"US","website":"example.com","phone":"+1234"}`,
+			etag:       `"4"`,
 			setupCtx: func(req *http.Request) context.Context {
-				company := models.Company{ID: 30, Name: "tes", Code: "tt", Country: "US", Website: "example.com", Phone: "+1234"}
-				return context.WithValue(req.Context(), CompanyCtxKey{}, company)
+				company := models.Company{ID: 30, Name: "tes", Code: "tt", Country: "US", Website: "example.com", Phone: "+1234", Version: 4}
+				return context.WithValue(req.Context(), handlers.CompanyCtxKey{}, company)
 			},
 			setupMock: func(companiesRepo *mocks.CompaniesRepo) {},
 		},
+		"view company history": {
+			method:     http.MethodGet,
+			path:       "/companies/30/history",
+			statusCode: http.StatusOK,
+			response:   `{"history":[{"id":1,"company_id":30,"action":"created","changes":{"name":{"from":"","to":"tes"}},"actor":"john","request_id":"req-1","client_ip":"127.0.0.1","created_at":"2022-05-10T12:00:00Z","prev_hash":"","hash":"1b5a1d5b2b1b9fba2e1cb4a2f3b8cba6d5d86fd5e1c0e77cf18b6e2a9b2c2ba1"}],"verified":true}`,
+			setupCtx: func(req *http.Request) context.Context {
+				company := models.Company{ID: 30}
+				return context.WithValue(req.Context(), handlers.CompanyCtxKey{}, company)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				records := []models.AuditRecord{{
+					ID:        1,
+					CompanyID: 30,
+					Action:    models.AuditActionCreated,
+					Changes:   models.Changes{"name": {To: "tes"}},
+					Actor:     "john",
+					RequestID: "req-1",
+					ClientIP:  "127.0.0.1",
+					CreatedAt: time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC),
+					Hash:      "1b5a1d5b2b1b9fba2e1cb4a2f3b8cba6d5d86fd5e1c0e77cf18b6e2a9b2c2ba1",
+				}}
+				companiesRepo.On("History", mock.Anything, uint64(30)).Return(records, nil)
+				companiesRepo.On("VerifyAudit", mock.Anything).Return(true, nil)
+			},
+		},
+		"cannot view company history": {
+			method:     http.MethodGet,
+			path:       "/companies/30/history",
+			statusCode: http.StatusInternalServerError,
+			response:   `{"message":"Cannot retrieve company history"}`,
+			setupCtx: func(req *http.Request) context.Context {
+				company := models.Company{ID: 30}
+				return context.WithValue(req.Context(), handlers.CompanyCtxKey{}, company)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("History", mock.Anything, uint64(30)).Return(nil, errors.New("cannot fetch history"))
+			},
+		},
+		"cannot verify company history": {
+			method:     http.MethodGet,
+			path:       "/companies/30/history",
+			statusCode: http.StatusInternalServerError,
+			response:   `{"message":"Cannot retrieve company history"}`,
+			setupCtx: func(req *http.Request) context.Context {
+				company := models.Company{ID: 30}
+				return context.WithValue(req.Context(), handlers.CompanyCtxKey{}, company)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("History", mock.Anything, uint64(30)).Return([]models.AuditRecord{}, nil)
+				companiesRepo.On("VerifyAudit", mock.Anything).Return(false, errors.New("cannot verify history"))
+			},
+		},
 		"view companies": {
 			method:     http.MethodGet,
 			path:       "/companies",
 			statusCode: http.StatusOK,
-			response:   `{"companies":[{"id":1,"name":"john","code":"","country":"","website":"","phone":""}]}`,
+			response:   `{"companies":[{"id":1,"name":"john","code":"","country":"","website":"","phone":""}],"has_more":false}`,
 			setupCtx: func(req *http.Request) context.Context {
-				data := requests.CompanyPayload{}
-				return context.WithValue(req.Context(), CompanyPayloadCtxKey{}, data)
+				data := requests.CompaniesQuery{}
+				return context.WithValue(req.Context(), handlers.CompaniesQueryCtxKey{}, data)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				page := models.CompaniesPage{Companies: []models.Company{{ID: 1, Name: "john"}}}
+				companiesRepo.On("GetAll", mock.Anything, mock.AnythingOfType("CompaniesQuery")).Return(page, nil)
+			},
+		},
+		"view companies page": {
+			method:     http.MethodGet,
+			path:       "/companies",
+			statusCode: http.StatusOK,
+			response:   `{"companies":[{"id":1,"name":"john","code":"","country":"","website":"","phone":""}],"next_cursor":"eyJpZCI6MX0","has_more":true}`,
+			setupCtx: func(req *http.Request) context.Context {
+				data := requests.CompaniesQuery{Limit: 1}
+				return context.WithValue(req.Context(), handlers.CompaniesQueryCtxKey{}, data)
 			},
 			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
-				companies := []models.Company{{ID: 1, Name: "john"}}
-				companiesRepo.On("GetAll", mock.AnythingOfType("CompanyPayload")).Return(companies, nil)
+				page := models.CompaniesPage{Companies: []models.Company{{ID: 1, Name: "john"}}, NextCursor: "eyJpZCI6MX0", HasMore: true}
+				companiesRepo.On("GetAll", mock.Anything, mock.AnythingOfType("CompaniesQuery")).Return(page, nil)
 			},
 		},
 		"cannot view companies": {
@@ -65,11 +137,82 @@ func TestCompanies(t *testing.T) {
 			statusCode: http.StatusInternalServerError,
 			response:   `{"message":"Cannot retrieve companies"}`,
 			setupCtx: func(req *http.Request) context.Context {
-				data := requests.CompanyPayload{}
-				return context.WithValue(req.Context(), CompanyPayloadCtxKey{}, data)
+				data := requests.CompaniesQuery{}
+				return context.WithValue(req.Context(), handlers.CompaniesQueryCtxKey{}, data)
 			},
 			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
-				companiesRepo.On("GetAll", mock.AnythingOfType("CompanyPayload")).Return([]models.Company{}, errors.New("cannot fetch companies"))
+				companiesRepo.On("GetAll", mock.Anything, mock.AnythingOfType("CompaniesQuery")).Return(models.CompaniesPage{}, errors.New("cannot fetch companies"))
+			},
+		},
+		"companies query timed out": {
+			method:     http.MethodGet,
+			path:       "/companies",
+			statusCode: http.StatusGatewayTimeout,
+			response:   `{"message":"Storage timed out","errors":["Cannot retrieve companies"]}`,
+			setupCtx: func(req *http.Request) context.Context {
+				data := requests.CompaniesQuery{}
+				return context.WithValue(req.Context(), handlers.CompaniesQueryCtxKey{}, data)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("GetAll", mock.Anything, mock.AnythingOfType("CompaniesQuery")).Return(models.CompaniesPage{}, context.DeadlineExceeded)
+			},
+		},
+		"search companies": {
+			method:     http.MethodGet,
+			path:       "/companies/search",
+			statusCode: http.StatusOK,
+			response:   `{"results":[{"company":{"id":1,"name":"Acme","code":"","country":"","website":"","phone":""},"score":1.5,"highlights":{"name":"\u003cem\u003eAcme\u003c/em\u003e"}}],"next_cursor":"eyJzIjoiYWNtZSIsIm8iOjF9","has_more":true}`,
+			setupCtx: func(req *http.Request) context.Context {
+				data := requests.SearchQuery{Q: "acme", Limit: 1}
+				return context.WithValue(req.Context(), handlers.SearchQueryCtxKey{}, data)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				page := models.SearchPage{
+					Results:    []models.SearchResult{{Company: models.Company{ID: 1, Name: "Acme"}, Score: 1.5, Highlights: map[string]string{"name": "<em>Acme</em>"}}},
+					NextCursor: "eyJzIjoiYWNtZSIsIm8iOjF9",
+					HasMore:    true,
+				}
+				companiesRepo.On("Search", mock.Anything, requests.SearchQuery{Q: "acme", Limit: 1}).Return(page, nil)
+			},
+		},
+		"cannot search companies": {
+			method:     http.MethodGet,
+			path:       "/companies/search",
+			statusCode: http.StatusInternalServerError,
+			response:   `{"message":"Cannot search companies"}`,
+			setupCtx: func(req *http.Request) context.Context {
+				return context.WithValue(req.Context(), handlers.SearchQueryCtxKey{}, requests.SearchQuery{Q: "acme"})
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("Search", mock.Anything, requests.SearchQuery{Q: "acme"}).Return(models.SearchPage{}, errors.New("cannot search"))
+			},
+		},
+		"view trash": {
+			method:     http.MethodGet,
+			path:       "/companies/trash",
+			statusCode: http.StatusOK,
+			response:   `{"companies":[{"id":1,"name":"john","code":"","country":"","website":"","phone":"","deleted_at":"2022-05-10T12:00:00Z"}],"has_more":false}`,
+			setupCtx: func(req *http.Request) context.Context {
+				data := requests.CompaniesQuery{}
+				return context.WithValue(req.Context(), handlers.CompaniesQueryCtxKey{}, data)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				deletedAt := gorm.DeletedAt{Time: time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC), Valid: true}
+				page := models.CompaniesPage{Companies: []models.Company{{ID: 1, Name: "john", DeletedAt: deletedAt}}}
+				companiesRepo.On("GetAllTrashed", mock.Anything, mock.AnythingOfType("CompaniesQuery")).Return(page, nil)
+			},
+		},
+		"cannot view trash": {
+			method:     http.MethodGet,
+			path:       "/companies/trash",
+			statusCode: http.StatusInternalServerError,
+			response:   `{"message":"Cannot retrieve companies"}`,
+			setupCtx: func(req *http.Request) context.Context {
+				data := requests.CompaniesQuery{}
+				return context.WithValue(req.Context(), handlers.CompaniesQueryCtxKey{}, data)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("GetAllTrashed", mock.Anything, mock.AnythingOfType("CompaniesQuery")).Return(models.CompaniesPage{}, errors.New("cannot fetch companies"))
 			},
 		},
 		"create company": {
@@ -79,11 +222,11 @@ func TestCompanies(t *testing.T) {
 			response:   `{"id":1,"name":"tes","code":"123","country":"US","website":"example.com","phone":"+1234"}`,
 			setupCtx: func(req *http.Request) context.Context {
 				data := requests.CompanyPayload{Name: "tes", Code: "123", Country: "US", Website: "example.com", Phone: "+1234"}
-				return context.WithValue(req.Context(), CompanyPayloadCtxKey{}, data)
+				return context.WithValue(req.Context(), handlers.CompanyPayloadCtxKey{}, data)
 			},
 			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
 				company := models.Company{ID: 1, Name: "tes", Code: "123", Country: "US", Website: "example.com", Phone: "+1234"}
-				companiesRepo.On("Create", mock.AnythingOfType("Company")).Return(company, nil)
+				companiesRepo.On("Create", mock.Anything, mock.AnythingOfType("Company")).Return(company, nil)
 			},
 		},
 		"company cannot be created": {
@@ -92,10 +235,22 @@ func TestCompanies(t *testing.T) {
 			statusCode: http.StatusInternalServerError,
 			response:   `{"message":"Company cannot be created"}`,
 			setupCtx: func(req *http.Request) context.Context {
-				return context.WithValue(req.Context(), CompanyPayloadCtxKey{}, requests.CompanyPayload{})
+				return context.WithValue(req.Context(), handlers.CompanyPayloadCtxKey{}, requests.CompanyPayload{})
 			},
 			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
-				companiesRepo.On("Create", mock.AnythingOfType("Company")).Return(models.Company{}, errors.New("cannot create"))
+				companiesRepo.On("Create", mock.Anything, mock.AnythingOfType("Company")).Return(models.Company{}, errors.New("cannot create"))
+			},
+		},
+		"company conflicts on create": {
+			method:     http.MethodPost,
+			path:       "/companies",
+			statusCode: http.StatusConflict,
+			response:   `{"message":"Company already exists","errors":["code is already used by company 5"],"field":"code","existing_id":5}`,
+			setupCtx: func(req *http.Request) context.Context {
+				return context.WithValue(req.Context(), handlers.CompanyPayloadCtxKey{}, requests.CompanyPayload{Code: "123"})
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("Create", mock.Anything, mock.AnythingOfType("Company")).Return(models.Company{}, &models.ConflictError{Field: "code", ExistingID: 5})
 			},
 		},
 		"cannot remove company": {
@@ -105,10 +260,23 @@ func TestCompanies(t *testing.T) {
 			response:   `{"message":"Cannot remove company"}`,
 			setupCtx: func(req *http.Request) context.Context {
 				company := models.Company{ID: 30}
-				return context.WithValue(req.Context(), CompanyCtxKey{}, company)
+				return context.WithValue(req.Context(), handlers.CompanyCtxKey{}, company)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("Delete", mock.Anything, models.Company{ID: 30}).Return(errors.New("cannot remove"))
+			},
+		},
+		"company modified before removal": {
+			method:     http.MethodDelete,
+			path:       "/companies/30",
+			statusCode: http.StatusPreconditionFailed,
+			response:   `{"message":"Company was modified"}`,
+			setupCtx: func(req *http.Request) context.Context {
+				company := models.Company{ID: 30, Version: 2}
+				return context.WithValue(req.Context(), handlers.CompanyCtxKey{}, company)
 			},
 			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
-				companiesRepo.On("Delete", uint64(30)).Return(errors.New("cannot remove"))
+				companiesRepo.On("Delete", mock.Anything, models.Company{ID: 30, Version: 2}).Return(models.ErrCompanyModified)
 			},
 		},
 		"company removed": {
@@ -118,10 +286,162 @@ func TestCompanies(t *testing.T) {
 			response:   `{"message":"Company removed"}`,
 			setupCtx: func(req *http.Request) context.Context {
 				company := models.Company{ID: 30}
-				return context.WithValue(req.Context(), CompanyCtxKey{}, company)
+				return context.WithValue(req.Context(), handlers.CompanyCtxKey{}, company)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("Delete", mock.Anything, models.Company{ID: 30}).Return(nil)
+			},
+		},
+		"company with subsidiaries is not removed": {
+			method:     http.MethodDelete,
+			path:       "/companies/30",
+			statusCode: http.StatusConflict,
+			response:   `{"message":"Company has subsidiaries","errors":["delete the subsidiaries first, or pass children=reparent to move them to the parent company"]}`,
+			setupCtx: func(req *http.Request) context.Context {
+				company := models.Company{ID: 30}
+				return context.WithValue(req.Context(), handlers.CompanyCtxKey{}, company)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("Delete", mock.Anything, models.Company{ID: 30}).Return(models.ErrCompanyHasChildren)
+			},
+		},
+		"company removed with subsidiaries moved to parent": {
+			method:     http.MethodDelete,
+			path:       "/companies/30?children=reparent",
+			statusCode: http.StatusOK,
+			response:   `{"message":"Company removed"}`,
+			setupCtx: func(req *http.Request) context.Context {
+				company := models.Company{ID: 30, Version: 2, ParentID: parentID(7)}
+				return context.WithValue(req.Context(), handlers.CompanyCtxKey{}, company)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("Transaction", mock.Anything, mock.Anything).Run(runTransaction(companiesRepo)).Return(nil)
+				companiesRepo.On("Children", mock.Anything, uint64(30)).Return([]models.Company{{ID: 31, Name: "child", ParentID: parentID(30), Version: 4}}, nil)
+				companiesRepo.On("Update", mock.Anything, models.Company{ID: 31, Name: "child", ParentID: parentID(7), Version: 4}).Return(models.Company{}, models.Changes{}, nil)
+				companiesRepo.On("Delete", mock.Anything, models.Company{ID: 30, Version: 2, ParentID: parentID(7)}).Return(nil)
+			},
+		},
+		"subsidiaries policy should be known": {
+			method:     http.MethodDelete,
+			path:       "/companies/30?children=orphan",
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid query params","errors":["children must be reject or reparent"]}`,
+			setupCtx: func(req *http.Request) context.Context {
+				return context.WithValue(req.Context(), handlers.CompanyCtxKey{}, models.Company{ID: 30})
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {},
+		},
+		"company cannot become its own ancestor": {
+			method:     http.MethodPut,
+			path:       "/companies/30",
+			statusCode: http.StatusUnprocessableEntity,
+			response:   `{"message":"Invalid parent company","errors":["company cannot be its own ancestor"]}`,
+			setupCtx: func(req *http.Request) context.Context {
+				company := models.Company{ID: 30, Version: 1}
+				data := requests.CompanyPayload{Name: "acme", ParentID: parentID(31)}
+				ctx := context.WithValue(req.Context(), handlers.CompanyCtxKey{}, company)
+				return context.WithValue(ctx, handlers.CompanyPayloadCtxKey{}, data)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("Update", mock.Anything, models.Company{ID: 30, Name: "acme", ParentID: parentID(31), Version: 1}).Return(models.Company{}, models.Changes{}, models.ErrCompanyCycle)
+			},
+		},
+		"view company children": {
+			method:     http.MethodGet,
+			path:       "/companies/30/children",
+			statusCode: http.StatusOK,
+			response:   `{"companies":[{"id":31,"name":"child","code":"","country":"","website":"","phone":"","parent_id":30}],"has_more":false}`,
+			setupCtx: func(req *http.Request) context.Context {
+				return context.WithValue(req.Context(), handlers.CompanyCtxKey{}, models.Company{ID: 30})
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("Children", mock.Anything, uint64(30)).Return([]models.Company{{ID: 31, Name: "child", ParentID: parentID(30)}}, nil)
+			},
+		},
+		"view company ancestors": {
+			method:     http.MethodGet,
+			path:       "/companies/30/ancestors",
+			statusCode: http.StatusOK,
+			response:   `{"companies":[{"id":7,"name":"group","code":"","country":"","website":"","phone":""}],"has_more":false}`,
+			setupCtx: func(req *http.Request) context.Context {
+				return context.WithValue(req.Context(), handlers.CompanyCtxKey{}, models.Company{ID: 30, ParentID: parentID(7)})
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("Ancestors", mock.Anything, uint64(30)).Return([]models.Company{{ID: 7, Name: "group"}}, nil)
+			},
+		},
+		"view company tree": {
+			method:     http.MethodGet,
+			path:       "/companies/30/tree",
+			statusCode: http.StatusOK,
+			response: `{"id":7,"name":"group","code":"","country":"","website":"","phone":"","children":[` +
+				`{"id":30,"name":"first","code":"","country":"","website":"","phone":"","parent_id":7,"children":[` +
+				`{"id":31,"name":"nested","code":"","country":"","website":"","phone":"","parent_id":30,"children":[]}]}]}`,
+			setupCtx: func(req *http.Request) context.Context {
+				return context.WithValue(req.Context(), handlers.CompanyCtxKey{}, models.Company{ID: 30, Name: "first", ParentID: parentID(7)})
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("Ancestors", mock.Anything, uint64(30)).Return([]models.Company{{ID: 7, Name: "group"}}, nil)
+				companiesRepo.On("Descendants", mock.Anything, uint64(7)).Return([]models.Company{
+					{ID: 30, Name: "first", ParentID: parentID(7)},
+					{ID: 31, Name: "nested", ParentID: parentID(30)},
+				}, nil)
+			},
+		},
+		"cannot view company tree": {
+			method:     http.MethodGet,
+			path:       "/companies/30/tree",
+			statusCode: http.StatusInternalServerError,
+			response:   `{"message":"Cannot retrieve company group"}`,
+			setupCtx: func(req *http.Request) context.Context {
+				return context.WithValue(req.Context(), handlers.CompanyCtxKey{}, models.Company{ID: 30})
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("Ancestors", mock.Anything, uint64(30)).Return([]models.Company{}, nil)
+				companiesRepo.On("Descendants", mock.Anything, uint64(30)).Return(nil, errors.New("cannot fetch"))
+			},
+		},
+		"cannot restore company": {
+			method:     http.MethodPost,
+			path:       "/companies/30/restore",
+			statusCode: http.StatusInternalServerError,
+			response:   `{"message":"Cannot restore company"}`,
+			setupCtx: func(req *http.Request) context.Context {
+				company := models.Company{ID: 30}
+				return context.WithValue(req.Context(), handlers.CompanyCtxKey{}, company)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("Restore", mock.Anything, uint64(30)).Return(errors.New("cannot restore"))
+			},
+		},
+		"company restored": {
+			method:     http.MethodPost,
+			path:       "/companies/30/restore",
+			statusCode: http.StatusOK,
+			response:   `{"id":30,"name":"tes","code":"","country":"","website":"","phone":""}`,
+			etag:       `"3"`,
+			setupCtx: func(req *http.Request) context.Context {
+				parentID := uint64(10)
+				company := models.Company{ID: 30, Name: "tes", ParentID: &parentID, Version: 2}
+				return context.WithValue(req.Context(), handlers.CompanyCtxKey{}, company)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("Restore", mock.Anything, uint64(30)).Return(nil)
+				companiesRepo.On("Get", mock.Anything, uint64(30)).Return(models.Company{ID: 30, Name: "tes", Version: 3}, nil)
+			},
+		},
+		"cannot retrieve restored company": {
+			method:     http.MethodPost,
+			path:       "/companies/30/restore",
+			statusCode: http.StatusInternalServerError,
+			response:   `{"message":"Cannot retrieve company"}`,
+			setupCtx: func(req *http.Request) context.Context {
+				company := models.Company{ID: 30}
+				return context.WithValue(req.Context(), handlers.CompanyCtxKey{}, company)
 			},
 			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
-				companiesRepo.On("Delete", uint64(30)).Return(nil)
+				companiesRepo.On("Restore", mock.Anything, uint64(30)).Return(nil)
+				companiesRepo.On("Get", mock.Anything, uint64(30)).Return(models.Company{}, errors.New("cannot get"))
 			},
 		},
 		"cannot update company": {
@@ -133,11 +453,43 @@ func TestCompanies(t *testing.T) {
 				company := models.Company{ID: 30}
 				data := requests.CompanyPayload{}
 
-				ctx := context.WithValue(req.Context(), CompanyCtxKey{}, company)
-				return context.WithValue(ctx, CompanyPayloadCtxKey{}, data)
+				ctx := context.WithValue(req.Context(), handlers.CompanyCtxKey{}, company)
+				return context.WithValue(ctx, handlers.CompanyPayloadCtxKey{}, data)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("Update", mock.Anything, mock.AnythingOfType("Company")).Return(models.Company{}, models.Changes{}, errors.New("cannot update"))
+			},
+		},
+		"company modified before update": {
+			method:     http.MethodPut,
+			path:       "/companies/30",
+			statusCode: http.StatusPreconditionFailed,
+			response:   `{"message":"Company was modified"}`,
+			setupCtx: func(req *http.Request) context.Context {
+				company := models.Company{ID: 30, Version: 2}
+				data := requests.CompanyPayload{}
+
+				ctx := context.WithValue(req.Context(), handlers.CompanyCtxKey{}, company)
+				return context.WithValue(ctx, handlers.CompanyPayloadCtxKey{}, data)
 			},
 			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
-				companiesRepo.On("Update", mock.AnythingOfType("Company")).Return(errors.New("cannot update"))
+				companiesRepo.On("Update", mock.Anything, models.Company{ID: 30, Version: 2}).Return(models.Company{}, models.Changes{}, models.ErrCompanyModified)
+			},
+		},
+		"company conflicts on update": {
+			method:     http.MethodPut,
+			path:       "/companies/30",
+			statusCode: http.StatusConflict,
+			response:   `{"message":"Company already exists","errors":["code is already used by company 5"],"field":"code","existing_id":5}`,
+			setupCtx: func(req *http.Request) context.Context {
+				company := models.Company{ID: 30, Version: 2}
+				data := requests.CompanyPayload{Code: "123"}
+
+				ctx := context.WithValue(req.Context(), handlers.CompanyCtxKey{}, company)
+				return context.WithValue(ctx, handlers.CompanyPayloadCtxKey{}, data)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("Update", mock.Anything, models.Company{ID: 30, Code: "123", Version: 2}).Return(models.Company{}, models.Changes{}, &models.ConflictError{Field: "code", ExistingID: 5})
 			},
 		},
 		"company updated": {
@@ -145,15 +497,249 @@ func TestCompanies(t *testing.T) {
 			path:       "/companies/30",
 			statusCode: http.StatusOK,
 			response:   `{"id":30,"name":"after","code":"a123","country":"AU","website":"after.com","phone":"+56789"}`,
+			etag:       `"3"`,
 			setupCtx: func(req *http.Request) context.Context {
-				company := models.Company{ID: 30, Name: "before", Code: "b123", Country: "BE", Website: "before.com", Phone: "+1234"}
+				company := models.Company{ID: 30, Name: "before", Code: "b123", Country: "BE", Website: "before.com", Phone: "+1234", Version: 2}
 				data := requests.CompanyPayload{Name: "after", Code: "a123", Country: "AU", Website: "after.com", Phone: "+56789"}
 
-				ctx := context.WithValue(req.Context(), CompanyCtxKey{}, company)
-				return context.WithValue(ctx, CompanyPayloadCtxKey{}, data)
+				ctx := context.WithValue(req.Context(), handlers.CompanyCtxKey{}, company)
+				return context.WithValue(ctx, handlers.CompanyPayloadCtxKey{}, data)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				before := models.Company{ID: 30, Name: "after", Code: "a123", Country: "AU", Website: "after.com", Phone: "+56789", Version: 2}
+				after := before
+				after.Version = 3
+				companiesRepo.On("Update", mock.Anything, before).Return(after, models.Changes{}, nil)
+			},
+		},
+		"company patched": {
+			method:     http.MethodPatch,
+			path:       "/companies/30",
+			statusCode: http.StatusOK,
+			response:   `{"id":30,"name":"after","code":"b123","country":"BE","website":"before.com","phone":"+1234"}`,
+			etag:       `"3"`,
+			setupCtx: func(req *http.Request) context.Context {
+				company := models.Company{ID: 30, Name: "before", Code: "b123", Country: "BE", Website: "before.com", Phone: "+1234", Version: 2}
+				data := requests.CompanyPayload{Name: "after", Code: "b123", Country: "BE", Website: "before.com", Phone: "+1234"}
+
+				ctx := context.WithValue(req.Context(), handlers.CompanyCtxKey{}, company)
+				return context.WithValue(ctx, handlers.CompanyPayloadCtxKey{}, data)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				before := models.Company{ID: 30, Name: "after", Code: "b123", Country: "BE", Website: "before.com", Phone: "+1234", Version: 2}
+				after := before
+				after.Version = 3
+				companiesRepo.On("Update", mock.Anything, before).Return(after, models.Changes{}, nil)
+			},
+		},
+		"apply bulk atomically": {
+			method:     http.MethodPost,
+			path:       "/companies/bulk",
+			statusCode: http.StatusOK,
+			response:   `{"mode":"atomic","succeeded":2,"failed":0,"results":[{"index":0,"op":"create","status":201,"company":{"id":1,"name":"tes","code":"123","country":"US","website":"example.com","phone":"+1234"},"version":1},{"index":1,"op":"delete","status":200}]}`,
+			setupCtx: func(req *http.Request) context.Context {
+				data := requests.BulkPayload{Mode: requests.BulkModeAtomic, Operations: []requests.BulkOperation{
+					{Op: requests.BulkOpCreate, Data: &requests.CompanyPayload{Name: "tes", Code: "123", Country: "US", Website: "example.com", Phone: "+1234"}},
+					{Op: requests.BulkOpDelete, ID: 2, Version: 3},
+				}}
+				return context.WithValue(req.Context(), handlers.BulkPayloadCtxKey{}, data)
 			},
 			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
-				companiesRepo.On("Update", mock.AnythingOfType("Company")).Return(nil)
+				created := models.Company{ID: 1, Name: "tes", Code: "123", Country: "US", Website: "example.com", Phone: "+1234", Version: 1}
+				existing := models.Company{ID: 2, Version: 3}
+				companiesRepo.On("Transaction", mock.Anything, mock.Anything).Run(runTransaction(companiesRepo)).Return(nil)
+				companiesRepo.On("Create", mock.Anything, mock.AnythingOfType("Company")).Return(created, nil)
+				companiesRepo.On("Get", mock.Anything, uint64(2)).Return(existing, nil)
+				companiesRepo.On("Delete", mock.Anything, existing).Return(nil)
+			},
+		},
+		"bulk with invalid operation is not applied": {
+			method:     http.MethodPost,
+			path:       "/companies/bulk",
+			statusCode: http.StatusBadRequest,
+			response:   `{"mode":"atomic","succeeded":0,"failed":2,"results":[{"index":0,"op":"delete","status":424,"error":{"message":"Operation not applied"}},{"index":1,"op":"update","status":400,"error":{"message":"Invalid request data","errors":["Field validation for 'Data' failed on the 'required_unless' tag"]}}]}`,
+			setupCtx: func(req *http.Request) context.Context {
+				data := requests.BulkPayload{Mode: requests.BulkModeAtomic, Operations: []requests.BulkOperation{
+					{Op: requests.BulkOpDelete, ID: 2, Version: 3},
+					{Op: requests.BulkOpUpdate, ID: 3, Version: 1, Errors: []string{"Field validation for 'Data' failed on the 'required_unless' tag"}},
+				}}
+				return context.WithValue(req.Context(), handlers.BulkPayloadCtxKey{}, data)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {},
+		},
+		"bulk is rolled back when operation fails": {
+			method:     http.MethodPost,
+			path:       "/companies/bulk",
+			statusCode: http.StatusPreconditionFailed,
+			response:   `{"mode":"atomic","succeeded":0,"failed":2,"results":[{"index":0,"op":"delete","status":424,"error":{"message":"Operation not applied"}},{"index":1,"op":"update","status":412,"error":{"message":"Company was modified"}}]}`,
+			setupCtx: func(req *http.Request) context.Context {
+				data := requests.BulkPayload{Mode: requests.BulkModeAtomic, Operations: []requests.BulkOperation{
+					{Op: requests.BulkOpDelete, ID: 2, Version: 3},
+					{Op: requests.BulkOpUpdate, ID: 3, Version: 1, Data: &requests.CompanyPayload{Name: "tes"}},
+				}}
+				return context.WithValue(req.Context(), handlers.BulkPayloadCtxKey{}, data)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				existing := models.Company{ID: 2, Version: 3}
+				companiesRepo.On("Transaction", mock.Anything, mock.Anything).Run(runTransaction(companiesRepo)).Return(errors.New("rolled back"))
+				companiesRepo.On("Get", mock.Anything, uint64(2)).Return(existing, nil)
+				companiesRepo.On("Delete", mock.Anything, existing).Return(nil)
+				companiesRepo.On("Get", mock.Anything, uint64(3)).Return(models.Company{ID: 3, Version: 2}, nil)
+			},
+		},
+		"bulk transaction cannot be committed": {
+			method:     http.MethodPost,
+			path:       "/companies/bulk",
+			statusCode: http.StatusInternalServerError,
+			response:   `{"message":"Cannot apply operations"}`,
+			setupCtx: func(req *http.Request) context.Context {
+				data := requests.BulkPayload{Mode: requests.BulkModeAtomic, Operations: []requests.BulkOperation{
+					{Op: requests.BulkOpDelete, ID: 2, Version: 3},
+				}}
+				return context.WithValue(req.Context(), handlers.BulkPayloadCtxKey{}, data)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				existing := models.Company{ID: 2, Version: 3}
+				companiesRepo.On("Transaction", mock.Anything, mock.Anything).Run(runTransaction(companiesRepo)).Return(errors.New("cannot commit"))
+				companiesRepo.On("Get", mock.Anything, uint64(2)).Return(existing, nil)
+				companiesRepo.On("Delete", mock.Anything, existing).Return(nil)
+			},
+		},
+		"apply bulk with best effort": {
+			method:     http.MethodPost,
+			path:       "/companies/bulk",
+			statusCode: http.StatusOK,
+			response:   `{"mode":"best_effort","succeeded":1,"failed":2,"results":[{"index":0,"op":"update","status":200,"company":{"id":3,"name":"new","code":"123","country":"US","website":"example.com","phone":"+1234"},"version":2},{"index":1,"op":"delete","status":404,"error":{"message":"Company not found"}},{"index":2,"op":"create","status":409,"error":{"message":"Company already exists","errors":["code is already used by company 3"],"field":"code","existing_id":3}}]}`,
+			setupCtx: func(req *http.Request) context.Context {
+				payload := &requests.CompanyPayload{Name: "new", Code: "123", Country: "US", Website: "example.com", Phone: "+1234"}
+				data := requests.BulkPayload{Mode: requests.BulkModeBestEffort, Operations: []requests.BulkOperation{
+					{Op: requests.BulkOpUpdate, ID: 3, Version: 1, Data: payload},
+					{Op: requests.BulkOpDelete, ID: 2, Version: 3},
+					{Op: requests.BulkOpCreate, Data: payload},
+				}}
+				return context.WithValue(req.Context(), handlers.BulkPayloadCtxKey{}, data)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				before := models.Company{ID: 3, Name: "new", Code: "123", Country: "US", Website: "example.com", Phone: "+1234", Version: 1}
+				after := before
+				after.Version = 2
+				companiesRepo.On("Get", mock.Anything, uint64(3)).Return(models.Company{ID: 3, Version: 1}, nil)
+				companiesRepo.On("Update", mock.Anything, before).Return(after, models.Changes{}, nil)
+				companiesRepo.On("Get", mock.Anything, uint64(2)).Return(models.Company{}, models.ErrCompanyNotFound)
+				companiesRepo.On("Create", mock.Anything, mock.AnythingOfType("Company")).Return(models.Company{}, &models.ConflictError{Field: "code", ExistingID: 3})
+			},
+		},
+		"export companies as csv in batches": {
+			method:     http.MethodGet,
+			path:       "/companies/export",
+			statusCode: http.StatusOK,
+			response:   "id,name,code,country,website,phone\n1,acme,c1,CY,acme.com,'+1234\n2,\"beta, ltd\",c2,US,beta.com,'+5678",
+			filename:   "companies.csv",
+			setupCtx: func(req *http.Request) context.Context {
+				ctx := context.WithValue(req.Context(), handlers.ExportQueryCtxKey{}, requests.ExportQuery{Format: requests.ExportCSV, BatchSize: 1})
+				return context.WithValue(ctx, handlers.CompaniesQueryCtxKey{}, requests.CompaniesQuery{Sort: "name", Limit: 20, Cursor: "ignored"})
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				first := models.CompaniesPage{Companies: []models.Company{{ID: 1, Name: "acme", Code: "c1", Country: "CY", Website: "acme.com", Phone: "+1234"}}, NextCursor: "next", HasMore: true}
+				second := models.CompaniesPage{Companies: []models.Company{{ID: 2, Name: "beta, ltd", Code: "c2", Country: "US", Website: "beta.com", Phone: "+5678"}}}
+				companiesRepo.On("GetAll", mock.Anything, requests.CompaniesQuery{Sort: "name", Limit: 1}).Return(first, nil)
+				companiesRepo.On("GetAll", mock.Anything, requests.CompaniesQuery{Sort: "name", Limit: 1, Cursor: "next"}).Return(second, nil)
+			},
+		},
+		"export companies as ndjson": {
+			method:     http.MethodGet,
+			path:       "/companies/export",
+			statusCode: http.StatusOK,
+			response:   `{"id":1,"name":"acme","code":"c1","country":"CY","website":"acme.com","phone":"+1234"}`,
+			filename:   "companies.ndjson",
+			setupCtx: func(req *http.Request) context.Context {
+				ctx := context.WithValue(req.Context(), handlers.ExportQueryCtxKey{}, requests.ExportQuery{Format: requests.ExportNDJSON, BatchSize: 10})
+				return context.WithValue(ctx, handlers.CompaniesQueryCtxKey{}, requests.CompaniesQuery{})
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				page := models.CompaniesPage{Companies: []models.Company{{ID: 1, Name: "acme", Code: "c1", Country: "CY", Website: "acme.com", Phone: "+1234"}}}
+				companiesRepo.On("GetAll", mock.Anything, requests.CompaniesQuery{Limit: 10}).Return(page, nil)
+			},
+		},
+		"cannot export companies": {
+			method:     http.MethodGet,
+			path:       "/companies/export",
+			statusCode: http.StatusInternalServerError,
+			response:   `{"message":"Cannot retrieve companies"}`,
+			setupCtx: func(req *http.Request) context.Context {
+				ctx := context.WithValue(req.Context(), handlers.ExportQueryCtxKey{}, requests.ExportQuery{Format: requests.ExportCSV, BatchSize: 10})
+				return context.WithValue(ctx, handlers.CompaniesQueryCtxKey{}, requests.CompaniesQuery{})
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("GetAll", mock.Anything, requests.CompaniesQuery{Limit: 10}).Return(models.CompaniesPage{}, errors.New("cannot fetch companies"))
+			},
+		},
+		"export is stopped when next batch fails": {
+			method:     http.MethodGet,
+			path:       "/companies/export",
+			statusCode: http.StatusOK,
+			response:   "id,name,code,country,website,phone\n1,acme,c1,CY,acme.com,'+1234",
+			filename:   "companies.csv",
+			setupCtx: func(req *http.Request) context.Context {
+				ctx := context.WithValue(req.Context(), handlers.ExportQueryCtxKey{}, requests.ExportQuery{Format: requests.ExportCSV, BatchSize: 1})
+				return context.WithValue(ctx, handlers.CompaniesQueryCtxKey{}, requests.CompaniesQuery{})
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				first := models.CompaniesPage{Companies: []models.Company{{ID: 1, Name: "acme", Code: "c1", Country: "CY", Website: "acme.com", Phone: "+1234"}}, NextCursor: "next", HasMore: true}
+				companiesRepo.On("GetAll", mock.Anything, requests.CompaniesQuery{Limit: 1}).Return(first, nil)
+				companiesRepo.On("GetAll", mock.Anything, requests.CompaniesQuery{Limit: 1, Cursor: "next"}).Return(models.CompaniesPage{}, context.DeadlineExceeded)
+			},
+		},
+		"import companies": {
+			method:     http.MethodPost,
+			path:       "/companies/import",
+			statusCode: http.StatusOK,
+			response:   `{"dry_run":false,"accepted":1,"updated":1,"rejected":2,"results":[{"line":2,"status":"accepted","id":7},{"line":3,"status":"updated","id":3},{"line":4,"status":"rejected","error":{"message":"Invalid request data","errors":["name is required"]}},{"line":5,"status":"rejected","id":4,"error":{"message":"Company was modified"}}]}`,
+			setupCtx: func(req *http.Request) context.Context {
+				body := "name,code,country,website,phone\n" +
+					"new,c1,US,example.com,+1234\n" +
+					"old,c2,US,example.com,+1234\n" +
+					",c3,US,example.com,+1234\n" +
+					"busy,c4,US,example.com,+1234\n"
+				return withImport(req, body, 10, false)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("GetByCode", mock.Anything, "c1", "US").Return(models.Company{}, models.ErrCompanyNotFound)
+				companiesRepo.On("Create", mock.Anything, models.Company{Name: "new", Code: "c1", Country: "US", Website: "example.com", Phone: "+1234"}).Return(models.Company{ID: 7}, nil)
+				companiesRepo.On("GetByCode", mock.Anything, "c2", "US").Return(models.Company{ID: 3, Version: 2}, nil)
+				companiesRepo.On("Update", mock.Anything, models.Company{ID: 3, Name: "old", Code: "c2", Country: "US", Website: "example.com", Phone: "+1234", Version: 2}).Return(models.Company{ID: 3, Version: 3}, models.Changes{}, nil)
+				companiesRepo.On("GetByCode", mock.Anything, "c4", "US").Return(models.Company{ID: 4, Version: 1}, nil)
+				companiesRepo.On("Update", mock.Anything, mock.AnythingOfType("Company")).Return(models.Company{}, models.Changes{}, models.ErrCompanyModified)
+			},
+		},
+		"import companies in dry-run": {
+			method:     http.MethodPost,
+			path:       "/companies/import",
+			statusCode: http.StatusOK,
+			response:   `{"dry_run":true,"accepted":1,"updated":1,"rejected":0,"results":[{"line":1,"status":"accepted"},{"line":3,"status":"updated","id":3}]}`,
+			setupCtx: func(req *http.Request) context.Context {
+				body := `{"name":"new","code":"c1","country":"US","website":"example.com","phone":"+1234"}` + "\n\n" +
+					`{"name":"old","code":"c2","country":"US","website":"example.com","phone":"+1234"}` + "\n"
+				return withImport(req, body, 10, true)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("GetByCode", mock.Anything, "c1", "US").Return(models.Company{}, models.ErrCompanyNotFound)
+				companiesRepo.On("GetByCode", mock.Anything, "c2", "US").Return(models.Company{ID: 3, Version: 2}, nil)
+			},
+		},
+		"import stops after too many rows": {
+			method:     http.MethodPost,
+			path:       "/companies/import",
+			statusCode: http.StatusRequestEntityTooLarge,
+			response:   `{"dry_run":false,"accepted":0,"updated":0,"rejected":1,"results":[{"line":2,"status":"rejected","error":{"message":"Cannot retrieve company"}}],"error":{"message":"Too many rows","errors":["at most 1 rows are allowed, rows from line 3 were not imported"]}}`,
+			setupCtx: func(req *http.Request) context.Context {
+				body := "name,code,country,website,phone\n" +
+					"new,c1,US,example.com,+1234\n" +
+					"old,c2,US,example.com,+1234\n"
+				return withImport(req, body, 1, false)
+			},
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("GetByCode", mock.Anything, "c1", "US").Return(models.Company{}, gorm.ErrInvalidDB)
 			},
 		},
 	}
@@ -161,7 +747,7 @@ func TestCompanies(t *testing.T) {
 	for name, c := range cases {
 		t.Run(name, func(t *testing.T) {
 			companiesRepo := new(mocks.CompaniesRepo)
-			companies := NewCompanies(companiesRepo)
+			companies := handlers.NewCompanies(companiesRepo)
 			c.setupMock(companiesRepo)
 
 			req := httptest.NewRequest(c.method, c.path, nil)
@@ -169,13 +755,79 @@ func TestCompanies(t *testing.T) {
 
 			mw := dymmyMw{}
 
-			srv := server.NewServer(chi.NewMux(), companies, mw, mw, mw)
+			srv := server.NewServer(chi.NewMux(), server.Handlers{
+				Companies: companies,
+				Contacts:  handlers.NewContacts(new(mocks.ContactsRepo)),
+				Webhooks:  handlers.NewWebhooks(new(mocks.WebhooksRepo)),
+				APIKeys:   handlers.NewAPIKeys(new(mocks.APIKeysRepo)),
+				Stats:     handlers.NewStats(nil),
+			}, server.Middlewares{
+				Company: mw,
+				Trashed: mw,
+				IfMatch: mw,
+				Payload: mw,
+				Patch:   mw,
+				Search:  mw,
+				Bulk:    mw,
+				Import:  mw,
+				Export:  mw,
+				Contact: mw,
+				Key:     mw,
+				Ip:      mw,
+				IpLimit: mw,
+				APIKey:  mw,
+				Auth:    mw,
+				Limit:   mw,
+				Policy:  mw,
+				Admin:   mw,
+				Audit:   mw,
+				Tenant:  mw,
+			})
 			srv.ServeHTTP(w, req.WithContext(c.setupCtx(req)))
 
 			assert.Equal(t, c.statusCode, w.Result().StatusCode)
 			assert.Equal(t, c.response, strings.Trim(w.Body.String(), "\n"))
+			assert.Equal(t, c.etag, w.Header().Get("ETag"))
+			if c.filename != "" {
+				assert.Equal(t, `attachment; filename=`+c.filename, w.Header().Get("Content-Disposition"))
+			}
 
 			companiesRepo.AssertExpectations(t)
 		})
 	}
 }
+
+// runTransaction runs the transaction function with the given repository.
+func runTransaction(companiesRepo *mocks.CompaniesRepo) func(mock.Arguments) {
+	return func(args mock.Arguments) {
+		args.Get(1).(func(handlers.CompaniesRepo) error)(companiesRepo)
+	}
+}
+
+// withImport returns the context with the import of the body, the media type is detected by the first character.
+func withImport(req *http.Request, body string, maxRows int, dryRun bool) context.Context {
+	mediaType := requests.CSVMediaType
+	if strings.HasPrefix(body, "{") {
+		mediaType = requests.NDJSONMediaType
+	}
+
+	validate := func(p *requests.CompanyPayload) []string {
+		if p.Name == "" {
+			return []string{"name is required"}
+		}
+		return nil
+	}
+
+	data, err := requests.NewCompanyImport(mediaType, strings.NewReader(body), maxRows, validate)
+	if err != nil {
+		panic(err)
+	}
+	data.DryRun = dryRun
+
+	return context.WithValue(req.Context(), handlers.CompanyImportCtxKey{}, data)
+}
+
+// parentID returns the reference to the parent company with the given id.
+func parentID(id uint64) *uint64 {
+	return &id
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/api/responses"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// ContactPayloadCtxKey is a key used for the Contact payload object in the context
+type ContactPayloadCtxKey struct{}
+
+// ContactsRepo repository
+type ContactsRepo interface {
+	CreateContact(context.Context, models.Contact) (models.Contact, error)
+	GetContact(ctx context.Context, companyID uint64, id uint64) (models.Contact, error)
+	GetContacts(ctx context.Context, companyID uint64) ([]models.Contact, error)
+	UpdateContact(context.Context, models.Contact) (models.Contact, error)
+	DeleteContact(ctx context.Context, companyID uint64, id uint64) error
+}
+
+// contacts handler, for managing the contacts of the company.
+// The company is loaded to the context by the CompanyCtx middleware.
+type contacts struct {
+	contactsRepo ContactsRepo
+}
+
+// NewContacts returns a new contacts handler with the given repository.
+func NewContacts(c ContactsRepo) *contacts {
+	return &contacts{contactsRepo: c}
+}
+
+// HandleContactCreate handles POST requests to add a contact to the company.
+func (h *contacts) HandleContactCreate(w http.ResponseWriter, r *http.Request) {
+	company := r.Context().Value(CompanyCtxKey{}).(models.Company)
+	data := r.Context().Value(ContactPayloadCtxKey{}).(requests.ContactPayload)
+
+	contact, err := h.contactsRepo.CreateContact(r.Context(), data.ToContact(company.ID))
+	if errors.Is(err, models.ErrCompanyNotFound) {
+		render.Render(w, r, &responses.ErrResponse{Message: "Company not found", HTTPStatusCode: http.StatusNotFound})
+		return
+	}
+
+	if err != nil {
+		render.Render(w, r, responses.NewStorageErrResponse(err, "Cannot create contact"))
+		return
+	}
+
+	render.Render(w, r, &responses.ContactResponse{Contact: &contact, HTTPStatusCode: http.StatusCreated})
+}
+
+// HandleContactGetAll handles GET requests to display all contacts of the company.
+func (h *contacts) HandleContactGetAll(w http.ResponseWriter, r *http.Request) {
+	company := r.Context().Value(CompanyCtxKey{}).(models.Company)
+
+	contacts, err := h.contactsRepo.GetContacts(r.Context(), company.ID)
+	if err != nil {
+		render.Render(w, r, responses.NewStorageErrResponse(err, "Cannot retrieve contacts"))
+		return
+	}
+
+	render.Render(w, r, &responses.ContactsResponse{Contacts: contacts, HTTPStatusCode: http.StatusOK})
+}
+
+// HandleContactGetOne handles GET requests to display single contact of the company.
+func (h *contacts) HandleContactGetOne(w http.ResponseWriter, r *http.Request) {
+	company := r.Context().Value(CompanyCtxKey{}).(models.Company)
+
+	id, err := contactID(r)
+	if err != nil {
+		render.Render(w, r, &responses.ErrResponse{Message: "Invalid ID", HTTPStatusCode: http.StatusBadRequest})
+		return
+	}
+
+	contact, err := h.contactsRepo.GetContact(r.Context(), company.ID, id)
+	if err != nil {
+		render.Render(w, r, contactErrResponse(err, "Cannot query contact"))
+		return
+	}
+
+	render.Render(w, r, &responses.ContactResponse{Contact: &contact, HTTPStatusCode: http.StatusOK})
+}
+
+// HandleContactUpdate handles PUT requests to replace the contact of the company.
+func (h *contacts) HandleContactUpdate(w http.ResponseWriter, r *http.Request) {
+	company := r.Context().Value(CompanyCtxKey{}).(models.Company)
+	data := r.Context().Value(ContactPayloadCtxKey{}).(requests.ContactPayload)
+
+	id, err := contactID(r)
+	if err != nil {
+		render.Render(w, r, &responses.ErrResponse{Message: "Invalid ID", HTTPStatusCode: http.StatusBadRequest})
+		return
+	}
+
+	contact := data.ToContact(company.ID)
+	contact.ID = id
+
+	contact, err = h.contactsRepo.UpdateContact(r.Context(), contact)
+	if err != nil {
+		render.Render(w, r, contactErrResponse(err, "Cannot update contact"))
+		return
+	}
+
+	render.Render(w, r, &responses.ContactResponse{Contact: &contact, HTTPStatusCode: http.StatusOK})
+}
+
+// HandleContactDelete handles DELETE requests to remove the contact of the company.
+func (h *contacts) HandleContactDelete(w http.ResponseWriter, r *http.Request) {
+	company := r.Context().Value(CompanyCtxKey{}).(models.Company)
+
+	id, err := contactID(r)
+	if err != nil {
+		render.Render(w, r, &responses.ErrResponse{Message: "Invalid ID", HTTPStatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if err := h.contactsRepo.DeleteContact(r.Context(), company.ID, id); err != nil {
+		render.Render(w, r, contactErrResponse(err, "Cannot remove contact"))
+		return
+	}
+
+	render.Render(w, r, &responses.ErrResponse{Message: "Contact removed", HTTPStatusCode: http.StatusOK})
+}
+
+// contactID parses the contact ID from the URL.
+func contactID(r *http.Request) (uint64, error) {
+	return strconv.ParseUint(chi.URLParam(r, "contactID"), 10, 64)
+}
+
+// contactErrResponse maps the contacts repository error to the response.
+func contactErrResponse(err error, msg string) render.Renderer {
+	if errors.Is(err, models.ErrContactNotFound) {
+		return &responses.ErrResponse{Message: "Contact not found", HTTPStatusCode: http.StatusNotFound}
+	}
+	return responses.NewStorageErrResponse(err, msg)
+}
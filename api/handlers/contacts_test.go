@@ -0,0 +1,207 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/api/server"
+	"github.com/brokeyourbike/xm-golang-exercise/mocks"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestContacts(t *testing.T) {
+	contact := models.Contact{ID: 2, CompanyID: 30, Name: "john", Role: "CEO", Email: "john@example.com", Phone: "+35799123456"}
+	payload := requests.ContactPayload{Name: "john", Role: "CEO", Email: "john@example.com", Phone: "+35799123456"}
+
+	cases := map[string]struct {
+		method     string
+		path       string
+		statusCode int
+		response   string
+		payload    bool
+		setupMock  func(contactsRepo *mocks.ContactsRepo)
+	}{
+		"list contacts": {
+			method:     http.MethodGet,
+			path:       "/companies/30/contacts",
+			statusCode: http.StatusOK,
+			response:   `{"contacts":[{"id":2,"company_id":30,"name":"john","role":"CEO","email":"john@example.com","phone":"+35799123456"}]}`,
+			setupMock: func(contactsRepo *mocks.ContactsRepo) {
+				contactsRepo.On("GetContacts", mock.Anything, uint64(30)).Return([]models.Contact{contact}, nil)
+			},
+		},
+		"cannot list contacts": {
+			method:     http.MethodGet,
+			path:       "/companies/30/contacts",
+			statusCode: http.StatusInternalServerError,
+			response:   `{"message":"Cannot retrieve contacts"}`,
+			setupMock: func(contactsRepo *mocks.ContactsRepo) {
+				contactsRepo.On("GetContacts", mock.Anything, uint64(30)).Return([]models.Contact{}, errors.New("err"))
+			},
+		},
+		"create contact": {
+			method:     http.MethodPost,
+			path:       "/companies/30/contacts",
+			statusCode: http.StatusCreated,
+			response:   `{"id":2,"company_id":30,"name":"john","role":"CEO","email":"john@example.com","phone":"+35799123456"}`,
+			payload:    true,
+			setupMock: func(contactsRepo *mocks.ContactsRepo) {
+				contactsRepo.On("CreateContact", mock.Anything, payload.ToContact(30)).Return(contact, nil)
+			},
+		},
+		"cannot create contact of deleted company": {
+			method:     http.MethodPost,
+			path:       "/companies/30/contacts",
+			statusCode: http.StatusNotFound,
+			response:   `{"message":"Company not found"}`,
+			payload:    true,
+			setupMock: func(contactsRepo *mocks.ContactsRepo) {
+				contactsRepo.On("CreateContact", mock.Anything, payload.ToContact(30)).Return(models.Contact{}, models.ErrCompanyNotFound)
+			},
+		},
+		"cannot create contact": {
+			method:     http.MethodPost,
+			path:       "/companies/30/contacts",
+			statusCode: http.StatusInternalServerError,
+			response:   `{"message":"Cannot create contact"}`,
+			payload:    true,
+			setupMock: func(contactsRepo *mocks.ContactsRepo) {
+				contactsRepo.On("CreateContact", mock.Anything, payload.ToContact(30)).Return(models.Contact{}, errors.New("err"))
+			},
+		},
+		"view contact": {
+			method:     http.MethodGet,
+			path:       "/companies/30/contacts/2",
+			statusCode: http.StatusOK,
+			response:   `{"id":2,"company_id":30,"name":"john","role":"CEO","email":"john@example.com","phone":"+35799123456"}`,
+			setupMock: func(contactsRepo *mocks.ContactsRepo) {
+				contactsRepo.On("GetContact", mock.Anything, uint64(30), uint64(2)).Return(contact, nil)
+			},
+		},
+		"cannot view missing contact": {
+			method:     http.MethodGet,
+			path:       "/companies/30/contacts/2",
+			statusCode: http.StatusNotFound,
+			response:   `{"message":"Contact not found"}`,
+			setupMock: func(contactsRepo *mocks.ContactsRepo) {
+				contactsRepo.On("GetContact", mock.Anything, uint64(30), uint64(2)).Return(models.Contact{}, models.ErrContactNotFound)
+			},
+		},
+		"cannot view contact with invalid id": {
+			method:     http.MethodGet,
+			path:       "/companies/30/contacts/99999999999999999999",
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid ID"}`,
+			setupMock:  func(contactsRepo *mocks.ContactsRepo) {},
+		},
+		"update contact": {
+			method:     http.MethodPut,
+			path:       "/companies/30/contacts/2",
+			statusCode: http.StatusOK,
+			response:   `{"id":2,"company_id":30,"name":"john","role":"CEO","email":"john@example.com","phone":"+35799123456"}`,
+			payload:    true,
+			setupMock: func(contactsRepo *mocks.ContactsRepo) {
+				contactsRepo.On("UpdateContact", mock.Anything, contact).Return(contact, nil)
+			},
+		},
+		"cannot update missing contact": {
+			method:     http.MethodPut,
+			path:       "/companies/30/contacts/2",
+			statusCode: http.StatusNotFound,
+			response:   `{"message":"Contact not found"}`,
+			payload:    true,
+			setupMock: func(contactsRepo *mocks.ContactsRepo) {
+				contactsRepo.On("UpdateContact", mock.Anything, contact).Return(contact, models.ErrContactNotFound)
+			},
+		},
+		"cannot update contact": {
+			method:     http.MethodPut,
+			path:       "/companies/30/contacts/2",
+			statusCode: http.StatusInternalServerError,
+			response:   `{"message":"Cannot update contact"}`,
+			payload:    true,
+			setupMock: func(contactsRepo *mocks.ContactsRepo) {
+				contactsRepo.On("UpdateContact", mock.Anything, contact).Return(contact, errors.New("err"))
+			},
+		},
+		"delete contact": {
+			method:     http.MethodDelete,
+			path:       "/companies/30/contacts/2",
+			statusCode: http.StatusOK,
+			response:   `{"message":"Contact removed"}`,
+			setupMock: func(contactsRepo *mocks.ContactsRepo) {
+				contactsRepo.On("DeleteContact", mock.Anything, uint64(30), uint64(2)).Return(nil)
+			},
+		},
+		"cannot delete missing contact": {
+			method:     http.MethodDelete,
+			path:       "/companies/30/contacts/2",
+			statusCode: http.StatusNotFound,
+			response:   `{"message":"Contact not found"}`,
+			setupMock: func(contactsRepo *mocks.ContactsRepo) {
+				contactsRepo.On("DeleteContact", mock.Anything, uint64(30), uint64(2)).Return(models.ErrContactNotFound)
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			contactsRepo := new(mocks.ContactsRepo)
+			c.setupMock(contactsRepo)
+
+			req := httptest.NewRequest(c.method, c.path, nil)
+			ctx := context.WithValue(req.Context(), handlers.CompanyCtxKey{}, models.Company{ID: 30})
+			if c.payload {
+				ctx = context.WithValue(ctx, handlers.ContactPayloadCtxKey{}, payload)
+			}
+
+			w := httptest.NewRecorder()
+
+			mw := dymmyMw{}
+
+			srv := server.NewServer(chi.NewMux(), server.Handlers{
+				Companies: handlers.NewCompanies(new(mocks.CompaniesRepo)),
+				Contacts:  handlers.NewContacts(contactsRepo),
+				Webhooks:  handlers.NewWebhooks(new(mocks.WebhooksRepo)),
+				APIKeys:   handlers.NewAPIKeys(new(mocks.APIKeysRepo)),
+				Stats:     handlers.NewStats(nil),
+			}, server.Middlewares{
+				Company: mw,
+				Trashed: mw,
+				IfMatch: mw,
+				Payload: mw,
+				Patch:   mw,
+				Search:  mw,
+				Bulk:    mw,
+				Import:  mw,
+				Export:  mw,
+				Contact: mw,
+				Key:     mw,
+				Ip:      mw,
+				IpLimit: mw,
+				APIKey:  mw,
+				Auth:    mw,
+				Limit:   mw,
+				Policy:  mw,
+				Admin:   mw,
+				Audit:   mw,
+				Tenant:  mw,
+			})
+			srv.ServeHTTP(w, req.WithContext(ctx))
+
+			assert.Equal(t, c.statusCode, w.Result().StatusCode)
+			assert.Equal(t, c.response, strings.Trim(w.Body.String(), "\n"))
+
+			contactsRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"mime"
+	"net/http"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/api/responses"
+	"github.com/go-chi/render"
+	log "github.com/sirupsen/logrus"
+)
+
+// HandleCompanyExport handles GET requests to export companies as CSV, NDJSON or XLSX.
+// It accepts the same filters and sort as HandleCompanyGetAll, limit and cursor are ignored.
+// Companies are read in batches and every batch is sent to the client before the next one is read.
+// Errors of the first batch are returned as usual, later errors abort the response,
+// so the client does not receive a truncated file as a complete one.
+func (c *companies) HandleCompanyExport(w http.ResponseWriter, r *http.Request) {
+	data := r.Context().Value(ExportQueryCtxKey{}).(requests.ExportQuery)
+	query := r.Context().Value(CompaniesQueryCtxKey{}).(requests.CompaniesQuery)
+
+	query.Limit, query.Cursor = data.BatchSize, ""
+
+	page, err := c.companiesRepo.GetAll(r.Context(), query)
+	if err != nil {
+		render.Render(w, r, responses.NewStorageErrResponse(err, "Cannot retrieve companies"))
+		return
+	}
+
+	w.Header().Set("Content-Type", data.MediaType())
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": data.Filename()}))
+	w.WriteHeader(http.StatusOK)
+
+	export, err := responses.NewExportWriter(data.Format, w)
+	if err != nil {
+		abortExport(w, err)
+		return
+	}
+
+	for {
+		for _, company := range page.Companies {
+			if err := export.Write(company); err != nil {
+				abortExport(w, err)
+				return
+			}
+		}
+
+		if err := export.Flush(); err != nil {
+			abortExport(w, err)
+			return
+		}
+
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+
+		if !page.HasMore {
+			break
+		}
+
+		query.Cursor = page.NextCursor
+
+		if page, err = c.companiesRepo.GetAll(r.Context(), query); err != nil {
+			abortExport(w, err)
+			return
+		}
+	}
+
+	if err := export.Close(); err != nil {
+		abortExport(w, err)
+	}
+}
+
+// abortExport closes the connection of the export response, which was already started.
+func abortExport(w http.ResponseWriter, err error) {
+	log.WithFields(log.Fields{"error": err}).Error("Export aborted")
+
+	if h, ok := w.(http.Hijacker); ok {
+		if conn, _, err := h.Hijack(); err == nil {
+			conn.Close()
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/responses"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/go-chi/render"
+)
+
+// HandleCompanyChildren handles GET requests to return the direct subsidiaries of the company.
+func (c *companies) HandleCompanyChildren(w http.ResponseWriter, r *http.Request) {
+	company := r.Context().Value(CompanyCtxKey{}).(models.Company)
+
+	children, err := c.companiesRepo.Children(r.Context(), company.ID)
+	if err != nil {
+		render.Render(w, r, responses.NewStorageErrResponse(err, "Cannot retrieve subsidiaries"))
+		return
+	}
+
+	render.Render(w, r, &responses.CompaniesResponse{Companies: children, HTTPStatusCode: http.StatusOK})
+}
+
+// HandleCompanyAncestors handles GET requests to return the parents of the company,
+// starting from the direct parent.
+func (c *companies) HandleCompanyAncestors(w http.ResponseWriter, r *http.Request) {
+	company := r.Context().Value(CompanyCtxKey{}).(models.Company)
+
+	ancestors, err := c.companiesRepo.Ancestors(r.Context(), company.ID)
+	if err != nil {
+		render.Render(w, r, errResponse(err, "Cannot retrieve parent companies"))
+		return
+	}
+
+	render.Render(w, r, &responses.CompaniesResponse{Companies: ancestors, HTTPStatusCode: http.StatusOK})
+}
+
+// HandleCompanyTree handles GET requests to return the whole group of the company,
+// as a tree starting from the top-most parent.
+func (c *companies) HandleCompanyTree(w http.ResponseWriter, r *http.Request) {
+	company := r.Context().Value(CompanyCtxKey{}).(models.Company)
+
+	ancestors, err := c.companiesRepo.Ancestors(r.Context(), company.ID)
+	if err != nil {
+		render.Render(w, r, errResponse(err, "Cannot retrieve company group"))
+		return
+	}
+
+	root := company
+	if len(ancestors) > 0 {
+		root = ancestors[len(ancestors)-1]
+	}
+
+	descendants, err := c.companiesRepo.Descendants(r.Context(), root.ID)
+	if err != nil {
+		render.Render(w, r, responses.NewStorageErrResponse(err, "Cannot retrieve company group"))
+		return
+	}
+
+	render.Render(w, r, &responses.CompanyTreeResponse{CompanyTree: models.NewCompanyTree(root, descendants), HTTPStatusCode: http.StatusOK})
+}
+
+// deleteReparenting moves the subsidiaries of the company to its parent and deletes the company,
+// in a single transaction. The subsidiaries become top-most companies, if the company has no parent.
+func deleteReparenting(r *http.Request, repo CompaniesRepo, company models.Company) error {
+	return repo.Transaction(r.Context(), func(tx CompaniesRepo) error {
+		children, err := tx.Children(r.Context(), company.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, child := range children {
+			child.ParentID = company.ParentID
+			if _, _, err := tx.Update(r.Context(), child); err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(r.Context(), company)
+	})
+}
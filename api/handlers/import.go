@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/api/responses"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/go-chi/render"
+)
+
+// HandleCompanyImport handles POST requests to import companies from CSV or NDJSON.
+// Every row is upserted by the company code independently, in the order of the rows.
+// In the dry-run mode the rows are only validated and looked up, nothing is written.
+// The import is stopped if the body cannot be read or has too many rows,
+// rows processed before are kept and reported with the status code of the error.
+func (c *companies) HandleCompanyImport(w http.ResponseWriter, r *http.Request) {
+	data := r.Context().Value(CompanyImportCtxKey{}).(*requests.CompanyImport)
+
+	resp := responses.ImportResponse{DryRun: data.DryRun, Results: []responses.ImportResult{}, HTTPStatusCode: http.StatusOK}
+
+	for {
+		row, err := data.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if errors.Is(err, requests.ErrTooManyRows) {
+			resp.Error = &responses.ErrResponse{
+				Message:        "Too many rows",
+				Errors:         []string{fmt.Sprintf("at most %d rows are allowed, rows from line %d were not imported", data.MaxRows, row.Line)},
+				HTTPStatusCode: http.StatusRequestEntityTooLarge,
+			}
+			break
+		}
+
+		if err != nil {
+			resp.Error = &responses.ErrResponse{Message: "Cannot read request body", Errors: []string{err.Error()}, HTTPStatusCode: http.StatusBadRequest}
+			break
+		}
+
+		resp.Add(importRow(r.Context(), c.companiesRepo, data.DryRun, row))
+	}
+
+	render.Render(w, r, &resp)
+}
+
+// importRow creates the company from the row, or updates the company with the same code.
+// Failures are reported the same as for the single-item requests.
+func importRow(ctx context.Context, repo CompaniesRepo, dryRun bool, row requests.ImportRow) responses.ImportResult {
+	result := responses.ImportResult{Line: row.Line}
+
+	fail := func(e *responses.ErrResponse) responses.ImportResult {
+		result.Status = responses.ImportRejected
+		result.Error = e
+		return result
+	}
+
+	if !row.Valid() {
+		return fail(&responses.ErrResponse{Message: "Invalid request data", Errors: row.Errors, HTTPStatusCode: http.StatusBadRequest})
+	}
+
+	company := row.Payload.ToCompany()
+
+	existing, err := repo.GetByCode(ctx, company.Code, company.Country)
+	if err != nil && !errors.Is(err, models.ErrCompanyNotFound) {
+		return fail(errResponse(err, "Cannot retrieve company"))
+	}
+
+	if err != nil {
+		result.Status = responses.ImportAccepted
+		if dryRun {
+			return result
+		}
+
+		company, err = repo.Create(ctx, company)
+		if err != nil {
+			return fail(errResponse(err, "Company cannot be created"))
+		}
+
+		result.ID = company.ID
+		return result
+	}
+
+	result.Status, result.ID = responses.ImportUpdated, existing.ID
+	if dryRun {
+		return result
+	}
+
+	company.ID = existing.ID
+	company.Version = existing.Version
+
+	// CSV has no parent column, so the parent is kept unless the row sets it
+	if company.ParentID == nil {
+		company.ParentID = existing.ParentID
+	}
+
+	if _, _, err := repo.Update(ctx, company); err != nil {
+		return fail(errResponse(err, "Cannot update company"))
+	}
+
+	return result
+}
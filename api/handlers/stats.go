@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/responses"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/go-chi/render"
+)
+
+// CacheStatsRepo provides the counters of the companies cache.
+type CacheStatsRepo interface {
+	Stats() models.CacheStats
+}
+
+// stats handler, for reading the counters of the service.
+type stats struct {
+	cache CacheStatsRepo
+}
+
+// NewStats returns a new stats handler with the given cache, nil if the cache is disabled.
+func NewStats(cache CacheStatsRepo) *stats {
+	return &stats{cache: cache}
+}
+
+// HandleCacheStats handles GET requests to display the hits and misses of the companies cache.
+func (h *stats) HandleCacheStats(w http.ResponseWriter, r *http.Request) {
+	if h.cache == nil {
+		render.Render(w, r, &responses.ErrResponse{Message: "Cache is disabled", HTTPStatusCode: http.StatusNotFound})
+		return
+	}
+
+	render.Render(w, r, &responses.CacheStatsResponse{CacheStats: h.cache.Stats(), HTTPStatusCode: http.StatusOK})
+}
@@ -0,0 +1,95 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/server"
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/db/cached"
+	"github.com/brokeyourbike/xm-golang-exercise/db/memory"
+	"github.com/brokeyourbike/xm-golang-exercise/mocks"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/coocood/freecache"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheStats(t *testing.T) {
+	cfg := configs.Config{}
+	cfg.Companies.CacheTTLSeconds = 60
+	repo := cached.NewCompaniesRepo(&cfg, memory.NewCompaniesRepo(&cfg), freecache.NewCache(1024*1024))
+
+	company, err := repo.Create(context.Background(), models.Company{Name: "acme", Code: "c1"})
+	require.NoError(t, err)
+
+	// the first read is a miss, the second is served from the cache
+	for i := 0; i < 2; i++ {
+		_, err = repo.Get(context.Background(), company.ID)
+		require.NoError(t, err)
+	}
+
+	cases := map[string]struct {
+		cache      handlers.CacheStatsRepo
+		statusCode int
+		response   string
+	}{
+		"cache stats": {
+			cache:      repo,
+			statusCode: http.StatusOK,
+			response:   `{"hits":1,"misses":1}`,
+		},
+		"cache is disabled": {
+			cache:      nil,
+			statusCode: http.StatusNotFound,
+			response:   `{"message":"Cache is disabled"}`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/stats/cache", nil)
+			w := httptest.NewRecorder()
+
+			mw := dymmyMw{}
+
+			srv := server.NewServer(chi.NewMux(), server.Handlers{
+				Companies: handlers.NewCompanies(new(mocks.CompaniesRepo)),
+				Contacts:  handlers.NewContacts(new(mocks.ContactsRepo)),
+				Webhooks:  handlers.NewWebhooks(new(mocks.WebhooksRepo)),
+				APIKeys:   handlers.NewAPIKeys(new(mocks.APIKeysRepo)),
+				Stats:     handlers.NewStats(c.cache),
+			}, server.Middlewares{
+				Company: mw,
+				Trashed: mw,
+				IfMatch: mw,
+				Payload: mw,
+				Patch:   mw,
+				Search:  mw,
+				Bulk:    mw,
+				Import:  mw,
+				Export:  mw,
+				Contact: mw,
+				Key:     mw,
+				Ip:      mw,
+				IpLimit: mw,
+				APIKey:  mw,
+				Auth:    mw,
+				Limit:   mw,
+				Policy:  mw,
+				Admin:   mw,
+				Audit:   mw,
+				Tenant:  mw,
+			})
+			srv.ServeHTTP(w, req)
+
+			assert.Equal(t, c.statusCode, w.Result().StatusCode)
+			assert.Equal(t, c.response, strings.Trim(w.Body.String(), "\n"))
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/responses"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// WebhooksRepo repository
+type WebhooksRepo interface {
+	DeadDeliveries(context.Context) ([]models.Delivery, error)
+	Redeliver(context.Context, uint64) (models.Delivery, error)
+}
+
+// webhooks handler, for managing the webhook deliveries.
+type webhooks struct {
+	webhooksRepo WebhooksRepo
+}
+
+// NewWebhooks returns a new webhooks handler with the given repository.
+func NewWebhooks(w WebhooksRepo) *webhooks {
+	return &webhooks{webhooksRepo: w}
+}
+
+// HandleDeadDeliveries handles GET requests to display deliveries, which exhausted their attempts.
+func (h *webhooks) HandleDeadDeliveries(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := h.webhooksRepo.DeadDeliveries(r.Context())
+	if err != nil {
+		render.Render(w, r, responses.NewStorageErrResponse(err, "Cannot retrieve deliveries"))
+		return
+	}
+
+	render.Render(w, r, &responses.DeliveriesResponse{Deliveries: deliveries, HTTPStatusCode: http.StatusOK})
+}
+
+// HandleRedeliver handles POST requests to deliver the event again.
+// The delivery is scheduled immediately, with all attempts available.
+func (h *webhooks) HandleRedeliver(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		render.Render(w, r, &responses.ErrResponse{Message: "Invalid ID", HTTPStatusCode: http.StatusBadRequest})
+		return
+	}
+
+	delivery, err := h.webhooksRepo.Redeliver(r.Context(), id)
+	if errors.Is(err, models.ErrDeliveryNotFound) {
+		render.Render(w, r, &responses.ErrResponse{Message: "Delivery not found", HTTPStatusCode: http.StatusNotFound})
+		return
+	}
+
+	if err != nil {
+		render.Render(w, r, responses.NewStorageErrResponse(err, "Cannot redeliver"))
+		return
+	}
+
+	render.Render(w, r, &responses.DeliveryResponse{Delivery: &delivery, HTTPStatusCode: http.StatusAccepted})
+}
@@ -0,0 +1,143 @@
+package handlers_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/server"
+	"github.com/brokeyourbike/xm-golang-exercise/mocks"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestWebhooks(t *testing.T) {
+	at := time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC)
+	delivery := models.Delivery{
+		ID:            2,
+		EventID:       1,
+		Event:         models.Event{ID: 1, Type: models.EventCompanyCreated, CompanyID: 3, CreatedAt: at},
+		URL:           "https://example.com/hook",
+		Status:        models.DeliveryDead,
+		Attempts:      8,
+		NextAttemptAt: at,
+		LastError:     "unexpected status 500",
+		CreatedAt:     at,
+	}
+
+	cases := map[string]struct {
+		method     string
+		path       string
+		statusCode int
+		response   string
+		setupMock  func(webhooksRepo *mocks.WebhooksRepo)
+	}{
+		"list dead deliveries": {
+			method:     http.MethodGet,
+			path:       "/webhooks/deliveries/dead",
+			statusCode: http.StatusOK,
+			response:   `{"deliveries":[{"id":2,"event_id":1,"event":{"id":1,"type":"company.created","company_id":3,"created_at":"2022-05-10T12:00:00Z"},"url":"https://example.com/hook","status":"dead","attempts":8,"next_attempt_at":"2022-05-10T12:00:00Z","last_error":"unexpected status 500","created_at":"2022-05-10T12:00:00Z"}]}`,
+			setupMock: func(webhooksRepo *mocks.WebhooksRepo) {
+				webhooksRepo.On("DeadDeliveries", mock.Anything).Return([]models.Delivery{delivery}, nil)
+			},
+		},
+		"cannot list dead deliveries": {
+			method:     http.MethodGet,
+			path:       "/webhooks/deliveries/dead",
+			statusCode: http.StatusInternalServerError,
+			response:   `{"message":"Cannot retrieve deliveries"}`,
+			setupMock: func(webhooksRepo *mocks.WebhooksRepo) {
+				webhooksRepo.On("DeadDeliveries", mock.Anything).Return([]models.Delivery{}, errors.New("err"))
+			},
+		},
+		"redeliver": {
+			method:     http.MethodPost,
+			path:       "/webhooks/deliveries/2/redeliver",
+			statusCode: http.StatusAccepted,
+			response:   `{"id":2,"event_id":1,"event":{"id":1,"type":"company.created","company_id":3,"created_at":"2022-05-10T12:00:00Z"},"url":"https://example.com/hook","status":"pending","attempts":0,"next_attempt_at":"2022-05-10T12:00:00Z","created_at":"2022-05-10T12:00:00Z"}`,
+			setupMock: func(webhooksRepo *mocks.WebhooksRepo) {
+				pending := delivery
+				pending.Status, pending.Attempts, pending.LastError = models.DeliveryPending, 0, ""
+				webhooksRepo.On("Redeliver", mock.Anything, uint64(2)).Return(pending, nil)
+			},
+		},
+		"cannot redeliver missing delivery": {
+			method:     http.MethodPost,
+			path:       "/webhooks/deliveries/2/redeliver",
+			statusCode: http.StatusNotFound,
+			response:   `{"message":"Delivery not found"}`,
+			setupMock: func(webhooksRepo *mocks.WebhooksRepo) {
+				webhooksRepo.On("Redeliver", mock.Anything, uint64(2)).Return(models.Delivery{}, models.ErrDeliveryNotFound)
+			},
+		},
+		"cannot redeliver": {
+			method:     http.MethodPost,
+			path:       "/webhooks/deliveries/2/redeliver",
+			statusCode: http.StatusInternalServerError,
+			response:   `{"message":"Cannot redeliver"}`,
+			setupMock: func(webhooksRepo *mocks.WebhooksRepo) {
+				webhooksRepo.On("Redeliver", mock.Anything, uint64(2)).Return(models.Delivery{}, errors.New("err"))
+			},
+		},
+		"cannot redeliver with invalid id": {
+			method:     http.MethodPost,
+			path:       "/webhooks/deliveries/99999999999999999999/redeliver",
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid ID"}`,
+			setupMock:  func(webhooksRepo *mocks.WebhooksRepo) {},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			webhooksRepo := new(mocks.WebhooksRepo)
+			c.setupMock(webhooksRepo)
+
+			req := httptest.NewRequest(c.method, c.path, nil)
+			w := httptest.NewRecorder()
+
+			mw := dymmyMw{}
+
+			srv := server.NewServer(chi.NewMux(), server.Handlers{
+				Companies: handlers.NewCompanies(new(mocks.CompaniesRepo)),
+				Contacts:  handlers.NewContacts(new(mocks.ContactsRepo)),
+				Webhooks:  handlers.NewWebhooks(webhooksRepo),
+				APIKeys:   handlers.NewAPIKeys(new(mocks.APIKeysRepo)),
+				Stats:     handlers.NewStats(nil),
+			}, server.Middlewares{
+				Company: mw,
+				Trashed: mw,
+				IfMatch: mw,
+				Payload: mw,
+				Patch:   mw,
+				Search:  mw,
+				Bulk:    mw,
+				Import:  mw,
+				Export:  mw,
+				Contact: mw,
+				Key:     mw,
+				Ip:      mw,
+				IpLimit: mw,
+				APIKey:  mw,
+				Auth:    mw,
+				Limit:   mw,
+				Policy:  mw,
+				Admin:   mw,
+				Audit:   mw,
+				Tenant:  mw,
+			})
+			srv.ServeHTTP(w, req)
+
+			assert.Equal(t, c.statusCode, w.Result().StatusCode)
+			assert.Equal(t, c.response, strings.Trim(w.Body.String(), "\n"))
+
+			webhooksRepo.AssertExpectations(t)
+		})
+	}
+}
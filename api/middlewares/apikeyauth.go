@@ -0,0 +1,92 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/responses"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/apikey"
+	"github.com/go-chi/render"
+	log "github.com/sirupsen/logrus"
+)
+
+// APIKeyHeader is the header used to send the API key.
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyTouchInterval is how often the last use of the API key is recorded,
+// so not every request writes to the storage.
+const apiKeyTouchInterval = time.Minute
+
+// APIKeyAuth is a middleware used to authenticate the requests with the API keys.
+type APIKeyAuth struct {
+	apiKeysRepo handlers.APIKeysRepo
+	now         func() time.Time
+}
+
+// NewAPIKeyAuth creates an instance of APIKeyAuth middleware.
+func NewAPIKeyAuth(r handlers.APIKeysRepo) *APIKeyAuth {
+	return &APIKeyAuth{apiKeysRepo: r, now: time.Now}
+}
+
+// Handle is used to verify the API key, and load the principal to context.
+// The API key is bound to the tenant it was created for, the tenant is loaded to context as well.
+// Requests without the API key are passed to the next middleware, which can authenticate them otherwise.
+func (a *APIKeyAuth) Handle(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		secret := r.Header.Get(APIKeyHeader)
+		if secret == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		prefix, ok := apikey.Prefix(secret)
+		if !ok {
+			render.Render(w, r, &responses.ErrResponse{Message: "Invalid API key", HTTPStatusCode: http.StatusUnauthorized})
+			return
+		}
+
+		key, err := a.apiKeysRepo.GetAPIKeyByPrefix(r.Context(), prefix)
+		if err != nil && !errors.Is(err, models.ErrAPIKeyNotFound) {
+			render.Render(w, r, responses.NewStorageErrResponse(err, "Cannot query API key"))
+			return
+		}
+
+		if err != nil || !apikey.Verify(secret, key.Hash) {
+			log.WithFields(log.Fields{"prefix": prefix}).Warn("API key invalid")
+			render.Render(w, r, &responses.ErrResponse{Message: "Invalid API key", HTTPStatusCode: http.StatusUnauthorized})
+			return
+		}
+
+		now := a.now()
+
+		if !key.Active(now) {
+			log.WithFields(log.Fields{"prefix": prefix}).Warn("API key revoked or expired")
+			render.Render(w, r, &responses.ErrResponse{Message: "Invalid API key", Errors: []string{"API key is revoked or expired"}, HTTPStatusCode: http.StatusUnauthorized})
+			return
+		}
+
+		if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+			if err := a.apiKeysRepo.TouchAPIKey(r.Context(), key.ID, now); err != nil {
+				log.WithFields(log.Fields{"prefix": prefix, "error": err}).Warn("Cannot record API key use")
+			}
+		}
+
+		principal := models.Principal{
+			Subject: "api-key:" + key.Prefix,
+			Tenant:  key.TenantID,
+			APIKey:  key.Prefix,
+			Scopes:  key.Scopes,
+		}
+
+		ctx := models.WithPrincipal(r.Context(), principal)
+		if principal.Tenant != "" {
+			ctx = models.WithTenant(ctx, principal.Tenant)
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+	return http.HandlerFunc(fn)
+}
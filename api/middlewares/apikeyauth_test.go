@@ -0,0 +1,144 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/db/memory"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/apikey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	now := time.Date(2022, 4, 1, 10, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	cases := map[string]struct {
+		key        models.APIKey
+		header     func(secret string) string
+		statusCode int
+		response   string
+		principal  models.Principal
+	}{
+		"valid key": {
+			key:        models.APIKey{Scopes: models.Scopes{models.ScopeCompaniesRead}, ExpiresAt: &future},
+			header:     func(secret string) string { return secret },
+			statusCode: http.StatusOK,
+			response:   "the end.",
+			principal:  models.Principal{Tenant: "acme", Scopes: []string{models.ScopeCompaniesRead}},
+		},
+		"no key": {
+			header:     func(secret string) string { return "" },
+			statusCode: http.StatusOK,
+			response:   "the end.",
+		},
+		"malformed key": {
+			header:     func(secret string) string { return "secret" },
+			statusCode: http.StatusUnauthorized,
+			response:   `{"message":"Invalid API key"}`,
+		},
+		"unknown key": {
+			header:     func(secret string) string { return "xm_000000000000_" + strings.Repeat("0", 64) },
+			statusCode: http.StatusUnauthorized,
+			response:   `{"message":"Invalid API key"}`,
+		},
+		"wrong secret": {
+			header:     func(secret string) string { return secret[:len(secret)-1] + "x" },
+			statusCode: http.StatusUnauthorized,
+			response:   `{"message":"Invalid API key"}`,
+		},
+		"expired key": {
+			key:        models.APIKey{ExpiresAt: &past},
+			header:     func(secret string) string { return secret },
+			statusCode: http.StatusUnauthorized,
+			response:   `{"message":"Invalid API key","errors":["API key is revoked or expired"]}`,
+		},
+		"revoked key": {
+			key:        models.APIKey{RevokedAt: &past},
+			header:     func(secret string) string { return secret },
+			statusCode: http.StatusUnauthorized,
+			response:   `{"message":"Invalid API key","errors":["API key is revoked or expired"]}`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			repo := memory.NewCompaniesRepo(&configs.Config{})
+
+			secret, prefix, err := apikey.Generate()
+			require.NoError(t, err)
+
+			c.key.Prefix, c.key.Hash = prefix, apikey.Hash(secret)
+			_, err = repo.CreateAPIKey(models.WithTenant(context.Background(), "acme"), c.key)
+			require.NoError(t, err)
+
+			mw := NewAPIKeyAuth(repo)
+			mw.now = func() time.Time { return now }
+
+			h := mw.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal, ok := models.PrincipalFrom(r.Context())
+				if c.principal.Tenant == "" {
+					assert.False(t, ok)
+				} else {
+					assert.Equal(t, "api-key:"+prefix, principal.Subject)
+					assert.Equal(t, prefix, principal.APIKey)
+					assert.Equal(t, c.principal.Tenant, principal.Tenant)
+					assert.Equal(t, c.principal.Scopes, principal.Scopes)
+					assert.Equal(t, c.principal.Tenant, models.TenantFrom(r.Context()))
+				}
+				w.Write([]byte("the end."))
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if header := c.header(secret); header != "" {
+				req.Header.Set(APIKeyHeader, header)
+			}
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			assert.Equal(t, c.statusCode, w.Result().StatusCode)
+			assert.Equal(t, c.response, strings.Trim(w.Body.String(), "\n"))
+		})
+	}
+}
+
+func TestAPIKeyAuthRecordsLastUse(t *testing.T) {
+	repo := memory.NewCompaniesRepo(&configs.Config{})
+
+	secret, prefix, err := apikey.Generate()
+	require.NoError(t, err)
+
+	_, err = repo.CreateAPIKey(context.Background(), models.APIKey{Prefix: prefix, Hash: apikey.Hash(secret)})
+	require.NoError(t, err)
+
+	now := time.Date(2022, 4, 1, 10, 0, 0, 0, time.UTC)
+
+	mw := NewAPIKeyAuth(repo)
+	h := mw.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	lastUsedAt := func(at time.Time) time.Time {
+		mw.now = func() time.Time { return at }
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(APIKeyHeader, secret)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		key, err := repo.GetAPIKeyByPrefix(context.Background(), prefix)
+		require.NoError(t, err)
+		require.NotNil(t, key.LastUsedAt)
+		return *key.LastUsedAt
+	}
+
+	assert.True(t, now.Equal(lastUsedAt(now)))
+	// uses within the interval are not recorded
+	assert.True(t, now.Equal(lastUsedAt(now.Add(apiKeyTouchInterval/2))))
+	assert.True(t, now.Add(apiKeyTouchInterval).Equal(lastUsedAt(now.Add(apiKeyTouchInterval))))
+}
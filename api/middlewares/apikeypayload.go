@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/api/responses"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/validator"
+	"github.com/go-chi/render"
+)
+
+// APIKeyPayloadCtx is a middleware used to validate incoming APIKey payload data.
+type APIKeyPayloadCtx struct {
+	validator *validator.Validation
+}
+
+// NewAPIKeyPayloadCtx creates an instance of APIKeyPayloadCtx middleware.
+func NewAPIKeyPayloadCtx(v *validator.Validation) *APIKeyPayloadCtx {
+	return &APIKeyPayloadCtx{validator: v}
+}
+
+// Handle is used to validate incoming APIKey payload data.
+// In case payload is invalid, we return formatted errors.
+func (a *APIKeyPayloadCtx) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data requests.APIKeyPayload
+
+		if json.NewDecoder(r.Body).Decode(&data) != nil {
+			render.Render(w, r, &responses.ErrResponse{
+				Message:        "Invalid JSON",
+				HTTPStatusCode: http.StatusBadRequest,
+			})
+			return
+		}
+
+		if errs := a.validator.Validate(&data); len(errs) != 0 {
+			render.Render(w, r, &responses.ErrResponse{
+				Message:        "Invalid request data",
+				Errors:         errs.Errors(),
+				HTTPStatusCode: http.StatusBadRequest,
+			})
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), handlers.APIKeyPayloadCtxKey{}, data)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,77 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/validator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyPayloadCtx(t *testing.T) {
+	cases := map[string]struct {
+		body       string
+		statusCode int
+		response   string
+	}{
+		"json should be valid": {
+			body:       "not-a-json",
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid JSON"}`,
+		},
+		"valid json will call next": {
+			body:       `{"name":"billing","scopes":["companies:read","companies:write"],"expires_at":"2999-01-01T00:00:00Z"}`,
+			statusCode: http.StatusOK,
+			response:   `the end.`,
+		},
+		"expiry is optional": {
+			body:       `{"name":"billing","scopes":["companies:delete"]}`,
+			statusCode: http.StatusOK,
+			response:   `the end.`,
+		},
+		"name is required": {
+			body:       `{"scopes":["companies:read"]}`,
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid request data","errors":["Field validation for 'Name' failed on the 'required' tag"]}`,
+		},
+		"scopes are required": {
+			body:       `{"name":"billing","scopes":[]}`,
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid request data","errors":["Field validation for 'Scopes' failed on the 'min' tag"]}`,
+		},
+		"scopes should be known": {
+			body:       `{"name":"billing","scopes":["companies:read","api-keys:manage"]}`,
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid request data","errors":["Field validation for 'Scopes[1]' failed on the 'oneof' tag"]}`,
+		},
+		"expiry should be in the future": {
+			body:       `{"name":"billing","scopes":["companies:read"],"expires_at":"2001-01-01T00:00:00Z"}`,
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid request data","errors":["Field validation for 'ExpiresAt' failed on the 'gt' tag"]}`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			mw := NewAPIKeyPayloadCtx(validator.NewValidation())
+			h := mw.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("the end."))
+				w.WriteHeader(http.StatusOK)
+
+				data := r.Context().Value(handlers.APIKeyPayloadCtxKey{})
+				assert.IsType(t, requests.APIKeyPayload{}, data)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(c.body))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			assert.Equal(t, c.statusCode, w.Result().StatusCode)
+			assert.Equal(t, c.response, strings.Trim(w.Body.String(), "\n"))
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package middlewares
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// anonymousActor is used when the request does not identify the actor.
+const anonymousActor = "anonymous"
+
+// unverifiedActorPrefix marks the actors sent by the anonymous callers in the X-Actor header,
+// since anyone can send any actor.
+const unverifiedActorPrefix = "unverified:"
+
+// maxActorLength is the size of the actor column of the audit records.
+const maxActorLength = 255
+
+// AuditCtx is a middleware used to load audit metadata to context.
+type AuditCtx struct{}
+
+// NewAuditCtx creates an instance of AuditCtx middleware.
+func NewAuditCtx() *AuditCtx {
+	return &AuditCtx{}
+}
+
+// Handle is used to collect the actor, request ID and client IP,
+// which are recorded along with the company changes.
+// The actor of the authenticated request is its principal, the X-Actor header is ignored.
+// The actor of the X-Actor header is recorded with the unverified: prefix.
+func (a *AuditCtx) Handle(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		ip := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			ip = host
+		}
+
+		actor := anonymousActor
+		if principal, ok := models.PrincipalFrom(r.Context()); ok {
+			actor = principal.Subject
+		} else if header := r.Header.Get("X-Actor"); header != "" {
+			actor = unverifiedActorPrefix + header
+		}
+		if len(actor) > maxActorLength {
+			actor = strings.ToValidUTF8(actor[:maxActorLength], "")
+		}
+
+		meta := models.AuditMeta{Actor: actor, RequestID: middleware.GetReqID(r.Context()), ClientIP: ip}
+		next.ServeHTTP(w, r.WithContext(models.WithAuditMeta(r.Context(), meta)))
+	}
+	return http.HandlerFunc(fn)
+}
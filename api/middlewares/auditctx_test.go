@@ -0,0 +1,75 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditCtx(t *testing.T) {
+	cases := map[string]struct {
+		remoteAddr string
+		actor      string
+		principal  *models.Principal
+		meta       models.AuditMeta
+	}{
+		"anonymous actor": {
+			remoteAddr: "127.0.0.1:1234",
+			actor:      "",
+			meta:       models.AuditMeta{Actor: "anonymous", RequestID: "req-1", ClientIP: "127.0.0.1"},
+		},
+		"known actor": {
+			remoteAddr: "[::1]:1234",
+			actor:      "john",
+			meta:       models.AuditMeta{Actor: "unverified:john", RequestID: "req-1", ClientIP: "::1"},
+		},
+		"long actor": {
+			remoteAddr: "127.0.0.1:1234",
+			actor:      strings.Repeat("j", 300),
+			meta:       models.AuditMeta{Actor: "unverified:" + strings.Repeat("j", 244), RequestID: "req-1", ClientIP: "127.0.0.1"},
+		},
+		"authenticated actor": {
+			remoteAddr: "127.0.0.1:1234",
+			actor:      "john",
+			principal:  &models.Principal{Subject: "jane"},
+			meta:       models.AuditMeta{Actor: "jane", RequestID: "req-1", ClientIP: "127.0.0.1"},
+		},
+		"remote addr without port": {
+			remoteAddr: "127.0.0.1",
+			actor:      "john",
+			meta:       models.AuditMeta{Actor: "unverified:john", RequestID: "req-1", ClientIP: "127.0.0.1"},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			mw := NewAuditCtx()
+			h := mw.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, c.meta, models.AuditMetaFrom(r.Context()))
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = c.remoteAddr
+			if c.actor != "" {
+				req.Header.Set("X-Actor", c.actor)
+			}
+
+			ctx := context.WithValue(req.Context(), middleware.RequestIDKey, "req-1")
+			if c.principal != nil {
+				ctx = models.WithPrincipal(ctx, *c.principal)
+			}
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req.WithContext(ctx))
+
+			assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/responses"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/rbac"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	log "github.com/sirupsen/logrus"
+)
+
+// Reasons of the denied requests.
+const (
+	ReasonMissingPermission = "missing_permission"
+	ReasonNoPolicyRule      = "no_policy_rule"
+)
+
+// authorizationCtxKey is a key used for the authorization in the context
+type authorizationCtxKey struct{}
+
+// authorization is the policy and the permissions of the authorized caller.
+type authorization struct {
+	policy      *rbac.Policy
+	permissions models.Scopes
+}
+
+// Authorize is a middleware used to authorize the callers with the policy.
+type Authorize struct {
+	policy *rbac.Policy
+}
+
+// NewAuthorize creates an instance of Authorize middleware.
+func NewAuthorize(policy *rbac.Policy) *Authorize {
+	return &Authorize{policy: policy}
+}
+
+// Handle is used to check the caller has the permission the policy requires for the route.
+// The permissions of the token callers are granted by their roles,
+// the scopes of the API key callers are their permissions.
+// Routes without a rule in the policy are denied.
+// Anonymous requests are not authorized, they are allowed only when the authentication is disabled.
+func (a *Authorize) Handle(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := models.PrincipalFrom(r.Context())
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		route, ok := routePattern(r)
+		if !ok {
+			// let the router respond with 404
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		permissions := models.Scopes(principal.Scopes)
+		if principal.APIKey == "" {
+			permissions = a.policy.Permissions(principal.Roles)
+		}
+
+		ctx := context.WithValue(r.Context(), authorizationCtxKey{}, authorization{policy: a.policy, permissions: permissions})
+
+		if resp := authorize(ctx, r.Method, route); resp != nil {
+			render.Render(w, r, resp)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+	return http.HandlerFunc(fn)
+}
+
+// routePattern returns the pattern of the route matching the request,
+// the second value reports whether any route matches.
+func routePattern(r *http.Request) (string, bool) {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.Routes == nil {
+		return "", false
+	}
+
+	tctx := chi.NewRouteContext()
+	if !rctx.Routes.Match(tctx, r.Method, r.URL.Path) {
+		return "", false
+	}
+
+	return tctx.RoutePattern(), true
+}
+
+// authorize returns the response of the denied request, or nil if the caller is allowed to use the route.
+// The callers not authorized by the Authorize middleware are not limited.
+func authorize(ctx context.Context, method string, route string) render.Renderer {
+	auth, ok := ctx.Value(authorizationCtxKey{}).(authorization)
+	if !ok {
+		return nil
+	}
+
+	principal, _ := models.PrincipalFrom(ctx)
+	route = rbac.Pattern(route)
+
+	permission, ok := auth.policy.Permission(method, route)
+	if !ok {
+		log.WithFields(log.Fields{"subject": principal.Subject, "method": method, "route": route}).Warn("No policy rule")
+		return &responses.ErrResponse{
+			Message:        "Permission denied",
+			Errors:         []string{"no permission is defined for " + method + " " + route},
+			Reason:         ReasonNoPolicyRule,
+			HTTPStatusCode: http.StatusForbidden,
+		}
+	}
+
+	if !auth.permissions.Has(permission) {
+		log.WithFields(log.Fields{"subject": principal.Subject, "permission": permission}).Warn("Permission missing")
+		return &responses.ErrResponse{
+			Message:        "Permission denied",
+			Errors:         []string{permission + " permission is required"},
+			Reason:         ReasonMissingPermission,
+			HTTPStatusCode: http.StatusForbidden,
+		}
+	}
+
+	return nil
+}
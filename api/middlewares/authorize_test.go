@@ -0,0 +1,199 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/rbac"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorize(t *testing.T) {
+	policy, err := rbac.Parse([]byte(`{
+		"roles": {"viewer": ["companies:read"], "admin": ["companies:read", "companies:delete"]},
+		"routes": {
+			"GET /companies": "companies:read",
+			"GET /companies/{id}": "companies:read",
+			"DELETE /companies/{id}": "companies:delete"
+		}
+	}`))
+	require.NoError(t, err)
+
+	viewer := &models.Principal{Subject: "john", Roles: []string{"viewer"}}
+
+	cases := map[string]struct {
+		method     string
+		path       string
+		principal  *models.Principal
+		statusCode int
+		response   string
+	}{
+		"anonymous": {
+			method:     http.MethodDelete,
+			path:       "/companies/1",
+			statusCode: http.StatusOK,
+			response:   "the end.",
+		},
+		"role grants permission": {
+			method:     http.MethodGet,
+			path:       "/companies/1",
+			principal:  viewer,
+			statusCode: http.StatusOK,
+			response:   "the end.",
+		},
+		"role grants permission on the route with trailing slash": {
+			method:     http.MethodGet,
+			path:       "/companies/",
+			principal:  viewer,
+			statusCode: http.StatusOK,
+			response:   "the end.",
+		},
+		"role does not grant permission": {
+			method:     http.MethodDelete,
+			path:       "/companies/1",
+			principal:  viewer,
+			statusCode: http.StatusForbidden,
+			response:   `{"message":"Permission denied","errors":["companies:delete permission is required"],"reason":"missing_permission"}`,
+		},
+		"any role grants permission": {
+			method:     http.MethodDelete,
+			path:       "/companies/1",
+			principal:  &models.Principal{Subject: "john", Roles: []string{"viewer", "admin"}},
+			statusCode: http.StatusOK,
+			response:   "the end.",
+		},
+		"no roles": {
+			method:     http.MethodGet,
+			path:       "/companies",
+			principal:  &models.Principal{Subject: "john"},
+			statusCode: http.StatusForbidden,
+			response:   `{"message":"Permission denied","errors":["companies:read permission is required"],"reason":"missing_permission"}`,
+		},
+		"unknown role": {
+			method:     http.MethodGet,
+			path:       "/companies",
+			principal:  &models.Principal{Subject: "john", Roles: []string{"root"}},
+			statusCode: http.StatusForbidden,
+			response:   `{"message":"Permission denied","errors":["companies:read permission is required"],"reason":"missing_permission"}`,
+		},
+		"scopes of API key are permissions": {
+			method:     http.MethodDelete,
+			path:       "/companies/1",
+			principal:  &models.Principal{APIKey: "p1", Scopes: []string{models.ScopeCompaniesDelete}, Roles: []string{"viewer"}},
+			statusCode: http.StatusOK,
+			response:   "the end.",
+		},
+		"roles of API key are ignored": {
+			method:     http.MethodGet,
+			path:       "/companies/1",
+			principal:  &models.Principal{APIKey: "p1", Scopes: []string{models.ScopeCompaniesDelete}, Roles: []string{"viewer"}},
+			statusCode: http.StatusForbidden,
+			response:   `{"message":"Permission denied","errors":["companies:read permission is required"],"reason":"missing_permission"}`,
+		},
+		"route without rule": {
+			method:     http.MethodGet,
+			path:       "/companies/1/history",
+			principal:  &models.Principal{Subject: "john", Roles: []string{"admin"}},
+			statusCode: http.StatusForbidden,
+			response:   `{"message":"Permission denied","errors":["no permission is defined for GET /companies/{id}/history"],"reason":"no_policy_rule"}`,
+		},
+		"unknown route": {
+			method:     http.MethodGet,
+			path:       "/companies/john",
+			principal:  viewer,
+			statusCode: http.StatusNotFound,
+			response:   "404 page not found",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			end := func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("the end."))
+			}
+
+			router := chi.NewRouter()
+			router.Route("/companies", func(r chi.Router) {
+				r.Use(NewAuthorize(policy).Handle)
+				r.Get("/", end)
+				r.Route("/{id:[0-9]+}", func(r chi.Router) {
+					r.Get("/", end)
+					r.Delete("/", end)
+					r.Get("/history", end)
+				})
+			})
+
+			req := httptest.NewRequest(c.method, c.path, nil)
+			if c.principal != nil {
+				req = req.WithContext(models.WithPrincipal(req.Context(), *c.principal))
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, c.statusCode, w.Result().StatusCode)
+			assert.Equal(t, c.response, strings.Trim(w.Body.String(), "\n"))
+		})
+	}
+}
+
+func TestAuthorizeAPIKeysWithDefaultPolicy(t *testing.T) {
+	policy, err := rbac.Parse(configs.DefaultPolicy)
+	require.NoError(t, err)
+
+	cases := map[string]struct {
+		principal  *models.Principal
+		statusCode int
+		response   string
+	}{
+		"admin with scope": {
+			principal:  &models.Principal{Subject: "john", Roles: []string{"admin"}, Scopes: []string{models.ScopeAPIKeysManage}},
+			statusCode: http.StatusOK,
+			response:   "the end.",
+		},
+		"editor with scope": {
+			principal:  &models.Principal{Subject: "john", Roles: []string{"editor"}, Scopes: []string{models.ScopeAPIKeysManage}},
+			statusCode: http.StatusForbidden,
+			response:   `{"message":"Permission denied","errors":["api-keys:manage permission is required"],"reason":"missing_permission"}`,
+		},
+		"admin without scope": {
+			principal:  &models.Principal{Subject: "john", Roles: []string{"admin"}},
+			statusCode: http.StatusForbidden,
+			response:   `{"message":"Insufficient scope","errors":["api-keys:manage scope is required"]}`,
+		},
+		"anonymous": {
+			statusCode: http.StatusUnauthorized,
+			response:   `{"message":"Authentication required"}`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			router := chi.NewRouter()
+			router.Route("/api-keys", func(r chi.Router) {
+				r.Use(NewAuthorize(policy).Handle)
+				r.Use(NewRequireScope(models.ScopeAPIKeysManage).Handle)
+				r.Post("/{id:[0-9]+}/rotate", func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte("the end."))
+				})
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/api-keys/1/rotate", nil)
+			if c.principal != nil {
+				req = req.WithContext(models.WithPrincipal(req.Context(), *c.principal))
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, c.statusCode, w.Result().StatusCode)
+			assert.Equal(t, c.response, strings.Trim(w.Body.String(), "\n"))
+		})
+	}
+}
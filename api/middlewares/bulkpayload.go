@@ -0,0 +1,107 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/api/responses"
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/validator"
+	"github.com/go-chi/render"
+)
+
+// maxBulkOperationBytes is the size of the body allowed for every bulk operation,
+// it is enough for the longest valid company, even with escaped characters.
+const maxBulkOperationBytes = 4 << 10
+
+// BulkPayloadCtx is a middleware used to validate incoming bulk payload data.
+type BulkPayloadCtx struct {
+	config    *configs.Config
+	validator *validator.Validation
+}
+
+// NewBulkPayloadCtx creates an instance of BulkPayloadCtx middleware.
+func NewBulkPayloadCtx(config *configs.Config, v *validator.Validation) *BulkPayloadCtx {
+	return &BulkPayloadCtx{config: config, validator: v}
+}
+
+// Handle is used to validate incoming bulk payload data.
+// In case the request itself is invalid, we return formatted errors,
+// while the errors of single operations are passed to the handler.
+// The body is limited by the number of allowed operations, so it is not decoded whole before they are counted.
+// Delete operations require the permission of the DELETE /companies/{id} route.
+func (b *BulkPayloadCtx) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data requests.BulkPayload
+
+		// one more operation is allowed for the mode and the brackets around the operations
+		limit := int64(b.config.Bulk.MaxOperations+1) * maxBulkOperationBytes
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, limit)).Decode(&data)
+
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			render.Render(w, r, &responses.ErrResponse{
+				Message:        "Request body too large",
+				Errors:         []string{fmt.Sprintf("at most %d bytes are allowed", limit)},
+				HTTPStatusCode: http.StatusRequestEntityTooLarge,
+			})
+			return
+		}
+
+		if err != nil {
+			render.Render(w, r, &responses.ErrResponse{
+				Message:        "Invalid JSON",
+				HTTPStatusCode: http.StatusBadRequest,
+			})
+			return
+		}
+
+		if errs := b.validator.Validate(&data); len(errs) != 0 {
+			render.Render(w, r, &responses.ErrResponse{
+				Message:        "Invalid request data",
+				Errors:         errs.Errors(),
+				HTTPStatusCode: http.StatusBadRequest,
+			})
+			return
+		}
+
+		if len(data.Operations) > b.config.Bulk.MaxOperations {
+			render.Render(w, r, &responses.ErrResponse{
+				Message:        "Too many operations",
+				Errors:         []string{fmt.Sprintf("at most %d operations are allowed", b.config.Bulk.MaxOperations)},
+				HTTPStatusCode: http.StatusRequestEntityTooLarge,
+			})
+			return
+		}
+
+		for _, op := range data.Operations {
+			if op.Op != requests.BulkOpDelete {
+				continue
+			}
+
+			if resp := authorize(r.Context(), http.MethodDelete, "/companies/{id}"); resp != nil {
+				render.Render(w, r, resp)
+				return
+			}
+			break
+		}
+
+		if data.Mode == "" {
+			data.Mode = requests.BulkModeAtomic
+		}
+
+		for i := range data.Operations {
+			if errs := b.validator.Validate(&data.Operations[i]); len(errs) != 0 {
+				data.Operations[i].Errors = errs.Errors()
+			}
+		}
+
+		ctx := context.WithValue(r.Context(), handlers.BulkPayloadCtxKey{}, data)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,121 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/rbac"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/validator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkPayloadCtx(t *testing.T) {
+	company := `{"name":"john","code":"123","country":"US","website":"example.com","phone":"+1234567898"}`
+
+	cases := map[string]struct {
+		limit       int
+		body        string
+		permissions models.Scopes
+		statusCode  int
+		response    string
+	}{
+		"json should be valid": {
+			limit:      3,
+			body:       "not-a-json",
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid JSON"}`,
+		},
+		"operations are required": {
+			limit:      3,
+			body:       `{"mode":"atomic","operations":[]}`,
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid request data","errors":["Field validation for 'Operations' failed on the 'min' tag"]}`,
+		},
+		"mode should be known": {
+			limit:      3,
+			body:       `{"mode":"moon","operations":[{"op":"delete","id":1,"version":1}]}`,
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid request data","errors":["Field validation for 'Mode' failed on the 'oneof' tag"]}`,
+		},
+		"number of operations is limited": {
+			limit:      2,
+			body:       `{"operations":[{"op":"delete","id":1,"version":1},{"op":"delete","id":2,"version":1},{"op":"delete","id":3,"version":1}]}`,
+			statusCode: http.StatusRequestEntityTooLarge,
+			response:   `{"message":"Too many operations","errors":["at most 2 operations are allowed"]}`,
+		},
+		"body size is limited": {
+			limit:      1,
+			body:       `{"operations":[{"op":"delete","id":1,"version":1,"padding":"` + strings.Repeat("x", 2*maxBulkOperationBytes) + `"}]}`,
+			statusCode: http.StatusRequestEntityTooLarge,
+			response:   `{"message":"Request body too large","errors":["at most 8192 bytes are allowed"]}`,
+		},
+		"valid operations will call next": {
+			limit:      3,
+			body:       `{"operations":[{"op":"create","data":` + company + `},{"op":"update","id":1,"version":2,"data":` + company + `},{"op":"delete","id":1,"version":3}]}`,
+			statusCode: http.StatusOK,
+			response:   `atomic [] [] []`,
+		},
+		"delete operations require the permission": {
+			limit:       3,
+			body:        `{"operations":[{"op":"create","data":` + company + `},{"op":"delete","id":1,"version":3}]}`,
+			permissions: models.Scopes{models.ScopeCompaniesWrite},
+			statusCode:  http.StatusForbidden,
+			response:    `{"message":"Permission denied","errors":["companies:delete permission is required"],"reason":"missing_permission"}`,
+		},
+		"delete operations with the permission will call next": {
+			limit:       3,
+			body:        `{"operations":[{"op":"delete","id":1,"version":3}]}`,
+			permissions: models.Scopes{models.ScopeCompaniesWrite, models.ScopeCompaniesDelete},
+			statusCode:  http.StatusOK,
+			response:    `atomic []`,
+		},
+		"invalid operations will call next with errors": {
+			limit:      3,
+			body:       `{"mode":"best_effort","operations":[{"op":"create","data":{"name":"john"}},{"op":"update","id":1,"version":2}]}`,
+			statusCode: http.StatusOK,
+			response: `best_effort ` +
+				`[Field validation for 'Code' failed on the 'required' tag|Field validation for 'Country' failed on the 'required' tag|Field validation for 'Website' failed on the 'required' tag|Field validation for 'Phone' failed on the 'required' tag] ` +
+				`[Field validation for 'Data' failed on the 'required_unless' tag]`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := configs.Config{}
+			cfg.Bulk.MaxOperations = c.limit
+
+			mw := NewBulkPayloadCtx(&cfg, validator.NewValidation())
+			h := mw.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data := r.Context().Value(handlers.BulkPayloadCtxKey{}).(requests.BulkPayload)
+
+				out := []string{data.Mode}
+				for _, op := range data.Operations {
+					out = append(out, "["+strings.Join(op.Errors, "|")+"]")
+				}
+
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(strings.Join(out, " ")))
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(c.body))
+			if c.permissions != nil {
+				policy, err := rbac.Parse(configs.DefaultPolicy)
+				require.NoError(t, err)
+				req = req.WithContext(context.WithValue(req.Context(), authorizationCtxKey{}, authorization{policy: policy, permissions: c.permissions}))
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			assert.Equal(t, c.statusCode, w.Result().StatusCode)
+			assert.Equal(t, c.response, strings.Trim(w.Body.String(), "\n"))
+		})
+	}
+}
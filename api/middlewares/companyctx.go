@@ -17,6 +17,7 @@ import (
 // CompanyCtx is a middleware used to load Company to context.
 type CompanyCtx struct {
 	companiesRepo handlers.CompaniesRepo
+	trashed       bool
 }
 
 // NewCompanyCtx creates an instance of CompanyCtx middleware.
@@ -24,6 +25,12 @@ func NewCompanyCtx(r handlers.CompaniesRepo) *CompanyCtx {
 	return &CompanyCtx{companiesRepo: r}
 }
 
+// NewTrashedCompanyCtx creates an instance of CompanyCtx middleware,
+// which loads only soft-deleted companies.
+func NewTrashedCompanyCtx(r handlers.CompaniesRepo) *CompanyCtx {
+	return &CompanyCtx{companiesRepo: r, trashed: true}
+}
+
 // Handle is used to load an Company object from
 // the URL parameters passed through in the request. In case
 // the Company could not be found, we stop here and return a 404.
@@ -36,7 +43,7 @@ func (c *CompanyCtx) Handle(next http.Handler) http.Handler {
 			return
 		}
 
-		company, err := c.companiesRepo.Get(uint64(id))
+		company, err := c.get(r.Context(), uint64(id))
 
 		if errors.Is(err, models.ErrCompanyNotFound) {
 			render.Render(w, r, &responses.ErrResponse{Message: "Company not found", HTTPStatusCode: http.StatusNotFound})
@@ -44,7 +51,7 @@ func (c *CompanyCtx) Handle(next http.Handler) http.Handler {
 		}
 
 		if err != nil {
-			render.Render(w, r, &responses.ErrResponse{Message: "Cannot query company", HTTPStatusCode: http.StatusInternalServerError})
+			render.Render(w, r, responses.NewStorageErrResponse(err, "Cannot query company"))
 			return
 		}
 
@@ -53,3 +60,11 @@ func (c *CompanyCtx) Handle(next http.Handler) http.Handler {
 	}
 	return http.HandlerFunc(fn)
 }
+
+// get loads either active or soft-deleted company.
+func (c *CompanyCtx) get(ctx context.Context, id uint64) (models.Company, error) {
+	if c.trashed {
+		return c.companiesRepo.GetTrashed(ctx, id)
+	}
+	return c.companiesRepo.Get(ctx, id)
+}
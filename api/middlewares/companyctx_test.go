@@ -1,6 +1,7 @@
 package middlewares
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -12,11 +13,13 @@ import (
 	"github.com/brokeyourbike/xm-golang-exercise/models"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"gorm.io/gorm"
 )
 
 func TestCompanyCtx(t *testing.T) {
 	cases := map[string]struct {
+		trashed    bool
 		companyID  string
 		statusCode int
 		response   string
@@ -33,7 +36,7 @@ func TestCompanyCtx(t *testing.T) {
 			statusCode: http.StatusNotFound,
 			response:   `{"message":"Company not found"}`,
 			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
-				companiesRepo.On("Get", uint64(10)).Return(models.Company{}, models.ErrCompanyNotFound)
+				companiesRepo.On("Get", mock.Anything, uint64(10)).Return(models.Company{}, models.ErrCompanyNotFound)
 			},
 		},
 		"cannot query company": {
@@ -41,7 +44,23 @@ func TestCompanyCtx(t *testing.T) {
 			statusCode: http.StatusInternalServerError,
 			response:   `{"message":"Cannot query company"}`,
 			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
-				companiesRepo.On("Get", uint64(10)).Return(models.Company{}, gorm.ErrInvalidData)
+				companiesRepo.On("Get", mock.Anything, uint64(10)).Return(models.Company{}, gorm.ErrInvalidData)
+			},
+		},
+		"company query timed out": {
+			companyID:  "10",
+			statusCode: http.StatusGatewayTimeout,
+			response:   `{"message":"Storage timed out","errors":["Cannot query company"]}`,
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("Get", mock.Anything, uint64(10)).Return(models.Company{}, fmt.Errorf("%w: bad connection", context.DeadlineExceeded))
+			},
+		},
+		"company query cancelled": {
+			companyID:  "10",
+			statusCode: http.StatusServiceUnavailable,
+			response:   `{"message":"Request cancelled","errors":["Cannot query company"]}`,
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("Get", mock.Anything, uint64(10)).Return(models.Company{}, context.Canceled)
 			},
 		},
 		"it can call next": {
@@ -49,7 +68,25 @@ func TestCompanyCtx(t *testing.T) {
 			statusCode: http.StatusOK,
 			response:   `the end.`,
 			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
-				companiesRepo.On("Get", uint64(10)).Return(models.Company{}, nil)
+				companiesRepo.On("Get", mock.Anything, uint64(10)).Return(models.Company{}, nil)
+			},
+		},
+		"trashed company not found": {
+			trashed:    true,
+			companyID:  "10",
+			statusCode: http.StatusNotFound,
+			response:   `{"message":"Company not found"}`,
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("GetTrashed", mock.Anything, uint64(10)).Return(models.Company{}, models.ErrCompanyNotFound)
+			},
+		},
+		"it can load trashed company": {
+			trashed:    true,
+			companyID:  "10",
+			statusCode: http.StatusOK,
+			response:   `the end.`,
+			setupMock: func(companiesRepo *mocks.CompaniesRepo) {
+				companiesRepo.On("GetTrashed", mock.Anything, uint64(10)).Return(models.Company{}, nil)
 			},
 		},
 	}
@@ -60,6 +97,9 @@ func TestCompanyCtx(t *testing.T) {
 			c.setupMock(companiesRepo)
 
 			mw := NewCompanyCtx(companiesRepo)
+			if c.trashed {
+				mw = NewTrashedCompanyCtx(companiesRepo)
+			}
 			h := mw.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				w.Write([]byte("the end."))
 				w.WriteHeader(http.StatusOK)
@@ -0,0 +1,77 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/api/responses"
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/validator"
+	"github.com/go-chi/render"
+)
+
+// CompanyImportCtx is a middleware used to prepare the import of companies.
+type CompanyImportCtx struct {
+	config    *configs.Config
+	validator *validator.Validation
+}
+
+// NewCompanyImportCtx creates an instance of CompanyImportCtx middleware.
+func NewCompanyImportCtx(cfg *configs.Config, v *validator.Validation) *CompanyImportCtx {
+	return &CompanyImportCtx{config: cfg, validator: v}
+}
+
+// Handle is used to check the import media type and the CSV header.
+// The rows are read and validated by the next handler, while the body is streamed.
+func (c *CompanyImportCtx) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dryRun := false
+
+		if v := r.URL.Query().Get("dry_run"); v != "" {
+			var err error
+			if dryRun, err = strconv.ParseBool(v); err != nil {
+				render.Render(w, r, &responses.ErrResponse{
+					Message:        "Invalid query params",
+					Errors:         []string{"dry_run must be a boolean"},
+					HTTPStatusCode: http.StatusBadRequest,
+				})
+				return
+			}
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+		data, err := requests.NewCompanyImport(mediaType, r.Body, c.config.Import.MaxRows, c.validate)
+
+		switch {
+		case errors.Is(err, requests.ErrUnsupportedImport):
+			render.Render(w, r, &responses.ErrResponse{
+				Message:        "Unsupported media type",
+				Errors:         []string{"expected " + requests.CSVMediaType + " or " + requests.NDJSONMediaType},
+				HTTPStatusCode: http.StatusUnsupportedMediaType,
+			})
+			return
+		case err != nil:
+			render.Render(w, r, &responses.ErrResponse{Message: "Invalid CSV header", Errors: []string{err.Error()}, HTTPStatusCode: http.StatusBadRequest})
+			return
+		}
+
+		data.DryRun = dryRun
+
+		ctx := context.WithValue(r.Context(), handlers.CompanyImportCtxKey{}, data)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// validate returns the validation errors of the import row.
+func (c *CompanyImportCtx) validate(data *requests.CompanyPayload) []string {
+	if errs := c.validator.Validate(data); len(errs) != 0 {
+		return errs.Errors()
+	}
+	return nil
+}
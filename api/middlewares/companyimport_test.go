@@ -0,0 +1,86 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/validator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompanyImportCtx(t *testing.T) {
+	cases := map[string]struct {
+		query       string
+		contentType string
+		body        string
+		statusCode  int
+		response    string
+	}{
+		"dry run should be boolean": {
+			query:       "?dry_run=maybe",
+			contentType: "text/csv",
+			body:        "name\n",
+			statusCode:  http.StatusBadRequest,
+			response:    `{"message":"Invalid query params","errors":["dry_run must be a boolean"]}`,
+		},
+		"media type should be supported": {
+			contentType: "application/json",
+			body:        `{}`,
+			statusCode:  http.StatusUnsupportedMediaType,
+			response:    `{"message":"Unsupported media type","errors":["expected text/csv or application/x-ndjson"]}`,
+		},
+		"csv header should be known": {
+			contentType: "text/csv",
+			body:        "name,version\n",
+			statusCode:  http.StatusBadRequest,
+			response:    `{"message":"Invalid CSV header","errors":["invalid CSV header: unknown or duplicate column 'version'"]}`,
+		},
+		"valid csv will call next": {
+			query:       "?dry_run=true",
+			contentType: "text/csv; charset=utf-8",
+			body:        "name,code,country,website,phone\njohn,123,US,example.com,+1234567898\njohn,,US,example.com,+1234567898\n",
+			statusCode:  http.StatusOK,
+			response:    `true 2 [] 3 [Field validation for 'Code' failed on the 'required' tag]`,
+		},
+		"valid ndjson will call next": {
+			contentType: "application/x-ndjson",
+			body:        `{"name":"john","code":"123","country":"US","website":"example.com","phone":"+1234567898"}`,
+			statusCode:  http.StatusOK,
+			response:    `false 1 []`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := configs.Config{}
+			cfg.Import.MaxRows = 10
+
+			mw := NewCompanyImportCtx(&cfg, validator.NewValidation())
+			h := mw.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data := r.Context().Value(handlers.CompanyImportCtxKey{}).(*requests.CompanyImport)
+
+				out := []string{fmt.Sprint(data.DryRun)}
+				for row, err := data.Next(); err == nil; row, err = data.Next() {
+					out = append(out, fmt.Sprint(row.Line), "["+strings.Join(row.Errors, "|")+"]")
+				}
+
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(strings.Join(out, " ")))
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/"+c.query, strings.NewReader(c.body))
+			req.Header.Set("Content-Type", c.contentType)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			assert.Equal(t, c.statusCode, w.Result().StatusCode)
+			assert.Equal(t, c.response, strings.Trim(w.Body.String(), "\n"))
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/api/responses"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/validator"
+	"github.com/go-chi/render"
+)
+
+// acceptPatch lists the supported patch media types.
+var acceptPatch = []string{requests.MergePatchMediaType, requests.JSONPatchMediaType}
+
+// CompanyPatchCtx is a middleware used to apply the patch to the Company.
+type CompanyPatchCtx struct {
+	validator *validator.Validation
+}
+
+// NewCompanyPatchCtx creates an instance of CompanyPatchCtx middleware.
+func NewCompanyPatchCtx(v *validator.Validation) *CompanyPatchCtx {
+	return &CompanyPatchCtx{validator: v}
+}
+
+// Handle is used to apply the patch to the Company loaded into the context.
+// The patched company is validated as a whole and passed to the next handler
+// the same as the Company payload data, so it is saved the same as by PUT requests.
+func (c *CompanyPatchCtx) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		company := r.Context().Value(handlers.CompanyCtxKey{}).(models.Company)
+
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			render.Render(w, r, &responses.ErrResponse{Message: "Cannot read request body", HTTPStatusCode: http.StatusBadRequest})
+			return
+		}
+
+		data, err := requests.CompanyPatch{MediaType: mediaType, Body: body}.Apply(company)
+
+		switch {
+		case errors.Is(err, requests.ErrUnsupportedPatch):
+			w.Header().Set("Accept-Patch", strings.Join(acceptPatch, ", "))
+			render.Render(w, r, &responses.ErrResponse{
+				Message:        "Unsupported media type",
+				Errors:         []string{"expected " + strings.Join(acceptPatch, " or ")},
+				HTTPStatusCode: http.StatusUnsupportedMediaType,
+			})
+			return
+		case errors.Is(err, requests.ErrInvalidPatch):
+			render.Render(w, r, &responses.ErrResponse{Message: "Invalid patch", Errors: []string{err.Error()}, HTTPStatusCode: http.StatusBadRequest})
+			return
+		case errors.Is(err, requests.ErrPatchFailed):
+			render.Render(w, r, &responses.ErrResponse{Message: "Patch cannot be applied", Errors: []string{err.Error()}, HTTPStatusCode: http.StatusConflict})
+			return
+		case err != nil:
+			render.Render(w, r, &responses.ErrResponse{Message: "Invalid request data", Errors: []string{err.Error()}, HTTPStatusCode: http.StatusUnprocessableEntity})
+			return
+		}
+
+		if errs := c.validator.Validate(&data); len(errs) != 0 {
+			render.Render(w, r, &responses.ErrResponse{
+				Message:        "Invalid request data",
+				Errors:         errs.Errors(),
+				HTTPStatusCode: http.StatusUnprocessableEntity,
+			})
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), handlers.CompanyPayloadCtxKey{}, data)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,93 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/validator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompanyPatchCtx(t *testing.T) {
+	cases := map[string]struct {
+		contentType string
+		body        string
+		statusCode  int
+		response    string
+		acceptPatch string
+	}{
+		"media type should be supported": {
+			contentType: "application/json",
+			body:        `{"name":"acme ltd"}`,
+			statusCode:  http.StatusUnsupportedMediaType,
+			response:    `{"message":"Unsupported media type","errors":["expected application/merge-patch+json or application/json-patch+json"]}`,
+			acceptPatch: "application/merge-patch+json, application/json-patch+json",
+		},
+		"patch should be valid": {
+			contentType: "application/json-patch+json",
+			body:        `{"op":"replace"}`,
+			statusCode:  http.StatusBadRequest,
+			response:    `{"message":"Invalid patch","errors":["invalid patch: json: cannot unmarshal object into Go value of type jsonpatch.Patch"]}`,
+		},
+		"patch should apply": {
+			contentType: "application/json-patch+json",
+			body:        `[{"op":"test","path":"/name","value":"beta"}]`,
+			statusCode:  http.StatusConflict,
+			response:    `{"message":"Patch cannot be applied","errors":["patch cannot be applied: testing value /name failed: test failed"]}`,
+		},
+		"patched company should be decoded": {
+			contentType: "application/merge-patch+json",
+			body:        `{"id":2}`,
+			statusCode:  http.StatusUnprocessableEntity,
+			response:    `{"message":"Invalid request data","errors":["invalid patch result: id cannot be changed"]}`,
+		},
+		"patched company should be valid": {
+			contentType: "application/merge-patch+json",
+			body:        `{"name":null,"country":"moon"}`,
+			statusCode:  http.StatusUnprocessableEntity,
+			response:    `{"message":"Invalid request data","errors":["Field validation for 'Name' failed on the 'required' tag","Field validation for 'Country' failed on the 'iso3166_1_alpha2' tag"]}`,
+		},
+		"merge patch will call next": {
+			contentType: "application/merge-patch+json; charset=utf-8",
+			body:        `{"name":"acme ltd"}`,
+			statusCode:  http.StatusOK,
+			response:    `acme ltd`,
+		},
+		"json patch will call next": {
+			contentType: "application/json-patch+json",
+			body:        `[{"op":"replace","path":"/name","value":"acme ltd"}]`,
+			statusCode:  http.StatusOK,
+			response:    `acme ltd`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			company := models.Company{ID: 1, Name: "acme", Code: "c1", Country: "CY", Website: "acme.com", Phone: "+35712345678", Version: 2}
+
+			mw := NewCompanyPatchCtx(validator.NewValidation())
+			h := mw.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data := r.Context().Value(handlers.CompanyPayloadCtxKey{}).(requests.CompanyPayload)
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(data.Name))
+			}))
+
+			req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(c.body))
+			req.Header.Set("Content-Type", c.contentType)
+			req = req.WithContext(context.WithValue(req.Context(), handlers.CompanyCtxKey{}, company))
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			assert.Equal(t, c.statusCode, w.Result().StatusCode)
+			assert.Equal(t, c.response, strings.Trim(w.Body.String(), "\n"))
+			assert.Equal(t, c.acceptPatch, w.Header().Get("Accept-Patch"))
+		})
+	}
+}
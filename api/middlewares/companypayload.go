@@ -13,7 +13,7 @@ import (
 	"github.com/gorilla/schema"
 )
 
-// CompanyCtx is a middleware used to validate incoming Company payload data.
+// CompanyPayloadCtx is a middleware used to validate incoming Company payload data.
 type CompanyPayloadCtx struct {
 	validator    *validator.Validation
 	queryDecoder *schema.Decoder
@@ -25,40 +25,89 @@ func NewCompanyPayloadCtx(v *validator.Validation, queryDecoder *schema.Decoder)
 }
 
 // Handle is used to validate incoming Company payload data.
+// For GET requests the query params are decoded into CompaniesQuery instead.
 // In case payload is invalid, we return formatted errors.
 func (c *CompanyPayloadCtx) Handle(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			c.handleQuery(next, w, r)
+			return
+		}
+
 		var data requests.CompanyPayload
 
-		if r.Method == http.MethodGet {
-			err := c.queryDecoder.Decode(&data, r.URL.Query())
-			if err != nil {
-				render.Render(w, r, &responses.ErrResponse{
-					Message:        "Invalid query params",
-					HTTPStatusCode: http.StatusBadRequest,
-				})
-				return
-			}
-		} else {
-			if json.NewDecoder(r.Body).Decode(&data) != nil {
-				render.Render(w, r, &responses.ErrResponse{
-					Message:        "Invalid JSON",
-					HTTPStatusCode: http.StatusBadRequest,
-				})
-				return
-			}
-
-			if errs := c.validator.Validate(&data); len(errs) != 0 {
-				render.Render(w, r, &responses.ErrResponse{
-					Message:        "Invalid request data",
-					Errors:         errs.Errors(),
-					HTTPStatusCode: http.StatusBadRequest,
-				})
-				return
-			}
+		if json.NewDecoder(r.Body).Decode(&data) != nil {
+			render.Render(w, r, &responses.ErrResponse{
+				Message:        "Invalid JSON",
+				HTTPStatusCode: http.StatusBadRequest,
+			})
+			return
+		}
+
+		if errs := c.validator.Validate(&data); len(errs) != 0 {
+			render.Render(w, r, &responses.ErrResponse{
+				Message:        "Invalid request data",
+				Errors:         errs.Errors(),
+				HTTPStatusCode: http.StatusBadRequest,
+			})
+			return
 		}
 
 		ctx := context.WithValue(r.Context(), handlers.CompanyPayloadCtxKey{}, data)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// handleQuery is used to decode and validate companies list query params.
+func (c *CompanyPayloadCtx) handleQuery(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	var data requests.CompaniesQuery
+
+	filters, params, err := requests.ParseFilters(r.URL.Query())
+	if err != nil {
+		render.Render(w, r, &responses.ErrResponse{
+			Message:        "Invalid query params",
+			Errors:         []string{err.Error()},
+			HTTPStatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	if c.queryDecoder.Decode(&data, params) != nil {
+		render.Render(w, r, &responses.ErrResponse{
+			Message:        "Invalid query params",
+			HTTPStatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	data.Filters = filters
+
+	if errs := c.validator.Validate(&data); len(errs) != 0 {
+		render.Render(w, r, &responses.ErrResponse{
+			Message:        "Invalid query params",
+			Errors:         errs.Errors(),
+			HTTPStatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	if _, err := data.SortKeys(); err != nil {
+		render.Render(w, r, &responses.ErrResponse{
+			Message:        "Invalid sort",
+			Errors:         []string{err.Error()},
+			HTTPStatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	if _, err := data.After(); err != nil {
+		render.Render(w, r, &responses.ErrResponse{
+			Message:        "Invalid cursor",
+			HTTPStatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), handlers.CompaniesQueryCtxKey{}, data)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
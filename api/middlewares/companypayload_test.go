@@ -47,7 +47,42 @@ func TestCompanyPayloadCtx(t *testing.T) {
 			path:       "/?moon=white",
 			body:       "",
 			statusCode: http.StatusBadRequest,
-			response:   `{"message":"Invalid query params"}`,
+			response:   `{"message":"Invalid query params","errors":["unknown filter field 'moon'"]}`,
+		},
+		"query will not accept unknown operator": {
+			method:     http.MethodGet,
+			path:       "/?name[like]=john",
+			body:       "",
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid query params","errors":["unknown filter operator 'like'"]}`,
+		},
+		"query will not accept unknown sort field": {
+			method:     http.MethodGet,
+			path:       "/?sort=-moon,name",
+			body:       "",
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid sort","errors":["unknown sort field 'moon'"]}`,
+		},
+		"query will not accept too big limit": {
+			method:     http.MethodGet,
+			path:       "/?limit=1000",
+			body:       "",
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid query params","errors":["Field validation for 'Limit' failed on the 'max' tag"]}`,
+		},
+		"query will not accept invalid cursor": {
+			method:     http.MethodGet,
+			path:       "/?cursor=moon",
+			body:       "",
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid cursor"}`,
+		},
+		"valid query will call next": {
+			method:     http.MethodGet,
+			path:       "/?name[iprefix]=jo&country=CY,GR&sort=-name,code&limit=10",
+			body:       "",
+			statusCode: http.StatusOK,
+			response:   `the end.`,
 		},
 	}
 
@@ -58,6 +93,12 @@ func TestCompanyPayloadCtx(t *testing.T) {
 				w.Write([]byte("the end."))
 				w.WriteHeader(http.StatusOK)
 
+				if r.Method == http.MethodGet {
+					data := r.Context().Value(handlers.CompaniesQueryCtxKey{})
+					assert.IsType(t, requests.CompaniesQuery{}, data)
+					return
+				}
+
 				data := r.Context().Value(handlers.CompanyPayloadCtxKey{})
 				assert.IsType(t, requests.CompanyPayload{}, data)
 			}))
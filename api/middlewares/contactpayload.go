@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/api/responses"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/validator"
+	"github.com/go-chi/render"
+)
+
+// ContactPayloadCtx is a middleware used to validate incoming Contact payload data.
+type ContactPayloadCtx struct {
+	validator *validator.Validation
+}
+
+// NewContactPayloadCtx creates an instance of ContactPayloadCtx middleware.
+func NewContactPayloadCtx(v *validator.Validation) *ContactPayloadCtx {
+	return &ContactPayloadCtx{validator: v}
+}
+
+// Handle is used to validate incoming Contact payload data.
+// In case payload is invalid, we return formatted errors.
+func (c *ContactPayloadCtx) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data requests.ContactPayload
+
+		if json.NewDecoder(r.Body).Decode(&data) != nil {
+			render.Render(w, r, &responses.ErrResponse{
+				Message:        "Invalid JSON",
+				HTTPStatusCode: http.StatusBadRequest,
+			})
+			return
+		}
+
+		if errs := c.validator.Validate(&data); len(errs) != 0 {
+			render.Render(w, r, &responses.ErrResponse{
+				Message:        "Invalid request data",
+				Errors:         errs.Errors(),
+				HTTPStatusCode: http.StatusBadRequest,
+			})
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), handlers.ContactPayloadCtxKey{}, data)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,82 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/validator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContactPayloadCtx(t *testing.T) {
+	cases := map[string]struct {
+		body       string
+		statusCode int
+		response   string
+	}{
+		"json should be valid": {
+			body:       "not-a-json",
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid JSON"}`,
+		},
+		"valid json will call next": {
+			body:       `{"name":"john","role":"CEO","email":"john@example.com","phone":"+35799123456"}`,
+			statusCode: http.StatusOK,
+			response:   `the end.`,
+		},
+		"email alone is enough": {
+			body:       `{"name":"john","email":"john@example.com"}`,
+			statusCode: http.StatusOK,
+			response:   `the end.`,
+		},
+		"phone alone is enough": {
+			body:       `{"name":"john","phone":"+35799123456"}`,
+			statusCode: http.StatusOK,
+			response:   `the end.`,
+		},
+		"name is required": {
+			body:       `{"email":"john@example.com"}`,
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid request data","errors":["Field validation for 'Name' failed on the 'required' tag"]}`,
+		},
+		"email or phone is required": {
+			body:       `{"name":"john"}`,
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid request data","errors":["Field validation for 'Email' failed on the 'required_without' tag","Field validation for 'Phone' failed on the 'required_without' tag"]}`,
+		},
+		"email should be valid": {
+			body:       `{"name":"john","email":"john"}`,
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid request data","errors":["Field validation for 'Email' failed on the 'email' tag"]}`,
+		},
+		"phone should be in E.164 format": {
+			body:       `{"name":"john","phone":"99 123 456"}`,
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid request data","errors":["Field validation for 'Phone' failed on the 'e164' tag"]}`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			mw := NewContactPayloadCtx(validator.NewValidation())
+			h := mw.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("the end."))
+				w.WriteHeader(http.StatusOK)
+
+				data := r.Context().Value(handlers.ContactPayloadCtxKey{})
+				assert.IsType(t, requests.ContactPayload{}, data)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(c.body))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			assert.Equal(t, c.statusCode, w.Result().StatusCode)
+			assert.Equal(t, c.response, strings.Trim(w.Body.String(), "\n"))
+		})
+	}
+}
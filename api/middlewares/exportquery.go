@@ -0,0 +1,53 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/api/responses"
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/validator"
+	"github.com/go-chi/render"
+)
+
+// ExportQueryCtx is a middleware used to validate companies export query params.
+type ExportQueryCtx struct {
+	config    *configs.Config
+	validator *validator.Validation
+}
+
+// NewExportQueryCtx creates an instance of ExportQueryCtx middleware.
+func NewExportQueryCtx(cfg *configs.Config, v *validator.Validation) *ExportQueryCtx {
+	return &ExportQueryCtx{config: cfg, validator: v}
+}
+
+// Handle is used to validate the export format.
+// The format param is removed from the query, so the remaining params
+// can be decoded into CompaniesQuery by the next middleware.
+func (e *ExportQueryCtx) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		data := requests.ExportQuery{Format: query.Get("format"), BatchSize: e.config.Export.BatchSize}
+
+		if errs := e.validator.Validate(&data); len(errs) != 0 {
+			render.Render(w, r, &responses.ErrResponse{
+				Message:        "Invalid query params",
+				Errors:         errs.Errors(),
+				HTTPStatusCode: http.StatusBadRequest,
+			})
+			return
+		}
+
+		query.Del("format")
+
+		u := *r.URL
+		u.RawQuery = query.Encode()
+
+		r = r.WithContext(context.WithValue(r.Context(), handlers.ExportQueryCtxKey{}, data))
+		r.URL = &u
+		next.ServeHTTP(w, r)
+	})
+}
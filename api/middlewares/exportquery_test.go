@@ -0,0 +1,61 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/validator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportQueryCtx(t *testing.T) {
+	cases := map[string]struct {
+		query      string
+		statusCode int
+		response   string
+	}{
+		"format is required": {
+			query:      "?name=acme",
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid query params","errors":["Field validation for 'Format' failed on the 'required' tag"]}`,
+		},
+		"format should be known": {
+			query:      "?format=pdf",
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid query params","errors":["Field validation for 'Format' failed on the 'oneof' tag"]}`,
+		},
+		"format is removed from query": {
+			query:      "?format=xlsx&name=acme&sort=-name",
+			statusCode: http.StatusOK,
+			response:   `xlsx 500 name=acme&sort=-name`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := configs.Config{}
+			cfg.Export.BatchSize = 500
+
+			mw := NewExportQueryCtx(&cfg, validator.NewValidation())
+			h := mw.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data := r.Context().Value(handlers.ExportQueryCtxKey{}).(requests.ExportQuery)
+
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprintf(w, "%s %d %s", data.Format, data.BatchSize, r.URL.RawQuery)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/"+c.query, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			assert.Equal(t, c.statusCode, w.Result().StatusCode)
+			assert.Equal(t, c.response, strings.Trim(w.Body.String(), "\n"))
+		})
+	}
+}
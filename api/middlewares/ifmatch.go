@@ -0,0 +1,72 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/responses"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/go-chi/render"
+)
+
+// IfMatch is a middleware used to verify the If-Match precondition
+// against the Company loaded to context.
+type IfMatch struct{}
+
+// NewIfMatch creates an instance of IfMatch middleware.
+func NewIfMatch() *IfMatch {
+	return &IfMatch{}
+}
+
+// Handle is used to verify that the client modifies the latest version of the Company.
+// In case the If-Match header is missing, we stop here and return a 428,
+// in case it does not match the Company ETag, we return a 412.
+// The wildcard is accepted only by DELETE, PUT and PATCH requests would overwrite
+// the changes the client has not seen, so they also return a 428.
+func (i *IfMatch) Handle(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("If-Match")
+		if header == "" {
+			render.Render(w, r, &responses.ErrResponse{Message: "If-Match header required", HTTPStatusCode: http.StatusPreconditionRequired})
+			return
+		}
+
+		if r.Method != http.MethodDelete && hasWildcard(header) {
+			render.Render(w, r, &responses.ErrResponse{Message: "If-Match header must contain the company version", HTTPStatusCode: http.StatusPreconditionRequired})
+			return
+		}
+
+		company := r.Context().Value(handlers.CompanyCtxKey{}).(models.Company)
+
+		if !etagMatches(header, company.ETag()) {
+			render.Render(w, r, &responses.ErrResponse{Message: "Company was modified", HTTPStatusCode: http.StatusPreconditionFailed})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
+
+// etagMatches performs the strong comparison of the If-Match header value,
+// which can contain a list of entity tags or a wildcard.
+func etagMatches(header string, etag string) bool {
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || v == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// hasWildcard reports whether the If-Match header value contains the wildcard.
+func hasWildcard(header string) bool {
+	for _, v := range strings.Split(header, ",") {
+		if strings.TrimSpace(v) == "*" {
+			return true
+		}
+	}
+	return false
+}
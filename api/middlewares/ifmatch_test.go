@@ -0,0 +1,92 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIfMatch(t *testing.T) {
+	cases := map[string]struct {
+		method     string
+		ifMatch    string
+		statusCode int
+		response   string
+	}{
+		"missing header": {
+			ifMatch:    "",
+			statusCode: http.StatusPreconditionRequired,
+			response:   `{"message":"If-Match header required"}`,
+		},
+		"stale version": {
+			ifMatch:    `"2"`,
+			statusCode: http.StatusPreconditionFailed,
+			response:   `{"message":"Company was modified"}`,
+		},
+		"weak tag does not match": {
+			ifMatch:    `W/"3"`,
+			statusCode: http.StatusPreconditionFailed,
+			response:   `{"message":"Company was modified"}`,
+		},
+		"current version": {
+			ifMatch:    `"3"`,
+			statusCode: http.StatusOK,
+			response:   `the end.`,
+		},
+		"one of the versions": {
+			ifMatch:    `"2", "3"`,
+			statusCode: http.StatusOK,
+			response:   `the end.`,
+		},
+		"wildcard on delete": {
+			method:     http.MethodDelete,
+			ifMatch:    `*`,
+			statusCode: http.StatusOK,
+			response:   `the end.`,
+		},
+		"wildcard on put": {
+			ifMatch:    `*`,
+			statusCode: http.StatusPreconditionRequired,
+			response:   `{"message":"If-Match header must contain the company version"}`,
+		},
+		"wildcard on patch": {
+			method:     http.MethodPatch,
+			ifMatch:    `"3", *`,
+			statusCode: http.StatusPreconditionRequired,
+			response:   `{"message":"If-Match header must contain the company version"}`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			mw := NewIfMatch()
+			h := mw.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("the end."))
+			}))
+
+			method := http.MethodPut
+			if c.method != "" {
+				method = c.method
+			}
+
+			req := httptest.NewRequest(method, "/", nil)
+			if c.ifMatch != "" {
+				req.Header.Set("If-Match", c.ifMatch)
+			}
+
+			ctx := context.WithValue(req.Context(), handlers.CompanyCtxKey{}, models.Company{ID: 10, Version: 3})
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req.WithContext(ctx))
+
+			assert.Equal(t, c.statusCode, w.Result().StatusCode)
+			assert.Equal(t, c.response, strings.Trim(w.Body.String(), "\n"))
+		})
+	}
+}
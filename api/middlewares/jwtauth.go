@@ -0,0 +1,121 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/responses"
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/jwt"
+	"github.com/go-chi/render"
+	log "github.com/sirupsen/logrus"
+)
+
+// TokenVerifier verifies the bearer tokens.
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (jwt.Claims, error)
+}
+
+// JWTAuth is a middleware used to authenticate the requests with the JWT bearer tokens.
+type JWTAuth struct {
+	config   *configs.Config
+	verifier TokenVerifier
+}
+
+// NewJWTAuth creates an instance of JWTAuth middleware.
+// Without the verifier the tokens are not accepted, and requests without the token are anonymous,
+// unless the authentication is required.
+func NewJWTAuth(cfg *configs.Config, verifier TokenVerifier) *JWTAuth {
+	return &JWTAuth{config: cfg, verifier: verifier}
+}
+
+// Handle is used to verify the bearer token, and load the principal to context.
+// Requests authenticated before, e.g. with the API key, are passed through.
+// The tenant claim of the token, or the default tenant without the claim, is loaded to context as well,
+// so the caller cannot access other tenants.
+func (j *JWTAuth) Handle(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := models.PrincipalFrom(r.Context()); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := bearerToken(r)
+
+		if !ok && j.verifier == nil && !j.config.Auth.Required {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !ok {
+			// without the verifier the callers can authenticate only with the API keys
+			if j.verifier != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer`)
+			}
+			render.Render(w, r, &responses.ErrResponse{Message: "Authentication required", HTTPStatusCode: http.StatusUnauthorized})
+			return
+		}
+
+		if j.verifier == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			render.Render(w, r, &responses.ErrResponse{Message: "Invalid token", Errors: []string{"tokens are not accepted"}, HTTPStatusCode: http.StatusUnauthorized})
+			return
+		}
+
+		claims, err := j.verifier.Verify(r.Context(), token)
+
+		if errors.Is(err, jwt.ErrNoKeys) {
+			log.WithFields(log.Fields{"error": err}).Error("Cannot load token keys")
+			render.Render(w, r, &responses.ErrResponse{Message: "Authentication is not available", HTTPStatusCode: http.StatusServiceUnavailable})
+			return
+		}
+
+		if err != nil {
+			log.WithFields(log.Fields{"error": err}).Warn("Token invalid")
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			render.Render(w, r, &responses.ErrResponse{Message: "Invalid token", Errors: []string{err.Error()}, HTTPStatusCode: http.StatusUnauthorized})
+			return
+		}
+
+		if claims.Subject == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			render.Render(w, r, &responses.ErrResponse{Message: "Invalid token", Errors: []string{"token has no subject"}, HTTPStatusCode: http.StatusUnauthorized})
+			return
+		}
+
+		principal := models.Principal{
+			Subject: claims.Subject,
+			Tenant:  claims.String(j.config.JWT.TenantClaim),
+			Scopes:  claims.Strings("scope"),
+			Roles:   claims.Strings(j.config.JWT.RolesClaim),
+		}
+
+		// the callers without the tenant claim are bound to the default tenant,
+		// so they cannot pick any tenant with the header
+		if principal.Tenant == "" {
+			principal.Tenant = j.config.Tenants.Default
+		}
+
+		log.WithFields(log.Fields{"subject": principal.Subject, "tenant": principal.Tenant}).Debug("Request authenticated")
+
+		ctx := models.WithPrincipal(r.Context(), principal)
+		if principal.Tenant != "" {
+			ctx = models.WithTenant(ctx, principal.Tenant)
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+	return http.HandlerFunc(fn)
+}
+
+// bearerToken returns the token of the Authorization header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
@@ -0,0 +1,168 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jwtKeys is a key source with the fixed keys.
+type jwtKeys struct {
+	keys []jwt.Key
+	err  error
+}
+
+func (k jwtKeys) Keys(ctx context.Context, refresh bool) ([]jwt.Key, error) {
+	return k.keys, k.err
+}
+
+func TestJWTAuth(t *testing.T) {
+	secret := []byte("secret")
+	keys := jwtKeys{keys: []jwt.Key{{ID: "1", Material: secret}}}
+
+	sign := func(claims map[string]interface{}) string {
+		token, err := jwt.Sign(jwt.HS256, secret, "1", claims)
+		require.NoError(t, err)
+		return token
+	}
+
+	exp := time.Now().Add(time.Minute).Unix()
+
+	cases := map[string]struct {
+		keys          jwt.KeySource
+		required      bool
+		authorization string
+		principal     *models.Principal
+		statusCode    int
+		response      string
+		authenticate  string
+		authenticated *models.Principal
+		tenant        string
+	}{
+		"valid token": {
+			keys:          keys,
+			authorization: "Bearer " + sign(map[string]interface{}{"sub": "john", "exp": exp, "tenant": "acme", "scope": "companies:read", "roles": []string{"viewer", "editor"}}),
+			statusCode:    http.StatusOK,
+			authenticated: &models.Principal{Subject: "john", Tenant: "acme", Scopes: []string{"companies:read"}, Roles: []string{"viewer", "editor"}},
+			tenant:        "acme",
+		},
+		"token without tenant": {
+			keys:          keys,
+			authorization: "bearer " + sign(map[string]interface{}{"sub": "john", "exp": exp}),
+			statusCode:    http.StatusOK,
+			authenticated: &models.Principal{Subject: "john", Tenant: "default"},
+			tenant:        "default",
+		},
+		"missing token": {
+			keys:         keys,
+			statusCode:   http.StatusUnauthorized,
+			response:     `{"message":"Authentication required"}`,
+			authenticate: "Bearer",
+		},
+		"other scheme": {
+			keys:          keys,
+			authorization: "Basic am9objpzZWNyZXQ=",
+			statusCode:    http.StatusUnauthorized,
+			response:      `{"message":"Authentication required"}`,
+			authenticate:  "Bearer",
+		},
+		"expired token": {
+			keys:          keys,
+			authorization: "Bearer " + sign(map[string]interface{}{"sub": "john", "exp": time.Now().Add(-time.Minute).Unix()}),
+			statusCode:    http.StatusUnauthorized,
+			response:      `{"message":"Invalid token","errors":["token is expired"]}`,
+			authenticate:  `Bearer error="invalid_token"`,
+		},
+		"token without subject": {
+			keys:          keys,
+			authorization: "Bearer " + sign(map[string]interface{}{"exp": exp}),
+			statusCode:    http.StatusUnauthorized,
+			response:      `{"message":"Invalid token","errors":["token has no subject"]}`,
+			authenticate:  `Bearer error="invalid_token"`,
+		},
+		"keys not available": {
+			keys:          jwtKeys{err: fmt.Errorf("%w: connection refused", jwt.ErrNoKeys)},
+			authorization: "Bearer " + sign(map[string]interface{}{"sub": "john", "exp": exp}),
+			statusCode:    http.StatusServiceUnavailable,
+			response:      `{"message":"Authentication is not available"}`,
+		},
+		"already authenticated": {
+			keys:          keys,
+			principal:     &models.Principal{Subject: "service"},
+			statusCode:    http.StatusOK,
+			authenticated: &models.Principal{Subject: "service"},
+		},
+		"disabled authentication": {
+			statusCode: http.StatusOK,
+		},
+		"required authentication without token keys": {
+			required:   true,
+			statusCode: http.StatusUnauthorized,
+			response:   `{"message":"Authentication required"}`,
+		},
+		"required authentication with API key": {
+			required:      true,
+			principal:     &models.Principal{Subject: "api-key:p1", APIKey: "p1"},
+			statusCode:    http.StatusOK,
+			authenticated: &models.Principal{Subject: "api-key:p1", APIKey: "p1"},
+		},
+		"token with disabled authentication": {
+			authorization: "Bearer " + sign(map[string]interface{}{"sub": "john", "exp": exp}),
+			statusCode:    http.StatusUnauthorized,
+			response:      `{"message":"Invalid token","errors":["tokens are not accepted"]}`,
+			authenticate:  `Bearer error="invalid_token"`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := configs.Config{}
+			cfg.JWT.TenantClaim = "tenant"
+			cfg.JWT.RolesClaim = "roles"
+			cfg.Tenants.Default = "default"
+			cfg.Auth.Required = c.required
+
+			var verifier TokenVerifier
+			if c.keys != nil {
+				verifier = jwt.NewVerifier(c.keys, nil, "", 0)
+			}
+
+			mw := NewJWTAuth(&cfg, verifier)
+			h := mw.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal, ok := models.PrincipalFrom(r.Context())
+				if c.authenticated == nil {
+					assert.False(t, ok)
+				} else {
+					assert.Equal(t, *c.authenticated, principal)
+				}
+				assert.Equal(t, c.tenant, models.TenantFrom(r.Context()))
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if c.authorization != "" {
+				req.Header.Set("Authorization", c.authorization)
+			}
+			if c.principal != nil {
+				req = req.WithContext(models.WithPrincipal(req.Context(), *c.principal))
+			}
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			assert.Equal(t, c.statusCode, w.Result().StatusCode)
+			assert.Equal(t, c.response, strings.Trim(w.Body.String(), "\n"))
+			assert.Equal(t, c.authenticate, w.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
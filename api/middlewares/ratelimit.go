@@ -0,0 +1,124 @@
+package middlewares
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/responses"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/ratelimit"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/rbac"
+	"github.com/go-chi/render"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultBucket is the name of the bucket shared by the routes without a limit.
+const defaultBucket = "*"
+
+// RateLimit is a middleware used to limit the rate of the requests of every client.
+type RateLimit struct {
+	store    ratelimit.Store
+	limits   ratelimit.Limits
+	failOpen bool
+	client   func(*http.Request) string
+	now      func() time.Time
+}
+
+// NewRateLimit creates an instance of RateLimit middleware.
+// If failOpen is set, the requests are allowed when the store is not available, otherwise they are rejected.
+func NewRateLimit(store ratelimit.Store, limits ratelimit.Limits, failOpen bool) *RateLimit {
+	return &RateLimit{store: store, limits: limits, failOpen: failOpen, client: clientKey, now: time.Now}
+}
+
+// NewIPRateLimit creates an instance of RateLimit middleware, which identifies the clients by the IP address only.
+// It runs before the authentication, so the API keys and tokens cannot be guessed without a limit.
+func NewIPRateLimit(store ratelimit.Store, limits ratelimit.Limits, failOpen bool) *RateLimit {
+	return &RateLimit{store: store, limits: limits, failOpen: failOpen, client: ipKey, now: time.Now}
+}
+
+// Handle is used to take a token from the bucket of the client for the route.
+// The clients are identified by the API key, the subject of the token, or the IP address of the anonymous callers.
+// The routes with a limit have their own buckets, other routes share the bucket of the default limit.
+// If the store is not available the requests are allowed when the middleware fails open, otherwise they fail with 503.
+func (l *RateLimit) Handle(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		route := ""
+		if pattern, ok := routePattern(r); ok {
+			route = r.Method + " " + rbac.Pattern(pattern)
+		}
+
+		bucket := route
+		limit, ok := l.limits.Routes[route]
+		if !ok {
+			if l.limits.Default == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			bucket, limit = defaultBucket, *l.limits.Default
+		}
+
+		client := l.client(r)
+
+		res, err := l.store.Take(r.Context(), client+"|"+bucket, limit, l.now())
+		if err != nil {
+			log.WithFields(log.Fields{"client": client, "error": err}).Warn("Cannot check rate limit")
+			if !l.failOpen {
+				render.Render(w, r, &responses.ErrResponse{Message: "Rate limit cannot be checked", HTTPStatusCode: http.StatusServiceUnavailable})
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Burst, ceilSeconds(limit.Period)))
+
+		if !res.Allowed {
+			log.WithFields(log.Fields{"client": client, "route": bucket}).Warn("Rate limit exceeded")
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			render.Render(w, r, &responses.ErrResponse{Message: "Too many requests", HTTPStatusCode: http.StatusTooManyRequests})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
+
+// clientKey returns the key of the client sending the request.
+func clientKey(r *http.Request) string {
+	if principal, ok := models.PrincipalFrom(r.Context()); ok {
+		if principal.APIKey != "" {
+			return "key:" + principal.APIKey
+		}
+		return "sub:" + principal.Subject
+	}
+
+	return "ip:" + remoteHost(r)
+}
+
+// ipKey returns the key of the IP address sending the request,
+// it does not share the buckets with the anonymous clients of clientKey.
+func ipKey(r *http.Request) string {
+	return "pre-auth:ip:" + remoteHost(r)
+}
+
+// remoteHost returns the IP address of the request, without the port.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ceilSeconds returns the duration in whole seconds, rounded up.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,278 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/ratelimit"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit ratelimit.Limit, now time.Time) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store is down")
+}
+
+// rateLimitRequest describes a request sent to the rate limited router.
+type rateLimitRequest struct {
+	method    string
+	path      string
+	ip        string
+	principal *models.Principal
+}
+
+func TestRateLimit(t *testing.T) {
+	limits := ratelimit.Limits{
+		Default: &ratelimit.Limit{Burst: 2, Period: time.Minute},
+		Routes:  map[string]ratelimit.Limit{"POST /companies": {Burst: 1, Period: 10 * time.Second}},
+	}
+
+	john := &models.Principal{Subject: "john"}
+	key := &models.Principal{Subject: "api-key:p1", APIKey: "p1"}
+
+	cases := map[string]struct {
+		limits     ratelimit.Limits
+		byIP       bool
+		store      ratelimit.Store
+		failOpen   bool
+		before     []rateLimitRequest
+		request    rateLimitRequest
+		statusCode int
+		response   string
+		headers    map[string]string
+	}{
+		"first request": {
+			limits:     limits,
+			request:    rateLimitRequest{method: http.MethodGet, path: "/companies", ip: "1.1.1.1"},
+			statusCode: http.StatusOK,
+			response:   "the end.",
+			headers:    map[string]string{"RateLimit-Limit": "2", "RateLimit-Remaining": "1", "RateLimit-Reset": "30", "RateLimit-Policy": "2;w=60", "Retry-After": ""},
+		},
+		"limit exceeded": {
+			limits: limits,
+			before: []rateLimitRequest{
+				{method: http.MethodGet, path: "/companies", ip: "1.1.1.1"},
+				{method: http.MethodGet, path: "/companies/1", ip: "1.1.1.1"},
+			},
+			request:    rateLimitRequest{method: http.MethodGet, path: "/companies", ip: "1.1.1.1"},
+			statusCode: http.StatusTooManyRequests,
+			response:   `{"message":"Too many requests"}`,
+			headers:    map[string]string{"RateLimit-Limit": "2", "RateLimit-Remaining": "0", "RateLimit-Reset": "60", "Retry-After": "30"},
+		},
+		"route has own limit": {
+			limits: limits,
+			before: []rateLimitRequest{
+				{method: http.MethodPost, path: "/companies", ip: "1.1.1.1"},
+			},
+			request:    rateLimitRequest{method: http.MethodPost, path: "/companies", ip: "1.1.1.1"},
+			statusCode: http.StatusTooManyRequests,
+			response:   `{"message":"Too many requests"}`,
+			headers:    map[string]string{"RateLimit-Limit": "1", "RateLimit-Policy": "1;w=10", "Retry-After": "10"},
+		},
+		"route has own bucket": {
+			limits: limits,
+			before: []rateLimitRequest{
+				{method: http.MethodPost, path: "/companies", ip: "1.1.1.1"},
+			},
+			request:    rateLimitRequest{method: http.MethodGet, path: "/companies", ip: "1.1.1.1"},
+			statusCode: http.StatusOK,
+			response:   "the end.",
+			headers:    map[string]string{"RateLimit-Limit": "2", "RateLimit-Remaining": "1"},
+		},
+		"clients have own buckets": {
+			limits: limits,
+			before: []rateLimitRequest{
+				{method: http.MethodPost, path: "/companies", ip: "1.1.1.1"},
+			},
+			request:    rateLimitRequest{method: http.MethodPost, path: "/companies", ip: "2.2.2.2"},
+			statusCode: http.StatusOK,
+			response:   "the end.",
+		},
+		"subject is the client": {
+			limits: limits,
+			before: []rateLimitRequest{
+				{method: http.MethodPost, path: "/companies", ip: "1.1.1.1", principal: john},
+			},
+			request:    rateLimitRequest{method: http.MethodPost, path: "/companies", ip: "2.2.2.2", principal: john},
+			statusCode: http.StatusTooManyRequests,
+			response:   `{"message":"Too many requests"}`,
+		},
+		"API key is the client": {
+			limits: limits,
+			before: []rateLimitRequest{
+				{method: http.MethodPost, path: "/companies", ip: "1.1.1.1", principal: key},
+				{method: http.MethodPost, path: "/companies", ip: "1.1.1.1", principal: john},
+			},
+			request:    rateLimitRequest{method: http.MethodPost, path: "/companies", ip: "2.2.2.2", principal: key},
+			statusCode: http.StatusTooManyRequests,
+			response:   `{"message":"Too many requests"}`,
+		},
+		"IP address is the client before authentication": {
+			limits: limits,
+			byIP:   true,
+			before: []rateLimitRequest{
+				{method: http.MethodPost, path: "/companies", ip: "1.1.1.1", principal: john},
+			},
+			request:    rateLimitRequest{method: http.MethodPost, path: "/companies", ip: "1.1.1.1", principal: key},
+			statusCode: http.StatusTooManyRequests,
+			response:   `{"message":"Too many requests"}`,
+		},
+		"unknown routes have default limit": {
+			limits: limits,
+			before: []rateLimitRequest{
+				{method: http.MethodGet, path: "/moon", ip: "1.1.1.1"},
+				{method: http.MethodGet, path: "/companies", ip: "1.1.1.1"},
+			},
+			request:    rateLimitRequest{method: http.MethodGet, path: "/moon", ip: "1.1.1.1"},
+			statusCode: http.StatusTooManyRequests,
+			response:   `{"message":"Too many requests"}`,
+		},
+		"routes without limit are not limited": {
+			limits: ratelimit.Limits{Routes: limits.Routes},
+			before: []rateLimitRequest{
+				{method: http.MethodGet, path: "/companies", ip: "1.1.1.1"},
+				{method: http.MethodGet, path: "/companies", ip: "1.1.1.1"},
+			},
+			request:    rateLimitRequest{method: http.MethodGet, path: "/companies", ip: "1.1.1.1"},
+			statusCode: http.StatusOK,
+			response:   "the end.",
+			headers:    map[string]string{"RateLimit-Limit": ""},
+		},
+		"store is not available": {
+			limits:     limits,
+			store:      failingStore{},
+			failOpen:   true,
+			request:    rateLimitRequest{method: http.MethodGet, path: "/companies", ip: "1.1.1.1"},
+			statusCode: http.StatusOK,
+			response:   "the end.",
+			headers:    map[string]string{"RateLimit-Limit": ""},
+		},
+		"store is not available and limit fails closed": {
+			limits:     limits,
+			store:      failingStore{},
+			request:    rateLimitRequest{method: http.MethodGet, path: "/companies", ip: "1.1.1.1"},
+			statusCode: http.StatusServiceUnavailable,
+			response:   `{"message":"Rate limit cannot be checked"}`,
+			headers:    map[string]string{"RateLimit-Limit": ""},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			store := c.store
+			if store == nil {
+				store = ratelimit.NewMemoryStore()
+			}
+
+			now := time.Date(2022, 4, 1, 10, 0, 0, 0, time.UTC)
+			mw := NewRateLimit(store, c.limits, c.failOpen)
+			if c.byIP {
+				mw = NewIPRateLimit(store, c.limits, c.failOpen)
+			}
+			mw.now = func() time.Time { return now }
+
+			end := func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("the end."))
+			}
+
+			router := chi.NewRouter()
+			router.Use(mw.Handle)
+			router.Route("/companies", func(r chi.Router) {
+				r.Get("/", end)
+				r.Post("/", end)
+				r.Get("/{id:[0-9]+}", end)
+			})
+
+			send := func(req rateLimitRequest) *httptest.ResponseRecorder {
+				r := httptest.NewRequest(req.method, req.path, nil)
+				r.RemoteAddr = req.ip + ":1234"
+				if req.principal != nil {
+					r = r.WithContext(models.WithPrincipal(r.Context(), *req.principal))
+				}
+
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, r)
+				return w
+			}
+
+			for _, req := range c.before {
+				send(req)
+			}
+
+			w := send(c.request)
+
+			assert.Equal(t, c.statusCode, w.Result().StatusCode)
+			assert.Equal(t, c.response, strings.Trim(w.Body.String(), "\n"))
+			for header, value := range c.headers {
+				assert.Equal(t, value, w.Header().Get(header), header)
+			}
+		})
+	}
+}
+
+func TestIPRateLimitHasOwnBuckets(t *testing.T) {
+	limits := ratelimit.Limits{Default: &ratelimit.Limit{Burst: 1, Period: time.Minute}}
+	store := ratelimit.NewMemoryStore()
+
+	router := chi.NewRouter()
+	router.Use(NewIPRateLimit(store, limits, false).Handle)
+	router.Use(NewRateLimit(store, limits, true).Handle)
+	router.Get("/companies", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("the end."))
+	})
+
+	send := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/companies", nil)
+		r.RemoteAddr = "1.1.1.1:1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, send().Result().StatusCode)
+	assert.Equal(t, http.StatusTooManyRequests, send().Result().StatusCode)
+}
+
+func TestRateLimitWhenRedisIsDown(t *testing.T) {
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr(), MaxRetries: -1})
+	defer client.Close()
+
+	srv.Close()
+
+	store := ratelimit.NewRedisStore(client, "ratelimit")
+	limits := ratelimit.Limits{Default: &ratelimit.Limit{Burst: 10, Period: time.Minute}}
+
+	send := func(mw *RateLimit) *httptest.ResponseRecorder {
+		router := chi.NewRouter()
+		router.Use(mw.Handle)
+		router.Get("/companies", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("the end."))
+		})
+
+		r := httptest.NewRequest(http.MethodGet, "/companies", nil)
+		r.RemoteAddr = "1.1.1.1:1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	// the defaults of RATE_LIMIT_IP_FAIL_OPEN and RATE_LIMIT_FAIL_OPEN
+	w := send(NewIPRateLimit(store, limits, false))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Result().StatusCode)
+	assert.Equal(t, `{"message":"Rate limit cannot be checked"}`, strings.Trim(w.Body.String(), "\n"))
+
+	w = send(NewRateLimit(store, limits, true))
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, "the end.", w.Body.String())
+}
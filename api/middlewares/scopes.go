@@ -0,0 +1,52 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/responses"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/go-chi/render"
+	log "github.com/sirupsen/logrus"
+)
+
+// RequireScope is a middleware used to allow only the callers with the scope.
+type RequireScope struct {
+	scope string
+}
+
+// NewRequireScope creates an instance of RequireScope middleware.
+func NewRequireScope(scope string) *RequireScope {
+	return &RequireScope{scope: scope}
+}
+
+// Handle is used to check the authenticated caller has the scope.
+func (s *RequireScope) Handle(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := models.PrincipalFrom(r.Context())
+		if !ok {
+			render.Render(w, r, &responses.ErrResponse{Message: "Authentication required", HTTPStatusCode: http.StatusUnauthorized})
+			return
+		}
+
+		if !models.Scopes(principal.Scopes).Has(s.scope) {
+			render.Render(w, r, insufficientScope(r.Context(), s.scope))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
+
+// insufficientScope returns the response of the request denied because of the missing scope.
+func insufficientScope(ctx context.Context, scope string) render.Renderer {
+	principal, _ := models.PrincipalFrom(ctx)
+	log.WithFields(log.Fields{"subject": principal.Subject, "scope": scope}).Warn("Scope missing")
+
+	return &responses.ErrResponse{
+		Message:        "Insufficient scope",
+		Errors:         []string{scope + " scope is required"},
+		HTTPStatusCode: http.StatusForbidden,
+	}
+}
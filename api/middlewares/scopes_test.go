@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireScope(t *testing.T) {
+	cases := map[string]struct {
+		principal  *models.Principal
+		statusCode int
+		response   string
+	}{
+		"anonymous": {
+			statusCode: http.StatusUnauthorized,
+			response:   `{"message":"Authentication required"}`,
+		},
+		"token with scope": {
+			principal:  &models.Principal{Subject: "admin", Scopes: []string{"openid", models.ScopeAPIKeysManage}},
+			statusCode: http.StatusOK,
+			response:   "the end.",
+		},
+		"token without scope": {
+			principal:  &models.Principal{Subject: "user", Scopes: []string{"openid"}},
+			statusCode: http.StatusForbidden,
+			response:   `{"message":"Insufficient scope","errors":["api-keys:manage scope is required"]}`,
+		},
+		"key without scope": {
+			principal:  &models.Principal{APIKey: "p1", Scopes: []string{models.ScopeCompaniesRead}},
+			statusCode: http.StatusForbidden,
+			response:   `{"message":"Insufficient scope","errors":["api-keys:manage scope is required"]}`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			h := NewRequireScope(models.ScopeAPIKeysManage).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("the end."))
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if c.principal != nil {
+				req = req.WithContext(models.WithPrincipal(req.Context(), *c.principal))
+			}
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			assert.Equal(t, c.statusCode, w.Result().StatusCode)
+			assert.Equal(t, c.response, strings.Trim(w.Body.String(), "\n"))
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/api/responses"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/validator"
+	"github.com/go-chi/render"
+	"github.com/gorilla/schema"
+)
+
+// SearchQueryCtx is a middleware used to validate companies search query params.
+type SearchQueryCtx struct {
+	validator    *validator.Validation
+	queryDecoder *schema.Decoder
+}
+
+// NewSearchQueryCtx creates an instance of SearchQueryCtx middleware.
+func NewSearchQueryCtx(v *validator.Validation, queryDecoder *schema.Decoder) *SearchQueryCtx {
+	return &SearchQueryCtx{validator: v, queryDecoder: queryDecoder}
+}
+
+// Handle is used to decode and validate companies search query params.
+// In case params are invalid, we return formatted errors.
+func (s *SearchQueryCtx) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data requests.SearchQuery
+
+		if err := s.queryDecoder.Decode(&data, r.URL.Query()); err != nil {
+			render.Render(w, r, &responses.ErrResponse{
+				Message:        "Invalid query params",
+				HTTPStatusCode: http.StatusBadRequest,
+			})
+			return
+		}
+
+		if errs := s.validator.Validate(&data); len(errs) != 0 {
+			render.Render(w, r, &responses.ErrResponse{
+				Message:        "Invalid query params",
+				Errors:         errs.Errors(),
+				HTTPStatusCode: http.StatusBadRequest,
+			})
+			return
+		}
+
+		if terms := data.Terms(); len(terms) == 0 || len(terms) > requests.MaxSearchTerms {
+			render.Render(w, r, &responses.ErrResponse{
+				Message:        "Invalid search query",
+				Errors:         []string{fmt.Sprintf("query must contain from 1 to %d words", requests.MaxSearchTerms)},
+				HTTPStatusCode: http.StatusBadRequest,
+			})
+			return
+		}
+
+		if _, err := data.Offset(); err != nil {
+			render.Render(w, r, &responses.ErrResponse{
+				Message:        "Invalid cursor",
+				HTTPStatusCode: http.StatusBadRequest,
+			})
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), handlers.SearchQueryCtxKey{}, data)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
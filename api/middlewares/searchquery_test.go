@@ -0,0 +1,74 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/pagination"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/validator"
+	"github.com/gorilla/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchQueryCtx(t *testing.T) {
+	cases := map[string]struct {
+		path       string
+		statusCode int
+		response   string
+	}{
+		"query is required": {
+			path:       "/",
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid query params","errors":["Field validation for 'Q' failed on the 'required' tag"]}`,
+		},
+		"query will not accept random field": {
+			path:       "/?q=acme&moon=white",
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid query params"}`,
+		},
+		"query must contain words": {
+			path:       "/?q=%2B-*",
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid search query","errors":["query must contain from 1 to 8 words"]}`,
+		},
+		"query must not contain too many words": {
+			path:       "/?q=a+b+c+d+e+f+g+h+i",
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid search query","errors":["query must contain from 1 to 8 words"]}`,
+		},
+		"query will not accept cursor for other query": {
+			path:       "/?q=acme&cursor=" + pagination.Cursor{Sort: "beta", Offset: 20}.Encode(),
+			statusCode: http.StatusBadRequest,
+			response:   `{"message":"Invalid cursor"}`,
+		},
+		"valid query will call next": {
+			path:       "/?q=acme+hold&limit=10&cursor=" + pagination.Cursor{Sort: "acme hold", Offset: 20}.Encode(),
+			statusCode: http.StatusOK,
+			response:   `the end.`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			mw := NewSearchQueryCtx(validator.NewValidation(), schema.NewDecoder())
+			h := mw.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("the end."))
+				w.WriteHeader(http.StatusOK)
+
+				data := r.Context().Value(handlers.SearchQueryCtxKey{})
+				assert.IsType(t, requests.SearchQuery{}, data)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			assert.Equal(t, c.statusCode, w.Result().StatusCode)
+			assert.Equal(t, c.response, strings.Trim(w.Body.String(), "\n"))
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package middlewares
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/responses"
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/go-chi/render"
+	log "github.com/sirupsen/logrus"
+)
+
+// tenantPattern matches the valid tenant IDs, they are used in the cache keys so a colon is not allowed.
+var tenantPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// TenantCtx is a middleware used to load the tenant of the request to context.
+type TenantCtx struct {
+	config *configs.Config
+}
+
+// NewTenantCtx creates an instance of TenantCtx middleware.
+func NewTenantCtx(cfg *configs.Config) *TenantCtx {
+	return &TenantCtx{config: cfg}
+}
+
+// Handle is used to resolve the tenant of the request.
+// A tenant loaded to context before, e.g. from the claims of the auth token, takes precedence,
+// and the authenticated callers without a tenant are bound to the default tenant,
+// the header cannot be used to switch to another tenant.
+// Only the anonymous callers choose the tenant with the header, falling back to the default tenant.
+func (t *TenantCtx) Handle(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		tenant := r.Header.Get(t.config.Tenants.Header)
+
+		if _, ok := models.PrincipalFrom(r.Context()); ok || models.HasTenant(r.Context()) {
+			pinned := models.TenantFrom(r.Context())
+			if pinned == "" {
+				pinned = t.config.Tenants.Default
+			}
+
+			if tenant != "" && tenant != pinned {
+				log.WithFields(log.Fields{"tenant": pinned, "header": tenant}).Warn("Tenant header does not match the principal")
+				render.Render(w, r, &responses.ErrResponse{Message: "Tenant not allowed", HTTPStatusCode: http.StatusForbidden})
+				return
+			}
+			tenant = pinned
+		}
+
+		if tenant == "" {
+			tenant = t.config.Tenants.Default
+		}
+
+		if tenant == "" {
+			render.Render(w, r, &responses.ErrResponse{Message: "Tenant is required", HTTPStatusCode: http.StatusBadRequest})
+			return
+		}
+
+		if !tenantPattern.MatchString(tenant) {
+			render.Render(w, r, &responses.ErrResponse{Message: "Invalid tenant", HTTPStatusCode: http.StatusBadRequest})
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(models.WithTenant(r.Context(), tenant)))
+	}
+	return http.HandlerFunc(fn)
+}
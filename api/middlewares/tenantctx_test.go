@@ -0,0 +1,165 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/db/memory"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantCtx(t *testing.T) {
+	cases := map[string]struct {
+		defaultTenant string
+		principal     string
+		subject       string
+		header        string
+		tenant        string
+		statusCode    int
+	}{
+		"tenant from header": {
+			defaultTenant: "default",
+			header:        "acme",
+			tenant:        "acme",
+			statusCode:    http.StatusOK,
+		},
+		"default tenant": {
+			defaultTenant: "default",
+			tenant:        "default",
+			statusCode:    http.StatusOK,
+		},
+		"tenant required": {
+			statusCode: http.StatusBadRequest,
+		},
+		"invalid tenant": {
+			defaultTenant: "default",
+			header:        "acme:1",
+			statusCode:    http.StatusBadRequest,
+		},
+		"tenant from principal": {
+			defaultTenant: "default",
+			principal:     "acme",
+			tenant:        "acme",
+			statusCode:    http.StatusOK,
+		},
+		"header matches principal": {
+			principal:  "acme",
+			header:     "acme",
+			tenant:     "acme",
+			statusCode: http.StatusOK,
+		},
+		"invalid tenant of principal": {
+			principal:  "acme:1",
+			statusCode: http.StatusBadRequest,
+		},
+		"principal without tenant": {
+			defaultTenant: "default",
+			subject:       "john",
+			tenant:        "default",
+			statusCode:    http.StatusOK,
+		},
+		"principal without tenant cannot choose tenant": {
+			defaultTenant: "default",
+			subject:       "john",
+			header:        "acme",
+			statusCode:    http.StatusForbidden,
+		},
+		"header does not match principal": {
+			principal:  "acme",
+			header:     "other",
+			statusCode: http.StatusForbidden,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := configs.Config{}
+			cfg.Tenants.Header = "X-Tenant-ID"
+			cfg.Tenants.Default = c.defaultTenant
+
+			mw := NewTenantCtx(&cfg)
+			h := mw.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, c.tenant, models.TenantFrom(r.Context()))
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if c.header != "" {
+				req.Header.Set("X-Tenant-ID", c.header)
+			}
+			if c.principal != "" {
+				req = req.WithContext(models.WithTenant(req.Context(), c.principal))
+			}
+			if c.subject != "" {
+				req = req.WithContext(models.WithPrincipal(req.Context(), models.Principal{Subject: c.subject}))
+			}
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			assert.Equal(t, c.statusCode, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestTenantCtxHidesCompaniesOfOtherTenants(t *testing.T) {
+	cfg := configs.Config{}
+	cfg.Tenants.Header = "X-Tenant-ID"
+	cfg.Tenants.Default = "default"
+
+	companiesRepo := memory.NewCompaniesRepo(&cfg)
+
+	company, err := companiesRepo.Create(models.WithTenant(context.Background(), "acme"), models.Company{Name: "Acme", Code: "ACME", Country: "Cyprus"})
+	require.NoError(t, err)
+
+	cases := map[string]struct {
+		tenant     string
+		statusCode int
+		response   string
+	}{
+		"owner tenant": {
+			tenant:     "acme",
+			statusCode: http.StatusOK,
+			response:   "the end.",
+		},
+		"other tenant": {
+			tenant:     "other",
+			statusCode: http.StatusNotFound,
+			response:   `{"message":"Company not found"}`,
+		},
+		"default tenant": {
+			statusCode: http.StatusNotFound,
+			response:   `{"message":"Company not found"}`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			h := NewCompanyCtx(companiesRepo).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("the end."))
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/%d", company.ID), nil)
+			if c.tenant != "" {
+				req.Header.Set("X-Tenant-ID", c.tenant)
+			}
+			w := httptest.NewRecorder()
+
+			r := chi.NewRouter()
+			r.Use(NewTenantCtx(&cfg).Handle)
+			r.Get("/{id:[0-9]+}", h.ServeHTTP)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, c.statusCode, w.Result().StatusCode)
+			assert.Equal(t, c.response, strings.Trim(w.Body.String(), "\n"))
+		})
+	}
+}
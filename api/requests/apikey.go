@@ -0,0 +1,33 @@
+package requests
+
+import (
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+)
+
+// APIKeyPayload is a data structure used to decode incoming API key data.
+// The expiry is optional, it must be in the future.
+type APIKeyPayload struct {
+	Name      string     `json:"name" validate:"required,gt=0,max=255"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,oneof=companies:read companies:write companies:delete"`
+	ExpiresAt *time.Time `json:"expires_at" validate:"omitempty,gt"`
+}
+
+// ToAPIKey creates a new APIKey from the APIKeyPayload, the repeated scopes are removed.
+func (a *APIKeyPayload) ToAPIKey() models.APIKey {
+	scopes := models.Scopes{}
+	for _, scope := range a.Scopes {
+		if !scopes.Has(scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	key := models.APIKey{Name: a.Name, Scopes: scopes}
+	if a.ExpiresAt != nil {
+		expiresAt := a.ExpiresAt.UTC().Truncate(time.Millisecond)
+		key.ExpiresAt = &expiresAt
+	}
+
+	return key
+}
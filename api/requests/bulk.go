@@ -0,0 +1,51 @@
+package requests
+
+// Bulk modes define how the operations are applied.
+const (
+	// BulkModeAtomic applies all operations in a single transaction, or none of them.
+	BulkModeAtomic = "atomic"
+	// BulkModeBestEffort applies every operation independently.
+	BulkModeBestEffort = "best_effort"
+)
+
+// Bulk operations define what is done with a single company.
+const (
+	BulkOpCreate = "create"
+	BulkOpUpdate = "update"
+	BulkOpDelete = "delete"
+)
+
+// BulkOperation is a data structure used to decode a single operation of the bulk request.
+// ID and Version identify the company to update or delete, the same as the URL and If-Match header do.
+type BulkOperation struct {
+	Op      string          `json:"op" validate:"required,oneof=create update delete"`
+	ID      uint64          `json:"id" validate:"required_unless=Op create"`
+	Version uint64          `json:"version" validate:"required_unless=Op create"`
+	Data    *CompanyPayload `json:"data" validate:"required_unless=Op delete"`
+
+	// Errors holds the validation errors of the operation.
+	Errors []string `json:"-" validate:"-"`
+}
+
+// BulkPayload is a data structure used to decode the bulk request.
+// Operations are validated one by one, so the invalid ones can be reported individually.
+type BulkPayload struct {
+	Mode       string          `json:"mode" validate:"omitempty,oneof=atomic best_effort"`
+	Operations []BulkOperation `json:"operations" validate:"required,min=1"`
+}
+
+// Atomic reports whether the operations must be applied in a single transaction.
+// Operations are atomic, unless the best-effort mode is requested.
+func (b *BulkPayload) Atomic() bool {
+	return b.Mode != BulkModeBestEffort
+}
+
+// Invalid reports whether any of the operations is invalid.
+func (b *BulkPayload) Invalid() bool {
+	for _, op := range b.Operations {
+		if len(op.Errors) != 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,13 @@
 package requests
 
-import "github.com/brokeyourbike/xm-golang-exercise/models"
+import (
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/pagination"
+)
+
+// DefaultLimit is a number of companies returned on a single page,
+// if the limit is not specified.
+const DefaultLimit = 20
 
 // CompanyPayload is a data structure used to decode incoming company data.
 type CompanyPayload struct {
@@ -9,6 +16,8 @@ type CompanyPayload struct {
 	Country string `json:"country" shema:"country" validate:"required,iso3166_1_alpha2"`
 	Website string `json:"website" shema:"website" validate:"required,fqdn"`
 	Phone   string `json:"phone" shema:"phone" validate:"required,e164"`
+
+	ParentID *uint64 `json:"parent_id" shema:"parent_id" validate:"omitempty,gt=0"`
 }
 
 // ToCompany creates a new Company from the CompanyPayload.
@@ -19,5 +28,62 @@ func (c *CompanyPayload) ToCompany() models.Company {
 		Country: c.Country,
 		Website: c.Website,
 		Phone:   c.Phone,
+
+		ParentID: c.ParentID,
+	}
+}
+
+// CompaniesQuery is a data structure used to decode companies list query params.
+type CompaniesQuery struct {
+	Filters []Filter `schema:"-" validate:"-"`
+	Sort    string   `schema:"sort" validate:"max=255"`
+	Limit   int      `schema:"limit" validate:"gte=0,max=100"`
+	Cursor  string   `schema:"cursor" validate:"max=1024"`
+}
+
+// PageSize returns the number of companies to return on a single page.
+func (q *CompaniesQuery) PageSize() int {
+	if q.Limit == 0 {
+		return DefaultLimit
 	}
+	return q.Limit
+}
+
+// SortKeys returns the parsed sort expression.
+func (q *CompaniesQuery) SortKeys() ([]SortKey, error) {
+	return ParseSort(q.Sort)
+}
+
+// After returns the decoded cursor, which points to the last company of the previous page.
+// Cursor created for a different sort expression is considered invalid.
+func (q *CompaniesQuery) After() (pagination.Cursor, error) {
+	c, err := pagination.Decode(q.Cursor)
+	if err != nil || q.Cursor == "" {
+		return c, err
+	}
+
+	keys, err := q.SortKeys()
+	if err != nil {
+		return c, err
+	}
+
+	if c.Sort != q.Sort || len(c.Values) != len(keys)-1 {
+		return c, pagination.ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// CursorFor creates a cursor pointing to the given company.
+func (q *CompaniesQuery) CursorFor(company models.Company) pagination.Cursor {
+	c := pagination.Cursor{ID: company.ID, Sort: q.Sort}
+
+	keys, _ := q.SortKeys()
+	for _, k := range keys {
+		if k.Field != "id" {
+			c.Values = append(c.Values, company.Field(k.Field))
+		}
+	}
+
+	return c
 }
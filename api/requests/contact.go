@@ -0,0 +1,23 @@
+package requests
+
+import "github.com/brokeyourbike/xm-golang-exercise/models"
+
+// ContactPayload is a data structure used to decode incoming contact data.
+// At least one of email or phone is required.
+type ContactPayload struct {
+	Name  string `json:"name" validate:"required,gt=0,max=255"`
+	Role  string `json:"role" validate:"max=255"`
+	Email string `json:"email" validate:"required_without=Phone,omitempty,email,max=255"`
+	Phone string `json:"phone" validate:"required_without=Email,omitempty,e164"`
+}
+
+// ToContact creates a new Contact of the company from the ContactPayload.
+func (c *ContactPayload) ToContact(companyID uint64) models.Contact {
+	return models.Contact{
+		CompanyID: companyID,
+		Name:      c.Name,
+		Role:      c.Role,
+		Email:     c.Email,
+		Phone:     c.Phone,
+	}
+}
@@ -0,0 +1,33 @@
+package requests
+
+// Export formats.
+const (
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson"
+	ExportXLSX   = "xlsx"
+)
+
+// exportMediaTypes maps the export formats to their media types.
+var exportMediaTypes = map[string]string{
+	ExportCSV:    CSVMediaType,
+	ExportNDJSON: NDJSONMediaType,
+	ExportXLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// ExportQuery is a data structure used to decode companies export query params.
+// The companies are filtered and sorted by the CompaniesQuery,
+// BatchSize is the number of companies read from the storage at once.
+type ExportQuery struct {
+	Format    string `schema:"format" validate:"required,oneof=csv ndjson xlsx"`
+	BatchSize int    `schema:"-" validate:"-"`
+}
+
+// MediaType returns the media type of the export file.
+func (q *ExportQuery) MediaType() string {
+	return exportMediaTypes[q.Format]
+}
+
+// Filename returns the name of the export file.
+func (q *ExportQuery) Filename() string {
+	return "companies." + q.Format
+}
@@ -0,0 +1,141 @@
+package requests
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// FilterOp defines an operator used to compare the company field with the filter values.
+type FilterOp string
+
+const (
+	OpEq        FilterOp = "eq"
+	OpNe        FilterOp = "ne"
+	OpIn        FilterOp = "in"
+	OpPrefix    FilterOp = "prefix"
+	OpContains  FilterOp = "contains"
+	OpIEq       FilterOp = "ieq"
+	OpIPrefix   FilterOp = "iprefix"
+	OpIContains FilterOp = "icontains"
+)
+
+// listSplitter separates values in the `in` filters and sort expression.
+const listSplitter = ","
+
+// filterOps is a set of supported filter operators.
+var filterOps = map[FilterOp]bool{
+	OpEq: true, OpNe: true, OpIn: true, OpPrefix: true, OpContains: true,
+	OpIEq: true, OpIPrefix: true, OpIContains: true,
+}
+
+// FilterFields is a set of company fields which can be used in filters.
+var FilterFields = map[string]bool{
+	"name": true, "code": true, "country": true, "website": true, "phone": true,
+}
+
+// reservedParams is a set of query params which are not filters.
+var reservedParams = map[string]bool{
+	"sort": true, "limit": true, "cursor": true,
+}
+
+// Filter defines a single condition applied to the company field.
+type Filter struct {
+	Field  string
+	Op     FilterOp
+	Values []string
+}
+
+// Match reports whether the given field value satisfies the filter.
+func (f Filter) Match(value string) bool {
+	switch f.Op {
+	case OpEq:
+		return value == f.Values[0]
+	case OpNe:
+		return value != f.Values[0]
+	case OpIn:
+		for _, v := range f.Values {
+			if value == v {
+				return true
+			}
+		}
+		return false
+	case OpPrefix:
+		return strings.HasPrefix(value, f.Values[0])
+	case OpContains:
+		return strings.Contains(value, f.Values[0])
+	case OpIEq:
+		return strings.EqualFold(value, f.Values[0])
+	case OpIPrefix:
+		return strings.HasPrefix(strings.ToLower(value), strings.ToLower(f.Values[0]))
+	case OpIContains:
+		return strings.Contains(strings.ToLower(value), strings.ToLower(f.Values[0]))
+	}
+	return false
+}
+
+// ParseFilters extracts filters from the query params.
+// Filters are passed as `field=value` or `field[op]=value`,
+// plain value containing a comma is treated as an `in` list.
+// Remaining query params (sort, limit, cursor) are returned as is.
+func ParseFilters(values url.Values) ([]Filter, url.Values, error) {
+	filters := []Filter{}
+	rest := url.Values{}
+
+	for key, vals := range values {
+		if reservedParams[key] {
+			rest[key] = vals
+			continue
+		}
+
+		field, op, err := parseFilterKey(key)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, v := range vals {
+			if f, ok := newFilter(field, op, v); ok {
+				filters = append(filters, f)
+			}
+		}
+	}
+
+	return filters, rest, nil
+}
+
+// parseFilterKey splits `field[op]` query param key into field and operator.
+func parseFilterKey(key string) (string, FilterOp, error) {
+	field, op := key, FilterOp("")
+
+	if i := strings.Index(key, "["); i != -1 && strings.HasSuffix(key, "]") {
+		field, op = key[:i], FilterOp(key[i+1:len(key)-1])
+		if !filterOps[op] {
+			return "", "", fmt.Errorf("unknown filter operator '%s'", op)
+		}
+	}
+
+	if !FilterFields[field] {
+		return "", "", fmt.Errorf("unknown filter field '%s'", field)
+	}
+
+	return field, op, nil
+}
+
+// newFilter creates a filter for a single query param value.
+// Empty values without an explicit operator are ignored.
+func newFilter(field string, op FilterOp, value string) (Filter, bool) {
+	switch {
+	case op == "" && value == "":
+		return Filter{}, false
+	case op == "" && strings.Contains(value, listSplitter):
+		op = OpIn
+	case op == "":
+		op = OpEq
+	}
+
+	if op == OpIn {
+		return Filter{Field: field, Op: op, Values: strings.Split(value, listSplitter)}, true
+	}
+
+	return Filter{Field: field, Op: op, Values: []string{value}}, true
+}
@@ -0,0 +1,95 @@
+package requests
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilters(t *testing.T) {
+	cases := map[string]struct {
+		query   string
+		filters []Filter
+		rest    url.Values
+		err     string
+	}{
+		"plain value is an exact match": {
+			query:   "name=john",
+			filters: []Filter{{Field: "name", Op: OpEq, Values: []string{"john"}}},
+			rest:    url.Values{},
+		},
+		"plain value with comma is a list": {
+			query:   "country=CY,GR",
+			filters: []Filter{{Field: "country", Op: OpIn, Values: []string{"CY", "GR"}}},
+			rest:    url.Values{},
+		},
+		"explicit operator": {
+			query:   "name[eq]=john,+inc",
+			filters: []Filter{{Field: "name", Op: OpEq, Values: []string{"john, inc"}}},
+			rest:    url.Values{},
+		},
+		"empty plain value is ignored": {
+			query:   "name=",
+			filters: []Filter{},
+			rest:    url.Values{},
+		},
+		"reserved params are returned": {
+			query:   "sort=-name&limit=10&cursor=abc",
+			filters: []Filter{},
+			rest:    url.Values{"sort": {"-name"}, "limit": {"10"}, "cursor": {"abc"}},
+		},
+		"unknown field": {
+			query: "moon=white",
+			err:   "unknown filter field 'moon'",
+		},
+		"unknown operator": {
+			query: "name[like]=john",
+			err:   "unknown filter operator 'like'",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			values, err := url.ParseQuery(c.query)
+			assert.NoError(t, err)
+
+			filters, rest, err := ParseFilters(values)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, c.filters, filters)
+			assert.Equal(t, c.rest, rest)
+		})
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	cases := map[string]struct {
+		filter Filter
+		value  string
+		match  bool
+	}{
+		"eq":              {Filter{Op: OpEq, Values: []string{"John"}}, "John", true},
+		"eq mismatch":     {Filter{Op: OpEq, Values: []string{"John"}}, "john", false},
+		"ne":              {Filter{Op: OpNe, Values: []string{"John"}}, "Jane", true},
+		"in":              {Filter{Op: OpIn, Values: []string{"CY", "GR"}}, "GR", true},
+		"in mismatch":     {Filter{Op: OpIn, Values: []string{"CY", "GR"}}, "US", false},
+		"prefix":          {Filter{Op: OpPrefix, Values: []string{"Jo"}}, "John", true},
+		"contains":        {Filter{Op: OpContains, Values: []string{"oh"}}, "John", true},
+		"ieq":             {Filter{Op: OpIEq, Values: []string{"JOHN"}}, "John", true},
+		"iprefix":         {Filter{Op: OpIPrefix, Values: []string{"JO"}}, "John", true},
+		"icontains":       {Filter{Op: OpIContains, Values: []string{"OH"}}, "John", true},
+		"unknown op":      {Filter{Op: "like", Values: []string{"John"}}, "John", false},
+		"prefix mismatch": {Filter{Op: OpPrefix, Values: []string{"oh"}}, "John", false},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, c.match, c.filter.Match(c.value))
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package requests
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Media types accepted by the import.
+const (
+	CSVMediaType    = "text/csv"
+	NDJSONMediaType = "application/x-ndjson"
+)
+
+// maxLineSize limits the size of a single NDJSON line.
+const maxLineSize = 1024 * 1024
+
+// ErrUnsupportedImport is an error raised when the import media type is not supported.
+var ErrUnsupportedImport = errors.New("unsupported import media type")
+
+// ErrTooManyRows is an error raised when the import has more rows than allowed.
+var ErrTooManyRows = errors.New("too many rows")
+
+// ErrInvalidHeader is an error raised when the CSV header has unknown or duplicate columns.
+var ErrInvalidHeader = errors.New("invalid CSV header")
+
+// ImportRow is a single row of the import, Line is the line number of the row in the body.
+// Errors hold the decoding and validation errors of the row.
+type ImportRow struct {
+	Line    int
+	Payload CompanyPayload
+	Errors  []string
+}
+
+// Valid reports whether the row can be imported.
+func (r ImportRow) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// CompanyImport reads the companies from the CSV or NDJSON body, one row at a time.
+// CSV columns are mapped to the CompanyPayload fields by the header row.
+// If DryRun is set, the rows are only validated.
+type CompanyImport struct {
+	DryRun   bool
+	MaxRows  int
+	rows     int
+	next     func() (ImportRow, error)
+	validate func(*CompanyPayload) []string
+}
+
+// NewCompanyImport creates a new CompanyImport of the body with the given media type,
+// which reads at most maxRows rows. Every row is validated with the validate function.
+func NewCompanyImport(mediaType string, body io.Reader, maxRows int, validate func(*CompanyPayload) []string) (*CompanyImport, error) {
+	i := CompanyImport{MaxRows: maxRows, validate: validate}
+
+	switch mediaType {
+	case CSVMediaType:
+		next, err := csvRows(body)
+		if err != nil {
+			return nil, err
+		}
+		i.next = next
+	case NDJSONMediaType:
+		i.next = ndjsonRows(body)
+	default:
+		return nil, ErrUnsupportedImport
+	}
+
+	return &i, nil
+}
+
+// Next returns the next row of the import.
+// It returns io.EOF when there are no more rows,
+// and ErrTooManyRows when there are more rows than MaxRows.
+func (i *CompanyImport) Next() (ImportRow, error) {
+	row, err := i.next()
+	if err != nil {
+		return row, err
+	}
+
+	if i.rows++; i.rows > i.MaxRows {
+		return row, ErrTooManyRows
+	}
+
+	if row.Valid() {
+		row.Errors = i.validate(&row.Payload)
+	}
+
+	return row, nil
+}
+
+// csvRows reads the header of the CSV body and returns the function reading the rows.
+func csvRows(body io.Reader) (func() (ImportRow, error), error) {
+	r := csv.NewReader(body)
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return func() (ImportRow, error) { return ImportRow{}, io.EOF }, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+	}
+
+	columns := make([]string, len(header))
+	seen := make(map[string]bool, len(header))
+
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, ok := payloadField(&CompanyPayload{}, name); !ok || seen[name] {
+			return nil, fmt.Errorf("%w: unknown or duplicate column '%s'", ErrInvalidHeader, name)
+		}
+		columns[i], seen[name] = name, true
+	}
+
+	return func() (ImportRow, error) {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return ImportRow{}, io.EOF
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return ImportRow{Line: parseErr.StartLine, Errors: []string{parseErr.Err.Error()}}, nil
+		}
+
+		if err != nil {
+			return ImportRow{}, err
+		}
+
+		line, _ := r.FieldPos(0)
+		row := ImportRow{Line: line}
+
+		for i, value := range record {
+			field, _ := payloadField(&row.Payload, columns[i])
+			*field = value
+		}
+
+		return row, nil
+	}, nil
+}
+
+// ndjsonRows returns the function reading the rows of the NDJSON body, blank lines are skipped.
+func ndjsonRows(body io.Reader) func() (ImportRow, error) {
+	s := bufio.NewScanner(body)
+	s.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	line := 0
+
+	return func() (ImportRow, error) {
+		for s.Scan() {
+			line++
+
+			data := bytes.TrimSpace(s.Bytes())
+			if len(data) == 0 {
+				continue
+			}
+
+			row := ImportRow{Line: line}
+
+			d := json.NewDecoder(bytes.NewReader(data))
+			d.DisallowUnknownFields()
+
+			if err := d.Decode(&row.Payload); err != nil {
+				row.Errors = []string{"Invalid JSON: " + err.Error()}
+			}
+
+			return row, nil
+		}
+
+		if err := s.Err(); err != nil {
+			return ImportRow{}, err
+		}
+
+		return ImportRow{}, io.EOF
+	}
+}
+
+// payloadField returns the pointer to the payload field with the given JSON name.
+func payloadField(p *CompanyPayload, name string) (*string, bool) {
+	switch name {
+	case "name":
+		return &p.Name, true
+	case "code":
+		return &p.Code, true
+	case "country":
+		return &p.Country, true
+	case "website":
+		return &p.Website, true
+	case "phone":
+		return &p.Phone, true
+	}
+	return nil, false
+}
@@ -0,0 +1,159 @@
+package requests
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompanyImportNext(t *testing.T) {
+	validate := func(p *CompanyPayload) []string {
+		if p.Name == "" {
+			return []string{"name is required"}
+		}
+		return nil
+	}
+
+	cases := map[string]struct {
+		mediaType string
+		body      string
+		maxRows   int
+		rows      []ImportRow
+		err       error
+	}{
+		"csv columns are mapped by header": {
+			mediaType: CSVMediaType,
+			body:      "\ufeffPhone, Name,code\n+1234,acme,c1\n,,c2\n",
+			maxRows:   10,
+			rows: []ImportRow{
+				{Line: 2, Payload: CompanyPayload{Name: "acme", Code: "c1", Phone: "+1234"}},
+				{Line: 3, Payload: CompanyPayload{Code: "c2"}, Errors: []string{"name is required"}},
+			},
+			err: io.EOF,
+		},
+		"csv with quoted values imported verbatim": {
+			mediaType: CSVMediaType,
+			body:      "name,code,phone\n'=acme,'c1,'+1234\n",
+			maxRows:   10,
+			rows: []ImportRow{
+				{Line: 2, Payload: CompanyPayload{Name: "'=acme", Code: "'c1", Phone: "'+1234"}},
+			},
+			err: io.EOF,
+		},
+		"csv with quoted multiline value": {
+			mediaType: CSVMediaType,
+			body:      "name,code\n\"acme\nltd\",c1\nbeta,c2\n",
+			maxRows:   10,
+			rows: []ImportRow{
+				{Line: 2, Payload: CompanyPayload{Name: "acme\nltd", Code: "c1"}},
+				{Line: 4, Payload: CompanyPayload{Name: "beta", Code: "c2"}},
+			},
+			err: io.EOF,
+		},
+		"csv row with wrong number of fields is rejected": {
+			mediaType: CSVMediaType,
+			body:      "name,code\nacme\nbeta,c2\n",
+			maxRows:   10,
+			rows: []ImportRow{
+				{Line: 2, Errors: []string{"wrong number of fields"}},
+				{Line: 3, Payload: CompanyPayload{Name: "beta", Code: "c2"}},
+			},
+			err: io.EOF,
+		},
+		"empty csv": {
+			mediaType: CSVMediaType,
+			maxRows:   10,
+			err:       io.EOF,
+		},
+		"ndjson skips blank lines": {
+			mediaType: NDJSONMediaType,
+			body:      "{\"name\":\"acme\",\"code\":\"c1\"}\n\n  \n{\"code\":\"c2\"}",
+			maxRows:   10,
+			rows: []ImportRow{
+				{Line: 1, Payload: CompanyPayload{Name: "acme", Code: "c1"}},
+				{Line: 4, Payload: CompanyPayload{Code: "c2"}, Errors: []string{"name is required"}},
+			},
+			err: io.EOF,
+		},
+		"ndjson rejects invalid lines": {
+			mediaType: NDJSONMediaType,
+			body:      "not-a-json\n{\"name\":\"acme\",\"version\":1}\n",
+			maxRows:   10,
+			rows: []ImportRow{
+				{Line: 1, Errors: []string{"Invalid JSON: invalid character 'o' in literal null (expecting 'u')"}},
+				{Line: 2, Payload: CompanyPayload{Name: "acme"}, Errors: []string{`Invalid JSON: json: unknown field "version"`}},
+			},
+			err: io.EOF,
+		},
+		"number of rows is limited": {
+			mediaType: NDJSONMediaType,
+			body:      "{\"name\":\"acme\"}\n{\"name\":\"beta\"}\n",
+			maxRows:   1,
+			rows: []ImportRow{
+				{Line: 1, Payload: CompanyPayload{Name: "acme"}},
+			},
+			err: ErrTooManyRows,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			data, err := NewCompanyImport(c.mediaType, strings.NewReader(c.body), c.maxRows, validate)
+			require.NoError(t, err)
+
+			rows := []ImportRow{}
+			for {
+				row, err := data.Next()
+				if err != nil {
+					assert.ErrorIs(t, err, c.err)
+					break
+				}
+				rows = append(rows, row)
+			}
+
+			if c.rows == nil {
+				c.rows = []ImportRow{}
+			}
+			assert.Equal(t, c.rows, rows)
+		})
+	}
+}
+
+func TestNewCompanyImportErrors(t *testing.T) {
+	cases := map[string]struct {
+		mediaType string
+		body      string
+		err       error
+	}{
+		"unsupported media type": {
+			mediaType: "application/json",
+			body:      `{}`,
+			err:       ErrUnsupportedImport,
+		},
+		"unknown column": {
+			mediaType: CSVMediaType,
+			body:      "name,version\n",
+			err:       ErrInvalidHeader,
+		},
+		"duplicate column": {
+			mediaType: CSVMediaType,
+			body:      "name,Name\n",
+			err:       ErrInvalidHeader,
+		},
+		"malformed header": {
+			mediaType: CSVMediaType,
+			body:      "\"name\n",
+			err:       ErrInvalidHeader,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewCompanyImport(c.mediaType, strings.NewReader(c.body), 10, func(*CompanyPayload) []string { return nil })
+			assert.ErrorIs(t, err, c.err)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+// Patch media types define the format of the PATCH request body.
+const (
+	// MergePatchMediaType is the media type of the JSON Merge Patch, described in RFC 7396.
+	MergePatchMediaType = "application/merge-patch+json"
+	// JSONPatchMediaType is the media type of the JSON Patch, described in RFC 6902.
+	JSONPatchMediaType = "application/json-patch+json"
+)
+
+var (
+	// ErrUnsupportedPatch is an error raised when the patch media type is not supported.
+	ErrUnsupportedPatch = errors.New("unsupported patch media type")
+	// ErrInvalidPatch is an error raised when the patch document is malformed.
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrPatchFailed is an error raised when the patch can not be applied to the company.
+	ErrPatchFailed = errors.New("patch cannot be applied")
+	// ErrInvalidPatchResult is an error raised when the patched company can not be decoded.
+	ErrInvalidPatchResult = errors.New("invalid patch result")
+)
+
+// CompanyPatch is a patch of the company in one of the supported media types.
+type CompanyPatch struct {
+	MediaType string
+	Body      []byte
+}
+
+// Apply applies the patch to the JSON representation of the company
+// and decodes the result into the CompanyPayload, which should be validated as a whole.
+// The id is a part of the representation, but it can not be changed.
+func (p CompanyPatch) Apply(company models.Company) (CompanyPayload, error) {
+	doc, err := json.Marshal(company)
+	if err != nil {
+		return CompanyPayload{}, err
+	}
+
+	var patched []byte
+
+	switch p.MediaType {
+	case MergePatchMediaType:
+		if !json.Valid(p.Body) {
+			return CompanyPayload{}, fmt.Errorf("%w: body is not a valid JSON", ErrInvalidPatch)
+		}
+		if patched, err = jsonpatch.MergePatch(doc, p.Body); err != nil {
+			return CompanyPayload{}, fmt.Errorf("%w: %v", ErrPatchFailed, err)
+		}
+	case JSONPatchMediaType:
+		patch, err := jsonpatch.DecodePatch(p.Body)
+		if err != nil {
+			return CompanyPayload{}, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		if patched, err = patch.Apply(doc); err != nil {
+			return CompanyPayload{}, fmt.Errorf("%w: %v", ErrPatchFailed, err)
+		}
+	default:
+		return CompanyPayload{}, ErrUnsupportedPatch
+	}
+
+	var result struct {
+		ID uint64 `json:"id"`
+		CompanyPayload
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&result); err != nil {
+		return CompanyPayload{}, fmt.Errorf("%w: %v", ErrInvalidPatchResult, err)
+	}
+
+	if result.ID != company.ID {
+		return CompanyPayload{}, fmt.Errorf("%w: id cannot be changed", ErrInvalidPatchResult)
+	}
+
+	return result.CompanyPayload, nil
+}
@@ -0,0 +1,83 @@
+package requests
+
+import (
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompanyPatchApply(t *testing.T) {
+	company := models.Company{ID: 1, Name: "acme", Code: "c1", Country: "CY", Website: "acme.com", Phone: "+1234", Version: 3}
+
+	cases := map[string]struct {
+		mediaType string
+		body      string
+		payload   CompanyPayload
+		err       error
+	}{
+		"merge patch": {
+			mediaType: MergePatchMediaType,
+			body:      `{"name":"acme ltd","website":null}`,
+			payload:   CompanyPayload{Name: "acme ltd", Code: "c1", Country: "CY", Website: "", Phone: "+1234"},
+		},
+		"merge patch with the same id": {
+			mediaType: MergePatchMediaType,
+			body:      `{"id":1,"code":"c2"}`,
+			payload:   CompanyPayload{Name: "acme", Code: "c2", Country: "CY", Website: "acme.com", Phone: "+1234"},
+		},
+		"merge patch cannot change id": {
+			mediaType: MergePatchMediaType,
+			body:      `{"id":2}`,
+			err:       ErrInvalidPatchResult,
+		},
+		"merge patch cannot add fields": {
+			mediaType: MergePatchMediaType,
+			body:      `{"version":5}`,
+			err:       ErrInvalidPatchResult,
+		},
+		"merge patch cannot change types": {
+			mediaType: MergePatchMediaType,
+			body:      `{"name":5}`,
+			err:       ErrInvalidPatchResult,
+		},
+		"merge patch should be valid json": {
+			mediaType: MergePatchMediaType,
+			body:      `{"name":`,
+			err:       ErrInvalidPatch,
+		},
+		"json patch": {
+			mediaType: JSONPatchMediaType,
+			body:      `[{"op":"test","path":"/code","value":"c1"},{"op":"replace","path":"/name","value":"acme ltd"},{"op":"copy","from":"/code","path":"/phone"}]`,
+			payload:   CompanyPayload{Name: "acme ltd", Code: "c1", Country: "CY", Website: "acme.com", Phone: "c1"},
+		},
+		"json patch with failed test": {
+			mediaType: JSONPatchMediaType,
+			body:      `[{"op":"test","path":"/code","value":"c2"},{"op":"replace","path":"/name","value":"acme ltd"}]`,
+			err:       ErrPatchFailed,
+		},
+		"json patch with missing path": {
+			mediaType: JSONPatchMediaType,
+			body:      `[{"op":"replace","path":"/moon","value":"white"}]`,
+			err:       ErrPatchFailed,
+		},
+		"json patch should be an array": {
+			mediaType: JSONPatchMediaType,
+			body:      `{"op":"replace","path":"/name","value":"acme ltd"}`,
+			err:       ErrInvalidPatch,
+		},
+		"unsupported media type": {
+			mediaType: "application/json",
+			body:      `{"name":"acme ltd"}`,
+			err:       ErrUnsupportedPatch,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			payload, err := CompanyPatch{MediaType: c.mediaType, Body: []byte(c.body)}.Apply(company)
+			assert.ErrorIs(t, err, c.err)
+			assert.Equal(t, c.payload, payload)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package requests
+
+import (
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/pagination"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/search"
+)
+
+// MaxSearchTerms is a maximum number of terms in the search query.
+const MaxSearchTerms = 8
+
+// SearchFields defines the company fields used in the full-text search, with their weights.
+var SearchFields = map[string]float64{
+	"name":    4,
+	"code":    2,
+	"website": 1,
+	"phone":   1,
+}
+
+// SearchDocument returns the company fields used in the full-text search.
+func SearchDocument(company models.Company) map[string]string {
+	doc := make(map[string]string, len(SearchFields))
+	for field := range SearchFields {
+		doc[field] = company.Field(field)
+	}
+	return doc
+}
+
+// SearchQuery is a data structure used to decode companies search query params.
+type SearchQuery struct {
+	Q      string `schema:"q" validate:"required,max=255"`
+	Limit  int    `schema:"limit" validate:"gte=0,max=100"`
+	Cursor string `schema:"cursor" validate:"max=1024"`
+}
+
+// PageSize returns the number of results to return on a single page.
+func (q *SearchQuery) PageSize() int {
+	if q.Limit == 0 {
+		return DefaultLimit
+	}
+	return q.Limit
+}
+
+// Terms returns the lowercase search terms.
+func (q *SearchQuery) Terms() []string {
+	return search.Tokenize(q.Q)
+}
+
+// Offset returns the number of results to skip, according to the cursor.
+// Cursor created for a different search query is considered invalid.
+func (q *SearchQuery) Offset() (int, error) {
+	c, err := pagination.Decode(q.Cursor)
+	if err != nil || q.Cursor == "" {
+		return 0, err
+	}
+
+	if c.Sort != q.Q || c.Offset <= 0 {
+		return 0, pagination.ErrInvalidCursor
+	}
+
+	return c.Offset, nil
+}
+
+// CursorAt creates a cursor pointing to the page starting at the given offset.
+func (q *SearchQuery) CursorAt(offset int) pagination.Cursor {
+	return pagination.Cursor{Sort: q.Q, Offset: offset}
+}
+
+// ResultFor creates a search result for the company, with the matching fields highlighted.
+func (q *SearchQuery) ResultFor(company models.Company, score float64) models.SearchResult {
+	return models.SearchResult{
+		Company:    company,
+		Score:      score,
+		Highlights: search.Highlights(SearchDocument(company), q.Terms()),
+	}
+}
+
+// PageOf returns a single page of the search results,
+// from the list of all results ranked by relevance.
+func (q *SearchQuery) PageOf(results []models.SearchResult, offset int) models.SearchPage {
+	page := models.SearchPage{Results: []models.SearchResult{}}
+
+	if offset >= len(results) {
+		return page
+	}
+
+	end := offset + q.PageSize()
+	if end < len(results) {
+		page.HasMore = true
+		page.NextCursor = q.CursorAt(end).Encode()
+	} else {
+		end = len(results)
+	}
+
+	page.Results = results[offset:end]
+	return page
+}
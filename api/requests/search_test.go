@@ -0,0 +1,75 @@
+package requests
+
+import (
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/pagination"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchQueryTerms(t *testing.T) {
+	q := SearchQuery{Q: "Acme  Holdings.com"}
+	assert.Equal(t, []string{"acme", "holdings", "com"}, q.Terms())
+}
+
+func TestSearchQueryOffset(t *testing.T) {
+	cases := map[string]struct {
+		cursor string
+		offset int
+		err    error
+	}{
+		"no cursor":             {cursor: "", offset: 0},
+		"cursor":                {cursor: pagination.Cursor{Sort: "acme", Offset: 20}.Encode(), offset: 20},
+		"cursor for other q":    {cursor: pagination.Cursor{Sort: "beta", Offset: 20}.Encode(), err: pagination.ErrInvalidCursor},
+		"cursor without offset": {cursor: pagination.Cursor{ID: 1, Sort: "acme"}.Encode(), err: pagination.ErrInvalidCursor},
+		"invalid cursor":        {cursor: "moon", err: pagination.ErrInvalidCursor},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			q := SearchQuery{Q: "acme", Cursor: c.cursor}
+			offset, err := q.Offset()
+			assert.Equal(t, c.err, err)
+			assert.Equal(t, c.offset, offset)
+		})
+	}
+}
+
+func TestSearchQueryCursorAt(t *testing.T) {
+	q := SearchQuery{Q: "acme"}
+	q.Cursor = q.CursorAt(40).Encode()
+
+	offset, err := q.Offset()
+	assert.NoError(t, err)
+	assert.Equal(t, 40, offset)
+}
+
+func TestSearchQueryResultFor(t *testing.T) {
+	q := SearchQuery{Q: "acme"}
+	company := models.Company{ID: 1, Name: "Acme", Code: "c1", Website: "acme.com"}
+
+	result := q.ResultFor(company, 1.5)
+	assert.Equal(t, company, result.Company)
+	assert.Equal(t, 1.5, result.Score)
+	assert.Equal(t, map[string]string{"name": "<em>Acme</em>", "website": "<em>acme</em>.com"}, result.Highlights)
+}
+
+func TestSearchQueryPageOf(t *testing.T) {
+	results := []models.SearchResult{{Score: 3}, {Score: 2}, {Score: 1}}
+	q := SearchQuery{Q: "acme", Limit: 2}
+
+	page := q.PageOf(results, 0)
+	assert.Equal(t, results[:2], page.Results)
+	assert.True(t, page.HasMore)
+	assert.Equal(t, q.CursorAt(2).Encode(), page.NextCursor)
+
+	page = q.PageOf(results, 2)
+	assert.Equal(t, results[2:], page.Results)
+	assert.False(t, page.HasMore)
+	assert.Empty(t, page.NextCursor)
+
+	page = q.PageOf(results, 4)
+	assert.Empty(t, page.Results)
+	assert.False(t, page.HasMore)
+}
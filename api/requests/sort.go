@@ -0,0 +1,48 @@
+package requests
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SortFields is a set of company fields which can be used for sorting.
+var SortFields = map[string]bool{
+	"id": true, "name": true, "code": true, "country": true, "website": true, "phone": true,
+}
+
+// SortKey defines a single sorting key.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort converts `-name,code` into a list of sort keys.
+// Minus sign in front of the field means descending order.
+// Sorting by id is always appended to make the order stable.
+func ParseSort(s string) ([]SortKey, error) {
+	keys := []SortKey{}
+	seen := map[string]bool{}
+
+	for _, part := range strings.Split(s, listSplitter) {
+		if part == "" {
+			continue
+		}
+
+		key := SortKey{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if !SortFields[key.Field] {
+			return nil, fmt.Errorf("unknown sort field '%s'", key.Field)
+		}
+		if seen[key.Field] {
+			return nil, fmt.Errorf("duplicate sort field '%s'", key.Field)
+		}
+
+		seen[key.Field] = true
+		keys = append(keys, key)
+	}
+
+	if !seen["id"] {
+		keys = append(keys, SortKey{Field: "id"})
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,49 @@
+package requests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSort(t *testing.T) {
+	cases := map[string]struct {
+		sort string
+		keys []SortKey
+		err  string
+	}{
+		"empty sort is ordered by id": {
+			sort: "",
+			keys: []SortKey{{Field: "id"}},
+		},
+		"multiple keys": {
+			sort: "-name,code",
+			keys: []SortKey{{Field: "name", Desc: true}, {Field: "code"}, {Field: "id"}},
+		},
+		"explicit id": {
+			sort: "-id",
+			keys: []SortKey{{Field: "id", Desc: true}},
+		},
+		"unknown field": {
+			sort: "moon",
+			err:  "unknown sort field 'moon'",
+		},
+		"duplicate field": {
+			sort: "name,-name",
+			err:  "duplicate sort field 'name'",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			keys, err := ParseSort(c.sort)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, c.keys, keys)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package responses
+
+import (
+	"net/http"
+
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/go-chi/render"
+)
+
+// APIKeyResponse is the response for the APIKey data model.
+// Key is set only when the key is created or rotated.
+type APIKeyResponse struct {
+	*models.APIKey
+	Key            string `json:"key,omitempty"`
+	HTTPStatusCode int    `json:"-"`
+}
+
+func (a APIKeyResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, a.HTTPStatusCode)
+	return nil
+}
+
+// APIKeysResponse is the response for multiple API keys.
+type APIKeysResponse struct {
+	APIKeys        []models.APIKey `json:"api_keys"`
+	HTTPStatusCode int             `json:"-"`
+}
+
+func (a APIKeysResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, a.HTTPStatusCode)
+	return nil
+}
@@ -0,0 +1,48 @@
+package responses
+
+import (
+	"net/http"
+
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/go-chi/render"
+)
+
+// BulkResult is the result of a single bulk operation.
+// Error has the same format as the response of the single-item request.
+type BulkResult struct {
+	Index   int             `json:"index"`
+	Op      string          `json:"op"`
+	Status  int             `json:"status"`
+	Company *models.Company `json:"company,omitempty"`
+	Version uint64          `json:"version,omitempty"`
+	Error   *ErrResponse    `json:"error,omitempty"`
+}
+
+// BulkResponse is the response for the bulk request.
+type BulkResponse struct {
+	Mode           string       `json:"mode"`
+	Succeeded      int          `json:"succeeded"`
+	Failed         int          `json:"failed"`
+	Results        []BulkResult `json:"results"`
+	HTTPStatusCode int          `json:"-"`
+}
+
+// NewBulkResponse creates a BulkResponse from the results of the operations.
+func NewBulkResponse(mode string, results []BulkResult, status int) *BulkResponse {
+	resp := BulkResponse{Mode: mode, Results: results, HTTPStatusCode: status}
+
+	for _, r := range results {
+		if r.Error != nil {
+			resp.Failed++
+		} else {
+			resp.Succeeded++
+		}
+	}
+
+	return &resp
+}
+
+func (b BulkResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, b.HTTPStatusCode)
+	return nil
+}
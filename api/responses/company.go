@@ -1,7 +1,10 @@
 package responses
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/brokeyourbike/xm-golang-exercise/models"
 	"github.com/go-chi/render"
@@ -9,9 +12,12 @@ import (
 
 // ErrResponse renderer type for handling all sorts of errors.
 type ErrResponse struct {
-	HTTPStatusCode int      `json:"-"`                // http response status code
-	Message        string   `json:"message"`          // status message
-	Errors         []string `json:"errors,omitempty"` // validation errors
+	HTTPStatusCode int      `json:"-"`                     // http response status code
+	Message        string   `json:"message"`               // status message
+	Errors         []string `json:"errors,omitempty"`      // validation errors
+	Reason         string   `json:"reason,omitempty"`      // machine-readable reason of the denial
+	Field          string   `json:"field,omitempty"`       // conflicting field
+	ExistingID     uint64   `json:"existing_id,omitempty"` // conflicting company id
 }
 
 func (e ErrResponse) Render(w http.ResponseWriter, r *http.Request) error {
@@ -19,6 +25,31 @@ func (e ErrResponse) Render(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+// NewStorageErrResponse creates the response for an unexpected storage error.
+// Queries exceeding the deadline are reported with 504, cancelled queries with 503.
+func NewStorageErrResponse(err error, message string) *ErrResponse {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &ErrResponse{Message: "Storage timed out", Errors: []string{message}, HTTPStatusCode: http.StatusGatewayTimeout}
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return &ErrResponse{Message: "Request cancelled", Errors: []string{message}, HTTPStatusCode: http.StatusServiceUnavailable}
+	}
+
+	return &ErrResponse{Message: message, HTTPStatusCode: http.StatusInternalServerError}
+}
+
+// NewConflictResponse creates the response for a company conflicting with an existing one.
+func NewConflictResponse(conflict *models.ConflictError) *ErrResponse {
+	return &ErrResponse{
+		Message:        "Company already exists",
+		Errors:         []string{conflict.Error()},
+		Field:          conflict.Field,
+		ExistingID:     conflict.ExistingID,
+		HTTPStatusCode: http.StatusConflict,
+	}
+}
+
 // CompanyResponse is the response for the Company data model.
 type CompanyResponse struct {
 	*models.Company
@@ -26,13 +57,19 @@ type CompanyResponse struct {
 }
 
 func (c CompanyResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if c.Company != nil && c.Company.Version != 0 {
+		w.Header().Set("ETag", c.Company.ETag())
+	}
+
 	render.Status(r, c.HTTPStatusCode)
 	return nil
 }
 
-// CompanyResponse is the response for multiple companies.
+// CompaniesResponse is the response for a single page of companies.
 type CompaniesResponse struct {
 	Companies      []models.Company `json:"companies"`
+	NextCursor     string           `json:"next_cursor,omitempty"`
+	HasMore        bool             `json:"has_more"`
 	HTTPStatusCode int              `json:"-"`
 }
 
@@ -40,3 +77,76 @@ func (c CompaniesResponse) Render(w http.ResponseWriter, r *http.Request) error
 	render.Status(r, c.HTTPStatusCode)
 	return nil
 }
+
+// SearchResponse is the response for a single page of the search results.
+// Highlights hold the HTML snippets of the matching fields.
+type SearchResponse struct {
+	Results        []models.SearchResult `json:"results"`
+	NextCursor     string                `json:"next_cursor,omitempty"`
+	HasMore        bool                  `json:"has_more"`
+	HTTPStatusCode int                   `json:"-"`
+}
+
+func (s SearchResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, s.HTTPStatusCode)
+	return nil
+}
+
+// TrashedCompany is the representation of the soft-deleted company.
+type TrashedCompany struct {
+	models.Company
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// TrashResponse is the response for a single page of soft-deleted companies.
+type TrashResponse struct {
+	Companies      []TrashedCompany `json:"companies"`
+	NextCursor     string           `json:"next_cursor,omitempty"`
+	HasMore        bool             `json:"has_more"`
+	HTTPStatusCode int              `json:"-"`
+}
+
+// NewTrashResponse creates a TrashResponse from the page of soft-deleted companies.
+func NewTrashResponse(page models.CompaniesPage, status int) *TrashResponse {
+	resp := TrashResponse{
+		Companies:      make([]TrashedCompany, 0, len(page.Companies)),
+		NextCursor:     page.NextCursor,
+		HasMore:        page.HasMore,
+		HTTPStatusCode: status,
+	}
+
+	for _, c := range page.Companies {
+		resp.Companies = append(resp.Companies, TrashedCompany{Company: c, DeletedAt: c.DeletedAt.Time})
+	}
+
+	return &resp
+}
+
+func (t TrashResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, t.HTTPStatusCode)
+	return nil
+}
+
+// HistoryResponse is the response for the company change history.
+// Verified reports whether the audit chain, which the history is part of, is intact.
+type HistoryResponse struct {
+	History        []models.AuditRecord `json:"history"`
+	Verified       bool                 `json:"verified"`
+	HTTPStatusCode int                  `json:"-"`
+}
+
+func (h HistoryResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, h.HTTPStatusCode)
+	return nil
+}
+
+// CompanyTreeResponse is the response for the company group, starting from the top-most parent.
+type CompanyTreeResponse struct {
+	models.CompanyTree
+	HTTPStatusCode int `json:"-"`
+}
+
+func (t CompanyTreeResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, t.HTTPStatusCode)
+	return nil
+}
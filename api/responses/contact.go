@@ -0,0 +1,30 @@
+package responses
+
+import (
+	"net/http"
+
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/go-chi/render"
+)
+
+// ContactResponse is the response for the Contact data model.
+type ContactResponse struct {
+	*models.Contact
+	HTTPStatusCode int `json:"-"`
+}
+
+func (c ContactResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, c.HTTPStatusCode)
+	return nil
+}
+
+// ContactsResponse is the response for multiple contacts.
+type ContactsResponse struct {
+	Contacts       []models.Contact `json:"contacts"`
+	HTTPStatusCode int              `json:"-"`
+}
+
+func (c ContactsResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, c.HTTPStatusCode)
+	return nil
+}
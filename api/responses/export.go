@@ -0,0 +1,126 @@
+package responses
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/xlsx"
+)
+
+// ErrUnsupportedExport is an error raised when the export format is not supported.
+var ErrUnsupportedExport = errors.New("unsupported export format")
+
+// exportColumns are the company fields written to the CSV and XLSX exports, in order.
+var exportColumns = []string{"id", "name", "code", "country", "website", "phone"}
+
+// ExportWriter writes the companies to the export file.
+// Flush sends the buffered companies to the underlying writer,
+// Close completes the file and does not close the underlying writer.
+type ExportWriter interface {
+	Write(models.Company) error
+	Flush() error
+	Close() error
+}
+
+// NewExportWriter creates the writer of the export file in the given format.
+// The CSV and XLSX files start with the header row.
+func NewExportWriter(format string, w io.Writer) (ExportWriter, error) {
+	switch format {
+	case requests.ExportCSV:
+		c := csvExport{w: csv.NewWriter(w)}
+		return &c, c.w.Write(exportColumns)
+	case requests.ExportNDJSON:
+		return &ndjsonExport{enc: json.NewEncoder(w)}, nil
+	case requests.ExportXLSX:
+		x, err := xlsx.NewWriter(w, "Companies")
+		if err != nil {
+			return nil, err
+		}
+		return &xlsxExport{w: x}, x.WriteRow(exportColumns)
+	}
+	return nil, ErrUnsupportedExport
+}
+
+// formulaPrefixes are the first characters, which make the spreadsheet apps evaluate the cell as a formula.
+const formulaPrefixes = "=+-@\t\r"
+
+// exportRow returns the company fields in the order of exportColumns.
+func exportRow(company models.Company) []string {
+	row := []string{strconv.FormatUint(company.ID, 10)}
+	for _, column := range exportColumns[1:] {
+		row = append(row, company.Field(column))
+	}
+	return row
+}
+
+// escapeFormula prefixes the value with a quote, if it would be evaluated as a formula.
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune(formulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// csvExport writes the companies as CSV rows.
+type csvExport struct {
+	w *csv.Writer
+}
+
+// Write escapes the fields, so they are not evaluated as formulas when the file is opened in a spreadsheet app.
+// The XLSX cells are inline strings, which are never evaluated, so they are written as they are.
+func (c *csvExport) Write(company models.Company) error {
+	row := exportRow(company)
+	for i := range row {
+		row[i] = escapeFormula(row[i])
+	}
+	return c.w.Write(row)
+}
+
+func (c *csvExport) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvExport) Close() error {
+	return c.Flush()
+}
+
+// ndjsonExport writes the companies as JSON lines, the same as in the JSON responses.
+type ndjsonExport struct {
+	enc *json.Encoder
+}
+
+func (n *ndjsonExport) Write(company models.Company) error {
+	return n.enc.Encode(company)
+}
+
+func (n *ndjsonExport) Flush() error {
+	return nil
+}
+
+func (n *ndjsonExport) Close() error {
+	return nil
+}
+
+// xlsxExport writes the companies as rows of the spreadsheet.
+type xlsxExport struct {
+	w *xlsx.Writer
+}
+
+func (x *xlsxExport) Write(company models.Company) error {
+	return x.w.WriteRow(exportRow(company))
+}
+
+func (x *xlsxExport) Flush() error {
+	return x.w.Flush()
+}
+
+func (x *xlsxExport) Close() error {
+	return x.w.Close()
+}
@@ -0,0 +1,59 @@
+package responses
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscapeFormula(t *testing.T) {
+	cases := map[string]string{
+		"":             "",
+		"acme":         "acme",
+		"=1+1":         "'=1+1",
+		"+35712345678": "'+35712345678",
+		"-1":           "'-1",
+		"@SUM(A1)":     "'@SUM(A1)",
+		"\tacme":       "'\tacme",
+		"\racme":       "'\racme",
+		"acme=1":       "acme=1",
+	}
+
+	for value, escaped := range cases {
+		assert.Equal(t, escaped, escapeFormula(value), value)
+	}
+}
+
+func TestExportWritersEscapeFormulasInCSV(t *testing.T) {
+	company := models.Company{ID: 1, Name: `=HYPERLINK("http://evil.com")`, Code: "c1", Country: "CY", Phone: "+357"}
+
+	write := func(format string) string {
+		var buf bytes.Buffer
+		w, err := NewExportWriter(format, &buf)
+		require.NoError(t, err)
+		require.NoError(t, w.Write(company))
+		require.NoError(t, w.Close())
+		return buf.String()
+	}
+
+	assert.Equal(t, "id,name,code,country,website,phone\n1,\"'=HYPERLINK(\"\"http://evil.com\"\")\",c1,CY,,'+357\n", write(requests.ExportCSV))
+
+	file := write(requests.ExportXLSX)
+	r, err := zip.NewReader(bytes.NewReader([]byte(file)), int64(len(file)))
+	require.NoError(t, err)
+
+	sheet, err := r.Open("xl/worksheets/sheet1.xml")
+	require.NoError(t, err)
+	data, err := io.ReadAll(sheet)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(data), `<t xml:space="preserve">=HYPERLINK(&#34;http://evil.com&#34;)</t>`)
+	assert.Contains(t, string(data), `<t xml:space="preserve">+357</t>`)
+	assert.NotContains(t, string(data), `&#39;`)
+}
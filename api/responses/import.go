@@ -0,0 +1,54 @@
+package responses
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+)
+
+// Import row statuses.
+const (
+	ImportAccepted = "accepted"
+	ImportUpdated  = "updated"
+	ImportRejected = "rejected"
+)
+
+// ImportResult is the result of a single import row.
+// Error has the same format as the response of the single-item request.
+type ImportResult struct {
+	Line   int          `json:"line"`
+	Status string       `json:"status"`
+	ID     uint64       `json:"id,omitempty"`
+	Error  *ErrResponse `json:"error,omitempty"`
+}
+
+// ImportResponse is the response for the import request.
+// Error describes why the import was stopped before the end of the body.
+type ImportResponse struct {
+	DryRun         bool           `json:"dry_run"`
+	Accepted       int            `json:"accepted"`
+	Updated        int            `json:"updated"`
+	Rejected       int            `json:"rejected"`
+	Results        []ImportResult `json:"results"`
+	Error          *ErrResponse   `json:"error,omitempty"`
+	HTTPStatusCode int            `json:"-"`
+}
+
+// Add adds the result of the row to the response.
+func (i *ImportResponse) Add(result ImportResult) {
+	switch result.Status {
+	case ImportAccepted:
+		i.Accepted++
+	case ImportUpdated:
+		i.Updated++
+	default:
+		i.Rejected++
+	}
+
+	i.Results = append(i.Results, result)
+}
+
+func (i ImportResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, i.HTTPStatusCode)
+	return nil
+}
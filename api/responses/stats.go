@@ -0,0 +1,19 @@
+package responses
+
+import (
+	"net/http"
+
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/go-chi/render"
+)
+
+// CacheStatsResponse is the response for the counters of the cache.
+type CacheStatsResponse struct {
+	models.CacheStats
+	HTTPStatusCode int `json:"-"`
+}
+
+func (c CacheStatsResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, c.HTTPStatusCode)
+	return nil
+}
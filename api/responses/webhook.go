@@ -0,0 +1,30 @@
+package responses
+
+import (
+	"net/http"
+
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/go-chi/render"
+)
+
+// DeliveryResponse is the response for the Delivery data model.
+type DeliveryResponse struct {
+	*models.Delivery
+	HTTPStatusCode int `json:"-"`
+}
+
+func (d DeliveryResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, d.HTTPStatusCode)
+	return nil
+}
+
+// DeliveriesResponse is the response for multiple deliveries.
+type DeliveriesResponse struct {
+	Deliveries     []models.Delivery `json:"deliveries"`
+	HTTPStatusCode int               `json:"-"`
+}
+
+func (d DeliveriesResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, d.HTTPStatusCode)
+	return nil
+}
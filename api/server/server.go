@@ -14,9 +14,60 @@ import (
 type CompaniesHandler interface {
 	HandleCompanyCreate(http.ResponseWriter, *http.Request)
 	HandleCompanyGetOne(http.ResponseWriter, *http.Request)
+	HandleCompanyHistory(http.ResponseWriter, *http.Request)
+	HandleCompanyChildren(http.ResponseWriter, *http.Request)
+	HandleCompanyAncestors(http.ResponseWriter, *http.Request)
+	HandleCompanyTree(http.ResponseWriter, *http.Request)
 	HandleCompanyGetAll(http.ResponseWriter, *http.Request)
+	HandleCompanySearch(http.ResponseWriter, *http.Request)
+	HandleCompanyGetTrash(http.ResponseWriter, *http.Request)
 	HandleCompanyUpdate(http.ResponseWriter, *http.Request)
 	HandleCompanyDelete(http.ResponseWriter, *http.Request)
+	HandleCompanyRestore(http.ResponseWriter, *http.Request)
+	HandleCompanyBulk(http.ResponseWriter, *http.Request)
+	HandleCompanyImport(http.ResponseWriter, *http.Request)
+	HandleCompanyExport(http.ResponseWriter, *http.Request)
+}
+
+// WebhooksHandler defines a set of handlers for the webhook deliveries
+// required to be used with the server.
+type WebhooksHandler interface {
+	HandleDeadDeliveries(http.ResponseWriter, *http.Request)
+	HandleRedeliver(http.ResponseWriter, *http.Request)
+}
+
+// ContactsHandler defines a set of handlers for the company contacts
+// required to be used with the server.
+type ContactsHandler interface {
+	HandleContactCreate(http.ResponseWriter, *http.Request)
+	HandleContactGetAll(http.ResponseWriter, *http.Request)
+	HandleContactGetOne(http.ResponseWriter, *http.Request)
+	HandleContactUpdate(http.ResponseWriter, *http.Request)
+	HandleContactDelete(http.ResponseWriter, *http.Request)
+}
+
+// APIKeysHandler defines a set of handlers for the API keys
+// required to be used with the server.
+type APIKeysHandler interface {
+	HandleAPIKeyCreate(http.ResponseWriter, *http.Request)
+	HandleAPIKeyGetAll(http.ResponseWriter, *http.Request)
+	HandleAPIKeyRotate(http.ResponseWriter, *http.Request)
+	HandleAPIKeyRevoke(http.ResponseWriter, *http.Request)
+}
+
+// StatsHandler defines a set of handlers for the counters of the service
+// required to be used with the server.
+type StatsHandler interface {
+	HandleCacheStats(http.ResponseWriter, *http.Request)
+}
+
+// Handlers defines the handlers used by the routes.
+type Handlers struct {
+	Companies CompaniesHandler
+	Contacts  ContactsHandler
+	Webhooks  WebhooksHandler
+	APIKeys   APIKeysHandler
+	Stats     StatsHandler
 }
 
 // Middleware defines a requirements for the middlewares
@@ -25,18 +76,44 @@ type Middleware interface {
 	Handle(http.Handler) http.Handler
 }
 
+// Middlewares defines the middlewares used by the routes.
+type Middlewares struct {
+	Company Middleware // loads the company from the URL
+	Trashed Middleware // loads the soft-deleted company from the URL
+	IfMatch Middleware // checks the company version
+	Payload Middleware // validates the company payload or list query
+	Patch   Middleware // applies the patch to the company
+	Search  Middleware // validates the search query
+	Bulk    Middleware // validates the bulk payload
+	Import  Middleware // prepares the import of companies
+	Export  Middleware // validates the export format
+	Contact Middleware // validates the contact payload
+	Key     Middleware // validates the API key payload
+	Ip      Middleware // checks the client country
+	IpLimit Middleware // limits the rate of the requests of the IP address, before the authentication
+	APIKey  Middleware // authenticates the API key callers
+	Auth    Middleware // authenticates the caller
+	Limit   Middleware // limits the rate of the requests of the caller
+	Policy  Middleware // authorizes the caller with the policy
+	Admin   Middleware // allows only the tokens with the scope to manage the API keys, after the policy
+	Audit   Middleware // collects the audit metadata
+	Tenant  Middleware // resolves the tenant of the request
+}
+
 // server represents mux.
 type server struct {
 	router    *chi.Mux
 	companies CompaniesHandler
-	mwCompany Middleware
-	mwPayload Middleware
-	mwIp      Middleware
+	contacts  ContactsHandler
+	webhooks  WebhooksHandler
+	apiKeys   APIKeysHandler
+	stats     StatsHandler
+	mw        Middlewares
 }
 
-// NewServer creates a new server with the given router and handlers.
-func NewServer(r *chi.Mux, c CompaniesHandler, mwCompany Middleware, mwPayload Middleware, mwIp Middleware) *server {
-	s := server{router: r, companies: c, mwCompany: mwCompany, mwPayload: mwPayload, mwIp: mwIp}
+// NewServer creates a new server with the given router, handlers and middlewares.
+func NewServer(r *chi.Mux, h Handlers, mw Middlewares) *server {
+	s := server{router: r, companies: h.Companies, contacts: h.Contacts, webhooks: h.Webhooks, apiKeys: h.APIKeys, stats: h.Stats, mw: mw}
 	return &s
 }
 
@@ -53,17 +130,64 @@ func (s *server) ListenAndServe(cfg *configs.Config) {
 
 // routes defines routes and middlewares.
 func (s *server) routes() {
+	s.router.Use(middleware.RequestID)
 	s.router.Use(middleware.RealIP)
 	s.router.Use(middleware.Recoverer)
+	s.router.Use(s.mw.IpLimit.Handle)
+	s.router.Use(s.mw.APIKey.Handle)
+	s.router.Use(s.mw.Auth.Handle)
+	s.router.Use(s.mw.Limit.Handle)
+	s.router.Use(s.mw.Audit.Handle)
+	s.router.Use(s.mw.Tenant.Handle)
 	s.router.Use(render.SetContentType(render.ContentTypeJSON))
 
 	s.router.Route("/companies", func(r chi.Router) {
-		r.With(s.mwIp.Handle, s.mwPayload.Handle).Post("/", s.companies.HandleCompanyCreate)
-		r.With(s.mwPayload.Handle).Get("/", s.companies.HandleCompanyGetAll)
+		r.Use(s.mw.Policy.Handle)
+		r.With(s.mw.Ip.Handle, s.mw.Payload.Handle).Post("/", s.companies.HandleCompanyCreate)
+		r.With(s.mw.Payload.Handle).Get("/", s.companies.HandleCompanyGetAll)
+		r.With(s.mw.Payload.Handle).Get("/trash", s.companies.HandleCompanyGetTrash)
+		r.With(s.mw.Search.Handle).Get("/search", s.companies.HandleCompanySearch)
+		r.With(s.mw.Ip.Handle, s.mw.Bulk.Handle).Post("/bulk", s.companies.HandleCompanyBulk)
+		r.With(s.mw.Ip.Handle, s.mw.Import.Handle).Post("/import", s.companies.HandleCompanyImport)
+		r.With(s.mw.Export.Handle, s.mw.Payload.Handle).Get("/export", s.companies.HandleCompanyExport)
 		r.Route("/{id:[0-9]+}", func(r chi.Router) {
-			r.With(s.mwCompany.Handle).Get("/", s.companies.HandleCompanyGetOne)
-			r.With(s.mwCompany.Handle, s.mwPayload.Handle).Put("/", s.companies.HandleCompanyUpdate)
-			r.With(s.mwIp.Handle, s.mwCompany.Handle).Delete("/", s.companies.HandleCompanyDelete)
+			r.With(s.mw.Company.Handle).Get("/", s.companies.HandleCompanyGetOne)
+			r.With(s.mw.Company.Handle).Get("/history", s.companies.HandleCompanyHistory)
+			r.With(s.mw.Company.Handle).Get("/children", s.companies.HandleCompanyChildren)
+			r.With(s.mw.Company.Handle).Get("/ancestors", s.companies.HandleCompanyAncestors)
+			r.With(s.mw.Company.Handle).Get("/tree", s.companies.HandleCompanyTree)
+			r.With(s.mw.Company.Handle, s.mw.IfMatch.Handle, s.mw.Payload.Handle).Put("/", s.companies.HandleCompanyUpdate)
+			r.With(s.mw.Company.Handle, s.mw.IfMatch.Handle, s.mw.Patch.Handle).Patch("/", s.companies.HandleCompanyUpdate)
+			r.With(s.mw.Ip.Handle, s.mw.Company.Handle, s.mw.IfMatch.Handle).Delete("/", s.companies.HandleCompanyDelete)
+			r.With(s.mw.Ip.Handle, s.mw.Trashed.Handle).Post("/restore", s.companies.HandleCompanyRestore)
+			r.Route("/contacts", func(r chi.Router) {
+				r.Use(s.mw.Company.Handle)
+				r.With(s.mw.Contact.Handle).Post("/", s.contacts.HandleContactCreate)
+				r.Get("/", s.contacts.HandleContactGetAll)
+				r.Get("/{contactID:[0-9]+}", s.contacts.HandleContactGetOne)
+				r.With(s.mw.Contact.Handle).Put("/{contactID:[0-9]+}", s.contacts.HandleContactUpdate)
+				r.Delete("/{contactID:[0-9]+}", s.contacts.HandleContactDelete)
+			})
 		})
 	})
+
+	s.router.Route("/webhooks/deliveries", func(r chi.Router) {
+		r.Use(s.mw.Policy.Handle)
+		r.Get("/dead", s.webhooks.HandleDeadDeliveries)
+		r.Post("/{id:[0-9]+}/redeliver", s.webhooks.HandleRedeliver)
+	})
+
+	s.router.Route("/stats", func(r chi.Router) {
+		r.Use(s.mw.Policy.Handle)
+		r.Get("/cache", s.stats.HandleCacheStats)
+	})
+
+	s.router.Route("/api-keys", func(r chi.Router) {
+		r.Use(s.mw.Policy.Handle)
+		r.Use(s.mw.Admin.Handle)
+		r.With(s.mw.Key.Handle).Post("/", s.apiKeys.HandleAPIKeyCreate)
+		r.Get("/", s.apiKeys.HandleAPIKeyGetAll)
+		r.Post("/{id:[0-9]+}/rotate", s.apiKeys.HandleAPIKeyRotate)
+		r.Delete("/{id:[0-9]+}", s.apiKeys.HandleAPIKeyRevoke)
+	})
 }
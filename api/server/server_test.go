@@ -0,0 +1,79 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/server"
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/mocks"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/rbac"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopMw struct{}
+
+func (nopMw) Handle(next http.Handler) http.Handler {
+	return next
+}
+
+func TestDefaultPolicyCoversAuthorizedRoutes(t *testing.T) {
+	policy, err := rbac.Parse(configs.DefaultPolicy)
+	require.NoError(t, err)
+
+	mw := nopMw{}
+	router := chi.NewMux()
+
+	srv := server.NewServer(router, server.Handlers{
+		Companies: handlers.NewCompanies(new(mocks.CompaniesRepo)),
+		Contacts:  handlers.NewContacts(new(mocks.ContactsRepo)),
+		Webhooks:  handlers.NewWebhooks(new(mocks.WebhooksRepo)),
+		APIKeys:   handlers.NewAPIKeys(new(mocks.APIKeysRepo)),
+		Stats:     handlers.NewStats(nil),
+	}, server.Middlewares{
+		Company: mw,
+		Trashed: mw,
+		IfMatch: mw,
+		Payload: mw,
+		Patch:   mw,
+		Search:  mw,
+		Bulk:    mw,
+		Import:  mw,
+		Export:  mw,
+		Contact: mw,
+		Key:     mw,
+		Ip:      mw,
+		IpLimit: mw,
+		APIKey:  mw,
+		Auth:    mw,
+		Limit:   mw,
+		Policy:  mw,
+		Admin:   mw,
+		Audit:   mw,
+		Tenant:  mw,
+	})
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	routes := 0
+	err = chi.Walk(router, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		authorized := false
+		for _, prefix := range []string{"/companies", "/webhooks", "/stats", "/api-keys"} {
+			authorized = authorized || strings.HasPrefix(route, prefix)
+		}
+		if !authorized {
+			return nil
+		}
+
+		routes++
+		_, ok := policy.Permission(method, route)
+		assert.True(t, ok, "%s %s has no rule", method, route)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Len(t, policy.Routes, routes)
+}
@@ -5,7 +5,73 @@ type Config struct {
 	Host     string `env:"HOST" envDefault:"127.0.0.1"`
 	Port     string `env:"PORT" envDefault:"9090"`
 	Database struct {
-		Dsn string `env:"DATABASE_DSN" envDefault:"u:p@tcp(127.0.0.1:3306)/db?charset=utf8mb4"`
+		Driver              string `env:"DATABASE_DRIVER" envDefault:"mysql"`
+		Dsn                 string `env:"DATABASE_DSN" envDefault:"u:p@tcp(127.0.0.1:3306)/db?charset=utf8mb4&parseTime=True"`
+		QueryTimeoutSeconds uint   `env:"DATABASE_QUERY_TIMEOUT_SECONDS" envDefault:"5"`
+
+		ReplicaDsns               []string `env:"DATABASE_REPLICA_DSNS"`
+		ReplicaStickySeconds      uint     `env:"DATABASE_REPLICA_STICKY_SECONDS" envDefault:"5"`
+		ReplicaHealthCheckSeconds uint     `env:"DATABASE_REPLICA_HEALTH_CHECK_SECONDS" envDefault:"10"`
+	}
+	Companies struct {
+		UniqueCodePerCountry      bool `env:"COMPANY_CODE_UNIQUE_PER_COUNTRY" envDefault:"false"`
+		CacheTTLSeconds           int  `env:"COMPANY_CACHE_TTL_SECONDS" envDefault:"60"`
+		CacheStatsIntervalSeconds uint `env:"COMPANY_CACHE_STATS_INTERVAL_SECONDS" envDefault:"300"`
+	}
+	Auth struct {
+		Required bool `env:"AUTH_REQUIRED" envDefault:"false"`
+	}
+	JWT struct {
+		JWKS           string   `env:"JWT_JWKS"`
+		JWKSTTLSeconds uint     `env:"JWT_JWKS_TTL_SECONDS" envDefault:"300"`
+		TimeoutSeconds uint     `env:"JWT_JWKS_TIMEOUT_SECONDS" envDefault:"10"`
+		Audience       []string `env:"JWT_AUDIENCE"`
+		Issuer         string   `env:"JWT_ISSUER"`
+		LeewaySeconds  uint     `env:"JWT_LEEWAY_SECONDS" envDefault:"30"`
+		TenantClaim    string   `env:"JWT_TENANT_CLAIM" envDefault:"tenant"`
+		RolesClaim     string   `env:"JWT_ROLES_CLAIM" envDefault:"roles"`
+	}
+	RBAC struct {
+		Policy string `env:"RBAC_POLICY"`
+	}
+	Tenants struct {
+		Header  string `env:"TENANT_HEADER" envDefault:"X-Tenant-ID"`
+		Default string `env:"TENANT_DEFAULT" envDefault:"default"`
+	}
+	RateLimit struct {
+		Default     string   `env:"RATE_LIMIT_DEFAULT" envDefault:"600/1m"`
+		IP          string   `env:"RATE_LIMIT_IP" envDefault:"1200/1m"`
+		Routes      []string `env:"RATE_LIMIT_ROUTES"`
+		Store       string   `env:"RATE_LIMIT_STORE" envDefault:"memory"`
+		RedisURL    string   `env:"RATE_LIMIT_REDIS_URL" envDefault:"redis://127.0.0.1:6379/0"`
+		RedisPrefix string   `env:"RATE_LIMIT_REDIS_PREFIX" envDefault:"ratelimit"`
+		FailOpen    bool     `env:"RATE_LIMIT_FAIL_OPEN" envDefault:"true"`
+		IPFailOpen  bool     `env:"RATE_LIMIT_IP_FAIL_OPEN" envDefault:"false"`
+	}
+	Bulk struct {
+		MaxOperations int `env:"BULK_MAX_OPERATIONS" envDefault:"1000"`
+	}
+	Import struct {
+		MaxRows int `env:"IMPORT_MAX_ROWS" envDefault:"10000"`
+	}
+	Export struct {
+		BatchSize int `env:"EXPORT_BATCH_SIZE" envDefault:"500"`
+	}
+	SoftDelete struct {
+		RetentionHours       uint `env:"SOFT_DELETE_RETENTION_HOURS" envDefault:"720"`
+		PurgeIntervalMinutes uint `env:"SOFT_DELETE_PURGE_INTERVAL_MINUTES" envDefault:"60"`
+	}
+	Webhooks struct {
+		URLs                []string `env:"WEBHOOK_URLS"`
+		Secret              string   `env:"WEBHOOK_SECRET"`
+		MaxAttempts         uint     `env:"WEBHOOK_MAX_ATTEMPTS" envDefault:"8"`
+		BackoffSeconds      uint     `env:"WEBHOOK_BACKOFF_SECONDS" envDefault:"10"`
+		TimeoutSeconds      uint     `env:"WEBHOOK_TIMEOUT_SECONDS" envDefault:"10"`
+		PollIntervalSeconds uint     `env:"WEBHOOK_POLL_INTERVAL_SECONDS" envDefault:"5"`
+	}
+	Events struct {
+		NatsURL       string `env:"EVENTS_NATS_URL"`
+		SubjectPrefix string `env:"EVENTS_SUBJECT_PREFIX" envDefault:"events"`
 	}
 	CacheSizeMb int `env:"CACHE_SIZE_MB" envDefault:"100"`
 	Ipapi       struct {
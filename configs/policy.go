@@ -0,0 +1,8 @@
+package configs
+
+import _ "embed"
+
+// DefaultPolicy is the authorization policy used when RBAC_POLICY is not set.
+//
+//go:embed policy.json
+var DefaultPolicy []byte
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"gorm.io/gorm"
+)
+
+// CreateAPIKey adds a new API key of the tenant to the database.
+func (c *CompaniesRepo) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	key.TenantID = models.TenantFrom(ctx)
+	key.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+
+	err := c.write(ctx).Create(&key).Error
+	return key, contextError(ctx, err)
+}
+
+// GetAPIKeys returns all API keys of the tenant, including the revoked and expired ones, ordered by id.
+func (c *CompaniesRepo) GetAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	keys := []models.APIKey{}
+
+	err := c.read(ctx).Scopes(tenantScope(ctx)).Order("id").Find(&keys).Error
+	return keys, contextError(ctx, err)
+}
+
+// RotateAPIKey replaces the prefix and hash of the API key, the previous key stops working immediately.
+// If the key does not exist or is revoked this function returns a ErrAPIKeyNotFound error.
+func (c *CompaniesRepo) RotateAPIKey(ctx context.Context, id uint64, prefix string, hash string) (models.APIKey, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	var key models.APIKey
+
+	err := c.write(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.APIKey{}).Scopes(tenantScope(ctx)).
+			Where("id = ? AND revoked_at IS NULL", id).
+			Updates(map[string]interface{}{"prefix": prefix, "hash": hash})
+
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			return models.ErrAPIKeyNotFound
+		}
+
+		return tx.Where("id = ?", id).First(&key).Error
+	})
+
+	return key, contextError(ctx, err)
+}
+
+// RevokeAPIKey revokes the API key, revoking the revoked key keeps the time of the first revocation.
+// If the key does not exist this function returns a ErrAPIKeyNotFound error.
+func (c *CompaniesRepo) RevokeAPIKey(ctx context.Context, id uint64) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	err := c.write(ctx).Transaction(func(tx *gorm.DB) error {
+		var n int64
+		if err := tx.Model(&models.APIKey{}).Scopes(tenantScope(ctx)).Where("id = ?", id).Count(&n).Error; err != nil {
+			return err
+		}
+
+		if n == 0 {
+			return models.ErrAPIKeyNotFound
+		}
+
+		return tx.Model(&models.APIKey{}).Scopes(tenantScope(ctx)).
+			Where("id = ? AND revoked_at IS NULL", id).
+			Update("revoked_at", now).Error
+	})
+
+	return contextError(ctx, err)
+}
+
+// GetAPIKeyByPrefix returns the API key with the given prefix, of any tenant.
+// The key is read from the primary, so the rotated key can be used immediately.
+// If the key does not exist this function returns a ErrAPIKeyNotFound error.
+func (c *CompaniesRepo) GetAPIKeyByPrefix(ctx context.Context, prefix string) (models.APIKey, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	var key models.APIKey
+
+	err := c.db.WithContext(ctx).Where("prefix = ?", prefix).First(&key).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return key, models.ErrAPIKeyNotFound
+	}
+
+	return key, contextError(ctx, err)
+}
+
+// TouchAPIKey records the time the API key was last used.
+func (c *CompaniesRepo) TouchAPIKey(ctx context.Context, id uint64, usedAt time.Time) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	err := c.db.WithContext(ctx).Model(&models.APIKey{}).Where("id = ?", id).
+		Update("last_used_at", usedAt.UTC().Truncate(time.Millisecond)).Error
+
+	return contextError(ctx, err)
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// writeAudit adds a new audit record for the company change,
+// chained to the latest audit record of any company, and moves the head of the chain to it.
+// The head is locked until the transaction ends, so the records are chained one at a time.
+func writeAudit(ctx context.Context, tx *gorm.DB, companyID uint64, action string, changes models.Changes) error {
+	var head models.AuditHead
+
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", models.AuditHeadID).First(&head).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	meta := models.AuditMetaFrom(ctx)
+	record := models.AuditRecord{
+		CompanyID: companyID,
+		Action:    action,
+		Changes:   changes,
+		Actor:     meta.Actor,
+		RequestID: meta.RequestID,
+		ClientIP:  meta.ClientIP,
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+		PrevHash:  head.Hash,
+	}
+	record.Hash = record.ComputeHash()
+
+	if err := tx.Create(&record).Error; err != nil {
+		return err
+	}
+
+	head.ID, head.RecordID, head.Hash = models.AuditHeadID, record.ID, record.Hash
+	return tx.Save(&head).Error
+}
+
+// VerifyAudit reports whether the audit records of all companies form an unbroken chain,
+// which ends at the head, and none of them was tampered with.
+// The records are read from the primary, so the records just written are included.
+func (c *CompaniesRepo) VerifyAudit(ctx context.Context) (bool, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	var (
+		head    models.AuditHead
+		records []models.AuditRecord
+	)
+
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("id = ?", models.AuditHeadID).First(&head).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return tx.Order("id").Find(&records).Error
+	})
+	if err != nil {
+		return false, contextError(ctx, err)
+	}
+
+	return models.VerifyAuditTrail(records, head), nil
+}
+
+// History returns all audit records of the company, ordered from the oldest.
+// Records of companies of other tenants are not returned.
+func (c *CompaniesRepo) History(ctx context.Context, companyID uint64) ([]models.AuditRecord, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	tx := c.read(ctx)
+	companies := tx.Unscoped().Model(&models.Company{}).Select("id").Where("tenant_id = ?", models.TenantFrom(ctx))
+
+	records := []models.AuditRecord{}
+	err := tx.Where("company_id = ? AND company_id IN (?)", companyID, companies).Order("id").Find(&records).Error
+	return records, contextError(ctx, err)
+}
@@ -0,0 +1,191 @@
+// Package cached provides the read-through cache of the companies repository.
+package cached
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+)
+
+// keyPrefix separates the company entries from other entries of the shared cache.
+const keyPrefix = "company:"
+
+// Storage defines the repository decorated by the cache.
+type Storage interface {
+	handlers.CompaniesRepo
+	Purge(context.Context, time.Time) (int64, error)
+}
+
+// Store defines the cache used to store the companies.
+type Store interface {
+	Get([]byte) ([]byte, error)
+	Set([]byte, []byte, int) error
+	Del([]byte) bool
+}
+
+// CompaniesRepo serves companies from the cache, and loads them from the storage on a miss.
+// Entries are keyed by the tenant from the context and the company id,
+// so a company is never served to another tenant.
+// Entries are invalidated on every write, other methods are passed to the storage.
+// A read racing with a write may put the previous version into the cache,
+// so the entry can be stale for at most the TTL.
+type CompaniesRepo struct {
+	stats models.CacheStats // first field, so the counters are aligned for the atomic operations
+	Storage
+	store Store
+	ttl   int
+}
+
+// NewCompaniesRepo creates a new instance of the CompaniesRepo.
+func NewCompaniesRepo(cfg *configs.Config, storage Storage, store Store) *CompaniesRepo {
+	return &CompaniesRepo{Storage: storage, store: store, ttl: cfg.Companies.CacheTTLSeconds}
+}
+
+// Stats returns the number of cache hits and misses.
+func (c *CompaniesRepo) Stats() models.CacheStats {
+	return models.CacheStats{Hits: atomic.LoadUint64(&c.stats.Hits), Misses: atomic.LoadUint64(&c.stats.Misses)}
+}
+
+// Get returns a single company from the cache, or from the storage on a miss.
+// Companies that were not found are not cached.
+func (c *CompaniesRepo) Get(ctx context.Context, id uint64) (models.Company, error) {
+	if company, ok := c.load(ctx, id); ok {
+		atomic.AddUint64(&c.stats.Hits, 1)
+		return company, nil
+	}
+
+	atomic.AddUint64(&c.stats.Misses, 1)
+
+	company, err := c.Storage.Get(ctx, id)
+	if err != nil {
+		return company, err
+	}
+
+	c.save(ctx, company)
+	return company, nil
+}
+
+// Create adds a new company to the storage.
+func (c *CompaniesRepo) Create(ctx context.Context, company models.Company) (models.Company, error) {
+	company, err := c.Storage.Create(ctx, company)
+	if err == nil {
+		c.invalidate(ctx, company.ID)
+	}
+	return company, err
+}
+
+// Update updates a company in the storage.
+// The entry is invalidated even if the update fails, since the cached version may be stale.
+func (c *CompaniesRepo) Update(ctx context.Context, company models.Company) (models.Company, models.Changes, error) {
+	defer c.invalidate(ctx, company.ID)
+	return c.Storage.Update(ctx, company)
+}
+
+// Delete soft-deletes a company from the storage.
+func (c *CompaniesRepo) Delete(ctx context.Context, company models.Company) error {
+	defer c.invalidate(ctx, company.ID)
+	return c.Storage.Delete(ctx, company)
+}
+
+// Restore restores a soft-deleted company.
+func (c *CompaniesRepo) Restore(ctx context.Context, id uint64) error {
+	defer c.invalidate(ctx, id)
+	return c.Storage.Restore(ctx, id)
+}
+
+// Transaction runs fn with the storage bound to a transaction.
+// Reads inside the transaction bypass the cache, so uncommitted companies are never cached.
+// Companies written inside the transaction are invalidated once it is finished.
+func (c *CompaniesRepo) Transaction(ctx context.Context, fn func(handlers.CompaniesRepo) error) error {
+	var written []uint64
+
+	defer func() {
+		for _, id := range written {
+			c.invalidate(ctx, id)
+		}
+	}()
+
+	return c.Storage.Transaction(ctx, func(tx handlers.CompaniesRepo) error {
+		return fn(&txRepo{CompaniesRepo: tx, written: &written})
+	})
+}
+
+// load returns the company from the cache.
+func (c *CompaniesRepo) load(ctx context.Context, id uint64) (models.Company, bool) {
+	var company models.Company
+
+	v, err := c.store.Get(key(ctx, id))
+	if err != nil {
+		return company, false
+	}
+
+	if gob.NewDecoder(bytes.NewReader(v)).Decode(&company) != nil {
+		return company, false
+	}
+
+	return company, true
+}
+
+// save puts the company into the cache.
+func (c *CompaniesRepo) save(ctx context.Context, company models.Company) {
+	var buf bytes.Buffer
+
+	if gob.NewEncoder(&buf).Encode(company) != nil {
+		return
+	}
+
+	c.store.Set(key(ctx, company.ID), buf.Bytes(), c.ttl)
+}
+
+// invalidate removes the company from the cache.
+func (c *CompaniesRepo) invalidate(ctx context.Context, id uint64) {
+	c.store.Del(key(ctx, id))
+}
+
+// key returns the cache key of the company of the tenant from the context.
+// The tenant goes first and cannot contain a colon, so keys of different tenants never collide.
+func key(ctx context.Context, id uint64) []byte {
+	return strconv.AppendUint([]byte(keyPrefix+models.TenantFrom(ctx)+":"), id, 10)
+}
+
+// txRepo records companies written inside the transaction.
+type txRepo struct {
+	handlers.CompaniesRepo
+	written *[]uint64
+}
+
+func (t *txRepo) Create(ctx context.Context, company models.Company) (models.Company, error) {
+	company, err := t.CompaniesRepo.Create(ctx, company)
+	if err == nil {
+		*t.written = append(*t.written, company.ID)
+	}
+	return company, err
+}
+
+func (t *txRepo) Update(ctx context.Context, company models.Company) (models.Company, models.Changes, error) {
+	*t.written = append(*t.written, company.ID)
+	return t.CompaniesRepo.Update(ctx, company)
+}
+
+func (t *txRepo) Delete(ctx context.Context, company models.Company) error {
+	*t.written = append(*t.written, company.ID)
+	return t.CompaniesRepo.Delete(ctx, company)
+}
+
+func (t *txRepo) Restore(ctx context.Context, id uint64) error {
+	*t.written = append(*t.written, id)
+	return t.CompaniesRepo.Restore(ctx, id)
+}
+
+func (t *txRepo) Transaction(ctx context.Context, fn func(handlers.CompaniesRepo) error) error {
+	return t.CompaniesRepo.Transaction(ctx, func(tx handlers.CompaniesRepo) error {
+		return fn(&txRepo{CompaniesRepo: tx, written: t.written})
+	})
+}
@@ -0,0 +1,152 @@
+package cached
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/db/dbtest"
+	"github.com/brokeyourbike/xm-golang-exercise/db/memory"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/coocood/freecache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+func newTestRepo() (*CompaniesRepo, *memory.CompaniesRepo) {
+	cfg := configs.Config{}
+	cfg.Companies.CacheTTLSeconds = 60
+
+	storage := memory.NewCompaniesRepo(&cfg)
+	return NewCompaniesRepo(&cfg, storage, freecache.NewCache(1024*1024)), storage
+}
+
+func TestCompaniesConformance(t *testing.T) {
+	suite.Run(t, &dbtest.CompaniesSuite{NewRepo: func() dbtest.Repo {
+		repo, _ := newTestRepo()
+		return repo
+	}})
+}
+
+func TestItServesCompanyFromCache(t *testing.T) {
+	ctx := context.Background()
+	repo, storage := newTestRepo()
+
+	company, err := repo.Create(ctx, models.Company{Name: "acme", Code: "c1"})
+	require.NoError(t, err)
+
+	found, err := repo.Get(ctx, company.ID)
+	require.NoError(t, err)
+	assert.Equal(t, company, found)
+
+	// the change made around the cache is not visible until the entry is invalidated
+	company.Name = "acme ltd"
+	_, _, err = storage.Update(ctx, company)
+	require.NoError(t, err)
+
+	found, err = repo.Get(ctx, company.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", found.Name)
+	assert.Equal(t, uint64(1), found.Version)
+
+	assert.Equal(t, models.CacheStats{Hits: 1, Misses: 1}, repo.Stats())
+}
+
+func TestItDoesNotCacheMissingCompany(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newTestRepo()
+
+	_, err := repo.Get(ctx, 1)
+	assert.ErrorIs(t, err, models.ErrCompanyNotFound)
+
+	company, err := repo.Create(ctx, models.Company{Name: "acme", Code: "c1"})
+	require.NoError(t, err)
+
+	found, err := repo.Get(ctx, company.ID)
+	require.NoError(t, err)
+	assert.Equal(t, company, found)
+
+	assert.Equal(t, models.CacheStats{Hits: 0, Misses: 2}, repo.Stats())
+}
+
+func TestItInvalidatesCompanyOnWrite(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newTestRepo()
+
+	company, err := repo.Create(ctx, models.Company{Name: "acme", Code: "c1"})
+	require.NoError(t, err)
+
+	_, err = repo.Get(ctx, company.ID)
+	require.NoError(t, err)
+
+	company.Name = "acme ltd"
+	company, _, err = repo.Update(ctx, company)
+	require.NoError(t, err)
+
+	found, err := repo.Get(ctx, company.ID)
+	require.NoError(t, err)
+	assert.Equal(t, company, found)
+
+	require.NoError(t, repo.Delete(ctx, company))
+
+	_, err = repo.Get(ctx, company.ID)
+	assert.ErrorIs(t, err, models.ErrCompanyNotFound)
+
+	require.NoError(t, repo.Restore(ctx, company.ID))
+
+	found, err = repo.Get(ctx, company.ID)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), found.Version)
+
+	assert.Equal(t, models.CacheStats{Hits: 0, Misses: 4}, repo.Stats())
+}
+
+func TestItInvalidatesCompanyAfterTransaction(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newTestRepo()
+
+	company, err := repo.Create(ctx, models.Company{Name: "acme", Code: "c1"})
+	require.NoError(t, err)
+
+	_, err = repo.Get(ctx, company.ID)
+	require.NoError(t, err)
+
+	failure := errors.New("failure")
+
+	err = repo.Transaction(ctx, func(tx handlers.CompaniesRepo) error {
+		found, err := tx.Get(ctx, company.ID)
+		require.NoError(t, err)
+
+		found.Name = "acme ltd"
+		_, _, err = tx.Update(ctx, found)
+		require.NoError(t, err)
+
+		// reads inside the transaction bypass the cache
+		found, err = tx.Get(ctx, company.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "acme ltd", found.Name)
+
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+
+	found, err := repo.Get(ctx, company.ID)
+	require.NoError(t, err)
+	assert.Equal(t, company, found)
+
+	err = repo.Transaction(ctx, func(tx handlers.CompaniesRepo) error {
+		found.Name = "acme ltd"
+		_, _, err := tx.Update(ctx, found)
+		return err
+	})
+	require.NoError(t, err)
+
+	found, err = repo.Get(ctx, company.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "acme ltd", found.Name)
+
+	assert.Equal(t, models.CacheStats{Hits: 0, Misses: 3}, repo.Stats())
+}
@@ -1,72 +1,422 @@
 package db
 
 import (
+	"context"
 	"errors"
+	"time"
 
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
 	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
 	"github.com/brokeyourbike/xm-golang-exercise/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CompaniesRepo allows to store and retrieve companies from the database.
 type CompaniesRepo struct {
-	db *gorm.DB
+	db         *gorm.DB
+	replicas   *Replicas
+	timeout    time.Duration
+	perCountry bool
+	webhooks   bool // the outbox events are written only when there are webhook URLs to deliver them to
 }
 
 // NewCompaniesRepo creates a new instance of the CompaniesRepo.
-func NewCompaniesRepo(db *gorm.DB) *CompaniesRepo {
-	return &CompaniesRepo{db: db}
+func NewCompaniesRepo(db *gorm.DB, cfg *configs.Config) *CompaniesRepo {
+	return &CompaniesRepo{
+		db:         db,
+		timeout:    time.Second * time.Duration(cfg.Database.QueryTimeoutSeconds),
+		perCountry: cfg.Companies.UniqueCodePerCountry,
+		webhooks:   len(cfg.Webhooks.URLs) > 0,
+	}
+}
+
+// WithReplicas returns a copy of the repository, which sends reads to the read replicas.
+func (c *CompaniesRepo) WithReplicas(replicas *Replicas) *CompaniesRepo {
+	return &CompaniesRepo{db: c.db, replicas: replicas, timeout: c.timeout, perCountry: c.perCountry, webhooks: c.webhooks}
+}
+
+// read returns the database used for reads outside of transactions.
+func (c *CompaniesRepo) read(ctx context.Context) *gorm.DB {
+	if c.replicas == nil {
+		return c.db.WithContext(ctx)
+	}
+	return c.replicas.reader(ctx).WithContext(ctx)
 }
 
-// Create adds a new company to the database.
-func (c *CompaniesRepo) Create(company models.Company) (models.Company, error) {
-	err := c.db.Create(&company).Error
-	return company, err
+// write returns the database used for writes, which are always sent to the primary.
+// The write is recorded before it starts, so the following reads of the client
+// are sent to the primary as soon as the write is committed.
+func (c *CompaniesRepo) write(ctx context.Context) *gorm.DB {
+	if c.replicas != nil {
+		c.replicas.wrote(ctx)
+	}
+	return c.db.WithContext(ctx)
+}
+
+// withTimeout returns the context limited by the query timeout, if the timeout is configured.
+func (c *CompaniesRepo) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout == 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// Transaction runs fn with the repository bound to a database transaction.
+// The transaction is committed if fn returns nil, otherwise it is rolled back.
+// Methods of the repository open nested transactions, which become savepoints,
+// so a failed operation does not abort the whole transaction.
+// The query timeout is applied to every query, not to the transaction.
+func (c *CompaniesRepo) Transaction(ctx context.Context, fn func(handlers.CompaniesRepo) error) error {
+	return c.write(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&CompaniesRepo{db: tx, timeout: c.timeout, perCountry: c.perCountry, webhooks: c.webhooks})
+	})
+}
+
+// Create adds a new company of the tenant from the context to the database.
+// If the parent company does not exist this function returns a ErrParentNotFound error.
+func (c *CompaniesRepo) Create(ctx context.Context, company models.Company) (models.Company, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	company.Version = 1
+	company.TenantID = models.TenantFrom(ctx)
+
+	err := c.write(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkParent(ctx, tx, company); err != nil {
+			return err
+		}
+		if err := tx.Create(&company).Error; err != nil {
+			return err
+		}
+		if err := writeAudit(ctx, tx, company.ID, models.AuditActionCreated, models.Diff(models.Company{}, company)); err != nil {
+			return err
+		}
+		return c.writeEvent(tx, models.EventCompanyCreated, company)
+	})
+
+	if err != nil {
+		return company, c.conflict(ctx, err, company)
+	}
+
+	return company, nil
 }
 
 // Get returns a single company from the database.
 // If a company with the given id does not exist in the database
 // this function returns a ErrCompanyNotFound error.
-func (c *CompaniesRepo) Get(id uint64) (models.Company, error) {
+func (c *CompaniesRepo) Get(ctx context.Context, id uint64) (models.Company, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	var company models.Company
+
+	err := c.read(ctx).Scopes(tenantScope(ctx)).Where("id = ?", id).First(&company).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return company, models.ErrCompanyNotFound
+	}
+
+	if err != nil {
+		return company, contextError(ctx, err)
+	}
+
+	return company, nil
+}
+
+// GetTrashed returns a single soft-deleted company from the database.
+// If a soft-deleted company with the given id does not exist in the database
+// this function returns a ErrCompanyNotFound error.
+func (c *CompaniesRepo) GetTrashed(ctx context.Context, id uint64) (models.Company, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	var company models.Company
 
-	err := c.db.Where("id = ?", id).First(&company).Error
+	err := c.read(ctx).Unscoped().Scopes(tenantScope(ctx)).Where("id = ? AND deleted_at IS NOT NULL", id).First(&company).Error
 
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return company, models.ErrCompanyNotFound
 	}
 
 	if err != nil {
-		return company, err
+		return company, contextError(ctx, err)
 	}
 
 	return company, nil
 }
 
-// GetAll returns all companies from the database.
-func (c *CompaniesRepo) GetAll(p requests.CompanyPayload) ([]models.Company, error) {
-	var companies []models.Company
+// GetByCode returns a single company with the given code from the database.
+// When codes are unique per country, the country must match as well.
+// If such a company does not exist in the database
+// this function returns a ErrCompanyNotFound error.
+func (c *CompaniesRepo) GetByCode(ctx context.Context, code string, country string) (models.Company, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	var company models.Company
+
+	tx := c.read(ctx).Scopes(tenantScope(ctx)).Where("code = ?", code)
+	if c.perCountry {
+		tx = tx.Where("country = ?", country)
+	}
+
+	err := tx.First(&company).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return company, models.ErrCompanyNotFound
+	}
+
+	if err != nil {
+		return company, contextError(ctx, err)
+	}
+
+	return company, nil
+}
+
+// GetAll returns a single page of companies from the database.
+// Companies are filtered and sorted according to the query,
+// the next page starts right after the cursor.
+func (c *CompaniesRepo) GetAll(ctx context.Context, q requests.CompaniesQuery) (models.CompaniesPage, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	page, err := c.findPage(c.read(ctx).Scopes(tenantScope(ctx)), q)
+	return page, contextError(ctx, err)
+}
+
+// GetAllTrashed returns a single page of soft-deleted companies from the database.
+func (c *CompaniesRepo) GetAllTrashed(ctx context.Context, q requests.CompaniesQuery) (models.CompaniesPage, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	page, err := c.findPage(c.read(ctx).Unscoped().Scopes(tenantScope(ctx)).Where("deleted_at IS NOT NULL"), q)
+	return page, contextError(ctx, err)
+}
+
+// findPage returns a single page of companies matching the query.
+func (c *CompaniesRepo) findPage(tx *gorm.DB, q requests.CompaniesQuery) (models.CompaniesPage, error) {
+	page := models.CompaniesPage{Companies: []models.Company{}}
+
+	keys, err := q.SortKeys()
+	if err != nil {
+		return page, err
+	}
 
-	err := c.db.Where(p.ToCompany()).Find(&companies).Error
+	after, err := q.After()
 	if err != nil {
-		return companies, err
+		return page, err
+	}
+
+	tx = applyFilters(tx, q.Filters)
+	tx = applyCursor(tx, keys, after)
+	tx = applyOrder(tx, keys)
+
+	limit := q.PageSize()
+	if err := tx.Limit(limit + 1).Find(&page.Companies).Error; err != nil {
+		return page, err
 	}
 
-	return companies, nil
+	if len(page.Companies) > limit {
+		page.Companies = page.Companies[:limit]
+		page.HasMore = true
+		page.NextCursor = q.CursorFor(page.Companies[limit-1]).Encode()
+	}
+
+	return page, nil
+}
+
+// Delete soft-deletes a company from the database.
+// If the company version in the database is different from the given one
+// this function returns a ErrCompanyModified error,
+// if the company has subsidiaries it returns a ErrCompanyHasChildren error.
+func (c *CompaniesRepo) Delete(ctx context.Context, company models.Company) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	err := c.write(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Scopes(tenantScope(ctx)).Where("version = ?", company.Version).Delete(&models.Company{ID: company.ID})
+
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			return models.ErrCompanyModified
+		}
+
+		if children, err := hasChildren(ctx, tx, company.ID); err != nil || children {
+			if err == nil {
+				err = models.ErrCompanyHasChildren
+			}
+			return err
+		}
+
+		if err := writeAudit(ctx, tx, company.ID, models.AuditActionDeleted, models.Changes{}); err != nil {
+			return err
+		}
+		return c.writeTrashedEvent(tx, models.EventCompanyDeleted, company.ID)
+	})
+
+	return contextError(ctx, err)
+}
+
+// Restore restores a soft-deleted company.
+// The parent of the company is cleared if it was deleted in the meantime.
+// If a soft-deleted company with the given id does not exist in the database
+// this function returns a ErrCompanyNotFound error.
+func (c *CompaniesRepo) Restore(ctx context.Context, id uint64) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	err := c.write(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Unscoped().Model(&models.Company{}).Scopes(tenantScope(ctx)).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			return models.ErrCompanyNotFound
+		}
+
+		var restored models.Company
+		if err := tx.Where("id = ?", id).First(&restored).Error; err != nil {
+			return err
+		}
+
+		before := restored
+		if err := detachParent(ctx, tx, &restored); err != nil {
+			return err
+		}
+
+		if err := writeAudit(ctx, tx, id, models.AuditActionRestored, models.Diff(before, restored)); err != nil {
+			return err
+		}
+		return c.writeEvent(tx, models.EventCompanyRestored, restored)
+	})
+
+	return contextError(ctx, err)
+}
+
+// Purge permanently removes companies soft-deleted before the given time, together with their contacts.
+// Companies of all tenants are removed. It returns the number of removed companies.
+func (c *CompaniesRepo) Purge(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	var n int64
+
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		trashed := tx.Unscoped().Model(&models.Company{}).Select("id").Where("deleted_at < ?", before)
+
+		// contacts are removed together with their company
+		if err := tx.Where("company_id IN (?)", trashed).Delete(&models.Contact{}).Error; err != nil {
+			return err
+		}
+
+		res := tx.Unscoped().Where("deleted_at < ?", before).Delete(&models.Company{})
+		n = res.RowsAffected
+		return res.Error
+	})
+
+	return n, contextError(ctx, err)
 }
 
-// Delete deletes a company from the database.
-func (c *CompaniesRepo) Delete(id uint64) error {
-	return c.db.Delete(&models.Company{ID: id}).Error
+// Update updates a company in the database and increments its version.
+// It returns the changed fields, compared to the company locked in the transaction.
+// If the company version in the database is different from the given one
+// this function returns a ErrCompanyModified error.
+// If the parent company does not exist it returns a ErrParentNotFound error,
+// if the company would become its own ancestor it returns a ErrCompanyCycle error.
+func (c *CompaniesRepo) Update(ctx context.Context, company models.Company) (models.Company, models.Changes, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	company.TenantID = models.TenantFrom(ctx)
+
+	var changes models.Changes
+
+	err := c.write(ctx).Transaction(func(tx *gorm.DB) error {
+		var before models.Company
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(tenantScope(ctx)).
+			Where("id = ? AND version = ?", company.ID, company.Version).
+			First(&before).Error
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.ErrCompanyModified
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := checkParent(ctx, tx, company); err != nil {
+			return err
+		}
+
+		// struct updates skip zero values, so the columns are selected explicitly,
+		// and the version is checked again in case the row was not locked, e.g. by SQLite
+		res := tx.Model(&models.Company{ID: company.ID}).Scopes(tenantScope(ctx)).Where("version = ?", company.Version).
+			Select("name", "code", "country", "website", "phone", "parent_id", "version").Updates(&models.Company{
+			Name:     company.Name,
+			Code:     company.Code,
+			Country:  company.Country,
+			Website:  company.Website,
+			Phone:    company.Phone,
+			ParentID: company.ParentID,
+			Version:  company.Version + 1,
+		})
+
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			return models.ErrCompanyModified
+		}
+
+		changes = models.Diff(before, company)
+		if err := writeAudit(ctx, tx, company.ID, models.AuditActionUpdated, changes); err != nil {
+			return err
+		}
+
+		updated := company
+		updated.Version++
+		return c.writeEvent(tx, models.EventCompanyUpdated, updated)
+	})
+
+	if err != nil {
+		return company, nil, c.conflict(ctx, err, company)
+	}
+
+	company.Version++
+	return company, changes, nil
 }
 
-// Update updates a company from the database.
-func (c *CompaniesRepo) Update(company models.Company) error {
-	return c.db.Model(&company).Updates(&models.Company{
-		Name:    company.Name,
-		Code:    company.Code,
-		Country: company.Country,
-		Website: company.Website,
-		Phone:   company.Phone,
-	}).Error
+// conflict converts the duplicate key error into a ConflictError, pointing to the company with the same code.
+// Soft-deleted companies keep their codes until they are purged, so they are looked up as well.
+func (c *CompaniesRepo) conflict(ctx context.Context, err error, company models.Company) error {
+	if !isDuplicateKey(c.db, err) {
+		return contextError(ctx, err)
+	}
+
+	var existing models.Company
+
+	// The company from the same country is looked up first, so the lookup
+	// is correct whether the code is unique globally or per country.
+	res := c.db.WithContext(ctx).Unscoped().Scopes(tenantScope(ctx)).Where("id <> ? AND code = ? AND country = ?", company.ID, company.Code, company.Country).Limit(1).Find(&existing)
+	if res.Error == nil && res.RowsAffected == 0 {
+		res = c.db.WithContext(ctx).Unscoped().Scopes(tenantScope(ctx)).Where("id <> ? AND code = ?", company.ID, company.Code).Limit(1).Find(&existing)
+	}
+
+	if res.Error != nil {
+		return contextError(ctx, res.Error)
+	}
+
+	return &models.ConflictError{Field: "code", ExistingID: existing.ID}
 }
@@ -1,13 +1,18 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
 	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/pagination"
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
@@ -44,7 +49,45 @@ func (s *CompaniesSuite) SetupDatabase() {
 
 func (s *CompaniesSuite) SetupTest() {
 	s.SetupDatabase()
-	s.repository = NewCompaniesRepo(s.db)
+	cfg := configs.Config{}
+	cfg.Webhooks.URLs = []string{"https://example.com/hook"}
+
+	s.repository = NewCompaniesRepo(s.db, &cfg)
+}
+
+// expectAudit expects the audit record to be written for the company, after the head of the audit chain.
+func (s *CompaniesSuite) expectAudit(companyID uint64, action string) {
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `audit_heads` WHERE id = ? ORDER BY `audit_heads`.`id` LIMIT 1 FOR UPDATE")).
+		WithArgs(models.AuditHeadID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "record_id", "hash"}).AddRow(models.AuditHeadID, 1, "prev-hash"))
+	s.mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `audit_records`")).
+		WithArgs(companyID, action, sqlmock.AnyArg(), "john", "req-1", "127.0.0.1", sqlmock.AnyArg(), "prev-hash", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE `audit_heads` SET `record_id`=?,`hash`=? WHERE `id` = ?")).
+		WithArgs(2, sqlmock.AnyArg(), models.AuditHeadID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+// expectEvent expects the outbox event to be written for the company.
+func (s *CompaniesSuite) expectEvent(companyID uint64, eventType string) {
+	s.mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `outbox_events`")).
+		WithArgs(eventType, companyID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+// expectTrashedEvent expects the company to be loaded and the outbox event to be written for it.
+func (s *CompaniesSuite) expectTrashedEvent(companyID uint64, eventType string) {
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies` WHERE id = ? ORDER BY `companies`.`id` LIMIT 1")).
+		WithArgs(companyID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(companyID, "test"))
+	s.expectEvent(companyID, eventType)
+}
+
+// expectChildren expects the active subsidiaries of the company to be counted.
+func (s *CompaniesSuite) expectChildren(companyID uint64, n int) {
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `companies` WHERE parent_id = ? AND tenant_id = ? AND `companies`.`deleted_at` IS NULL")).
+		WithArgs(companyID, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(n))
 }
 
 func (s *CompaniesSuite) AfterTest(_, _ string) {
@@ -55,6 +98,10 @@ func TestUsers(t *testing.T) {
 	suite.Run(t, new(CompaniesSuite))
 }
 
+var tenantCtx = models.WithTenant(context.Background(), "acme")
+
+var auditCtx = models.WithAuditMeta(tenantCtx, models.AuditMeta{Actor: "john", RequestID: "req-1", ClientIP: "127.0.0.1"})
+
 func (s *CompaniesSuite) TestItCanCreateCompany() {
 	company := models.Company{
 		Name:    "test",
@@ -66,13 +113,62 @@ func (s *CompaniesSuite) TestItCanCreateCompany() {
 
 	s.mock.ExpectBegin()
 	s.mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `companies`")).
-		WithArgs(company.Name, company.Code, company.Country, company.Website, company.Phone).
+		WithArgs(company.Name, company.Code, company.Country, company.Website, company.Phone, nil, "acme", 1, nil).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	s.expectAudit(1, models.AuditActionCreated)
+	s.expectEvent(1, models.EventCompanyCreated)
 	s.mock.ExpectCommit()
 
-	c, err := s.repository.Create(company)
+	c, err := s.repository.Create(auditCtx, company)
 	assert.NoError(s.T(), err)
 	assert.Equal(s.T(), uint64(1), c.ID)
+	assert.Equal(s.T(), uint64(1), c.Version)
+}
+
+func (s *CompaniesSuite) TestItDoesNotWriteEventWithoutWebhookURLs() {
+	s.repository = NewCompaniesRepo(s.db, &configs.Config{})
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `companies`")).
+		WithArgs("test", "c1", "", "", "", nil, "acme", 1, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	s.expectAudit(1, models.AuditActionCreated)
+	s.mock.ExpectCommit()
+
+	_, err := s.repository.Create(auditCtx, models.Company{Name: "test", Code: "c1"})
+	assert.NoError(s.T(), err)
+}
+
+func (s *CompaniesSuite) TestItCanReturnConflictErrorOnCreate() {
+	company := models.Company{Name: "test", Code: "super-hash", Country: "US"}
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `companies`")).
+		WillReturnError(&mysqldriver.MySQLError{Number: 1062, Message: "Duplicate entry"})
+	s.mock.ExpectRollback()
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies` WHERE (id <> ? AND code = ? AND country = ?) AND tenant_id = ? LIMIT 1")).
+		WithArgs(0, company.Code, company.Country, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies` WHERE (id <> ? AND code = ?) AND tenant_id = ? LIMIT 1")).
+		WithArgs(0, company.Code, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+
+	_, err := s.repository.Create(auditCtx, company)
+	assert.ErrorIs(s.T(), err, models.ErrCompanyConflict)
+	assert.Equal(s.T(), &models.ConflictError{Field: "code", ExistingID: 5}, err)
+}
+
+func (s *CompaniesSuite) TestItCannotCreateCompanyWithMissingParent() {
+	parentID := uint64(5)
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies` WHERE id = ? AND tenant_id = ? AND `companies`.`deleted_at` IS NULL ORDER BY `companies`.`id` LIMIT 1 FOR UPDATE")).
+		WithArgs(parentID, "acme").
+		WillReturnError(gorm.ErrRecordNotFound)
+	s.mock.ExpectRollback()
+
+	_, err := s.repository.Create(auditCtx, models.Company{Name: "test", Code: "c1", ParentID: &parentID})
+	assert.ErrorIs(s.T(), err, models.ErrParentNotFound)
 }
 
 func (s *CompaniesSuite) TestItCanGetCompanyById() {
@@ -85,45 +181,230 @@ func (s *CompaniesSuite) TestItCanGetCompanyById() {
 		Phone:   "+12345",
 	}
 
-	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies`")).
-		WithArgs(company.ID).
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies` WHERE id = ? AND tenant_id = ?")).
+		WithArgs(company.ID, "acme").
 		WillReturnRows((sqlmock.NewRows([]string{"id", "name", "code", "country", "website", "phone"})).
 			AddRow("3", "test", "c12", "UA", "test.com", "+12345"))
 
-	res, err := s.repository.Get(company.ID)
+	res, err := s.repository.Get(tenantCtx, company.ID)
 	assert.NoError(s.T(), err)
 	assert.Equal(s.T(), company, res)
 }
 
 func (s *CompaniesSuite) TestItCanReturnErrCompanyNotFound() {
-	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies`")).
-		WithArgs(10).
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies` WHERE id = ? AND tenant_id = ?")).
+		WithArgs(10, "acme").
 		WillReturnError(gorm.ErrRecordNotFound)
 
-	res, err := s.repository.Get(10)
+	res, err := s.repository.Get(tenantCtx, 10)
 	assert.ErrorIs(s.T(), err, models.ErrCompanyNotFound)
 	assert.Equal(s.T(), models.Company{}, res)
 }
 
+func (s *CompaniesSuite) TestItCanGetCompanyByCode() {
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies` WHERE code = ? AND tenant_id = ? AND `companies`.`deleted_at` IS NULL ORDER BY `companies`.`id` LIMIT 1")).
+		WithArgs("c12", "acme").
+		WillReturnRows((sqlmock.NewRows([]string{"id", "code", "country"})).AddRow("3", "c12", "UA"))
+
+	res, err := s.repository.GetByCode(tenantCtx, "c12", "CY")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), uint64(3), res.ID)
+}
+
+func (s *CompaniesSuite) TestItCanReturnErrCompanyNotFoundByCode() {
+	cfg := configs.Config{}
+	cfg.Companies.UniqueCodePerCountry = true
+	s.repository = NewCompaniesRepo(s.db, &cfg)
+
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies` WHERE code = ? AND country = ? AND tenant_id = ? AND `companies`.`deleted_at` IS NULL ORDER BY `companies`.`id` LIMIT 1")).
+		WithArgs("c12", "CY", "acme").
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	_, err := s.repository.GetByCode(tenantCtx, "c12", "CY")
+	assert.ErrorIs(s.T(), err, models.ErrCompanyNotFound)
+}
+
 func (s *CompaniesSuite) TestItCanReturnGeneralError() {
-	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies`")).
-		WithArgs(10).
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies` WHERE id = ? AND tenant_id = ?")).
+		WithArgs(10, "acme").
 		WillReturnError(gorm.ErrInvalidField)
 
-	res, err := s.repository.Get(10)
+	res, err := s.repository.Get(tenantCtx, 10)
 	assert.ErrorIs(s.T(), err, gorm.ErrInvalidField)
 	assert.Equal(s.T(), models.Company{}, res)
 }
 
+func (s *CompaniesSuite) TestItCanReturnDeadlineExceeded() {
+	s.repository.timeout = time.Millisecond * 10
+
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies` WHERE id = ? AND tenant_id = ? AND `companies`.`deleted_at` IS NULL ORDER BY `companies`.`id` LIMIT 1")).
+		WithArgs(10, "acme").
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+
+	_, err := s.repository.Get(tenantCtx, 10)
+	assert.ErrorIs(s.T(), err, context.DeadlineExceeded)
+}
+
 func (s *CompaniesSuite) TestItCanDeleteCompany() {
 	s.mock.ExpectBegin()
-	s.mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `companies`")).
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE `companies` SET `deleted_at`=? WHERE version = ? AND tenant_id = ? AND `companies`.`id` = ? AND `companies`.`deleted_at` IS NULL")).
+		WithArgs(sqlmock.AnyArg(), 2, "acme", 10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.expectChildren(10, 0)
+	s.expectAudit(10, models.AuditActionDeleted)
+	s.expectTrashedEvent(10, models.EventCompanyDeleted)
+	s.mock.ExpectCommit()
+
+	err := s.repository.Delete(auditCtx, models.Company{ID: 10, Version: 2})
+	assert.NoError(s.T(), err)
+}
+
+func (s *CompaniesSuite) TestItCannotDeleteCompanyWithChildren() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE `companies` SET `deleted_at`=? WHERE version = ? AND tenant_id = ? AND `companies`.`id` = ? AND `companies`.`deleted_at` IS NULL")).
+		WithArgs(sqlmock.AnyArg(), 2, "acme", 10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.expectChildren(10, 2)
+	s.mock.ExpectRollback()
+
+	err := s.repository.Delete(auditCtx, models.Company{ID: 10, Version: 2})
+	assert.ErrorIs(s.T(), err, models.ErrCompanyHasChildren)
+}
+
+func (s *CompaniesSuite) TestItCannotDeleteModifiedCompany() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE `companies` SET `deleted_at`=? WHERE version = ? AND tenant_id = ? AND `companies`.`id` = ? AND `companies`.`deleted_at` IS NULL")).
+		WithArgs(sqlmock.AnyArg(), 2, "acme", 10).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectRollback()
+
+	err := s.repository.Delete(auditCtx, models.Company{ID: 10, Version: 2})
+	assert.ErrorIs(s.T(), err, models.ErrCompanyModified)
+}
+
+func (s *CompaniesSuite) TestItCanGetTrashedCompanyById() {
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies` WHERE (id = ? AND deleted_at IS NOT NULL) AND tenant_id = ?")).
+		WithArgs(3, "acme").
+		WillReturnRows((sqlmock.NewRows([]string{"id", "name", "deleted_at"})).
+			AddRow("3", "test", time.Now()))
+
+	res, err := s.repository.GetTrashed(tenantCtx, 3)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), uint64(3), res.ID)
+	assert.True(s.T(), res.DeletedAt.Valid)
+}
+
+func (s *CompaniesSuite) TestItCanReturnErrCompanyNotFoundForTrashedCompany() {
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies` WHERE (id = ? AND deleted_at IS NOT NULL) AND tenant_id = ?")).
+		WithArgs(3, "acme").
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	_, err := s.repository.GetTrashed(tenantCtx, 3)
+	assert.ErrorIs(s.T(), err, models.ErrCompanyNotFound)
+}
+
+func (s *CompaniesSuite) TestItCanListTrashedCompanies() {
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies` WHERE deleted_at IS NOT NULL AND tenant_id = ? ORDER BY id LIMIT 21")).
+		WithArgs("acme").
+		WillReturnRows((sqlmock.NewRows([]string{"id", "name", "deleted_at"})).
+			AddRow("3", "test", time.Now()))
+
+	page, err := s.repository.GetAllTrashed(tenantCtx, requests.CompaniesQuery{})
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), page.Companies, 1)
+}
+
+func (s *CompaniesSuite) TestItCanRestoreCompany() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE `companies` SET `deleted_at`=?,`version`=version + 1 WHERE (id = ? AND deleted_at IS NOT NULL) AND tenant_id = ?")).
+		WithArgs(nil, 10, "acme").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies` WHERE id = ? AND `companies`.`deleted_at` IS NULL ORDER BY `companies`.`id` LIMIT 1")).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(10, "test"))
+	s.expectAudit(10, models.AuditActionRestored)
+	s.expectEvent(10, models.EventCompanyRestored)
+	s.mock.ExpectCommit()
+
+	err := s.repository.Restore(auditCtx, 10)
+	assert.NoError(s.T(), err)
+}
+
+func (s *CompaniesSuite) TestItDetachesDeletedParentOnRestore() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE `companies` SET `deleted_at`=?,`version`=version + 1 WHERE (id = ? AND deleted_at IS NOT NULL) AND tenant_id = ?")).
+		WithArgs(nil, 10, "acme").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies` WHERE id = ? AND `companies`.`deleted_at` IS NULL ORDER BY `companies`.`id` LIMIT 1")).
 		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id"}).AddRow(10, "test", 5))
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `companies` WHERE id = ? AND tenant_id = ? AND `companies`.`deleted_at` IS NULL")).
+		WithArgs(5, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE `companies` SET `parent_id`=? WHERE `companies`.`deleted_at` IS NULL AND `id` = ?")).
+		WithArgs(nil, 10).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.expectAudit(10, models.AuditActionRestored)
+	s.expectEvent(10, models.EventCompanyRestored)
+	s.mock.ExpectCommit()
+
+	err := s.repository.Restore(auditCtx, 10)
+	assert.NoError(s.T(), err)
+}
+
+func (s *CompaniesSuite) TestItCannotRestoreMissingCompany() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE `companies` SET `deleted_at`=?,`version`=version + 1 WHERE (id = ? AND deleted_at IS NOT NULL) AND tenant_id = ?")).
+		WithArgs(nil, 10, "acme").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectRollback()
+
+	err := s.repository.Restore(auditCtx, 10)
+	assert.ErrorIs(s.T(), err, models.ErrCompanyNotFound)
+}
+
+func (s *CompaniesSuite) TestItCanPurgeCompanies() {
+	before := time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `contacts` WHERE company_id IN (SELECT `id` FROM `companies` WHERE deleted_at < ?)")).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	s.mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `companies` WHERE deleted_at < ?")).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	s.mock.ExpectCommit()
+
+	n, err := s.repository.Purge(context.Background(), before)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(2), n)
+}
+
+func (s *CompaniesSuite) TestItLocksCompanyWhenCreatingContact() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `companies` WHERE id = ? AND tenant_id = ? AND `companies`.`deleted_at` IS NULL ORDER BY `companies`.`id` LIMIT 1 FOR UPDATE")).
+		WithArgs(10, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+	s.mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `contacts` (`company_id`,`name`,`role`,`email`,`phone`,`tenant_id`) VALUES (?,?,?,?,?,?)")).
+		WithArgs(10, "john", "CEO", "john@example.com", "", "acme").
+		WillReturnResult(sqlmock.NewResult(3, 1))
 	s.mock.ExpectCommit()
 
-	err := s.repository.Delete(10)
+	contact, err := s.repository.CreateContact(tenantCtx, models.Contact{CompanyID: 10, Name: "john", Role: "CEO", Email: "john@example.com"})
 	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), uint64(3), contact.ID)
+}
+
+func (s *CompaniesSuite) TestItCannotCreateContactOfMissingCompany() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `companies` WHERE id = ? AND tenant_id = ? AND `companies`.`deleted_at` IS NULL ORDER BY `companies`.`id` LIMIT 1 FOR UPDATE")).
+		WithArgs(10, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	s.mock.ExpectRollback()
+
+	_, err := s.repository.CreateContact(tenantCtx, models.Contact{CompanyID: 10, Name: "john"})
+	assert.ErrorIs(s.T(), err, models.ErrCompanyNotFound)
 }
 
 func (s *CompaniesSuite) TestItCanUpdateCompany() {
@@ -134,26 +415,176 @@ func (s *CompaniesSuite) TestItCanUpdateCompany() {
 		Country: "UA",
 		Website: "test.com",
 		Phone:   "+12345",
+		Version: 2,
 	}
 
 	s.mock.ExpectBegin()
-	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE `companies`")).
-		WithArgs(company.Name, company.Code, company.Country, company.Website, company.Phone, company.ID).
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies` WHERE (id = ? AND version = ?) AND tenant_id = ? AND `companies`.`deleted_at` IS NULL ORDER BY `companies`.`id` LIMIT 1 FOR UPDATE")).
+		WithArgs(company.ID, 2, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "code", "country", "website", "phone", "version"}).
+			AddRow(3, "before", "c12", "UA", "test.com", "+12345", 2))
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE `companies` SET `name`=?,`code`=?,`country`=?,`website`=?,`phone`=?,`parent_id`=?,`version`=? WHERE version = ? AND tenant_id = ? AND `companies`.`deleted_at` IS NULL AND `id` = ?")).
+		WithArgs(company.Name, company.Code, company.Country, company.Website, company.Phone, nil, 3, 2, "acme", company.ID).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.expectAudit(3, models.AuditActionUpdated)
+	s.expectEvent(3, models.EventCompanyUpdated)
 	s.mock.ExpectCommit()
 
-	err := s.repository.Update(company)
+	updated, _, err := s.repository.Update(auditCtx, company)
 	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), uint64(3), updated.Version)
+}
+
+func (s *CompaniesSuite) TestItCannotUpdateModifiedCompany() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies` WHERE (id = ? AND version = ?) AND tenant_id = ?")).
+		WithArgs(3, 2, "acme").
+		WillReturnError(gorm.ErrRecordNotFound)
+	s.mock.ExpectRollback()
+
+	_, _, err := s.repository.Update(auditCtx, models.Company{ID: 3, Name: "test", Version: 2})
+	assert.ErrorIs(s.T(), err, models.ErrCompanyModified)
+}
+
+func (s *CompaniesSuite) TestItCannotUpdateCompanyModifiedAfterRead() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies` WHERE (id = ? AND version = ?) AND tenant_id = ?")).
+		WithArgs(3, 2, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "version"}).AddRow(3, "before", 2))
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE `companies` SET")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectRollback()
+
+	_, _, err := s.repository.Update(auditCtx, models.Company{ID: 3, Name: "test", Version: 2})
+	assert.ErrorIs(s.T(), err, models.ErrCompanyModified)
+}
+
+func (s *CompaniesSuite) TestItCanGetCompanyHistory() {
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `audit_records` WHERE company_id = ? AND company_id IN (SELECT `id` FROM `companies` WHERE tenant_id = ?) ORDER BY id")).
+		WithArgs(3, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "company_id", "action", "changes"}).
+			AddRow(1, 3, "created", `{"name":{"from":"","to":"test"}}`))
+
+	records, err := s.repository.History(tenantCtx, 3)
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), records, 1)
+	assert.Equal(s.T(), models.Changes{"name": {To: "test"}}, records[0].Changes)
 }
 
 func (s *CompaniesSuite) TestItCanSearchCompany() {
-	p := requests.CompanyPayload{Website: "https://google.com", Phone: "+12345"}
+	q := requests.CompaniesQuery{Filters: []requests.Filter{
+		{Field: "website", Op: requests.OpEq, Values: []string{"https://google.com"}},
+		{Field: "phone", Op: requests.OpEq, Values: []string{"+12345"}},
+	}}
 
-	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies`")).
-		WithArgs(p.Website, p.Phone).
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies` WHERE website = ? AND phone = ? AND tenant_id = ? AND `companies`.`deleted_at` IS NULL ORDER BY id LIMIT 21")).
+		WithArgs("https://google.com", "+12345", "acme").
 		WillReturnRows((sqlmock.NewRows([]string{"id", "name", "code", "country", "website", "phone"})).
 			AddRow("3", "test", "c12", "UA", "test.com", "+12345"))
 
-	_, err := s.repository.GetAll(p)
+	page, err := s.repository.GetAll(tenantCtx, q)
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), page.Companies, 1)
+	assert.False(s.T(), page.HasMore)
+	assert.Empty(s.T(), page.NextCursor)
+}
+
+func (s *CompaniesSuite) TestItCanSearchCompaniesWithFullText() {
+	rows := sqlmock.NewRows([]string{"id", "name", "code", "score"}).
+		AddRow(1, "Acme Holdings", "h1", 2.5).
+		AddRow(2, "Acme", "a1", 1.5)
+
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT *, MATCH(name, code, website, phone) AGAINST (? IN BOOLEAN MODE) AS score FROM `companies` WHERE MATCH(name, code, website, phone) AGAINST (? IN BOOLEAN MODE) AND tenant_id = ? AND `companies`.`deleted_at` IS NULL ORDER BY score DESC, id LIMIT 2 OFFSET 1")).
+		WithArgs("+acme* +hold*", "+acme* +hold*", "acme").
+		WillReturnRows(rows)
+
+	q := requests.SearchQuery{Q: "Acme Hold", Limit: 1, Cursor: pagination.Cursor{Sort: "Acme Hold", Offset: 1}.Encode()}
+	page, err := s.repository.Search(tenantCtx, q)
+	require.NoError(s.T(), err)
+
+	require.Len(s.T(), page.Results, 1)
+	assert.Equal(s.T(), uint64(1), page.Results[0].Company.ID)
+	assert.Equal(s.T(), 2.5, page.Results[0].Score)
+	assert.Equal(s.T(), map[string]string{"name": "<em>Acme</em> <em>Holdings</em>"}, page.Results[0].Highlights)
+	assert.True(s.T(), page.HasMore)
+	assert.Equal(s.T(), q.CursorAt(2).Encode(), page.NextCursor)
+}
+
+func (s *CompaniesSuite) TestItCanFilterCompaniesWithOperators() {
+	q := requests.CompaniesQuery{Filters: []requests.Filter{
+		{Field: "name", Op: requests.OpIPrefix, Values: []string{"10%_"}},
+		{Field: "code", Op: requests.OpContains, Values: []string{"c!"}},
+		{Field: "country", Op: requests.OpIn, Values: []string{"CY", "GR"}},
+		{Field: "phone", Op: requests.OpNe, Values: []string{"+12345"}},
+	}}
+
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies` WHERE LOWER(name) LIKE LOWER(?) ESCAPE '!' AND code LIKE ? ESCAPE '!' AND country IN (?,?) AND phone <> ? AND tenant_id = ? AND `companies`.`deleted_at` IS NULL ORDER BY id LIMIT 21")).
+		WithArgs("10!%!_%", "%c!!%", "CY", "GR", "+12345", "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "code", "country", "website", "phone"}))
+
+	page, err := s.repository.GetAll(tenantCtx, q)
 	assert.NoError(s.T(), err)
+	assert.Len(s.T(), page.Companies, 0)
+}
+
+func (s *CompaniesSuite) TestItCanSortCompanies() {
+	q := requests.CompaniesQuery{Sort: "-name,code", Limit: 1}
+	q.Cursor = q.CursorFor(models.Company{ID: 5, Name: "john", Code: "c12"}).Encode()
+
+	q.Filters = []requests.Filter{{Field: "country", Op: requests.OpEq, Values: []string{"UA"}}}
+
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies` WHERE country = ? AND (name < ? OR name = ? AND code > ? OR name = ? AND code = ? AND id > ?) AND tenant_id = ? AND `companies`.`deleted_at` IS NULL ORDER BY name DESC,code,id LIMIT 2")).
+		WithArgs("UA", "john", "john", "c12", "john", "c12", 5, "acme").
+		WillReturnRows((sqlmock.NewRows([]string{"id", "name", "code", "country", "website", "phone"})).
+			AddRow("3", "jane", "c10", "UA", "test.com", "+12345").
+			AddRow("4", "jane", "c11", "UA", "test.com", "+12345"))
+
+	page, err := s.repository.GetAll(tenantCtx, q)
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), page.Companies, 1)
+	assert.True(s.T(), page.HasMore)
+	assert.Equal(s.T(), q.CursorFor(models.Company{ID: 3, Name: "jane", Code: "c10"}).Encode(), page.NextCursor)
+}
+
+func (s *CompaniesSuite) TestItCanPaginateCompanies() {
+	q := requests.CompaniesQuery{Sort: "-id", Limit: 2, Cursor: pagination.Cursor{ID: 5, Sort: "-id"}.Encode()}
+
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies` WHERE id < ? AND tenant_id = ? AND `companies`.`deleted_at` IS NULL ORDER BY id DESC LIMIT 3")).
+		WithArgs(5, "acme").
+		WillReturnRows((sqlmock.NewRows([]string{"id", "name", "code", "country", "website", "phone"})).
+			AddRow("4", "first", "c12", "UA", "test.com", "+12345").
+			AddRow("3", "second", "c13", "UA", "test.com", "+12345").
+			AddRow("2", "third", "c14", "UA", "test.com", "+12345"))
+
+	page, err := s.repository.GetAll(tenantCtx, q)
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), page.Companies, 2)
+	assert.True(s.T(), page.HasMore)
+	assert.Equal(s.T(), pagination.Cursor{ID: 3, Sort: "-id"}.Encode(), page.NextCursor)
+}
+
+func (s *CompaniesSuite) TestItCannotPaginateWithCursorForDifferentSort() {
+	q := requests.CompaniesQuery{Sort: "name"}
+	q.Cursor = q.CursorFor(models.Company{ID: 5, Name: "john"}).Encode()
+	q.Sort = "code"
+
+	_, err := s.repository.GetAll(tenantCtx, q)
+	assert.ErrorIs(s.T(), err, pagination.ErrInvalidCursor)
+}
+
+func (s *CompaniesSuite) TestItCanPaginateSortedCompanies() {
+	q := requests.CompaniesQuery{Sort: "name"}
+	q.Cursor = q.CursorFor(models.Company{ID: 5, Name: "john"}).Encode()
+
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `companies` WHERE (name > ? OR name = ? AND id > ?) AND tenant_id = ? AND `companies`.`deleted_at` IS NULL ORDER BY name,id LIMIT 21")).
+		WithArgs("john", "john", 5, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	_, err := s.repository.GetAll(tenantCtx, q)
+	assert.NoError(s.T(), err)
+}
+
+func (s *CompaniesSuite) TestItCannotPaginateWithInvalidCursor() {
+	_, err := s.repository.GetAll(tenantCtx, requests.CompaniesQuery{Cursor: "moon"})
+	assert.ErrorIs(s.T(), err, pagination.ErrInvalidCursor)
 }
@@ -0,0 +1,190 @@
+package db
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/db/dbtest"
+	"github.com/brokeyourbike/xm-golang-exercise/migrations"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDatabase opens the database, and removes all data from it.
+func openTestDatabase(t *testing.T, cfg *configs.Config) *CompaniesRepo {
+	orm, err := Open(cfg)
+	require.NoError(t, err)
+
+	orm.Logger = logger.Default.LogMode(logger.Silent)
+
+	// migrations cannot be rolled back while tenants share the company codes
+	if orm.Migrator().HasTable(&models.Company{}) {
+		require.NoError(t, orm.Exec("DELETE FROM companies").Error)
+	}
+
+	m := migrations.NewMigrator(orm, cfg)
+	require.NoError(t, m.To(0))
+	require.NoError(t, m.Up())
+
+	return NewCompaniesRepo(orm, cfg)
+}
+
+func TestSQLiteConformance(t *testing.T) {
+	cfg := configs.Config{}
+	cfg.Database.Driver = DriverSQLite
+	cfg.Database.Dsn = filepath.Join(t.TempDir(), "test.db")
+
+	suite.Run(t, &dbtest.CompaniesSuite{NewRepo: func() dbtest.Repo {
+		return openTestDatabase(t, &cfg)
+	}})
+}
+
+func TestSQLiteWebhooksConformance(t *testing.T) {
+	cfg := configs.Config{}
+	cfg.Database.Driver = DriverSQLite
+	cfg.Database.Dsn = filepath.Join(t.TempDir(), "test.db")
+
+	withURLs := cfg
+	withURLs.Webhooks.URLs = []string{"https://example.com/hook"}
+
+	suite.Run(t, &dbtest.WebhooksSuite{
+		NewRepo:            func() dbtest.OutboxRepo { return openTestDatabase(t, &withURLs) },
+		NewRepoWithoutURLs: func() dbtest.OutboxRepo { return openTestDatabase(t, &cfg) },
+	})
+}
+
+func TestSQLiteContactsConformance(t *testing.T) {
+	cfg := configs.Config{}
+	cfg.Database.Driver = DriverSQLite
+	cfg.Database.Dsn = filepath.Join(t.TempDir(), "test.db")
+
+	suite.Run(t, &dbtest.ContactsSuite{NewRepo: func() dbtest.ContactsRepo {
+		return openTestDatabase(t, &cfg)
+	}})
+}
+
+func TestSQLiteAPIKeysConformance(t *testing.T) {
+	cfg := configs.Config{}
+	cfg.Database.Driver = DriverSQLite
+	cfg.Database.Dsn = filepath.Join(t.TempDir(), "test.db")
+
+	suite.Run(t, &dbtest.APIKeysSuite{NewRepo: func() dbtest.APIKeysRepo {
+		return openTestDatabase(t, &cfg)
+	}})
+}
+
+func TestSQLiteConformanceCodePerCountry(t *testing.T) {
+	cfg := configs.Config{}
+	cfg.Database.Driver = DriverSQLite
+	cfg.Database.Dsn = filepath.Join(t.TempDir(), "test.db")
+	cfg.Companies.UniqueCodePerCountry = true
+
+	suite.Run(t, &dbtest.CompaniesSuite{CodePerCountry: true, NewRepo: func() dbtest.Repo {
+		return openTestDatabase(t, &cfg)
+	}})
+}
+
+// TestExternalDatabaseConformance runs the suite against MySQL or PostgreSQL,
+// configured with TEST_DATABASE_DRIVER and TEST_DATABASE_DSN environment variables.
+func TestExternalDatabaseConformance(t *testing.T) {
+	cfg := configs.Config{}
+	cfg.Database.Driver, cfg.Database.Dsn = os.Getenv("TEST_DATABASE_DRIVER"), os.Getenv("TEST_DATABASE_DSN")
+	if cfg.Database.Driver == "" || cfg.Database.Dsn == "" {
+		t.Skip("TEST_DATABASE_DRIVER and TEST_DATABASE_DSN are not set")
+	}
+
+	suite.Run(t, &dbtest.CompaniesSuite{NewRepo: func() dbtest.Repo {
+		return openTestDatabase(t, &cfg)
+	}})
+}
+
+// TestExternalDatabaseWebhooksConformance runs the webhooks suite against MySQL or PostgreSQL,
+// configured the same as TestExternalDatabaseConformance.
+func TestExternalDatabaseWebhooksConformance(t *testing.T) {
+	cfg := configs.Config{}
+	cfg.Database.Driver, cfg.Database.Dsn = os.Getenv("TEST_DATABASE_DRIVER"), os.Getenv("TEST_DATABASE_DSN")
+	if cfg.Database.Driver == "" || cfg.Database.Dsn == "" {
+		t.Skip("TEST_DATABASE_DRIVER and TEST_DATABASE_DSN are not set")
+	}
+
+	withURLs := cfg
+	withURLs.Webhooks.URLs = []string{"https://example.com/hook"}
+
+	suite.Run(t, &dbtest.WebhooksSuite{
+		NewRepo:            func() dbtest.OutboxRepo { return openTestDatabase(t, &withURLs) },
+		NewRepoWithoutURLs: func() dbtest.OutboxRepo { return openTestDatabase(t, &cfg) },
+	})
+}
+
+// TestExternalDatabaseContactsConformance runs the contacts suite against MySQL or PostgreSQL,
+// configured the same as TestExternalDatabaseConformance.
+func TestExternalDatabaseContactsConformance(t *testing.T) {
+	cfg := configs.Config{}
+	cfg.Database.Driver, cfg.Database.Dsn = os.Getenv("TEST_DATABASE_DRIVER"), os.Getenv("TEST_DATABASE_DSN")
+	if cfg.Database.Driver == "" || cfg.Database.Dsn == "" {
+		t.Skip("TEST_DATABASE_DRIVER and TEST_DATABASE_DSN are not set")
+	}
+
+	suite.Run(t, &dbtest.ContactsSuite{NewRepo: func() dbtest.ContactsRepo {
+		return openTestDatabase(t, &cfg)
+	}})
+}
+
+// TestExternalDatabaseAPIKeysConformance runs the API keys suite against MySQL or PostgreSQL,
+// configured the same as TestExternalDatabaseConformance.
+func TestExternalDatabaseAPIKeysConformance(t *testing.T) {
+	cfg := configs.Config{}
+	cfg.Database.Driver, cfg.Database.Dsn = os.Getenv("TEST_DATABASE_DRIVER"), os.Getenv("TEST_DATABASE_DSN")
+	if cfg.Database.Driver == "" || cfg.Database.Dsn == "" {
+		t.Skip("TEST_DATABASE_DRIVER and TEST_DATABASE_DSN are not set")
+	}
+
+	suite.Run(t, &dbtest.APIKeysSuite{NewRepo: func() dbtest.APIKeysRepo {
+		return openTestDatabase(t, &cfg)
+	}})
+}
+
+func TestSQLiteAuditDetectsDeletedRecords(t *testing.T) {
+	cfg := configs.Config{}
+	cfg.Database.Driver = DriverSQLite
+	cfg.Database.Dsn = filepath.Join(t.TempDir(), "test.db")
+
+	repo := openTestDatabase(t, &cfg)
+	ctx := context.Background()
+
+	acme, err := repo.Create(ctx, models.Company{Name: "acme", Code: "c1"})
+	require.NoError(t, err)
+	require.NoError(t, repo.Delete(ctx, acme))
+	_, err = repo.Create(ctx, models.Company{Name: "beta", Code: "c2"})
+	require.NoError(t, err)
+
+	verified, err := repo.VerifyAudit(ctx)
+	require.NoError(t, err)
+	require.True(t, verified)
+
+	// the latest record of acme is followed by the record of beta
+	require.NoError(t, repo.db.Where("company_id = ? AND action = ?", acme.ID, models.AuditActionDeleted).Delete(&models.AuditRecord{}).Error)
+
+	verified, err = repo.VerifyAudit(ctx)
+	require.NoError(t, err)
+	assert.False(t, verified, "latest record of the company is deleted")
+
+	repo = openTestDatabase(t, &cfg)
+
+	acme, err = repo.Create(ctx, models.Company{Name: "acme", Code: "c1"})
+	require.NoError(t, err)
+	require.NoError(t, repo.Delete(ctx, acme))
+
+	// no record refers to the tail of the chain, it is detected by the head
+	require.NoError(t, repo.db.Where("company_id = ? AND action = ?", acme.ID, models.AuditActionDeleted).Delete(&models.AuditRecord{}).Error)
+
+	verified, err = repo.VerifyAudit(ctx)
+	require.NoError(t, err)
+	assert.False(t, verified, "tail of the chain is deleted")
+}
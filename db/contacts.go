@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateContact adds a new contact of the company to the database.
+// If the company does not exist this function returns a ErrCompanyNotFound error.
+func (c *CompaniesRepo) CreateContact(ctx context.Context, contact models.Contact) (models.Contact, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	contact.TenantID = models.TenantFrom(ctx)
+
+	err := c.write(ctx).Transaction(func(tx *gorm.DB) error {
+		// the company is locked, so it cannot be deleted until the contact is added
+		if err := lockCompany(ctx, tx, contact.CompanyID); err != nil {
+			return err
+		}
+		return tx.Create(&contact).Error
+	})
+
+	return contact, contextError(ctx, err)
+}
+
+// GetContact returns a single contact of the company.
+// If the contact does not exist this function returns a ErrContactNotFound error.
+func (c *CompaniesRepo) GetContact(ctx context.Context, companyID uint64, id uint64) (models.Contact, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	var contact models.Contact
+
+	err := c.read(ctx).Scopes(tenantScope(ctx)).Where("id = ? AND company_id = ?", id, companyID).First(&contact).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return contact, models.ErrContactNotFound
+	}
+
+	return contact, contextError(ctx, err)
+}
+
+// GetContacts returns all contacts of the company, ordered by id.
+func (c *CompaniesRepo) GetContacts(ctx context.Context, companyID uint64) ([]models.Contact, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	contacts := []models.Contact{}
+
+	err := c.read(ctx).Scopes(tenantScope(ctx)).Where("company_id = ?", companyID).Order("id").Find(&contacts).Error
+	return contacts, contextError(ctx, err)
+}
+
+// UpdateContact updates the contact of the company.
+// If the contact does not exist this function returns a ErrContactNotFound error.
+func (c *CompaniesRepo) UpdateContact(ctx context.Context, contact models.Contact) (models.Contact, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	contact.TenantID = models.TenantFrom(ctx)
+
+	res := c.write(ctx).Model(&models.Contact{}).Scopes(tenantScope(ctx)).
+		Where("id = ? AND company_id = ?", contact.ID, contact.CompanyID).
+		Select("name", "role", "email", "phone").
+		Updates(&contact)
+
+	if res.Error != nil {
+		return contact, contextError(ctx, res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return contact, models.ErrContactNotFound
+	}
+
+	return contact, nil
+}
+
+// DeleteContact removes the contact of the company from the database.
+// If the contact does not exist this function returns a ErrContactNotFound error.
+func (c *CompaniesRepo) DeleteContact(ctx context.Context, companyID uint64, id uint64) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	res := c.write(ctx).Scopes(tenantScope(ctx)).Where("id = ? AND company_id = ?", id, companyID).Delete(&models.Contact{})
+
+	if res.Error != nil {
+		return contextError(ctx, res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return models.ErrContactNotFound
+	}
+
+	return nil
+}
+
+// lockCompany locks the active company of the tenant until the end of the transaction.
+// If the company does not exist this function returns a ErrCompanyNotFound error.
+func lockCompany(ctx context.Context, tx *gorm.DB, id uint64) error {
+	var company models.Company
+
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(tenantScope(ctx)).Select("id").Where("id = ?", id).First(&company).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.ErrCompanyNotFound
+	}
+
+	return err
+}
@@ -0,0 +1,153 @@
+package dbtest
+
+import (
+	"context"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/stretchr/testify/suite"
+)
+
+// APIKeysRepo defines the repository tested by the API keys conformance suite.
+type APIKeysRepo interface {
+	handlers.APIKeysRepo
+}
+
+// APIKeysSuite is the conformance test suite for the API keys.
+// NewRepo must return an empty repository for every test.
+type APIKeysSuite struct {
+	suite.Suite
+	NewRepo func() APIKeysRepo
+	repo    APIKeysRepo
+}
+
+func (s *APIKeysSuite) SetupTest() {
+	s.repo = s.NewRepo()
+}
+
+// key adds an API key of the tenant.
+func (s *APIKeysSuite) key(ctx context.Context, prefix string) models.APIKey {
+	key, err := s.repo.CreateAPIKey(ctx, models.APIKey{
+		Name:   "billing",
+		Prefix: prefix,
+		Hash:   "hash-" + prefix,
+		Scopes: models.Scopes{models.ScopeCompaniesRead, models.ScopeCompaniesWrite},
+	})
+	s.Require().NoError(err)
+	return key
+}
+
+func (s *APIKeysSuite) TestItCanManageAPIKeys() {
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Millisecond)
+
+	key, err := s.repo.CreateAPIKey(tenantA, models.APIKey{
+		Name:      "billing",
+		Prefix:    "p1",
+		Hash:      "h1",
+		Scopes:    models.Scopes{models.ScopeCompaniesRead},
+		ExpiresAt: &expiresAt,
+	})
+	s.Require().NoError(err)
+	s.NotZero(key.ID)
+	s.Equal("a", key.TenantID)
+	s.WithinDuration(time.Now(), key.CreatedAt, time.Minute)
+
+	keys, err := s.repo.GetAPIKeys(tenantA)
+	s.Require().NoError(err)
+	s.Require().Len(keys, 1)
+	s.Equal("billing", keys[0].Name)
+	s.Equal("p1", keys[0].Prefix)
+	s.Equal("h1", keys[0].Hash)
+	s.Equal(models.Scopes{models.ScopeCompaniesRead}, keys[0].Scopes)
+	s.Require().NotNil(keys[0].ExpiresAt)
+	s.True(expiresAt.Equal(*keys[0].ExpiresAt))
+	s.Nil(keys[0].LastUsedAt)
+	s.Nil(keys[0].RevokedAt)
+
+	rotated, err := s.repo.RotateAPIKey(tenantA, key.ID, "p2", "h2")
+	s.Require().NoError(err)
+	s.Equal(key.ID, rotated.ID)
+	s.Equal("billing", rotated.Name)
+	s.Equal("p2", rotated.Prefix)
+	s.Equal("h2", rotated.Hash)
+	s.Equal(models.Scopes{models.ScopeCompaniesRead}, rotated.Scopes)
+
+	_, err = s.repo.GetAPIKeyByPrefix(context.Background(), "p1")
+	s.ErrorIs(err, models.ErrAPIKeyNotFound)
+
+	s.Require().NoError(s.repo.RevokeAPIKey(tenantA, key.ID))
+
+	keys, err = s.repo.GetAPIKeys(tenantA)
+	s.Require().NoError(err)
+	s.Require().Len(keys, 1)
+	s.Require().NotNil(keys[0].RevokedAt)
+	revokedAt := *keys[0].RevokedAt
+
+	// revoking again keeps the time of the first revocation
+	time.Sleep(10 * time.Millisecond)
+	s.Require().NoError(s.repo.RevokeAPIKey(tenantA, key.ID))
+
+	keys, err = s.repo.GetAPIKeys(tenantA)
+	s.Require().NoError(err)
+	s.True(revokedAt.Equal(*keys[0].RevokedAt))
+
+	_, err = s.repo.RotateAPIKey(tenantA, key.ID, "p3", "h3")
+	s.ErrorIs(err, models.ErrAPIKeyNotFound)
+}
+
+func (s *APIKeysSuite) TestItCannotChangeMissingAPIKeys() {
+	_, err := s.repo.RotateAPIKey(tenantA, 999, "p1", "h1")
+	s.ErrorIs(err, models.ErrAPIKeyNotFound)
+
+	s.ErrorIs(s.repo.RevokeAPIKey(tenantA, 999), models.ErrAPIKeyNotFound)
+}
+
+func (s *APIKeysSuite) TestItFindsAPIKeysOfAllTenantsByPrefix() {
+	a := s.key(tenantA, "p1")
+	b := s.key(tenantB, "p2")
+
+	found, err := s.repo.GetAPIKeyByPrefix(context.Background(), "p1")
+	s.Require().NoError(err)
+	s.Equal(a.ID, found.ID)
+	s.Equal("a", found.TenantID)
+
+	found, err = s.repo.GetAPIKeyByPrefix(context.Background(), "p2")
+	s.Require().NoError(err)
+	s.Equal(b.ID, found.ID)
+	s.Equal("b", found.TenantID)
+
+	_, err = s.repo.GetAPIKeyByPrefix(context.Background(), "p3")
+	s.ErrorIs(err, models.ErrAPIKeyNotFound)
+}
+
+func (s *APIKeysSuite) TestItRecordsLastUseOfAPIKeys() {
+	key := s.key(tenantA, "p1")
+	usedAt := time.Now().UTC().Truncate(time.Millisecond)
+
+	s.Require().NoError(s.repo.TouchAPIKey(context.Background(), key.ID, usedAt))
+
+	found, err := s.repo.GetAPIKeyByPrefix(context.Background(), "p1")
+	s.Require().NoError(err)
+	s.Require().NotNil(found.LastUsedAt)
+	s.True(usedAt.Equal(*found.LastUsedAt))
+}
+
+func (s *APIKeysSuite) TestTenantsCannotManageAPIKeysOfOtherTenants() {
+	key := s.key(tenantA, "p1")
+	s.key(tenantB, "p2")
+
+	keys, err := s.repo.GetAPIKeys(tenantB)
+	s.Require().NoError(err)
+	s.Require().Len(keys, 1)
+	s.Equal("p2", keys[0].Prefix)
+
+	_, err = s.repo.RotateAPIKey(tenantB, key.ID, "p3", "h3")
+	s.ErrorIs(err, models.ErrAPIKeyNotFound)
+
+	s.ErrorIs(s.repo.RevokeAPIKey(tenantB, key.ID), models.ErrAPIKeyNotFound)
+
+	found, err := s.repo.GetAPIKeyByPrefix(context.Background(), "p1")
+	s.Require().NoError(err)
+	s.Nil(found.RevokedAt)
+}
@@ -0,0 +1,654 @@
+// Package dbtest provides the conformance test suite,
+// which every companies repository implementation should pass.
+package dbtest
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/stretchr/testify/suite"
+)
+
+// Repo defines the repository tested by the conformance suite.
+type Repo interface {
+	handlers.CompaniesRepo
+	Purge(context.Context, time.Time) (int64, error)
+}
+
+// CompaniesSuite is the conformance test suite for the companies repositories.
+// NewRepo must return an empty repository for every test,
+// CodePerCountry must be set when the repository allows the same code in different countries.
+//
+// Database engines differ in the collation rules,
+// so the suite does not rely on the case sensitivity of comparisons.
+type CompaniesSuite struct {
+	suite.Suite
+	NewRepo        func() Repo
+	CodePerCountry bool
+	repo           Repo
+}
+
+var auditCtx = models.WithAuditMeta(context.Background(), models.AuditMeta{Actor: "john", RequestID: "req-1", ClientIP: "127.0.0.1"})
+
+func (s *CompaniesSuite) SetupTest() {
+	s.repo = s.NewRepo()
+}
+
+// create adds companies to the repository.
+func (s *CompaniesSuite) create(companies ...models.Company) []models.Company {
+	created := make([]models.Company, 0, len(companies))
+
+	for _, c := range companies {
+		c, err := s.repo.Create(auditCtx, c)
+		s.Require().NoError(err)
+		created = append(created, c)
+	}
+
+	return created
+}
+
+// names returns the names of the companies.
+func names(companies []models.Company) []string {
+	n := make([]string, 0, len(companies))
+	for _, c := range companies {
+		n = append(n, c.Name)
+	}
+	return n
+}
+
+func (s *CompaniesSuite) TestItCanCreateAndGetCompany() {
+	company := s.create(models.Company{Name: "acme", Code: "c1", Country: "CY", Website: "acme.com", Phone: "+123"})[0]
+
+	s.NotZero(company.ID)
+	s.Equal(uint64(1), company.Version)
+
+	found, err := s.repo.Get(context.Background(), company.ID)
+	s.Require().NoError(err)
+	s.Equal(company.ID, found.ID)
+	s.Equal("acme", found.Name)
+	s.Equal("c1", found.Code)
+	s.Equal("CY", found.Country)
+	s.Equal("acme.com", found.Website)
+	s.Equal("+123", found.Phone)
+	s.Equal(uint64(1), found.Version)
+}
+
+func (s *CompaniesSuite) TestItReturnsErrCompanyNotFound() {
+	_, err := s.repo.Get(context.Background(), 100)
+	s.ErrorIs(err, models.ErrCompanyNotFound)
+
+	_, err = s.repo.GetTrashed(context.Background(), 100)
+	s.ErrorIs(err, models.ErrCompanyNotFound)
+}
+
+func (s *CompaniesSuite) TestItCanUpdateCompany() {
+	company := s.create(models.Company{Name: "acme", Code: "c1"})[0]
+
+	company.Name = "acme ltd"
+	updated, changes, err := s.repo.Update(auditCtx, company)
+	s.Require().NoError(err)
+	s.Equal(uint64(2), updated.Version)
+	s.Equal(models.Changes{"name": {From: "acme", To: "acme ltd"}}, changes)
+
+	found, err := s.repo.Get(context.Background(), company.ID)
+	s.Require().NoError(err)
+	s.Equal("acme ltd", found.Name)
+	s.Equal(uint64(2), found.Version)
+}
+
+func (s *CompaniesSuite) TestItCanUpdateFieldsToZeroValues() {
+	company := s.create(models.Company{Name: "acme", Code: "c1", Website: "acme.com", Phone: "+1234"})[0]
+
+	company.Website = ""
+	company.Phone = ""
+	_, _, err := s.repo.Update(auditCtx, company)
+	s.Require().NoError(err)
+
+	found, err := s.repo.Get(context.Background(), company.ID)
+	s.Require().NoError(err)
+	s.Equal("", found.Website)
+	s.Equal("", found.Phone)
+}
+
+func (s *CompaniesSuite) TestItCannotUpdateStaleCompany() {
+	company := s.create(models.Company{Name: "acme", Code: "c1"})[0]
+
+	_, _, err := s.repo.Update(auditCtx, company)
+	s.Require().NoError(err)
+
+	_, _, err = s.repo.Update(auditCtx, company)
+	s.ErrorIs(err, models.ErrCompanyModified)
+}
+
+func (s *CompaniesSuite) TestItCanDeleteAndRestoreCompany() {
+	company := s.create(models.Company{Name: "acme", Code: "c1"})[0]
+
+	s.Require().NoError(s.repo.Delete(auditCtx, company))
+
+	_, err := s.repo.Get(context.Background(), company.ID)
+	s.ErrorIs(err, models.ErrCompanyNotFound)
+
+	trashed, err := s.repo.GetTrashed(context.Background(), company.ID)
+	s.Require().NoError(err)
+	s.True(trashed.DeletedAt.Valid)
+
+	s.Require().NoError(s.repo.Restore(auditCtx, company.ID))
+	s.ErrorIs(s.repo.Restore(auditCtx, company.ID), models.ErrCompanyNotFound)
+
+	restored, err := s.repo.Get(context.Background(), company.ID)
+	s.Require().NoError(err)
+	s.Equal(uint64(2), restored.Version)
+}
+
+func (s *CompaniesSuite) TestItCannotDeleteStaleCompany() {
+	company := s.create(models.Company{Name: "acme", Code: "c1"})[0]
+
+	_, _, err := s.repo.Update(auditCtx, company)
+	s.Require().NoError(err)
+
+	s.ErrorIs(s.repo.Delete(auditCtx, company), models.ErrCompanyModified)
+}
+
+func (s *CompaniesSuite) TestItCanListTrashedCompanies() {
+	companies := s.create(models.Company{Name: "a", Code: "c1"}, models.Company{Name: "b", Code: "c2"})
+	s.Require().NoError(s.repo.Delete(auditCtx, companies[1]))
+
+	page, err := s.repo.GetAll(context.Background(), requests.CompaniesQuery{})
+	s.Require().NoError(err)
+	s.Equal([]string{"a"}, names(page.Companies))
+
+	trash, err := s.repo.GetAllTrashed(context.Background(), requests.CompaniesQuery{})
+	s.Require().NoError(err)
+	s.Equal([]string{"b"}, names(trash.Companies))
+}
+
+func (s *CompaniesSuite) TestItCanPurgeCompanies() {
+	companies := s.create(models.Company{Name: "a", Code: "c1"}, models.Company{Name: "b", Code: "c2"})
+	s.Require().NoError(s.repo.Delete(auditCtx, companies[0]))
+
+	n, err := s.repo.Purge(context.Background(), time.Now().Add(-time.Hour))
+	s.Require().NoError(err)
+	s.Equal(int64(0), n)
+
+	n, err = s.repo.Purge(context.Background(), time.Now().Add(time.Hour))
+	s.Require().NoError(err)
+	s.Equal(int64(1), n)
+
+	_, err = s.repo.GetTrashed(context.Background(), companies[0].ID)
+	s.ErrorIs(err, models.ErrCompanyNotFound)
+
+	_, err = s.repo.Get(context.Background(), companies[1].ID)
+	s.NoError(err)
+}
+
+func (s *CompaniesSuite) TestItCanFilterCompanies() {
+	s.create(
+		models.Company{Name: "alpha one", Code: "a1", Country: "CY"},
+		models.Company{Name: "alpha two", Code: "a2", Country: "GR"},
+		models.Company{Name: "beta", Code: "b1", Country: "US"},
+		models.Company{Name: "gamma_1%", Code: "g1", Country: "CY"},
+	)
+
+	cases := map[string]struct {
+		filters []requests.Filter
+		names   []string
+	}{
+		"eq": {
+			filters: []requests.Filter{{Field: "code", Op: requests.OpEq, Values: []string{"a2"}}},
+			names:   []string{"alpha two"},
+		},
+		"ne": {
+			filters: []requests.Filter{{Field: "country", Op: requests.OpNe, Values: []string{"CY"}}},
+			names:   []string{"alpha two", "beta"},
+		},
+		"in": {
+			filters: []requests.Filter{{Field: "country", Op: requests.OpIn, Values: []string{"GR", "US"}}},
+			names:   []string{"alpha two", "beta"},
+		},
+		"prefix": {
+			filters: []requests.Filter{{Field: "name", Op: requests.OpPrefix, Values: []string{"alpha"}}},
+			names:   []string{"alpha one", "alpha two"},
+		},
+		"contains with wildcards": {
+			filters: []requests.Filter{{Field: "name", Op: requests.OpContains, Values: []string{"_1%"}}},
+			names:   []string{"gamma_1%"},
+		},
+		"case-insensitive": {
+			filters: []requests.Filter{{Field: "name", Op: requests.OpIContains, Values: []string{"ALPHA T"}}},
+			names:   []string{"alpha two"},
+		},
+		"multiple filters": {
+			filters: []requests.Filter{
+				{Field: "name", Op: requests.OpIPrefix, Values: []string{"ALPHA"}},
+				{Field: "country", Op: requests.OpIEq, Values: []string{"cy"}},
+			},
+			names: []string{"alpha one"},
+		},
+	}
+
+	for name, c := range cases {
+		s.Run(name, func() {
+			page, err := s.repo.GetAll(context.Background(), requests.CompaniesQuery{Filters: c.filters})
+			s.Require().NoError(err)
+			s.Equal(c.names, names(page.Companies))
+		})
+	}
+}
+
+func (s *CompaniesSuite) TestItCanSortAndPaginateCompanies() {
+	s.create(
+		models.Company{Name: "b", Code: "b2"},
+		models.Company{Name: "a", Code: "a1"},
+		models.Company{Name: "b", Code: "b1"},
+		models.Company{Name: "c", Code: "c1"},
+		models.Company{Name: "a", Code: "a2"},
+	)
+
+	q := requests.CompaniesQuery{Sort: "-name,code", Limit: 2}
+
+	var all []models.Company
+	for i := 0; i < 3; i++ {
+		page, err := s.repo.GetAll(context.Background(), q)
+		s.Require().NoError(err)
+
+		all = append(all, page.Companies...)
+		s.Equal(i < 2, page.HasMore)
+
+		q.Cursor = page.NextCursor
+	}
+
+	s.Equal([]string{"c", "b", "b", "a", "a"}, names(all))
+
+	codes := []string{}
+	for _, c := range all {
+		codes = append(codes, c.Code)
+	}
+	s.Equal([]string{"c1", "b1", "b2", "a1", "a2"}, codes)
+}
+
+func (s *CompaniesSuite) TestItRejectsDuplicateCode() {
+	companies := s.create(models.Company{Name: "a", Code: "c1", Country: "CY"}, models.Company{Name: "b", Code: "c2", Country: "CY"})
+
+	var conflict *models.ConflictError
+
+	_, err := s.repo.Create(auditCtx, models.Company{Name: "c", Code: "c1", Country: "CY"})
+	s.ErrorIs(err, models.ErrCompanyConflict)
+	s.Require().ErrorAs(err, &conflict)
+	s.Equal(&models.ConflictError{Field: "code", ExistingID: companies[0].ID}, conflict)
+
+	update := companies[1]
+	update.Code = "c1"
+	_, _, err = s.repo.Update(auditCtx, update)
+	s.Require().ErrorAs(err, &conflict)
+	s.Equal(&models.ConflictError{Field: "code", ExistingID: companies[0].ID}, conflict)
+
+	found, err := s.repo.Get(context.Background(), companies[1].ID)
+	s.Require().NoError(err)
+	s.Equal("c2", found.Code)
+	s.Equal(uint64(1), found.Version)
+
+	_, err = s.repo.Create(auditCtx, models.Company{Name: "d", Code: "c1", Country: "GR"})
+	if s.CodePerCountry {
+		s.NoError(err)
+	} else {
+		s.ErrorIs(err, models.ErrCompanyConflict)
+	}
+}
+
+func (s *CompaniesSuite) TestItCanGetCompanyByCode() {
+	companies := s.create(models.Company{Name: "a", Code: "c1", Country: "CY"}, models.Company{Name: "b", Code: "c2", Country: "GR"})
+
+	found, err := s.repo.GetByCode(context.Background(), "c1", "CY")
+	s.Require().NoError(err)
+	s.Equal(companies[0].ID, found.ID)
+
+	found, err = s.repo.GetByCode(context.Background(), "c2", "CY")
+	if s.CodePerCountry {
+		s.ErrorIs(err, models.ErrCompanyNotFound)
+	} else {
+		s.Require().NoError(err)
+		s.Equal(companies[1].ID, found.ID)
+	}
+
+	_, err = s.repo.GetByCode(context.Background(), "c3", "CY")
+	s.ErrorIs(err, models.ErrCompanyNotFound)
+
+	// soft-deleted companies are not found, the same as by id
+	s.Require().NoError(s.repo.Delete(auditCtx, companies[0]))
+	_, err = s.repo.GetByCode(context.Background(), "c1", "CY")
+	s.ErrorIs(err, models.ErrCompanyNotFound)
+}
+
+func (s *CompaniesSuite) TestSoftDeletedCompanyKeepsCode() {
+	company := s.create(models.Company{Name: "a", Code: "c1", Country: "CY"})[0]
+	s.Require().NoError(s.repo.Delete(auditCtx, company))
+
+	var conflict *models.ConflictError
+
+	_, err := s.repo.Create(auditCtx, models.Company{Name: "b", Code: "c1", Country: "CY"})
+	s.Require().ErrorAs(err, &conflict)
+	s.Equal(company.ID, conflict.ExistingID)
+
+	_, err = s.repo.Purge(context.Background(), time.Now().Add(time.Hour))
+	s.Require().NoError(err)
+
+	s.create(models.Company{Name: "b", Code: "c1", Country: "CY"})
+}
+
+func (s *CompaniesSuite) TestItCanSearchCompanies() {
+	companies := s.create(
+		models.Company{Name: "Acme Holdings", Code: "h1", Website: "holdings.com"},
+		models.Company{Name: "Beta", Code: "acme-2", Website: "beta.com"},
+		models.Company{Name: "Acmeverse", Code: "v1", Website: "acmeverse.com"},
+		models.Company{Name: "Acme Trashed", Code: "t1", Website: "trashed.com"},
+		models.Company{Name: "Gamma", Code: "g1", Website: "gamma.com"},
+	)
+	s.Require().NoError(s.repo.Delete(auditCtx, companies[3]))
+
+	page, err := s.repo.Search(context.Background(), requests.SearchQuery{Q: "acme hold"})
+	s.Require().NoError(err)
+	s.Require().Len(page.Results, 1)
+	s.Equal(companies[0].ID, page.Results[0].Company.ID)
+	s.Equal("<em>Acme</em> <em>Holdings</em>", page.Results[0].Highlights["name"])
+	s.Equal("<em>holdings</em>.com", page.Results[0].Highlights["website"])
+
+	q := requests.SearchQuery{Q: "ACME", Limit: 2}
+
+	var found []models.Company
+	for i := 0; i < 2; i++ {
+		page, err := s.repo.Search(context.Background(), q)
+		s.Require().NoError(err)
+		s.Equal(i == 0, page.HasMore)
+
+		for _, r := range page.Results {
+			s.Greater(r.Score, 0.0)
+			found = append(found, r.Company)
+		}
+
+		q.Cursor = page.NextCursor
+	}
+
+	s.ElementsMatch([]string{"Acme Holdings", "Beta", "Acmeverse"}, names(found))
+
+	page, err = s.repo.Search(context.Background(), requests.SearchQuery{Q: "delta"})
+	s.Require().NoError(err)
+	s.Empty(page.Results)
+	s.False(page.HasMore)
+}
+
+func (s *CompaniesSuite) TestItCommitsTransaction() {
+	companies := s.create(models.Company{Name: "acme", Code: "c1"}, models.Company{Name: "beta", Code: "c2"})
+
+	var created models.Company
+
+	err := s.repo.Transaction(auditCtx, func(tx handlers.CompaniesRepo) error {
+		var err error
+
+		// a failed operation does not abort the transaction
+		_, err = tx.Create(auditCtx, models.Company{Name: "gamma", Code: "c1"})
+		s.Require().ErrorIs(err, models.ErrCompanyConflict)
+
+		created, err = tx.Create(auditCtx, models.Company{Name: "gamma", Code: "c3"})
+		s.Require().NoError(err)
+
+		update := companies[0]
+		update.Name = "acme ltd"
+		_, _, err = tx.Update(auditCtx, update)
+		s.Require().NoError(err)
+
+		return tx.Delete(auditCtx, companies[1])
+	})
+	s.Require().NoError(err)
+
+	found, err := s.repo.Get(context.Background(), created.ID)
+	s.Require().NoError(err)
+	s.Equal("gamma", found.Name)
+
+	found, err = s.repo.Get(context.Background(), companies[0].ID)
+	s.Require().NoError(err)
+	s.Equal("acme ltd", found.Name)
+
+	_, err = s.repo.Get(context.Background(), companies[1].ID)
+	s.ErrorIs(err, models.ErrCompanyNotFound)
+
+	history, err := s.repo.History(context.Background(), companies[0].ID)
+	s.Require().NoError(err)
+	s.Len(history, 2)
+
+	page, err := s.repo.Search(context.Background(), requests.SearchQuery{Q: "gamma"})
+	s.Require().NoError(err)
+	s.Len(page.Results, 1)
+}
+
+func (s *CompaniesSuite) TestItRollsBackTransaction() {
+	companies := s.create(models.Company{Name: "acme", Code: "c1"}, models.Company{Name: "beta", Code: "c2"})
+	failure := errors.New("failure")
+
+	err := s.repo.Transaction(auditCtx, func(tx handlers.CompaniesRepo) error {
+		_, err := tx.Create(auditCtx, models.Company{Name: "gamma", Code: "c3"})
+		s.Require().NoError(err)
+
+		update := companies[0]
+		update.Name = "acme ltd"
+		_, _, err = tx.Update(auditCtx, update)
+		s.Require().NoError(err)
+
+		s.Require().NoError(tx.Delete(auditCtx, companies[1]))
+
+		return failure
+	})
+	s.ErrorIs(err, failure)
+
+	page, err := s.repo.GetAll(context.Background(), requests.CompaniesQuery{Sort: "name"})
+	s.Require().NoError(err)
+	s.Equal([]string{"acme", "beta"}, names(page.Companies))
+	s.Equal(companies, page.Companies)
+
+	history, err := s.repo.History(context.Background(), companies[0].ID)
+	s.Require().NoError(err)
+	s.Len(history, 1)
+
+	results, err := s.repo.Search(context.Background(), requests.SearchQuery{Q: "gamma"})
+	s.Require().NoError(err)
+	s.Empty(results.Results)
+
+	// the code of the rolled back company can be used again
+	s.create(models.Company{Name: "gamma", Code: "c3"})
+}
+
+func (s *CompaniesSuite) TestItHonoursCancelledContext() {
+	company := s.create(models.Company{Name: "acme", Code: "c1"})[0]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := s.repo.Get(ctx, company.ID)
+	s.ErrorIs(err, context.Canceled)
+
+	_, err = s.repo.GetAll(ctx, requests.CompaniesQuery{})
+	s.ErrorIs(err, context.Canceled)
+
+	_, err = s.repo.Create(ctx, models.Company{Name: "beta", Code: "c2"})
+	s.ErrorIs(err, context.Canceled)
+
+	company.Name = "acme ltd"
+	_, _, err = s.repo.Update(ctx, company)
+	s.ErrorIs(err, context.Canceled)
+
+	found, err := s.repo.Get(context.Background(), company.ID)
+	s.Require().NoError(err)
+	s.Equal("acme", found.Name)
+}
+
+func (s *CompaniesSuite) TestItRecordsCompanyHistory() {
+	company := s.create(models.Company{Name: "acme", Code: "c1"})[0]
+
+	company.Name = "acme ltd"
+	company, _, err := s.repo.Update(auditCtx, company)
+	s.Require().NoError(err)
+	s.Require().NoError(s.repo.Delete(auditCtx, company))
+	s.Require().NoError(s.repo.Restore(auditCtx, company.ID))
+
+	records, err := s.repo.History(context.Background(), company.ID)
+	s.Require().NoError(err)
+	s.Require().Len(records, 4)
+
+	actions := []string{}
+	for _, r := range records {
+		actions = append(actions, r.Action)
+		s.Equal("john", r.Actor)
+		s.Equal("req-1", r.RequestID)
+		s.Equal("127.0.0.1", r.ClientIP)
+	}
+
+	s.Equal([]string{models.AuditActionCreated, models.AuditActionUpdated, models.AuditActionDeleted, models.AuditActionRestored}, actions)
+	s.Equal(models.Changes{"name": {From: "acme", To: "acme ltd"}}, records[1].Changes)
+
+	verified, err := s.repo.VerifyAudit(context.Background())
+	s.Require().NoError(err)
+	s.True(verified)
+}
+
+func (s *CompaniesSuite) TestItChainsAuditOfAllCompanies() {
+	companies := s.create(models.Company{Name: "acme", Code: "c1"}, models.Company{Name: "beta", Code: "c2"})
+	s.Require().NoError(s.repo.Delete(auditCtx, companies[0]))
+
+	first, err := s.repo.History(context.Background(), companies[0].ID)
+	s.Require().NoError(err)
+	s.Require().Len(first, 2)
+
+	second, err := s.repo.History(context.Background(), companies[1].ID)
+	s.Require().NoError(err)
+	s.Require().Len(second, 1)
+
+	s.Equal(first[0].Hash, second[0].PrevHash)
+	s.Equal(second[0].Hash, first[1].PrevHash)
+
+	verified, err := s.repo.VerifyAudit(context.Background())
+	s.Require().NoError(err)
+	s.True(verified)
+}
+
+// parentOf returns the parent reference to the company.
+func parentOf(company models.Company) *uint64 {
+	id := company.ID
+	return &id
+}
+
+func (s *CompaniesSuite) TestItCanBuildCompanyHierarchy() {
+	ctx := context.Background()
+
+	group := s.create(models.Company{Name: "group", Code: "g"})[0]
+	first := s.create(models.Company{Name: "first", Code: "f", ParentID: parentOf(group)})[0]
+	second := s.create(models.Company{Name: "second", Code: "s", ParentID: parentOf(group)})[0]
+	nested := s.create(models.Company{Name: "nested", Code: "n", ParentID: parentOf(first)})[0]
+
+	found, err := s.repo.Get(ctx, nested.ID)
+	s.Require().NoError(err)
+	s.Require().NotNil(found.ParentID)
+	s.Equal(first.ID, *found.ParentID)
+
+	children, err := s.repo.Children(ctx, group.ID)
+	s.Require().NoError(err)
+	s.Equal([]string{"first", "second"}, names(children))
+
+	children, err = s.repo.Children(ctx, nested.ID)
+	s.Require().NoError(err)
+	s.Empty(children)
+
+	ancestors, err := s.repo.Ancestors(ctx, nested.ID)
+	s.Require().NoError(err)
+	s.Equal([]string{"first", "group"}, names(ancestors))
+
+	ancestors, err = s.repo.Ancestors(ctx, group.ID)
+	s.Require().NoError(err)
+	s.Empty(ancestors)
+
+	_, err = s.repo.Ancestors(ctx, 100)
+	s.ErrorIs(err, models.ErrCompanyNotFound)
+
+	descendants, err := s.repo.Descendants(ctx, group.ID)
+	s.Require().NoError(err)
+	s.Equal([]string{"first", "second", "nested"}, names(descendants))
+
+	// the parent can be removed
+	second.ParentID = nil
+	_, _, err = s.repo.Update(auditCtx, second)
+	s.Require().NoError(err)
+
+	children, err = s.repo.Children(ctx, group.ID)
+	s.Require().NoError(err)
+	s.Equal([]string{"first"}, names(children))
+}
+
+func (s *CompaniesSuite) TestItRejectsInvalidParent() {
+	companies := s.create(models.Company{Name: "group", Code: "g"}, models.Company{Name: "lonely", Code: "l"})
+	group := companies[0]
+	child := s.create(models.Company{Name: "child", Code: "c", ParentID: parentOf(group)})[0]
+
+	missing := uint64(100)
+	_, err := s.repo.Create(auditCtx, models.Company{Name: "orphan", Code: "o", ParentID: &missing})
+	s.ErrorIs(err, models.ErrParentNotFound)
+
+	group.ParentID = parentOf(group)
+	_, _, err = s.repo.Update(auditCtx, group)
+	s.ErrorIs(err, models.ErrCompanyCycle)
+
+	group.ParentID = parentOf(child)
+	_, _, err = s.repo.Update(auditCtx, group)
+	s.ErrorIs(err, models.ErrCompanyCycle)
+
+	s.Require().NoError(s.repo.Delete(auditCtx, companies[1]))
+	child.ParentID = parentOf(companies[1])
+	_, _, err = s.repo.Update(auditCtx, child)
+	s.ErrorIs(err, models.ErrParentNotFound, "soft-deleted company cannot be a parent")
+
+	found, err := s.repo.Get(context.Background(), group.ID)
+	s.Require().NoError(err)
+	s.Nil(found.ParentID)
+}
+
+func (s *CompaniesSuite) TestItCannotDeleteCompanyWithChildren() {
+	group := s.create(models.Company{Name: "group", Code: "g"})[0]
+	child := s.create(models.Company{Name: "child", Code: "c", ParentID: parentOf(group)})[0]
+
+	s.ErrorIs(s.repo.Delete(auditCtx, group), models.ErrCompanyHasChildren)
+
+	_, err := s.repo.Get(context.Background(), group.ID)
+	s.Require().NoError(err, "company is not deleted")
+
+	// soft-deleted subsidiaries do not prevent the delete
+	s.Require().NoError(s.repo.Delete(auditCtx, child))
+	s.Require().NoError(s.repo.Delete(auditCtx, group))
+}
+
+func (s *CompaniesSuite) TestItDetachesDeletedParentOnRestore() {
+	group := s.create(models.Company{Name: "group", Code: "g"})[0]
+	child := s.create(models.Company{Name: "child", Code: "c", ParentID: parentOf(group)})[0]
+
+	s.Require().NoError(s.repo.Delete(auditCtx, child))
+	s.Require().NoError(s.repo.Delete(auditCtx, group))
+	s.Require().NoError(s.repo.Restore(auditCtx, child.ID))
+
+	restored, err := s.repo.Get(context.Background(), child.ID)
+	s.Require().NoError(err)
+	s.Nil(restored.ParentID)
+
+	records, err := s.repo.History(context.Background(), child.ID)
+	s.Require().NoError(err)
+	s.Equal(models.Changes{"parent_id": {From: strconv.FormatUint(group.ID, 10)}}, records[len(records)-1].Changes)
+
+	// the parent is kept, if it is still active
+	other := s.create(models.Company{Name: "other", Code: "o", ParentID: parentOf(child)})[0]
+	s.Require().NoError(s.repo.Delete(auditCtx, other))
+	s.Require().NoError(s.repo.Restore(auditCtx, other.ID))
+
+	restored, err = s.repo.Get(context.Background(), other.ID)
+	s.Require().NoError(err)
+	s.Equal(parentOf(child), restored.ParentID)
+}
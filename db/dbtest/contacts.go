@@ -0,0 +1,143 @@
+package dbtest
+
+import (
+	"context"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/stretchr/testify/suite"
+)
+
+// ContactsRepo defines the repository tested by the contacts conformance suite.
+type ContactsRepo interface {
+	Repo
+	handlers.ContactsRepo
+}
+
+// ContactsSuite is the conformance test suite for the company contacts.
+// NewRepo must return an empty repository for every test.
+type ContactsSuite struct {
+	suite.Suite
+	NewRepo func() ContactsRepo
+	repo    ContactsRepo
+	company models.Company
+}
+
+func (s *ContactsSuite) SetupTest() {
+	s.repo = s.NewRepo()
+
+	company, err := s.repo.Create(auditCtx, models.Company{Name: "acme", Code: "c1", Country: "CY"})
+	s.Require().NoError(err)
+	s.company = company
+}
+
+// contact adds a contact to the company.
+func (s *ContactsSuite) contact(companyID uint64, name string) models.Contact {
+	contact, err := s.repo.CreateContact(context.Background(), models.Contact{CompanyID: companyID, Name: name, Email: name + "@example.com"})
+	s.Require().NoError(err)
+	return contact
+}
+
+func (s *ContactsSuite) TestItCanManageContacts() {
+	contact := s.contact(s.company.ID, "john")
+	s.NotZero(contact.ID)
+
+	got, err := s.repo.GetContact(context.Background(), s.company.ID, contact.ID)
+	s.Require().NoError(err)
+	s.Equal(contact, got)
+
+	contact.Role, contact.Phone = "CEO", "+35799123456"
+	_, err = s.repo.UpdateContact(context.Background(), contact)
+	s.Require().NoError(err)
+
+	got, err = s.repo.GetContact(context.Background(), s.company.ID, contact.ID)
+	s.Require().NoError(err)
+	s.Equal(contact, got)
+
+	s.Require().NoError(s.repo.DeleteContact(context.Background(), s.company.ID, contact.ID))
+
+	_, err = s.repo.GetContact(context.Background(), s.company.ID, contact.ID)
+	s.ErrorIs(err, models.ErrContactNotFound)
+}
+
+func (s *ContactsSuite) TestItListsContactsOfTheCompany() {
+	other, err := s.repo.Create(auditCtx, models.Company{Name: "beta", Code: "c2", Country: "CY"})
+	s.Require().NoError(err)
+
+	s.contact(s.company.ID, "john")
+	s.contact(other.ID, "jane")
+	s.contact(s.company.ID, "jack")
+
+	contacts, err := s.repo.GetContacts(context.Background(), s.company.ID)
+	s.Require().NoError(err)
+	s.Require().Len(contacts, 2)
+	s.Equal("john", contacts[0].Name)
+	s.Equal("jack", contacts[1].Name)
+
+	contacts, err = s.repo.GetContacts(context.Background(), 999)
+	s.Require().NoError(err)
+	s.Empty(contacts)
+}
+
+func (s *ContactsSuite) TestItDoesNotAccessContactsOfOtherCompany() {
+	other, err := s.repo.Create(auditCtx, models.Company{Name: "beta", Code: "c2", Country: "CY"})
+	s.Require().NoError(err)
+
+	contact := s.contact(s.company.ID, "john")
+
+	_, err = s.repo.GetContact(context.Background(), other.ID, contact.ID)
+	s.ErrorIs(err, models.ErrContactNotFound)
+
+	moved := contact
+	moved.CompanyID, moved.Name = other.ID, "jane"
+	_, err = s.repo.UpdateContact(context.Background(), moved)
+	s.ErrorIs(err, models.ErrContactNotFound)
+
+	s.ErrorIs(s.repo.DeleteContact(context.Background(), other.ID, contact.ID), models.ErrContactNotFound)
+
+	got, err := s.repo.GetContact(context.Background(), s.company.ID, contact.ID)
+	s.Require().NoError(err)
+	s.Equal(contact, got)
+}
+
+func (s *ContactsSuite) TestItCannotAddContactToMissingCompany() {
+	_, err := s.repo.CreateContact(context.Background(), models.Contact{CompanyID: 999, Name: "john"})
+	s.ErrorIs(err, models.ErrCompanyNotFound)
+
+	s.Require().NoError(s.repo.Delete(auditCtx, s.company))
+
+	_, err = s.repo.CreateContact(context.Background(), models.Contact{CompanyID: s.company.ID, Name: "john"})
+	s.ErrorIs(err, models.ErrCompanyNotFound)
+}
+
+func (s *ContactsSuite) TestItKeepsContactsOfRestoredCompany() {
+	contact := s.contact(s.company.ID, "john")
+
+	s.Require().NoError(s.repo.Delete(auditCtx, s.company))
+	s.Require().NoError(s.repo.Restore(auditCtx, s.company.ID))
+
+	contacts, err := s.repo.GetContacts(context.Background(), s.company.ID)
+	s.Require().NoError(err)
+	s.Equal([]models.Contact{contact}, contacts)
+}
+
+func (s *ContactsSuite) TestItPurgesContactsWithTheCompany() {
+	other, err := s.repo.Create(auditCtx, models.Company{Name: "beta", Code: "c2", Country: "CY"})
+	s.Require().NoError(err)
+
+	contact := s.contact(s.company.ID, "john")
+	kept := s.contact(other.ID, "jane")
+
+	s.Require().NoError(s.repo.Delete(auditCtx, s.company))
+
+	n, err := s.repo.Purge(context.Background(), time.Now().Add(time.Hour))
+	s.Require().NoError(err)
+	s.Equal(int64(1), n)
+
+	_, err = s.repo.GetContact(context.Background(), s.company.ID, contact.ID)
+	s.ErrorIs(err, models.ErrContactNotFound)
+
+	_, err = s.repo.GetContact(context.Background(), other.ID, kept.ID)
+	s.NoError(err)
+}
@@ -0,0 +1,157 @@
+package dbtest
+
+import (
+	"context"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+)
+
+var (
+	tenantA = models.WithTenant(auditCtx, "a")
+	tenantB = models.WithTenant(auditCtx, "b")
+)
+
+func (s *CompaniesSuite) TestTenantsCanUseTheSameCode() {
+	a, err := s.repo.Create(tenantA, models.Company{Name: "acme", Code: "c1", Country: "CY"})
+	s.Require().NoError(err)
+
+	b, err := s.repo.Create(tenantB, models.Company{Name: "beta", Code: "c1", Country: "CY"})
+	s.Require().NoError(err)
+
+	found, err := s.repo.GetByCode(tenantA, "c1", "CY")
+	s.Require().NoError(err)
+	s.Equal(a.ID, found.ID)
+
+	found, err = s.repo.GetByCode(tenantB, "c1", "CY")
+	s.Require().NoError(err)
+	s.Equal(b.ID, found.ID)
+
+	_, err = s.repo.Create(tenantB, models.Company{Name: "gamma", Code: "c1", Country: "CY"})
+	s.ErrorIs(err, models.ErrCompanyConflict)
+}
+
+func (s *CompaniesSuite) TestTenantsCannotReadCompaniesOfOtherTenants() {
+	a, err := s.repo.Create(tenantA, models.Company{Name: "acme", Code: "c1", Country: "CY"})
+	s.Require().NoError(err)
+
+	// read by the owner first, so a cached company would be served to the other tenant
+	_, err = s.repo.Get(tenantA, a.ID)
+	s.Require().NoError(err)
+
+	_, err = s.repo.Get(tenantB, a.ID)
+	s.ErrorIs(err, models.ErrCompanyNotFound)
+
+	_, err = s.repo.GetByCode(tenantB, "c1", "CY")
+	s.ErrorIs(err, models.ErrCompanyNotFound)
+
+	page, err := s.repo.GetAll(tenantB, requests.CompaniesQuery{})
+	s.Require().NoError(err)
+	s.Empty(page.Companies)
+
+	search, err := s.repo.Search(tenantB, requests.SearchQuery{Q: "acme"})
+	s.Require().NoError(err)
+	s.Empty(search.Results)
+
+	history, err := s.repo.History(tenantB, a.ID)
+	s.Require().NoError(err)
+	s.Empty(history)
+
+	s.Require().NoError(s.repo.Delete(tenantA, a))
+
+	_, err = s.repo.GetTrashed(tenantB, a.ID)
+	s.ErrorIs(err, models.ErrCompanyNotFound)
+
+	trash, err := s.repo.GetAllTrashed(tenantB, requests.CompaniesQuery{})
+	s.Require().NoError(err)
+	s.Empty(trash.Companies)
+}
+
+func (s *CompaniesSuite) TestTenantsCannotChangeCompaniesOfOtherTenants() {
+	a, err := s.repo.Create(tenantA, models.Company{Name: "acme", Code: "c1", Country: "CY"})
+	s.Require().NoError(err)
+
+	changed := a
+	changed.Name = "beta"
+	_, _, err = s.repo.Update(tenantB, changed)
+	s.ErrorIs(err, models.ErrCompanyModified)
+
+	s.ErrorIs(s.repo.Delete(tenantB, a), models.ErrCompanyModified)
+
+	s.Require().NoError(s.repo.Delete(tenantA, a))
+	s.ErrorIs(s.repo.Restore(tenantB, a.ID), models.ErrCompanyNotFound)
+	s.Require().NoError(s.repo.Restore(tenantA, a.ID))
+
+	found, err := s.repo.Get(tenantA, a.ID)
+	s.Require().NoError(err)
+	s.Equal("acme", found.Name)
+}
+
+func (s *CompaniesSuite) TestTenantsCannotUseParentsOfOtherTenants() {
+	parent, err := s.repo.Create(tenantA, models.Company{Name: "acme", Code: "c1", Country: "CY"})
+	s.Require().NoError(err)
+
+	_, err = s.repo.Create(tenantA, models.Company{Name: "beta", Code: "c2", Country: "CY", ParentID: &parent.ID})
+	s.Require().NoError(err)
+
+	_, err = s.repo.Create(tenantB, models.Company{Name: "gamma", Code: "c3", Country: "CY", ParentID: &parent.ID})
+	s.ErrorIs(err, models.ErrParentNotFound)
+
+	children, err := s.repo.Children(tenantB, parent.ID)
+	s.Require().NoError(err)
+	s.Empty(children)
+
+	descendants, err := s.repo.Descendants(tenantB, parent.ID)
+	s.Require().NoError(err)
+	s.Empty(descendants)
+}
+
+func (s *ContactsSuite) TestTenantsCannotAccessContactsOfOtherTenants() {
+	company, err := s.repo.Create(tenantA, models.Company{Name: "acme", Code: "c1", Country: "CY"})
+	s.Require().NoError(err)
+
+	_, err = s.repo.CreateContact(tenantB, models.Contact{CompanyID: company.ID, Name: "jane", Email: "jane@example.com"})
+	s.ErrorIs(err, models.ErrCompanyNotFound)
+
+	contact, err := s.repo.CreateContact(tenantA, models.Contact{CompanyID: company.ID, Name: "john", Email: "john@example.com"})
+	s.Require().NoError(err)
+
+	_, err = s.repo.GetContact(tenantB, company.ID, contact.ID)
+	s.ErrorIs(err, models.ErrContactNotFound)
+
+	contacts, err := s.repo.GetContacts(tenantB, company.ID)
+	s.Require().NoError(err)
+	s.Empty(contacts)
+
+	_, err = s.repo.UpdateContact(tenantB, contact)
+	s.ErrorIs(err, models.ErrContactNotFound)
+
+	s.ErrorIs(s.repo.DeleteContact(tenantB, company.ID, contact.ID), models.ErrContactNotFound)
+
+	got, err := s.repo.GetContact(tenantA, company.ID, contact.ID)
+	s.Require().NoError(err)
+	s.Equal(contact, got)
+}
+
+func (s *WebhooksSuite) TestTenantsCannotAccessDeliveriesOfOtherTenants() {
+	_, err := s.repo.Create(tenantA, models.Company{Name: "acme", Code: "c1", Country: "CY"})
+	s.Require().NoError(err)
+
+	delivery := s.claim("https://example.com/hook")[0]
+	s.Equal("a", delivery.Event.TenantID)
+
+	delivery.Status = models.DeliveryDead
+	s.Require().NoError(s.repo.SaveDelivery(context.Background(), delivery))
+
+	dead, err := s.repo.DeadDeliveries(tenantB)
+	s.Require().NoError(err)
+	s.Empty(dead)
+
+	_, err = s.repo.Redeliver(tenantB, delivery.ID)
+	s.ErrorIs(err, models.ErrDeliveryNotFound)
+
+	dead, err = s.repo.DeadDeliveries(tenantA)
+	s.Require().NoError(err)
+	s.Require().Len(dead, 1)
+	s.Equal(delivery.ID, dead[0].ID)
+}
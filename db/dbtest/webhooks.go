@@ -0,0 +1,217 @@
+package dbtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/jobs"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/stretchr/testify/suite"
+)
+
+// OutboxRepo defines the repository tested by the webhooks conformance suite.
+type OutboxRepo interface {
+	Repo
+	handlers.WebhooksRepo
+	jobs.WebhooksStore
+}
+
+// WebhooksSuite is the conformance test suite for the outbox events and webhook deliveries.
+// NewRepo must return an empty repository configured with the webhook URLs for every test,
+// NewRepoWithoutURLs must return an empty repository configured without them.
+type WebhooksSuite struct {
+	suite.Suite
+	NewRepo            func() OutboxRepo
+	NewRepoWithoutURLs func() OutboxRepo
+	repo               OutboxRepo
+}
+
+const lease = time.Minute
+
+func (s *WebhooksSuite) SetupTest() {
+	s.repo = s.NewRepo()
+}
+
+// claim dispatches all events to the URLs and claims the deliveries due now.
+func (s *WebhooksSuite) claim(urls ...string) []models.Delivery {
+	_, err := s.repo.DispatchEvents(context.Background(), urls, 100)
+	s.Require().NoError(err)
+
+	deliveries, err := s.repo.ClaimDeliveries(context.Background(), time.Now().UTC(), lease, 100)
+	s.Require().NoError(err)
+
+	return deliveries
+}
+
+// types returns the event types of the deliveries.
+func types(deliveries []models.Delivery) []string {
+	t := make([]string, 0, len(deliveries))
+	for _, d := range deliveries {
+		t = append(t, d.Event.Type)
+	}
+	return t
+}
+
+func (s *WebhooksSuite) TestItWritesEventsForCompanyChanges() {
+	company, err := s.repo.Create(auditCtx, models.Company{Name: "acme", Code: "c1", Country: "CY"})
+	s.Require().NoError(err)
+
+	company.Name = "beta"
+	company, _, err = s.repo.Update(auditCtx, company)
+	s.Require().NoError(err)
+
+	s.Require().NoError(s.repo.Delete(auditCtx, company))
+	s.Require().NoError(s.repo.Restore(auditCtx, company.ID))
+
+	deliveries := s.claim("https://example.com/hook")
+	s.Equal([]string{models.EventCompanyCreated, models.EventCompanyUpdated, models.EventCompanyDeleted, models.EventCompanyRestored}, types(deliveries))
+
+	var data models.Company
+	s.Require().NoError(json.Unmarshal([]byte(deliveries[1].Event.Data), &data))
+	s.Equal(company.ID, deliveries[1].Event.CompanyID)
+	s.Equal("beta", data.Name)
+
+	s.Require().NoError(json.Unmarshal([]byte(deliveries[3].Event.Data), &data))
+	s.Equal(company.ID, data.ID)
+	s.Equal("beta", data.Name)
+}
+
+func (s *WebhooksSuite) TestItDoesNotWriteEventsWithoutURLs() {
+	s.repo = s.NewRepoWithoutURLs()
+
+	company, err := s.repo.Create(auditCtx, models.Company{Name: "acme", Code: "c1", Country: "CY"})
+	s.Require().NoError(err)
+	s.Require().NoError(s.repo.Delete(auditCtx, company))
+	s.Require().NoError(s.repo.Restore(auditCtx, company.ID))
+
+	s.Empty(s.claim("https://example.com/hook"))
+}
+
+func (s *WebhooksSuite) TestItDoesNotWriteEventsForRolledBackChanges() {
+	errRollback := errors.New("rollback")
+
+	err := s.repo.Transaction(context.Background(), func(tx handlers.CompaniesRepo) error {
+		if _, err := tx.Create(auditCtx, models.Company{Name: "acme", Code: "c1", Country: "CY"}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	s.Require().ErrorIs(err, errRollback)
+
+	s.Empty(s.claim("https://example.com/hook"))
+}
+
+func (s *WebhooksSuite) TestItDispatchesEventsToEveryURLOnce() {
+	_, err := s.repo.Create(auditCtx, models.Company{Name: "acme", Code: "c1", Country: "CY"})
+	s.Require().NoError(err)
+
+	n, err := s.repo.DispatchEvents(context.Background(), []string{"https://a.com", "https://b.com"}, 100)
+	s.Require().NoError(err)
+	s.Equal(1, n)
+
+	n, err = s.repo.DispatchEvents(context.Background(), []string{"https://a.com", "https://b.com"}, 100)
+	s.Require().NoError(err)
+	s.Equal(0, n)
+
+	deliveries, err := s.repo.ClaimDeliveries(context.Background(), time.Now().UTC(), lease, 100)
+	s.Require().NoError(err)
+	s.Require().Len(deliveries, 2)
+
+	urls := []string{deliveries[0].URL, deliveries[1].URL}
+	s.ElementsMatch([]string{"https://a.com", "https://b.com"}, urls)
+
+	for _, d := range deliveries {
+		s.Equal(models.DeliveryPending, d.Status)
+		s.Equal(uint(0), d.Attempts)
+	}
+}
+
+func (s *WebhooksSuite) TestItDoesNotClaimDeliveriesTwice() {
+	_, err := s.repo.Create(auditCtx, models.Company{Name: "acme", Code: "c1", Country: "CY"})
+	s.Require().NoError(err)
+
+	s.Len(s.claim("https://example.com/hook"), 1)
+
+	claimed, err := s.repo.ClaimDeliveries(context.Background(), time.Now().UTC(), lease, 100)
+	s.Require().NoError(err)
+	s.Empty(claimed)
+
+	// the lease expires, if the delivery is not saved in time
+	claimed, err = s.repo.ClaimDeliveries(context.Background(), time.Now().UTC().Add(lease*2), lease, 100)
+	s.Require().NoError(err)
+	s.Len(claimed, 1)
+}
+
+func (s *WebhooksSuite) TestItSavesDeliveryOutcome() {
+	_, err := s.repo.Create(auditCtx, models.Company{Name: "acme", Code: "c1", Country: "CY"})
+	s.Require().NoError(err)
+
+	delivery := s.claim("https://example.com/hook")[0]
+
+	next := time.Now().UTC().Add(time.Minute * 10)
+	delivery.Attempts = 1
+	delivery.LastError = "unexpected status 500"
+	delivery.NextAttemptAt = next
+	s.Require().NoError(s.repo.SaveDelivery(context.Background(), delivery))
+
+	claimed, err := s.repo.ClaimDeliveries(context.Background(), next.Add(-time.Second), lease, 100)
+	s.Require().NoError(err)
+	s.Empty(claimed)
+
+	claimed, err = s.repo.ClaimDeliveries(context.Background(), next.Add(time.Second), lease, 100)
+	s.Require().NoError(err)
+	s.Require().Len(claimed, 1)
+	s.Equal(uint(1), claimed[0].Attempts)
+	s.Equal("unexpected status 500", claimed[0].LastError)
+
+	delivered := time.Now().UTC()
+	claimed[0].Attempts = 2
+	claimed[0].Status = models.DeliveryDelivered
+	claimed[0].DeliveredAt = &delivered
+	s.Require().NoError(s.repo.SaveDelivery(context.Background(), claimed[0]))
+
+	claimed, err = s.repo.ClaimDeliveries(context.Background(), next.Add(lease*2), lease, 100)
+	s.Require().NoError(err)
+	s.Empty(claimed)
+}
+
+func (s *WebhooksSuite) TestItCanListAndRedeliverDeadDeliveries() {
+	_, err := s.repo.Create(auditCtx, models.Company{Name: "acme", Code: "c1", Country: "CY"})
+	s.Require().NoError(err)
+
+	delivery := s.claim("https://example.com/hook")[0]
+	delivery.Attempts = 8
+	delivery.Status = models.DeliveryDead
+	delivery.LastError = "unexpected status 500"
+	s.Require().NoError(s.repo.SaveDelivery(context.Background(), delivery))
+
+	dead, err := s.repo.DeadDeliveries(context.Background())
+	s.Require().NoError(err)
+	s.Require().Len(dead, 1)
+	s.Equal(delivery.ID, dead[0].ID)
+	s.Equal(models.EventCompanyCreated, dead[0].Event.Type)
+	s.Equal("unexpected status 500", dead[0].LastError)
+
+	redelivered, err := s.repo.Redeliver(context.Background(), delivery.ID)
+	s.Require().NoError(err)
+	s.Equal(models.DeliveryPending, redelivered.Status)
+	s.Equal(uint(0), redelivered.Attempts)
+	s.Empty(redelivered.LastError)
+	s.Equal(models.EventCompanyCreated, redelivered.Event.Type)
+
+	dead, err = s.repo.DeadDeliveries(context.Background())
+	s.Require().NoError(err)
+	s.Empty(dead)
+
+	claimed, err := s.repo.ClaimDeliveries(context.Background(), time.Now().UTC().Add(time.Second), lease, 100)
+	s.Require().NoError(err)
+	s.Len(claimed, 1)
+}
+
+func (s *WebhooksSuite) TestItReturnsErrDeliveryNotFound() {
+	_, err := s.repo.Redeliver(context.Background(), 99)
+	s.ErrorIs(err, models.ErrDeliveryNotFound)
+}
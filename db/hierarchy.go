@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Children returns the direct subsidiaries of the company, ordered by id.
+func (c *CompaniesRepo) Children(ctx context.Context, id uint64) ([]models.Company, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	children := []models.Company{}
+
+	err := c.read(ctx).Scopes(tenantScope(ctx)).Where("parent_id = ?", id).Order("id").Find(&children).Error
+	return children, contextError(ctx, err)
+}
+
+// Ancestors returns the parent of the company, the parent of the parent and so on, up to the root.
+// If the company does not exist this function returns a ErrCompanyNotFound error.
+func (c *CompaniesRepo) Ancestors(ctx context.Context, id uint64) ([]models.Company, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	// the same database is used for the whole walk, so it sees a single replica
+	tx := c.read(ctx)
+	ancestors := []models.Company{}
+
+	var company models.Company
+
+	err := tx.Scopes(tenantScope(ctx)).Where("id = ?", id).First(&company).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ancestors, models.ErrCompanyNotFound
+	}
+
+	seen := map[uint64]bool{id: true}
+
+	for err == nil && company.ParentID != nil && !seen[*company.ParentID] {
+		seen[*company.ParentID] = true
+
+		var parent models.Company
+		if err = tx.Scopes(tenantScope(ctx)).Where("id = ?", *company.ParentID).First(&parent).Error; err == nil {
+			ancestors = append(ancestors, parent)
+			company = parent
+		}
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+
+	return ancestors, contextError(ctx, err)
+}
+
+// Descendants returns all subsidiaries of the company, level by level,
+// companies of the same level are ordered by id.
+func (c *CompaniesRepo) Descendants(ctx context.Context, id uint64) ([]models.Company, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	tx := c.read(ctx)
+	descendants := []models.Company{}
+
+	seen := map[uint64]bool{id: true}
+	level := []uint64{id}
+
+	for len(level) > 0 {
+		var children []models.Company
+		if err := tx.Scopes(tenantScope(ctx)).Where("parent_id IN ?", level).Order("id").Find(&children).Error; err != nil {
+			return descendants, contextError(ctx, err)
+		}
+
+		level = nil
+		for _, child := range children {
+			if !seen[child.ID] {
+				seen[child.ID] = true
+				level = append(level, child.ID)
+				descendants = append(descendants, child)
+			}
+		}
+	}
+
+	return descendants, nil
+}
+
+// checkParent returns a ErrParentNotFound error if the parent of the company does not exist in the tenant,
+// and a ErrCompanyCycle error if the company is one of the ancestors of its parent.
+// The ancestors are locked, so concurrent updates cannot create a cycle
+// and the parent cannot be deleted until the transaction ends.
+func checkParent(ctx context.Context, tx *gorm.DB, company models.Company) error {
+	seen := map[uint64]bool{}
+
+	for id := company.ParentID; id != nil; {
+		if *id == company.ID || seen[*id] {
+			return models.ErrCompanyCycle
+		}
+		seen[*id] = true
+
+		var ancestor models.Company
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(tenantScope(ctx)).Where("id = ?", *id).First(&ancestor).Error
+
+		if errors.Is(err, gorm.ErrRecordNotFound) && id == company.ParentID {
+			return models.ErrParentNotFound
+		}
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		id = ancestor.ParentID
+	}
+
+	return nil
+}
+
+// hasChildren reports whether the company has subsidiaries, soft-deleted ones are not counted.
+func hasChildren(ctx context.Context, tx *gorm.DB, id uint64) (bool, error) {
+	var n int64
+	err := tx.Model(&models.Company{}).Scopes(tenantScope(ctx)).Where("parent_id = ?", id).Count(&n).Error
+	return n > 0, err
+}
+
+// detachParent clears the parent of the company, if the parent is no longer active.
+func detachParent(ctx context.Context, tx *gorm.DB, company *models.Company) error {
+	if company.ParentID == nil {
+		return nil
+	}
+
+	var n int64
+	if err := tx.Model(&models.Company{}).Scopes(tenantScope(ctx)).Where("id = ?", *company.ParentID).Count(&n).Error; err != nil || n > 0 {
+		return err
+	}
+
+	company.ParentID = nil
+	return tx.Model(&models.Company{ID: company.ID}).Update("parent_id", nil).Error
+}
@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+)
+
+// CreateAPIKey adds a new API key of the tenant to the memory.
+func (c *CompaniesRepo) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return key, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lastAPIKeyID++
+	key.ID = c.lastAPIKeyID
+	key.TenantID = models.TenantFrom(ctx)
+	key.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+	c.apiKeys[key.ID] = key
+
+	return key, nil
+}
+
+// GetAPIKeys returns all API keys of the tenant, including the revoked and expired ones, ordered by id.
+func (c *CompaniesRepo) GetAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	keys := []models.APIKey{}
+
+	if err := ctx.Err(); err != nil {
+		return keys, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	tenant := models.TenantFrom(ctx)
+
+	for _, key := range c.apiKeys {
+		if key.TenantID == tenant {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+// RotateAPIKey replaces the prefix and hash of the API key, the previous key stops working immediately.
+// If the key does not exist or is revoked this function returns a ErrAPIKeyNotFound error.
+func (c *CompaniesRepo) RotateAPIKey(ctx context.Context, id uint64, prefix string, hash string) (models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return models.APIKey{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key, ok := c.apiKeys[id]
+	if !ok || key.TenantID != models.TenantFrom(ctx) || key.RevokedAt != nil {
+		return models.APIKey{}, models.ErrAPIKeyNotFound
+	}
+
+	key.Prefix, key.Hash = prefix, hash
+	c.apiKeys[id] = key
+
+	return key, nil
+}
+
+// RevokeAPIKey revokes the API key, revoking the revoked key keeps the time of the first revocation.
+// If the key does not exist this function returns a ErrAPIKeyNotFound error.
+func (c *CompaniesRepo) RevokeAPIKey(ctx context.Context, id uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key, ok := c.apiKeys[id]
+	if !ok || key.TenantID != models.TenantFrom(ctx) {
+		return models.ErrAPIKeyNotFound
+	}
+
+	if key.RevokedAt == nil {
+		now := time.Now().UTC().Truncate(time.Millisecond)
+		key.RevokedAt = &now
+		c.apiKeys[id] = key
+	}
+
+	return nil
+}
+
+// GetAPIKeyByPrefix returns the API key with the given prefix, of any tenant.
+// If the key does not exist this function returns a ErrAPIKeyNotFound error.
+func (c *CompaniesRepo) GetAPIKeyByPrefix(ctx context.Context, prefix string) (models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return models.APIKey{}, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, key := range c.apiKeys {
+		if key.Prefix == prefix {
+			return key, nil
+		}
+	}
+
+	return models.APIKey{}, models.ErrAPIKeyNotFound
+}
+
+// TouchAPIKey records the time the API key was last used.
+func (c *CompaniesRepo) TouchAPIKey(ctx context.Context, id uint64, usedAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if key, ok := c.apiKeys[id]; ok {
+		usedAt = usedAt.UTC().Truncate(time.Millisecond)
+		key.LastUsedAt = &usedAt
+		c.apiKeys[id] = key
+	}
+
+	return nil
+}
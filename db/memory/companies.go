@@ -0,0 +1,558 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/search"
+	"gorm.io/gorm"
+)
+
+// CompaniesRepo allows to store and retrieve companies from the memory.
+// It is intended to be used for the local development and tests.
+// Operations do not block, so the context is only checked before they start.
+// Companies of all tenants are kept together, every operation skips the companies of other tenants.
+type CompaniesRepo struct {
+	perCountry     bool
+	webhooks       bool // the outbox events are written only when there are webhook URLs to deliver them to
+	mu             sync.RWMutex
+	lastID         uint64
+	lastAuditID    uint64
+	lastEventID    uint64
+	lastDeliveryID uint64
+	lastContactID  uint64
+	lastAPIKeyID   uint64
+	companies      map[uint64]models.Company
+	audit          []models.AuditRecord
+	auditHead      models.AuditHead
+	events         []models.Event
+	deliveries     map[uint64]models.Delivery
+	contacts       map[uint64]models.Contact
+	apiKeys        map[uint64]models.APIKey
+	index          *search.Index
+}
+
+// NewCompaniesRepo creates a new instance of the CompaniesRepo.
+func NewCompaniesRepo(cfg *configs.Config) *CompaniesRepo {
+	return &CompaniesRepo{
+		perCountry: cfg.Companies.UniqueCodePerCountry,
+		webhooks:   len(cfg.Webhooks.URLs) > 0,
+		companies:  make(map[uint64]models.Company),
+		auditHead:  models.AuditHead{ID: models.AuditHeadID},
+		deliveries: make(map[uint64]models.Delivery),
+		contacts:   make(map[uint64]models.Contact),
+		apiKeys:    make(map[uint64]models.APIKey),
+		index:      search.NewIndex(requests.SearchFields),
+	}
+}
+
+// Transaction runs fn with a copy of the repository.
+// The changes made to the copy are kept only if fn returns nil.
+// Transactions hold the lock while they run, so they are serialized with other operations.
+func (c *CompaniesRepo) Transaction(ctx context.Context, fn func(handlers.CompaniesRepo) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tx := c.clone()
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	c.lastID, c.lastAuditID, c.lastEventID = tx.lastID, tx.lastAuditID, tx.lastEventID
+	c.companies, c.audit, c.auditHead, c.events, c.index = tx.companies, tx.audit, tx.auditHead, tx.events, tx.index
+
+	return nil
+}
+
+// clone returns a deep copy of the repository.
+// It must be called while holding the lock.
+func (c *CompaniesRepo) clone() *CompaniesRepo {
+	tx := &CompaniesRepo{
+		perCountry:     c.perCountry,
+		webhooks:       c.webhooks,
+		lastID:         c.lastID,
+		lastAuditID:    c.lastAuditID,
+		lastEventID:    c.lastEventID,
+		lastDeliveryID: c.lastDeliveryID,
+		lastContactID:  c.lastContactID,
+		lastAPIKeyID:   c.lastAPIKeyID,
+		companies:      make(map[uint64]models.Company, len(c.companies)),
+		audit:          append([]models.AuditRecord{}, c.audit...),
+		auditHead:      c.auditHead,
+		events:         append([]models.Event{}, c.events...),
+		deliveries:     c.deliveries,
+		contacts:       c.contacts,
+		apiKeys:        c.apiKeys,
+		index:          search.NewIndex(requests.SearchFields),
+	}
+
+	for id, company := range c.companies {
+		tx.companies[id] = company
+		if !company.DeletedAt.Valid {
+			tx.index.Put(id, requests.SearchDocument(company))
+		}
+	}
+
+	// deliveries, contacts and API keys are not changed by the company operations, so they are shared
+
+	return tx
+}
+
+// Create adds a new company of the tenant from the context to the memory.
+// If the parent company does not exist this function returns a ErrParentNotFound error.
+func (c *CompaniesRepo) Create(ctx context.Context, company models.Company) (models.Company, error) {
+	if err := ctx.Err(); err != nil {
+		return company, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	company.TenantID = models.TenantFrom(ctx)
+
+	if err := c.checkParent(company); err != nil {
+		return company, err
+	}
+
+	if err := c.conflict(company); err != nil {
+		return company, err
+	}
+
+	c.lastID++
+	company.ID = c.lastID
+	company.Version = 1
+	company.DeletedAt = gorm.DeletedAt{}
+
+	c.companies[company.ID] = company
+	c.index.Put(company.ID, requests.SearchDocument(company))
+	c.writeAudit(ctx, company.ID, models.AuditActionCreated, models.Diff(models.Company{}, company))
+	c.writeEvent(models.EventCompanyCreated, company)
+
+	return company, nil
+}
+
+// Get returns a single company from the memory.
+// If a company with the given id does not exist
+// this function returns a ErrCompanyNotFound error.
+func (c *CompaniesRepo) Get(ctx context.Context, id uint64) (models.Company, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Company{}, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	company, ok := c.owned(ctx, id)
+	if !ok || company.DeletedAt.Valid {
+		return models.Company{}, models.ErrCompanyNotFound
+	}
+
+	return company, nil
+}
+
+// GetTrashed returns a single soft-deleted company from the memory.
+// If a soft-deleted company with the given id does not exist
+// this function returns a ErrCompanyNotFound error.
+func (c *CompaniesRepo) GetTrashed(ctx context.Context, id uint64) (models.Company, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Company{}, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	company, ok := c.owned(ctx, id)
+	if !ok || !company.DeletedAt.Valid {
+		return models.Company{}, models.ErrCompanyNotFound
+	}
+
+	return company, nil
+}
+
+// GetByCode returns a single company with the given code from the memory.
+// When codes are unique per country, the country must match as well.
+// If such a company does not exist
+// this function returns a ErrCompanyNotFound error.
+func (c *CompaniesRepo) GetByCode(ctx context.Context, code string, country string) (models.Company, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Company{}, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	tenant := models.TenantFrom(ctx)
+
+	for _, company := range c.companies {
+		if company.TenantID != tenant || company.DeletedAt.Valid || company.Code != code {
+			continue
+		}
+		if c.perCountry && company.Country != country {
+			continue
+		}
+		return company, nil
+	}
+
+	return models.Company{}, models.ErrCompanyNotFound
+}
+
+// GetAll returns a single page of companies from the memory.
+func (c *CompaniesRepo) GetAll(ctx context.Context, q requests.CompaniesQuery) (models.CompaniesPage, error) {
+	return c.findPage(ctx, q, false)
+}
+
+// GetAllTrashed returns a single page of soft-deleted companies from the memory.
+func (c *CompaniesRepo) GetAllTrashed(ctx context.Context, q requests.CompaniesQuery) (models.CompaniesPage, error) {
+	return c.findPage(ctx, q, true)
+}
+
+// findPage returns a single page of companies matching the query,
+// it mimics the keyset pagination performed by the database.
+func (c *CompaniesRepo) findPage(ctx context.Context, q requests.CompaniesQuery, trashed bool) (models.CompaniesPage, error) {
+	page := models.CompaniesPage{Companies: []models.Company{}}
+
+	if err := ctx.Err(); err != nil {
+		return page, err
+	}
+
+	keys, err := q.SortKeys()
+	if err != nil {
+		return page, err
+	}
+
+	after, err := q.After()
+	if err != nil {
+		return page, err
+	}
+
+	tenant := models.TenantFrom(ctx)
+
+	c.mu.RLock()
+	for _, company := range c.companies {
+		if company.TenantID != tenant || company.DeletedAt.Valid != trashed || !matches(company, q.Filters) {
+			continue
+		}
+		if after.ID != 0 && compare(keys, company, after.ID, after.Values) <= 0 {
+			continue
+		}
+		page.Companies = append(page.Companies, company)
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(page.Companies, func(i, j int) bool {
+		other := q.CursorFor(page.Companies[j])
+		return compare(keys, page.Companies[i], other.ID, other.Values) < 0
+	})
+
+	if limit := q.PageSize(); len(page.Companies) > limit {
+		page.Companies = page.Companies[:limit]
+		page.HasMore = true
+		page.NextCursor = q.CursorFor(page.Companies[limit-1]).Encode()
+	}
+
+	return page, nil
+}
+
+// Search returns a single page of companies matching the search query, ranked by relevance.
+// Soft-deleted companies are removed from the index, so they are never found.
+// The index is shared by all tenants, so the hits of other tenants are skipped.
+func (c *CompaniesRepo) Search(ctx context.Context, q requests.SearchQuery) (models.SearchPage, error) {
+	page := models.SearchPage{Results: []models.SearchResult{}}
+
+	if err := ctx.Err(); err != nil {
+		return page, err
+	}
+
+	offset, err := q.Offset()
+	if err != nil {
+		return page, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	hits := c.index.Search(q.Terms())
+
+	results := make([]models.SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		if company, ok := c.owned(ctx, hit.ID); ok {
+			results = append(results, q.ResultFor(company, hit.Score))
+		}
+	}
+
+	return q.PageOf(results, offset), nil
+}
+
+// Delete soft-deletes a company from the memory.
+// If the company version is different from the given one
+// this function returns a ErrCompanyModified error,
+// if the company has subsidiaries it returns a ErrCompanyHasChildren error.
+func (c *CompaniesRepo) Delete(ctx context.Context, company models.Company) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	existing, ok := c.owned(ctx, company.ID)
+	if !ok || existing.DeletedAt.Valid || existing.Version != company.Version {
+		return models.ErrCompanyModified
+	}
+
+	if len(c.children(existing.TenantID, company.ID)) > 0 {
+		return models.ErrCompanyHasChildren
+	}
+
+	existing.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	c.companies[company.ID] = existing
+	c.index.Remove(company.ID)
+	c.writeAudit(ctx, company.ID, models.AuditActionDeleted, models.Changes{})
+	c.writeEvent(models.EventCompanyDeleted, existing)
+
+	return nil
+}
+
+// Restore restores a soft-deleted company.
+// The parent of the company is cleared if it was deleted in the meantime.
+// If a soft-deleted company with the given id does not exist
+// this function returns a ErrCompanyNotFound error.
+func (c *CompaniesRepo) Restore(ctx context.Context, id uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	existing, ok := c.owned(ctx, id)
+	if !ok || !existing.DeletedAt.Valid {
+		return models.ErrCompanyNotFound
+	}
+
+	before := existing
+	if existing.ParentID != nil && !c.active(existing.TenantID, *existing.ParentID) {
+		existing.ParentID = nil
+	}
+
+	existing.DeletedAt = gorm.DeletedAt{}
+	existing.Version++
+	c.companies[id] = existing
+	c.index.Put(id, requests.SearchDocument(existing))
+	c.writeAudit(ctx, id, models.AuditActionRestored, models.Diff(before, existing))
+	c.writeEvent(models.EventCompanyRestored, existing)
+
+	return nil
+}
+
+// Purge permanently removes companies soft-deleted before the given time, together with their contacts.
+// Companies of all tenants are removed. It returns the number of removed companies.
+func (c *CompaniesRepo) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var n int64
+	for id, company := range c.companies {
+		if company.DeletedAt.Valid && company.DeletedAt.Time.Before(before) {
+			delete(c.companies, id)
+			n++
+		}
+	}
+
+	// contacts are removed together with their company
+	for id, contact := range c.contacts {
+		if _, ok := c.companies[contact.CompanyID]; !ok {
+			delete(c.contacts, id)
+		}
+	}
+
+	return n, nil
+}
+
+// Update updates a company in the memory and increments its version.
+// It returns the changed fields.
+// If the company version is different from the given one
+// this function returns a ErrCompanyModified error.
+// If the parent company does not exist it returns a ErrParentNotFound error,
+// if the company would become its own ancestor it returns a ErrCompanyCycle error.
+func (c *CompaniesRepo) Update(ctx context.Context, company models.Company) (models.Company, models.Changes, error) {
+	if err := ctx.Err(); err != nil {
+		return company, nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	before, ok := c.owned(ctx, company.ID)
+	if !ok || before.DeletedAt.Valid || before.Version != company.Version {
+		return company, nil, models.ErrCompanyModified
+	}
+
+	company.TenantID = before.TenantID
+
+	if err := c.checkParent(company); err != nil {
+		return company, nil, err
+	}
+
+	if err := c.conflict(company); err != nil {
+		return company, nil, err
+	}
+
+	company.Version++
+	changes := models.Diff(before, company)
+	c.companies[company.ID] = company
+	c.index.Put(company.ID, requests.SearchDocument(company))
+	c.writeAudit(ctx, company.ID, models.AuditActionUpdated, changes)
+	c.writeEvent(models.EventCompanyUpdated, company)
+
+	return company, changes, nil
+}
+
+// conflict returns a ConflictError, if the company code is already used by another company of the tenant.
+// Soft-deleted companies keep their codes until they are purged, the same as in the database.
+// It must be called while holding the lock.
+func (c *CompaniesRepo) conflict(company models.Company) error {
+	for id, existing := range c.companies {
+		if id == company.ID || existing.TenantID != company.TenantID || existing.Code != company.Code {
+			continue
+		}
+		if c.perCountry && existing.Country != company.Country {
+			continue
+		}
+		return &models.ConflictError{Field: "code", ExistingID: id}
+	}
+	return nil
+}
+
+// History returns all audit records of the company, ordered from the oldest.
+// Records of companies of other tenants are not returned.
+func (c *CompaniesRepo) History(ctx context.Context, companyID uint64) ([]models.AuditRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if _, ok := c.owned(ctx, companyID); !ok {
+		return []models.AuditRecord{}, nil
+	}
+
+	records := []models.AuditRecord{}
+	for _, record := range c.audit {
+		if record.CompanyID == companyID {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// VerifyAudit reports whether the audit records of all companies form an unbroken chain,
+// which ends at the head, and none of them was tampered with.
+func (c *CompaniesRepo) VerifyAudit(ctx context.Context) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return models.VerifyAuditTrail(c.audit, c.auditHead), nil
+}
+
+// writeAudit adds a new audit record for the company change,
+// chained to the latest audit record of any company, and moves the head of the chain to it.
+// It must be called while holding the write lock.
+func (c *CompaniesRepo) writeAudit(ctx context.Context, companyID uint64, action string, changes models.Changes) {
+	meta := models.AuditMetaFrom(ctx)
+
+	c.lastAuditID++
+	record := models.AuditRecord{
+		ID:        c.lastAuditID,
+		CompanyID: companyID,
+		Action:    action,
+		Changes:   changes,
+		Actor:     meta.Actor,
+		RequestID: meta.RequestID,
+		ClientIP:  meta.ClientIP,
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+		PrevHash:  c.auditHead.Hash,
+	}
+	record.Hash = record.ComputeHash()
+
+	c.audit = append(c.audit, record)
+	c.auditHead.RecordID, c.auditHead.Hash = record.ID, record.Hash
+}
+
+// owned returns the company, if it belongs to the tenant from the context,
+// whether it is soft-deleted or not. It must be called while holding the lock.
+func (c *CompaniesRepo) owned(ctx context.Context, id uint64) (models.Company, bool) {
+	company, ok := c.companies[id]
+	if !ok || company.TenantID != models.TenantFrom(ctx) {
+		return models.Company{}, false
+	}
+	return company, true
+}
+
+// matches reports whether the company satisfies all filters.
+func matches(company models.Company, filters []requests.Filter) bool {
+	for _, f := range filters {
+		if !f.Match(company.Field(f.Field)) {
+			return false
+		}
+	}
+	return true
+}
+
+// compare compares the company with the position described by id and values,
+// which are the sort key values, except for id, in the order of keys.
+func compare(keys []requests.SortKey, company models.Company, id uint64, values []string) int {
+	next := 0
+
+	for _, k := range keys {
+		var r int
+
+		if k.Field == "id" {
+			r = compareIDs(company.ID, id)
+		} else {
+			r = strings.Compare(company.Field(k.Field), values[next])
+			next++
+		}
+
+		if k.Desc {
+			r = -r
+		}
+
+		if r != 0 {
+			return r
+		}
+	}
+
+	return 0
+}
+
+// compareIDs compares two ids.
+func compareIDs(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
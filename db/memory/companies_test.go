@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/db/dbtest"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestCompaniesConformance(t *testing.T) {
+	suite.Run(t, &dbtest.CompaniesSuite{NewRepo: func() dbtest.Repo {
+		return NewCompaniesRepo(&configs.Config{})
+	}})
+}
+
+func TestCompaniesConformanceCodePerCountry(t *testing.T) {
+	cfg := configs.Config{}
+	cfg.Companies.UniqueCodePerCountry = true
+
+	suite.Run(t, &dbtest.CompaniesSuite{CodePerCountry: true, NewRepo: func() dbtest.Repo {
+		return NewCompaniesRepo(&cfg)
+	}})
+}
+
+func TestWebhooksConformance(t *testing.T) {
+	cfg := configs.Config{}
+	cfg.Webhooks.URLs = []string{"https://example.com/hook"}
+
+	suite.Run(t, &dbtest.WebhooksSuite{
+		NewRepo:            func() dbtest.OutboxRepo { return NewCompaniesRepo(&cfg) },
+		NewRepoWithoutURLs: func() dbtest.OutboxRepo { return NewCompaniesRepo(&configs.Config{}) },
+	})
+}
+
+func TestContactsConformance(t *testing.T) {
+	suite.Run(t, &dbtest.ContactsSuite{NewRepo: func() dbtest.ContactsRepo {
+		return NewCompaniesRepo(&configs.Config{})
+	}})
+}
+
+func TestAPIKeysConformance(t *testing.T) {
+	suite.Run(t, &dbtest.APIKeysSuite{NewRepo: func() dbtest.APIKeysRepo {
+		return NewCompaniesRepo(&configs.Config{})
+	}})
+}
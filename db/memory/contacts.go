@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+)
+
+// CreateContact adds a new contact of the company to the memory.
+// If the company does not exist this function returns a ErrCompanyNotFound error.
+func (c *CompaniesRepo) CreateContact(ctx context.Context, contact models.Contact) (models.Contact, error) {
+	if err := ctx.Err(); err != nil {
+		return contact, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	contact.TenantID = models.TenantFrom(ctx)
+	if !c.active(contact.TenantID, contact.CompanyID) {
+		return contact, models.ErrCompanyNotFound
+	}
+
+	c.lastContactID++
+	contact.ID = c.lastContactID
+	c.contacts[contact.ID] = contact
+
+	return contact, nil
+}
+
+// GetContact returns a single contact of the company.
+// If the contact does not exist this function returns a ErrContactNotFound error.
+func (c *CompaniesRepo) GetContact(ctx context.Context, companyID uint64, id uint64) (models.Contact, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Contact{}, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	contact, ok := c.contacts[id]
+	if !ok || contact.TenantID != models.TenantFrom(ctx) || contact.CompanyID != companyID {
+		return models.Contact{}, models.ErrContactNotFound
+	}
+
+	return contact, nil
+}
+
+// GetContacts returns all contacts of the company, ordered by id.
+func (c *CompaniesRepo) GetContacts(ctx context.Context, companyID uint64) ([]models.Contact, error) {
+	contacts := []models.Contact{}
+
+	if err := ctx.Err(); err != nil {
+		return contacts, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	tenant := models.TenantFrom(ctx)
+
+	for _, contact := range c.contacts {
+		if contact.TenantID == tenant && contact.CompanyID == companyID {
+			contacts = append(contacts, contact)
+		}
+	}
+
+	sort.Slice(contacts, func(i, j int) bool { return contacts[i].ID < contacts[j].ID })
+	return contacts, nil
+}
+
+// UpdateContact updates the contact of the company.
+// If the contact does not exist this function returns a ErrContactNotFound error.
+func (c *CompaniesRepo) UpdateContact(ctx context.Context, contact models.Contact) (models.Contact, error) {
+	if err := ctx.Err(); err != nil {
+		return contact, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	contact.TenantID = models.TenantFrom(ctx)
+	if existing, ok := c.contacts[contact.ID]; !ok || existing.TenantID != contact.TenantID || existing.CompanyID != contact.CompanyID {
+		return contact, models.ErrContactNotFound
+	}
+
+	c.contacts[contact.ID] = contact
+	return contact, nil
+}
+
+// DeleteContact removes the contact of the company from the memory.
+// If the contact does not exist this function returns a ErrContactNotFound error.
+func (c *CompaniesRepo) DeleteContact(ctx context.Context, companyID uint64, id uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if contact, ok := c.contacts[id]; !ok || contact.TenantID != models.TenantFrom(ctx) || contact.CompanyID != companyID {
+		return models.ErrContactNotFound
+	}
+
+	delete(c.contacts, id)
+	return nil
+}
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+)
+
+// Children returns the direct subsidiaries of the company, ordered by id.
+func (c *CompaniesRepo) Children(ctx context.Context, id uint64) ([]models.Company, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.children(models.TenantFrom(ctx), id), nil
+}
+
+// Ancestors returns the parent of the company, the parent of the parent and so on, up to the root.
+// If the company does not exist this function returns a ErrCompanyNotFound error.
+func (c *CompaniesRepo) Ancestors(ctx context.Context, id uint64) ([]models.Company, error) {
+	ancestors := []models.Company{}
+
+	if err := ctx.Err(); err != nil {
+		return ancestors, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	tenant := models.TenantFrom(ctx)
+	if !c.active(tenant, id) {
+		return ancestors, models.ErrCompanyNotFound
+	}
+
+	seen := map[uint64]bool{id: true}
+
+	for company := c.companies[id]; company.ParentID != nil && !seen[*company.ParentID]; {
+		seen[*company.ParentID] = true
+
+		if !c.active(tenant, *company.ParentID) {
+			break
+		}
+
+		company = c.companies[*company.ParentID]
+		ancestors = append(ancestors, company)
+	}
+
+	return ancestors, nil
+}
+
+// Descendants returns all subsidiaries of the company, level by level,
+// companies of the same level are ordered by id.
+func (c *CompaniesRepo) Descendants(ctx context.Context, id uint64) ([]models.Company, error) {
+	descendants := []models.Company{}
+
+	if err := ctx.Err(); err != nil {
+		return descendants, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	tenant := models.TenantFrom(ctx)
+	seen := map[uint64]bool{id: true}
+
+	for level := []uint64{id}; len(level) > 0; {
+		var children []models.Company
+		for _, parentID := range level {
+			children = append(children, c.children(tenant, parentID)...)
+		}
+
+		sort.Slice(children, func(i, j int) bool { return children[i].ID < children[j].ID })
+
+		level = nil
+		for _, child := range children {
+			if !seen[child.ID] {
+				seen[child.ID] = true
+				level = append(level, child.ID)
+				descendants = append(descendants, child)
+			}
+		}
+	}
+
+	return descendants, nil
+}
+
+// children returns the active subsidiaries of the company of the tenant, ordered by id.
+// It must be called while holding the lock.
+func (c *CompaniesRepo) children(tenant string, id uint64) []models.Company {
+	children := []models.Company{}
+
+	for _, company := range c.companies {
+		if company.TenantID == tenant && !company.DeletedAt.Valid && company.ParentID != nil && *company.ParentID == id {
+			children = append(children, company)
+		}
+	}
+
+	sort.Slice(children, func(i, j int) bool { return children[i].ID < children[j].ID })
+	return children
+}
+
+// active reports whether the company of the tenant exists and is not soft-deleted.
+// It must be called while holding the lock.
+func (c *CompaniesRepo) active(tenant string, id uint64) bool {
+	company, ok := c.companies[id]
+	return ok && company.TenantID == tenant && !company.DeletedAt.Valid
+}
+
+// checkParent returns a ErrParentNotFound error if the parent of the company does not exist in the tenant,
+// and a ErrCompanyCycle error if the company is one of the ancestors of its parent.
+// It must be called while holding the lock.
+func (c *CompaniesRepo) checkParent(company models.Company) error {
+	if company.ParentID != nil && !c.active(company.TenantID, *company.ParentID) {
+		return models.ErrParentNotFound
+	}
+
+	seen := map[uint64]bool{}
+
+	for id := company.ParentID; id != nil && c.active(company.TenantID, *id); id = c.companies[*id].ParentID {
+		if *id == company.ID || seen[*id] {
+			return models.ErrCompanyCycle
+		}
+		seen[*id] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+)
+
+// writeEvent adds a new outbox event for the company change.
+// Nothing is written when no webhook URLs are configured, since the event would never be delivered.
+// It must be called while holding the write lock.
+func (c *CompaniesRepo) writeEvent(eventType string, company models.Company) {
+	if !c.webhooks {
+		return
+	}
+
+	// the company is always encodable, so the error is not possible
+	event, _ := models.NewEvent(eventType, company)
+
+	c.lastEventID++
+	event.ID = c.lastEventID
+	c.events = append(c.events, event)
+}
+
+// DispatchEvents creates a pending delivery of every new outbox event for every URL,
+// and marks the events as dispatched. It returns the number of dispatched events.
+func (c *CompaniesRepo) DispatchEvents(ctx context.Context, urls []string, limit int) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for i := range c.events {
+		if n == limit {
+			break
+		}
+		if c.events[i].DispatchedAt != nil {
+			continue
+		}
+
+		now := time.Now().UTC()
+		c.events[i].DispatchedAt = &now
+
+		for _, url := range urls {
+			c.lastDeliveryID++
+			c.deliveries[c.lastDeliveryID] = models.Delivery{
+				ID:            c.lastDeliveryID,
+				EventID:       c.events[i].ID,
+				TenantID:      c.events[i].TenantID,
+				URL:           url,
+				Status:        models.DeliveryPending,
+				NextAttemptAt: now,
+				CreatedAt:     now,
+			}
+		}
+
+		n++
+	}
+
+	return n, nil
+}
+
+// ClaimDeliveries returns pending deliveries due at the given time, together with their events.
+// Claimed deliveries are postponed by the lease, so they are not claimed again until then.
+func (c *CompaniesRepo) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Delivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	claimed := []models.Delivery{}
+	for _, delivery := range c.deliveries {
+		if delivery.Status == models.DeliveryPending && !delivery.NextAttemptAt.After(now) {
+			claimed = append(claimed, c.withEvent(delivery))
+		}
+	}
+
+	sort.Slice(claimed, func(i, j int) bool {
+		if !claimed[i].NextAttemptAt.Equal(claimed[j].NextAttemptAt) {
+			return claimed[i].NextAttemptAt.Before(claimed[j].NextAttemptAt)
+		}
+		return claimed[i].ID < claimed[j].ID
+	})
+
+	if len(claimed) > limit {
+		claimed = claimed[:limit]
+	}
+
+	for _, delivery := range claimed {
+		delivery.NextAttemptAt = now.Add(lease)
+		delivery.Event = models.Event{}
+		c.deliveries[delivery.ID] = delivery
+	}
+
+	return claimed, nil
+}
+
+// SaveDelivery saves the outcome of the delivery attempt.
+func (c *CompaniesRepo) SaveDelivery(ctx context.Context, delivery models.Delivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	existing, ok := c.deliveries[delivery.ID]
+	if !ok {
+		return nil
+	}
+
+	existing.Status = delivery.Status
+	existing.Attempts = delivery.Attempts
+	existing.NextAttemptAt = delivery.NextAttemptAt
+	existing.LastError = delivery.LastError
+	existing.DeliveredAt = delivery.DeliveredAt
+	c.deliveries[delivery.ID] = existing
+
+	return nil
+}
+
+// DeadDeliveries returns all deliveries of the tenant, which exhausted their attempts, ordered from the oldest.
+func (c *CompaniesRepo) DeadDeliveries(ctx context.Context) ([]models.Delivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	tenant := models.TenantFrom(ctx)
+
+	deliveries := []models.Delivery{}
+	for _, delivery := range c.deliveries {
+		if delivery.TenantID == tenant && delivery.Status == models.DeliveryDead {
+			deliveries = append(deliveries, c.withEvent(delivery))
+		}
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].ID < deliveries[j].ID })
+
+	return deliveries, nil
+}
+
+// Redeliver schedules the delivery to be sent again immediately, with all attempts available.
+// If a delivery with the given id does not exist for the tenant
+// this function returns a ErrDeliveryNotFound error.
+func (c *CompaniesRepo) Redeliver(ctx context.Context, id uint64) (models.Delivery, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Delivery{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delivery, ok := c.deliveries[id]
+	if !ok || delivery.TenantID != models.TenantFrom(ctx) {
+		return models.Delivery{}, models.ErrDeliveryNotFound
+	}
+
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now().UTC()
+	delivery.LastError = ""
+	c.deliveries[id] = delivery
+
+	return c.withEvent(delivery), nil
+}
+
+// withEvent returns the delivery together with its event.
+// Events are appended in the order of their ids, so they are binary searched.
+// It must be called while holding the lock.
+func (c *CompaniesRepo) withEvent(delivery models.Delivery) models.Delivery {
+	i := sort.Search(len(c.events), func(i int) bool { return c.events[i].ID >= delivery.EventID })
+	if i < len(c.events) && c.events[i].ID == delivery.EventID {
+		delivery.Event = c.events[i]
+	}
+	return delivery
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	gorm_logrus "github.com/onrik/gorm-logrus"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Supported database drivers.
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+)
+
+// Open opens a connection to the configured database.
+// Memory driver is not backed by the database, so it can not be opened.
+func Open(cfg *configs.Config) (*gorm.DB, error) {
+	return open(cfg.Database.Driver, cfg.Database.Dsn, &gorm.Config{Logger: gorm_logrus.New()})
+}
+
+// open opens a connection to the database with the given driver and DSN.
+func open(driver, dsn string, config *gorm.Config) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+
+	switch driver {
+	case DriverMySQL:
+		dialector = mysql.Open(dsn)
+	case DriverPostgres:
+		dialector = postgres.Open(dsn)
+	case DriverSQLite:
+		dialector = sqlite.Open(dsn)
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", driver)
+	}
+
+	return gorm.Open(dialector, config)
+}
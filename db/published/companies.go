@@ -0,0 +1,185 @@
+// Package published provides the companies repository, which publishes the company changes to the message broker.
+package published
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Storage defines the repository decorated by the publisher.
+type Storage interface {
+	handlers.CompaniesRepo
+	Purge(context.Context, time.Time) (int64, error)
+}
+
+// Publisher defines the message broker used to publish the events.
+type Publisher interface {
+	Publish(ctx context.Context, subject string, data []byte) error
+}
+
+// CompaniesRepo publishes an event after every successful write to the storage,
+// other methods are passed to the storage. Events of the writes made inside
+// a transaction are published once the transaction is committed.
+// The write is not reverted if the event cannot be published, the failure is logged.
+type CompaniesRepo struct {
+	Storage
+	publisher Publisher
+	prefix    string
+}
+
+// NewCompaniesRepo creates a new instance of the CompaniesRepo.
+func NewCompaniesRepo(cfg *configs.Config, storage Storage, publisher Publisher) *CompaniesRepo {
+	return &CompaniesRepo{Storage: storage, publisher: publisher, prefix: cfg.Events.SubjectPrefix}
+}
+
+// Create adds a new company to the storage.
+func (c *CompaniesRepo) Create(ctx context.Context, company models.Company) (models.Company, error) {
+	events := &[]models.CompanyEvent{}
+	company, err := create(ctx, c.Storage, events, company)
+	c.publish(err, *events)
+	return company, err
+}
+
+// Update updates a company in the storage.
+func (c *CompaniesRepo) Update(ctx context.Context, company models.Company) (models.Company, models.Changes, error) {
+	events := &[]models.CompanyEvent{}
+	company, changes, err := update(ctx, c.Storage, events, company)
+	c.publish(err, *events)
+	return company, changes, err
+}
+
+// Delete soft-deletes a company from the storage.
+func (c *CompaniesRepo) Delete(ctx context.Context, company models.Company) error {
+	events := &[]models.CompanyEvent{}
+	err := remove(ctx, c.Storage, events, company)
+	c.publish(err, *events)
+	return err
+}
+
+// Restore restores a soft-deleted company.
+func (c *CompaniesRepo) Restore(ctx context.Context, id uint64) error {
+	events := &[]models.CompanyEvent{}
+	err := restore(ctx, c.Storage, events, id)
+	c.publish(err, *events)
+	return err
+}
+
+// Transaction runs fn with the storage bound to a transaction.
+// Events are published only if the transaction is committed.
+func (c *CompaniesRepo) Transaction(ctx context.Context, fn func(handlers.CompaniesRepo) error) error {
+	events := &[]models.CompanyEvent{}
+
+	err := c.Storage.Transaction(ctx, func(tx handlers.CompaniesRepo) error {
+		return fn(&txRepo{CompaniesRepo: tx, events: events})
+	})
+
+	c.publish(err, *events)
+	return err
+}
+
+// publish publishes the events, unless the write failed.
+// The write is done, so the events are published even if the request is cancelled.
+func (c *CompaniesRepo) publish(err error, events []models.CompanyEvent) {
+	if err != nil {
+		return
+	}
+
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err == nil {
+			err = c.publisher.Publish(context.Background(), c.prefix+"."+event.Type, data)
+		}
+
+		if err != nil {
+			log.WithFields(log.Fields{"id": event.ID, "type": event.Type, "company_id": event.Company.ID, "error": err}).Error("Cannot publish event")
+		}
+	}
+}
+
+// create adds a new company to the repository, and records the event.
+func create(ctx context.Context, repo handlers.CompaniesRepo, events *[]models.CompanyEvent, company models.Company) (models.Company, error) {
+	company, err := repo.Create(ctx, company)
+	if err == nil {
+		*events = append(*events, models.NewCompanyEvent(models.EventCompanyCreated, company, models.Diff(models.Company{}, company)))
+	}
+	return company, err
+}
+
+// update updates a company in the repository, and records the event
+// with the changed fields found by the repository, in the same transaction as the update.
+func update(ctx context.Context, repo handlers.CompaniesRepo, events *[]models.CompanyEvent, company models.Company) (models.Company, models.Changes, error) {
+	company, changes, err := repo.Update(ctx, company)
+	if err == nil {
+		*events = append(*events, models.NewCompanyEvent(models.EventCompanyUpdated, company, changes))
+	}
+	return company, changes, err
+}
+
+// remove soft-deletes a company from the repository, and records the event.
+func remove(ctx context.Context, repo handlers.CompaniesRepo, events *[]models.CompanyEvent, company models.Company) error {
+	err := repo.Delete(ctx, company)
+	if err == nil {
+		company.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+		*events = append(*events, models.NewCompanyEvent(models.EventCompanyDeleted, company, nil))
+	}
+	return err
+}
+
+// restore restores a soft-deleted company in the repository, and records the event.
+func restore(ctx context.Context, repo handlers.CompaniesRepo, events *[]models.CompanyEvent, id uint64) error {
+	if err := repo.Restore(ctx, id); err != nil {
+		return err
+	}
+
+	company, err := repo.Get(ctx, id)
+	if err != nil {
+		log.WithFields(log.Fields{"company_id": id, "error": err}).Error("Cannot load restored company")
+		return nil
+	}
+
+	*events = append(*events, models.NewCompanyEvent(models.EventCompanyRestored, company, nil))
+	return nil
+}
+
+// txRepo records events of the writes made inside the transaction.
+// Events of a nested transaction are kept only if it succeeds.
+type txRepo struct {
+	handlers.CompaniesRepo
+	events *[]models.CompanyEvent
+}
+
+func (t *txRepo) Create(ctx context.Context, company models.Company) (models.Company, error) {
+	return create(ctx, t.CompaniesRepo, t.events, company)
+}
+
+func (t *txRepo) Update(ctx context.Context, company models.Company) (models.Company, models.Changes, error) {
+	return update(ctx, t.CompaniesRepo, t.events, company)
+}
+
+func (t *txRepo) Delete(ctx context.Context, company models.Company) error {
+	return remove(ctx, t.CompaniesRepo, t.events, company)
+}
+
+func (t *txRepo) Restore(ctx context.Context, id uint64) error {
+	return restore(ctx, t.CompaniesRepo, t.events, id)
+}
+
+func (t *txRepo) Transaction(ctx context.Context, fn func(handlers.CompaniesRepo) error) error {
+	nested := &[]models.CompanyEvent{}
+
+	err := t.CompaniesRepo.Transaction(ctx, func(tx handlers.CompaniesRepo) error {
+		return fn(&txRepo{CompaniesRepo: tx, events: nested})
+	})
+
+	if err == nil {
+		*t.events = append(*t.events, *nested...)
+	}
+	return err
+}
@@ -0,0 +1,178 @@
+package published
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/db/dbtest"
+	"github.com/brokeyourbike/xm-golang-exercise/db/memory"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// message is a message published to the recorder.
+type message struct {
+	subject string
+	event   models.CompanyEvent
+}
+
+// recorder records the published messages.
+type recorder struct {
+	mu       sync.Mutex
+	messages []message
+	err      error
+}
+
+func (r *recorder) Publish(_ context.Context, subject string, data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var event models.CompanyEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return err
+	}
+
+	r.messages = append(r.messages, message{subject: subject, event: event})
+	return r.err
+}
+
+func newTestRepo() (*CompaniesRepo, *recorder) {
+	cfg := configs.Config{}
+	cfg.Events.SubjectPrefix = "events"
+
+	publisher := &recorder{}
+	return NewCompaniesRepo(&cfg, memory.NewCompaniesRepo(&cfg), publisher), publisher
+}
+
+func TestCompaniesConformance(t *testing.T) {
+	suite.Run(t, &dbtest.CompaniesSuite{NewRepo: func() dbtest.Repo {
+		repo, _ := newTestRepo()
+		return repo
+	}})
+}
+
+func TestItPublishesCompanyChanges(t *testing.T) {
+	ctx := context.Background()
+	repo, publisher := newTestRepo()
+
+	company, err := repo.Create(ctx, models.Company{Name: "acme", Code: "c1", Country: "CY"})
+	require.NoError(t, err)
+
+	company.Name = "acme ltd"
+	company, _, err = repo.Update(ctx, company)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Delete(ctx, company))
+	require.NoError(t, repo.Restore(ctx, company.ID))
+
+	require.Len(t, publisher.messages, 4)
+
+	subjects := []string{}
+	for _, m := range publisher.messages {
+		subjects = append(subjects, m.subject)
+		assert.Equal(t, models.CompanyEventSchemaVersion, m.event.SchemaVersion)
+		assert.NotEmpty(t, m.event.ID)
+		assert.Equal(t, company.ID, m.event.Company.ID)
+	}
+	assert.Equal(t, []string{"events.company.created", "events.company.updated", "events.company.deleted", "events.company.restored"}, subjects)
+
+	created := publisher.messages[0].event
+	assert.Equal(t, models.CompanySnapshot{ID: company.ID, Name: "acme", Code: "c1", Country: "CY", Version: 1}, created.Company)
+	assert.Equal(t, models.Changes{"name": {To: "acme"}, "code": {To: "c1"}, "country": {To: "CY"}}, created.Changes)
+
+	updated := publisher.messages[1].event
+	assert.Equal(t, uint64(2), updated.Company.Version)
+	assert.Equal(t, models.Changes{"name": {From: "acme", To: "acme ltd"}}, updated.Changes)
+
+	deleted := publisher.messages[2].event
+	assert.True(t, deleted.Company.Deleted)
+	assert.Empty(t, deleted.Changes)
+
+	restored := publisher.messages[3].event
+	assert.False(t, restored.Company.Deleted)
+	assert.Equal(t, uint64(3), restored.Company.Version)
+}
+
+func TestItDoesNotPublishFailedWrites(t *testing.T) {
+	ctx := context.Background()
+	repo, publisher := newTestRepo()
+
+	company, err := repo.Create(ctx, models.Company{Name: "acme", Code: "c1"})
+	require.NoError(t, err)
+
+	_, err = repo.Create(ctx, models.Company{Name: "beta", Code: "c1"})
+	assert.ErrorIs(t, err, models.ErrCompanyConflict)
+
+	_, _, err = repo.Update(ctx, models.Company{ID: company.ID, Name: "beta", Version: 5})
+	assert.ErrorIs(t, err, models.ErrCompanyModified)
+
+	_, _, err = repo.Update(ctx, models.Company{ID: 99, Name: "beta", Version: 1})
+	assert.ErrorIs(t, err, models.ErrCompanyModified)
+
+	assert.ErrorIs(t, repo.Restore(ctx, company.ID), models.ErrCompanyNotFound)
+
+	assert.Len(t, publisher.messages, 1)
+}
+
+func TestItPublishesCommittedTransaction(t *testing.T) {
+	ctx := context.Background()
+	repo, publisher := newTestRepo()
+	errNested := errors.New("nested")
+
+	err := repo.Transaction(ctx, func(tx handlers.CompaniesRepo) error {
+		if _, err := tx.Create(ctx, models.Company{Name: "acme", Code: "c1"}); err != nil {
+			return err
+		}
+
+		// events of the rolled back nested transaction are discarded
+		err := tx.Transaction(ctx, func(tx handlers.CompaniesRepo) error {
+			if _, err := tx.Create(ctx, models.Company{Name: "beta", Code: "c2"}); err != nil {
+				return err
+			}
+			return errNested
+		})
+		assert.ErrorIs(t, err, errNested)
+
+		assert.Empty(t, publisher.messages, "events are published after the commit")
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.Len(t, publisher.messages, 1)
+	assert.Equal(t, "acme", publisher.messages[0].event.Company.Name)
+}
+
+func TestItDoesNotPublishRolledBackTransaction(t *testing.T) {
+	ctx := context.Background()
+	repo, publisher := newTestRepo()
+	errRollback := errors.New("rollback")
+
+	err := repo.Transaction(ctx, func(tx handlers.CompaniesRepo) error {
+		if _, err := tx.Create(ctx, models.Company{Name: "acme", Code: "c1"}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+
+	assert.Empty(t, publisher.messages)
+}
+
+func TestItKeepsWriteIfEventCannotBePublished(t *testing.T) {
+	ctx := context.Background()
+	repo, publisher := newTestRepo()
+	publisher.err = errors.New("broker is down")
+
+	company, err := repo.Create(ctx, models.Company{Name: "acme", Code: "c1"})
+	require.NoError(t, err)
+
+	_, err = repo.Get(ctx, company.ID)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/pagination"
+	"gorm.io/gorm"
+)
+
+// likeEscaper escapes LIKE wildcards, `!` is used as an escape character
+// because backslash is treated differently by the database engines.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// tenantScope limits the query to the rows of the tenant from the context.
+func tenantScope(ctx context.Context) func(*gorm.DB) *gorm.DB {
+	tenant := models.TenantFrom(ctx)
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("tenant_id = ?", tenant)
+	}
+}
+
+// applyFilters narrows the query down with the given filters.
+// Field names are validated by the requests package, so they are safe to be used as columns.
+func applyFilters(tx *gorm.DB, filters []requests.Filter) *gorm.DB {
+	for _, f := range filters {
+		switch f.Op {
+		case requests.OpEq:
+			tx = tx.Where(fmt.Sprintf("%s = ?", f.Field), f.Values[0])
+		case requests.OpNe:
+			tx = tx.Where(fmt.Sprintf("%s <> ?", f.Field), f.Values[0])
+		case requests.OpIn:
+			tx = tx.Where(fmt.Sprintf("%s IN ?", f.Field), f.Values)
+		case requests.OpPrefix:
+			tx = tx.Where(fmt.Sprintf("%s LIKE ? ESCAPE '!'", f.Field), likeEscaper.Replace(f.Values[0])+"%")
+		case requests.OpContains:
+			tx = tx.Where(fmt.Sprintf("%s LIKE ? ESCAPE '!'", f.Field), "%"+likeEscaper.Replace(f.Values[0])+"%")
+		case requests.OpIEq:
+			tx = tx.Where(fmt.Sprintf("LOWER(%s) = LOWER(?)", f.Field), f.Values[0])
+		case requests.OpIPrefix:
+			tx = tx.Where(fmt.Sprintf("LOWER(%s) LIKE LOWER(?) ESCAPE '!'", f.Field), likeEscaper.Replace(f.Values[0])+"%")
+		case requests.OpIContains:
+			tx = tx.Where(fmt.Sprintf("LOWER(%s) LIKE LOWER(?) ESCAPE '!'", f.Field), "%"+likeEscaper.Replace(f.Values[0])+"%")
+		}
+	}
+	return tx
+}
+
+// applyOrder sorts the query by the given keys.
+func applyOrder(tx *gorm.DB, keys []requests.SortKey) *gorm.DB {
+	for _, k := range keys {
+		if k.Desc {
+			tx = tx.Order(k.Field + " DESC")
+		} else {
+			tx = tx.Order(k.Field)
+		}
+	}
+	return tx
+}
+
+// applyCursor narrows the query down to the rows placed after the cursor.
+// For keys (a, b, id) it builds `a > ? OR a = ? AND b > ? OR a = ? AND b = ? AND id > ?`,
+// where the comparison is flipped for the descending keys.
+// gorm wraps it in parentheses when combined with the other conditions.
+func applyCursor(tx *gorm.DB, keys []requests.SortKey, after pagination.Cursor) *gorm.DB {
+	if after.ID == 0 {
+		return tx
+	}
+
+	values, next := make([]interface{}, len(keys)), 0
+	for i, k := range keys {
+		if k.Field == "id" {
+			values[i] = after.ID
+			continue
+		}
+		values[i] = after.Values[next]
+		next++
+	}
+
+	var (
+		ors  []string
+		args []interface{}
+	)
+
+	for i, k := range keys {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, keys[j].Field+" = ?")
+			args = append(args, values[j])
+		}
+
+		op := ">"
+		if k.Desc {
+			op = "<"
+		}
+
+		ands = append(ands, fmt.Sprintf("%s %s ?", k.Field, op))
+		args = append(args, values[i])
+		ors = append(ors, strings.Join(ands, " AND "))
+	}
+
+	return tx.Where(strings.Join(ors, " OR "), args...)
+}
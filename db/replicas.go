@@ -0,0 +1,171 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	gorm_logrus "github.com/onrik/gorm-logrus"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// pingTimeout limits the time of a single replica health check.
+const pingTimeout = time.Second
+
+// Replicas routes reads between the read replicas and the primary database.
+// Reads are sent to the healthy replicas in turns, or to the primary when none of them is healthy.
+// Reads of a client stick to the primary for a short time after the client writes,
+// so the client sees its own writes despite the replication lag.
+// Clients are identified by the authenticated principal and the IP address, see clientKey.
+type Replicas struct {
+	next     uint64 // first field, so it is aligned for the atomic operations
+	primary  *gorm.DB
+	dbs      []*gorm.DB
+	sticky   time.Duration
+	interval time.Duration
+
+	mu       sync.Mutex
+	healthy  []bool
+	checked  time.Time
+	checking bool
+	writes   map[string]time.Time
+	pruned   time.Time
+}
+
+// OpenReplicas opens connections to the configured read replicas.
+// Replicas are not required to be available, unavailable ones are skipped until they recover.
+func OpenReplicas(primary *gorm.DB, cfg *configs.Config) (*Replicas, error) {
+	dbs := make([]*gorm.DB, 0, len(cfg.Database.ReplicaDsns))
+
+	for i, dsn := range cfg.Database.ReplicaDsns {
+		orm, err := open(cfg.Database.Driver, dsn, &gorm.Config{Logger: gorm_logrus.New(), DisableAutomaticPing: true})
+		if err != nil {
+			return nil, fmt.Errorf("cannot open replica %d: %w", i, err)
+		}
+		dbs = append(dbs, orm)
+	}
+
+	return NewReplicas(primary, dbs, cfg), nil
+}
+
+// NewReplicas creates a new instance of the Replicas.
+func NewReplicas(primary *gorm.DB, dbs []*gorm.DB, cfg *configs.Config) *Replicas {
+	healthy := make([]bool, len(dbs))
+	for i := range healthy {
+		healthy[i] = true
+	}
+
+	return &Replicas{
+		primary:  primary,
+		dbs:      dbs,
+		sticky:   time.Second * time.Duration(cfg.Database.ReplicaStickySeconds),
+		interval: time.Second * time.Duration(cfg.Database.ReplicaHealthCheckSeconds),
+		healthy:  healthy,
+		writes:   make(map[string]time.Time),
+	}
+}
+
+// Check pings the replicas and records which of them are healthy.
+func (r *Replicas) Check(ctx context.Context) {
+	healthy := make([]bool, len(r.dbs))
+
+	for i, orm := range r.dbs {
+		healthy[i] = ping(ctx, orm) == nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range healthy {
+		if healthy[i] != r.healthy[i] {
+			log.WithField("replica", i).WithField("healthy", healthy[i]).Warn("replica health changed")
+		}
+	}
+
+	r.healthy, r.checked, r.checking = healthy, time.Now(), false
+}
+
+// reader returns the database used for reads of the client.
+func (r *Replicas) reader(ctx context.Context) *gorm.DB {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if key := clientKey(ctx); key != "" && time.Since(r.writes[key]) < r.sticky {
+		return r.primary
+	}
+
+	if !r.checking && time.Since(r.checked) >= r.interval {
+		r.checking = true
+		go r.Check(context.Background())
+	}
+
+	healthy := make([]*gorm.DB, 0, len(r.dbs))
+	for i, orm := range r.dbs {
+		if r.healthy[i] {
+			healthy = append(healthy, orm)
+		}
+	}
+
+	if len(healthy) == 0 {
+		return r.primary
+	}
+
+	return healthy[atomic.AddUint64(&r.next, 1)%uint64(len(healthy))]
+}
+
+// wrote records that the client wrote to the primary.
+func (r *Replicas) wrote(ctx context.Context) {
+	key := clientKey(ctx)
+	if key == "" || r.sticky == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.writes[key] = now
+
+	if now.Sub(r.pruned) < r.sticky {
+		return
+	}
+
+	for k, t := range r.writes {
+		if now.Sub(t) >= r.sticky {
+			delete(r.writes, k)
+		}
+	}
+	r.pruned = now
+}
+
+// ping checks that the database is reachable.
+func ping(ctx context.Context, orm *gorm.DB) error {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	sqlDB, err := orm.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.PingContext(ctx)
+}
+
+// clientKey identifies the client making the request, by the principal and the IP address.
+// The anonymous clients are identified by the IP address only, since their actor is not verified.
+// Background jobs are not identified, so their reads never stick to the primary.
+func clientKey(ctx context.Context) string {
+	meta := models.AuditMetaFrom(ctx)
+	if meta.ClientIP == "" {
+		return ""
+	}
+	if principal, ok := models.PrincipalFrom(ctx); ok {
+		return principal.Subject + "@" + meta.ClientIP
+	}
+	return meta.ClientIP
+}
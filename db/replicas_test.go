@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// openTestReplicas opens the empty primary and replica databases,
+// so the reads sent to the replica do not find companies written to the primary.
+func openTestReplicas(t *testing.T) (*CompaniesRepo, *Replicas) {
+	dir := t.TempDir()
+
+	cfg := configs.Config{}
+	cfg.Database.Driver = DriverSQLite
+	cfg.Database.ReplicaStickySeconds = 60
+	cfg.Database.ReplicaHealthCheckSeconds = 60
+
+	cfg.Database.Dsn = filepath.Join(dir, "replica.db")
+	replica := openTestDatabase(t, &cfg)
+
+	cfg.Database.Dsn = filepath.Join(dir, "primary.db")
+	primary := openTestDatabase(t, &cfg)
+
+	replicas := NewReplicas(primary.db, []*gorm.DB{replica.db}, &cfg)
+	replicas.checked = time.Now()
+
+	return primary.WithReplicas(replicas), replicas
+}
+
+func clientCtx(subject string) context.Context {
+	ctx := models.WithPrincipal(context.Background(), models.Principal{Subject: subject})
+	return models.WithAuditMeta(ctx, models.AuditMeta{Actor: subject, ClientIP: "127.0.0.1"})
+}
+
+func TestReplicasServeReads(t *testing.T) {
+	repo, _ := openTestReplicas(t)
+
+	company, err := repo.Create(clientCtx("john"), models.Company{Name: "acme", Code: "c1"})
+	require.NoError(t, err)
+
+	_, err = repo.Get(clientCtx("jane"), company.ID)
+	assert.ErrorIs(t, err, models.ErrCompanyNotFound)
+
+	page, err := repo.GetAll(clientCtx("jane"), requests.CompaniesQuery{})
+	require.NoError(t, err)
+	assert.Empty(t, page.Companies)
+
+	_, err = repo.Get(context.Background(), company.ID)
+	assert.ErrorIs(t, err, models.ErrCompanyNotFound)
+}
+
+func TestReplicasStickToPrimaryAfterWrite(t *testing.T) {
+	repo, replicas := openTestReplicas(t)
+
+	company, err := repo.Create(clientCtx("john"), models.Company{Name: "acme", Code: "c1"})
+	require.NoError(t, err)
+
+	found, err := repo.Get(clientCtx("john"), company.ID)
+	require.NoError(t, err)
+	assert.Equal(t, company.ID, found.ID)
+
+	page, err := repo.GetAll(clientCtx("john"), requests.CompaniesQuery{})
+	require.NoError(t, err)
+	assert.Len(t, page.Companies, 1)
+
+	replicas.writes[clientKey(clientCtx("john"))] = time.Now().Add(-time.Minute)
+
+	_, err = repo.Get(clientCtx("john"), company.ID)
+	assert.ErrorIs(t, err, models.ErrCompanyNotFound)
+}
+
+func TestReplicasStickToPrimaryForSamePrincipal(t *testing.T) {
+	repo, _ := openTestReplicas(t)
+
+	principal := models.WithPrincipal(context.Background(), models.Principal{Subject: "john"})
+	write := models.WithAuditMeta(principal, models.AuditMeta{Actor: "john", ClientIP: "127.0.0.1"})
+	read := models.WithAuditMeta(principal, models.AuditMeta{Actor: "apikey:2", ClientIP: "127.0.0.1"})
+
+	company, err := repo.Create(write, models.Company{Name: "acme", Code: "c1"})
+	require.NoError(t, err)
+
+	// the reads of the principal stick to the primary, whatever actor is recorded
+	found, err := repo.Get(read, company.ID)
+	require.NoError(t, err)
+	assert.Equal(t, company.ID, found.ID)
+
+	_, err = repo.Get(clientCtx("jane"), company.ID)
+	assert.ErrorIs(t, err, models.ErrCompanyNotFound)
+}
+
+func TestReplicasIdentifyAnonymousClientsByIP(t *testing.T) {
+	john := models.WithAuditMeta(context.Background(), models.AuditMeta{Actor: "unverified:john", ClientIP: "127.0.0.1"})
+	jane := models.WithAuditMeta(context.Background(), models.AuditMeta{Actor: "unverified:jane", ClientIP: "127.0.0.1"})
+
+	assert.Equal(t, "127.0.0.1", clientKey(john))
+	assert.Equal(t, clientKey(john), clientKey(jane))
+	assert.NotEqual(t, clientKey(john), clientKey(clientCtx("john")))
+}
+
+func TestReplicasFallBackToPrimary(t *testing.T) {
+	repo, replicas := openTestReplicas(t)
+
+	company, err := repo.Create(clientCtx("john"), models.Company{Name: "acme", Code: "c1"})
+	require.NoError(t, err)
+
+	sqlDB, err := replicas.dbs[0].DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+
+	replicas.Check(context.Background())
+
+	found, err := repo.Get(clientCtx("jane"), company.ID)
+	require.NoError(t, err)
+	assert.Equal(t, company.ID, found.ID)
+}
+
+func TestReplicasAreNotUsedInTransaction(t *testing.T) {
+	repo, _ := openTestReplicas(t)
+
+	err := repo.Transaction(context.Background(), func(tx handlers.CompaniesRepo) error {
+		company, err := tx.Create(context.Background(), models.Company{Name: "acme", Code: "c1"})
+		require.NoError(t, err)
+
+		_, err = tx.Get(context.Background(), company.ID)
+		return err
+	})
+	assert.NoError(t, err)
+}
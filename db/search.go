@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/brokeyourbike/xm-golang-exercise/api/requests"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/search"
+	"gorm.io/gorm"
+)
+
+// fullTextMatch matches companies against the FULLTEXT index added by the migrations on MySQL.
+const fullTextMatch = "MATCH(name, code, website, phone) AGAINST (? IN BOOLEAN MODE)"
+
+// searchCandidates is a maximum number of companies ranked by the built-in index for a single query.
+const searchCandidates = 1000
+
+// scoredCompany is a company with its full-text search score.
+type scoredCompany struct {
+	models.Company
+	Score float64
+}
+
+// Search returns a single page of companies matching the search query, ranked by relevance.
+// MySQL ranks the companies with the FULLTEXT index, other databases
+// preselect them by substring and rank with the built-in index.
+func (c *CompaniesRepo) Search(ctx context.Context, q requests.SearchQuery) (models.SearchPage, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	offset, err := q.Offset()
+	if err != nil {
+		return models.SearchPage{Results: []models.SearchResult{}}, err
+	}
+
+	var page models.SearchPage
+
+	tx := c.read(ctx).Scopes(tenantScope(ctx))
+
+	if c.db.Dialector.Name() == DriverMySQL {
+		page, err = c.searchFullText(tx, q, offset)
+	} else {
+		page, err = c.searchIndex(tx, q, offset)
+	}
+
+	return page, contextError(ctx, err)
+}
+
+// searchFullText searches the companies with the MySQL FULLTEXT index.
+// Every term is required and matched by prefix, the same as in the built-in index.
+func (c *CompaniesRepo) searchFullText(tx *gorm.DB, q requests.SearchQuery, offset int) (models.SearchPage, error) {
+	page := models.SearchPage{Results: []models.SearchResult{}}
+
+	terms := q.Terms()
+	for i, t := range terms {
+		terms[i] = "+" + t + "*"
+	}
+	against := strings.Join(terms, " ")
+
+	limit := q.PageSize()
+
+	var rows []scoredCompany
+	err := tx.Model(&models.Company{}).
+		Select("*, "+fullTextMatch+" AS score", against).
+		Where(fullTextMatch, against).
+		Order("score DESC, id").
+		Limit(limit + 1).
+		Offset(offset).
+		Find(&rows).Error
+
+	if err != nil {
+		return page, err
+	}
+
+	if len(rows) > limit {
+		rows = rows[:limit]
+		page.HasMore = true
+		page.NextCursor = q.CursorAt(offset + limit).Encode()
+	}
+
+	for _, r := range rows {
+		page.Results = append(page.Results, q.ResultFor(r.Company, r.Score))
+	}
+
+	return page, nil
+}
+
+// searchIndex preselects the companies containing every term in any of the search fields,
+// and ranks them with the built-in index. At most searchCandidates companies are ranked.
+func (c *CompaniesRepo) searchIndex(tx *gorm.DB, q requests.SearchQuery, offset int) (models.SearchPage, error) {
+	fields := make([]string, 0, len(requests.SearchFields))
+	for field := range requests.SearchFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	terms := q.Terms()
+	for _, t := range terms {
+		conds := make([]string, 0, len(fields))
+		args := make([]interface{}, 0, len(fields))
+
+		for _, field := range fields {
+			conds = append(conds, fmt.Sprintf("LOWER(%s) LIKE ? ESCAPE '!'", field))
+			args = append(args, "%"+likeEscaper.Replace(t)+"%")
+		}
+
+		tx = tx.Where(strings.Join(conds, " OR "), args...)
+	}
+
+	var companies []models.Company
+	if err := tx.Order("id").Limit(searchCandidates).Find(&companies).Error; err != nil {
+		return models.SearchPage{Results: []models.SearchResult{}}, err
+	}
+
+	index := search.NewIndex(requests.SearchFields)
+	byID := make(map[uint64]models.Company, len(companies))
+
+	for _, company := range companies {
+		index.Put(company.ID, requests.SearchDocument(company))
+		byID[company.ID] = company
+	}
+
+	hits := index.Search(terms)
+
+	results := make([]models.SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		results = append(results, q.ResultFor(byID[hit.ID], hit.Score))
+	}
+
+	return q.PageOf(results, offset), nil
+}
@@ -0,0 +1,172 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// writeEvent adds a new outbox event for the company change.
+// Nothing is written when no webhook URLs are configured, since the event would never be delivered.
+func (c *CompaniesRepo) writeEvent(tx *gorm.DB, eventType string, company models.Company) error {
+	if !c.webhooks {
+		return nil
+	}
+
+	event, err := models.NewEvent(eventType, company)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&event).Error
+}
+
+// writeTrashedEvent adds a new outbox event for the company change,
+// with the company loaded from the database, whether it is soft-deleted or not.
+func (c *CompaniesRepo) writeTrashedEvent(tx *gorm.DB, eventType string, id uint64) error {
+	if !c.webhooks {
+		return nil
+	}
+
+	var company models.Company
+	if err := tx.Unscoped().Where("id = ?", id).First(&company).Error; err != nil {
+		return err
+	}
+	return c.writeEvent(tx, eventType, company)
+}
+
+// DispatchEvents creates a pending delivery of every new outbox event for every URL,
+// and marks the events as dispatched. It returns the number of dispatched events.
+// Events dispatched concurrently by another instance are skipped.
+func (c *CompaniesRepo) DispatchEvents(ctx context.Context, urls []string, limit int) (int, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	var events []models.Event
+
+	err := c.db.WithContext(ctx).Where("dispatched_at IS NULL").Order("id").Limit(limit).Find(&events).Error
+	if err != nil {
+		return 0, contextError(ctx, err)
+	}
+
+	n := 0
+	for _, event := range events {
+		now := time.Now().UTC()
+
+		err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			res := tx.Model(&models.Event{}).
+				Where("id = ? AND dispatched_at IS NULL", event.ID).
+				Update("dispatched_at", now)
+
+			if res.Error != nil || res.RowsAffected == 0 {
+				return res.Error
+			}
+
+			for _, url := range urls {
+				delivery := models.Delivery{EventID: event.ID, TenantID: event.TenantID, URL: url, Status: models.DeliveryPending, NextAttemptAt: now}
+				if err := tx.Omit(clause.Associations).Create(&delivery).Error; err != nil {
+					return err
+				}
+			}
+
+			n++
+			return nil
+		})
+
+		if err != nil {
+			return n, contextError(ctx, err)
+		}
+	}
+
+	return n, nil
+}
+
+// ClaimDeliveries returns pending deliveries due at the given time, together with their events.
+// Claimed deliveries are postponed by the lease, so other instances do not send them at the same time.
+func (c *CompaniesRepo) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Delivery, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	var due []models.Delivery
+
+	err := c.db.WithContext(ctx).Preload("Event").
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+		Order("next_attempt_at, id").Limit(limit).Find(&due).Error
+
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	claimed := make([]models.Delivery, 0, len(due))
+	for _, delivery := range due {
+		res := c.db.WithContext(ctx).Model(&models.Delivery{}).
+			Where("id = ? AND status = ? AND next_attempt_at <= ?", delivery.ID, models.DeliveryPending, now).
+			Update("next_attempt_at", now.Add(lease))
+
+		if res.Error != nil {
+			return claimed, contextError(ctx, res.Error)
+		}
+
+		if res.RowsAffected == 1 {
+			claimed = append(claimed, delivery)
+		}
+	}
+
+	return claimed, nil
+}
+
+// SaveDelivery saves the outcome of the delivery attempt.
+func (c *CompaniesRepo) SaveDelivery(ctx context.Context, delivery models.Delivery) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	err := c.db.WithContext(ctx).Model(&models.Delivery{ID: delivery.ID}).
+		Select("status", "attempts", "next_attempt_at", "last_error", "delivered_at").
+		Omit(clause.Associations).
+		Updates(&delivery).Error
+
+	return contextError(ctx, err)
+}
+
+// DeadDeliveries returns all deliveries of the tenant, which exhausted their attempts, ordered from the oldest.
+func (c *CompaniesRepo) DeadDeliveries(ctx context.Context) ([]models.Delivery, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	deliveries := []models.Delivery{}
+	err := c.read(ctx).Scopes(tenantScope(ctx)).Preload("Event").Where("status = ?", models.DeliveryDead).Order("id").Find(&deliveries).Error
+	return deliveries, contextError(ctx, err)
+}
+
+// Redeliver schedules the delivery to be sent again immediately, with all attempts available.
+// If a delivery with the given id does not exist in the database for the tenant
+// this function returns a ErrDeliveryNotFound error.
+func (c *CompaniesRepo) Redeliver(ctx context.Context, id uint64) (models.Delivery, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	var delivery models.Delivery
+
+	err := c.write(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.Delivery{}).Scopes(tenantScope(ctx)).Where("id = ?", id).Updates(map[string]interface{}{
+			"status":          models.DeliveryPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now().UTC(),
+			"last_error":      "",
+		})
+
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			return models.ErrDeliveryNotFound
+		}
+
+		return tx.Preload("Event").Where("id = ?", id).First(&delivery).Error
+	})
+
+	return delivery, contextError(ctx, err)
+}
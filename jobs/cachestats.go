@@ -0,0 +1,65 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// CacheStats defines the cache counting its hits and misses.
+type CacheStats interface {
+	Stats() models.CacheStats
+}
+
+// CacheReporter is a job that logs the hits and misses of the companies cache,
+// so the operators can tell how well the cache works.
+type CacheReporter struct {
+	config *configs.Config
+	cache  CacheStats
+	last   models.CacheStats
+}
+
+// NewCacheReporter creates an instance of CacheReporter job.
+func NewCacheReporter(config *configs.Config, cache CacheStats) *CacheReporter {
+	return &CacheReporter{config: config, cache: cache}
+}
+
+// Run reports the cache stats periodically, until the context is done.
+// The reports are disabled when the interval is zero.
+func (c *CacheReporter) Run(ctx context.Context) {
+	if c.config.Companies.CacheStatsIntervalSeconds == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Second * time.Duration(c.config.Companies.CacheStatsIntervalSeconds))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Report()
+		}
+	}
+}
+
+// Report logs the hits and misses since the previous report, nothing is logged if the cache was not used.
+func (c *CacheReporter) Report() {
+	stats := c.cache.Stats()
+	hits, misses := stats.Hits-c.last.Hits, stats.Misses-c.last.Misses
+	c.last = stats
+
+	if hits+misses == 0 {
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"hits":      hits,
+		"misses":    misses,
+		"hit_ratio": float64(hits) / float64(hits+misses),
+	}).Info("Company cache stats")
+}
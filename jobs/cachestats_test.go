@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cacheStats is a cache with the given counters.
+type cacheStats struct {
+	stats models.CacheStats
+}
+
+func (c *cacheStats) Stats() models.CacheStats {
+	return c.stats
+}
+
+func TestCacheReporter(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	cache := &cacheStats{stats: models.CacheStats{Hits: 3, Misses: 1}}
+	r := NewCacheReporter(&configs.Config{}, cache)
+
+	r.Report()
+	require.Len(t, hook.AllEntries(), 1)
+	assert.Equal(t, log.Fields{"hits": uint64(3), "misses": uint64(1), "hit_ratio": 0.75}, hook.LastEntry().Data)
+
+	// the counters are reported since the previous report
+	cache.stats = models.CacheStats{Hits: 4, Misses: 2}
+	r.Report()
+	require.Len(t, hook.AllEntries(), 2)
+	assert.Equal(t, log.Fields{"hits": uint64(1), "misses": uint64(1), "hit_ratio": 0.5}, hook.LastEntry().Data)
+
+	// nothing is reported without the cache use
+	r.Report()
+	assert.Len(t, hook.AllEntries(), 2)
+}
@@ -0,0 +1,58 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	log "github.com/sirupsen/logrus"
+)
+
+// CompaniesPurger defines functions used to permanently remove soft-deleted companies.
+type CompaniesPurger interface {
+	Purge(context.Context, time.Time) (int64, error)
+}
+
+// Purger is a job that permanently removes companies,
+// which were soft-deleted longer than the retention period ago.
+type Purger struct {
+	config *configs.Config
+	repo   CompaniesPurger
+	now    func() time.Time
+}
+
+// NewPurger creates an instance of Purger job.
+func NewPurger(config *configs.Config, repo CompaniesPurger) *Purger {
+	return &Purger{config: config, repo: repo, now: time.Now}
+}
+
+// Run purges the companies periodically, until the context is done.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute * time.Duration(p.config.SoftDelete.PurgeIntervalMinutes))
+	defer ticker.Stop()
+
+	for {
+		p.Purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge permanently removes companies soft-deleted before the retention period.
+func (p *Purger) Purge(ctx context.Context) {
+	before := p.now().Add(-time.Hour * time.Duration(p.config.SoftDelete.RetentionHours))
+
+	n, err := p.repo.Purge(ctx, before)
+	if err != nil {
+		log.WithFields(log.Fields{"before": before, "error": err}).Error("Cannot purge companies")
+		return
+	}
+
+	if n > 0 {
+		log.WithFields(log.Fields{"count": n, "before": before}).Info("Companies purged")
+	}
+}
@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/mocks"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPurger(t *testing.T) {
+	now := time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	cases := map[string]struct {
+		retentionHours uint
+		setupMock      func(repo *mocks.CompaniesPurger)
+	}{
+		"it purges companies deleted before retention period": {
+			retentionHours: 24,
+			setupMock: func(repo *mocks.CompaniesPurger) {
+				repo.On("Purge", mock.Anything, now.Add(-time.Hour*24)).Return(int64(3), nil)
+			},
+		},
+		"it can handle errors": {
+			retentionHours: 1,
+			setupMock: func(repo *mocks.CompaniesPurger) {
+				repo.On("Purge", mock.Anything, now.Add(-time.Hour)).Return(int64(0), errors.New("cannot purge"))
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := configs.Config{}
+			cfg.SoftDelete.RetentionHours = c.retentionHours
+
+			repo := new(mocks.CompaniesPurger)
+			c.setupMock(repo)
+
+			p := NewPurger(&cfg, repo)
+			p.now = func() time.Time { return now }
+			p.Purge(context.Background())
+
+			repo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/webhook"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// eventsBatch is the number of outbox events dispatched at once.
+	eventsBatch = 100
+	// deliveriesBatch is the number of deliveries claimed at once.
+	deliveriesBatch = 10
+	// maxBackoff limits the delay between the delivery attempts.
+	maxBackoff = time.Hour
+)
+
+// WebhooksStore defines functions used to deliver the outbox events to the webhooks.
+type WebhooksStore interface {
+	DispatchEvents(ctx context.Context, urls []string, limit int) (int, error)
+	ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Delivery, error)
+	SaveDelivery(context.Context, models.Delivery) error
+}
+
+// WebhookClient defines the HTTP client used to deliver the events.
+type WebhookClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// Dispatcher is a job that creates a delivery of every outbox event for every webhook URL,
+// and sends the due deliveries. Failed deliveries are retried with the exponential backoff,
+// until the attempts are exhausted and the delivery becomes dead.
+// Events are delivered at least once, receivers should use the X-Webhook-Id header to skip duplicates.
+type Dispatcher struct {
+	config *configs.Config
+	repo   WebhooksStore
+	client WebhookClient
+	now    func() time.Time
+}
+
+// NewDispatcher creates an instance of Dispatcher job.
+func NewDispatcher(config *configs.Config, repo WebhooksStore, client WebhookClient) *Dispatcher {
+	return &Dispatcher{config: config, repo: repo, client: client, now: time.Now}
+}
+
+// Run dispatches the events periodically, until the context is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Second * time.Duration(d.config.Webhooks.PollIntervalSeconds))
+	defer ticker.Stop()
+
+	for {
+		d.Dispatch(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch creates the deliveries of the new events and sends all due deliveries.
+func (d *Dispatcher) Dispatch(ctx context.Context) {
+	for {
+		n, err := d.repo.DispatchEvents(ctx, d.config.Webhooks.URLs, eventsBatch)
+		if err != nil {
+			log.WithFields(log.Fields{"error": err}).Error("Cannot dispatch events")
+			return
+		}
+		if n < eventsBatch {
+			break
+		}
+	}
+
+	// the claim expires, if the deliveries are not saved in time
+	lease := (time.Second*time.Duration(d.config.Webhooks.TimeoutSeconds) + time.Second) * deliveriesBatch
+
+	for {
+		deliveries, err := d.repo.ClaimDeliveries(ctx, d.now().UTC(), lease, deliveriesBatch)
+		if err != nil {
+			log.WithFields(log.Fields{"error": err}).Error("Cannot claim deliveries")
+			return
+		}
+
+		for _, delivery := range deliveries {
+			delivery = d.Deliver(ctx, delivery)
+
+			if err := d.repo.SaveDelivery(ctx, delivery); err != nil {
+				log.WithFields(log.Fields{"id": delivery.ID, "error": err}).Error("Cannot save delivery")
+			}
+		}
+
+		if len(deliveries) < deliveriesBatch {
+			return
+		}
+	}
+}
+
+// Deliver sends the event to the webhook URL and returns the delivery with the outcome of the attempt.
+func (d *Dispatcher) Deliver(ctx context.Context, delivery models.Delivery) models.Delivery {
+	now := d.now().UTC()
+	delivery.Attempts++
+
+	err := d.send(ctx, delivery, now)
+	if err == nil {
+		delivery.Status = models.DeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+		return delivery
+	}
+
+	delivery.LastError = err.Error()
+
+	if delivery.Attempts >= d.config.Webhooks.MaxAttempts {
+		delivery.Status = models.DeliveryDead
+		log.WithFields(log.Fields{"id": delivery.ID, "url": delivery.URL, "error": err}).Warn("Delivery is dead")
+		return delivery
+	}
+
+	delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+	return delivery
+}
+
+// send posts the signed event to the webhook URL, any status except 2xx is a failure.
+func (d *Dispatcher) send(ctx context.Context, delivery models.Delivery, now time.Time) error {
+	body, err := delivery.Event.Body()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(d.config.Webhooks.TimeoutSeconds))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.HeaderID, strconv.FormatUint(delivery.ID, 10))
+	req.Header.Set(webhook.HeaderEvent, delivery.Event.Type)
+	req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(d.config.Webhooks.Secret, now, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// backoff returns the delay before the next attempt, which doubles after every attempt.
+func (d *Dispatcher) backoff(attempts uint) time.Duration {
+	delay := time.Second * time.Duration(d.config.Webhooks.BackoffSeconds)
+
+	for i := uint(1); i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > maxBackoff {
+		return maxBackoff
+	}
+
+	return delay
+}
@@ -0,0 +1,166 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/mocks"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDispatcherDeliver(t *testing.T) {
+	now := time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	cases := map[string]struct {
+		status        int
+		attempts      uint
+		wantStatus    string
+		wantAttempts  uint
+		wantNext      time.Time
+		wantError     string
+		wantDelivered bool
+	}{
+		"it delivers the event": {
+			status:        http.StatusNoContent,
+			wantStatus:    models.DeliveryDelivered,
+			wantAttempts:  1,
+			wantDelivered: true,
+		},
+		"it retries after the backoff": {
+			status:       http.StatusInternalServerError,
+			attempts:     2,
+			wantStatus:   models.DeliveryPending,
+			wantAttempts: 3,
+			wantNext:     now.Add(time.Second * 40),
+			wantError:    "unexpected status 500",
+		},
+		"it limits the backoff": {
+			status:       http.StatusBadGateway,
+			attempts:     20,
+			wantStatus:   models.DeliveryPending,
+			wantAttempts: 21,
+			wantNext:     now.Add(maxBackoff),
+			wantError:    "unexpected status 502",
+		},
+		"it gives up after the last attempt": {
+			status:       http.StatusNotFound,
+			attempts:     29,
+			wantStatus:   models.DeliveryDead,
+			wantAttempts: 30,
+			wantError:    "unexpected status 404",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			var req *http.Request
+			var body []byte
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				req = r
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(c.status)
+			}))
+			defer srv.Close()
+
+			cfg := configs.Config{}
+			cfg.Webhooks.Secret = "secret"
+			cfg.Webhooks.MaxAttempts = 30
+			cfg.Webhooks.BackoffSeconds = 10
+			cfg.Webhooks.TimeoutSeconds = 1
+
+			d := NewDispatcher(&cfg, new(mocks.WebhooksStore), srv.Client())
+			d.now = func() time.Time { return now }
+
+			delivery := models.Delivery{
+				ID:       5,
+				Event:    models.Event{ID: 1, Type: models.EventCompanyCreated, CompanyID: 3, TenantID: "xm", Data: `{"id":3}`, CreatedAt: now},
+				URL:      srv.URL,
+				Status:   models.DeliveryPending,
+				Attempts: c.attempts,
+			}
+
+			delivery = d.Deliver(context.Background(), delivery)
+
+			if c.wantDelivered {
+				assert.Equal(t, &now, delivery.DeliveredAt)
+			} else {
+				assert.Nil(t, delivery.DeliveredAt)
+			}
+
+			assert.Equal(t, c.wantStatus, delivery.Status)
+			assert.Equal(t, c.wantAttempts, delivery.Attempts)
+			assert.Equal(t, c.wantNext, delivery.NextAttemptAt)
+			assert.Equal(t, c.wantError, delivery.LastError)
+
+			assert.Equal(t, `{"id":1,"type":"company.created","tenant_id":"xm","created_at":"2022-05-10T12:00:00Z","data":{"id":3}}`, string(body))
+			assert.Equal(t, "5", req.Header.Get(webhook.HeaderID))
+			assert.Equal(t, models.EventCompanyCreated, req.Header.Get(webhook.HeaderEvent))
+			assert.Equal(t, "1652184000", req.Header.Get(webhook.HeaderTimestamp))
+			assert.True(t, webhook.Verify("secret", req.Header.Get(webhook.HeaderSignature), req.Header.Get(webhook.HeaderTimestamp), body))
+		})
+	}
+}
+
+func TestDispatcherDispatch(t *testing.T) {
+	now := time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC)
+	lease := time.Second * 20
+
+	cases := map[string]struct {
+		setupMock func(repo *mocks.WebhooksStore, url string)
+	}{
+		"it sends and saves the claimed deliveries": {
+			setupMock: func(repo *mocks.WebhooksStore, url string) {
+				repo.On("DispatchEvents", mock.Anything, []string{url}, eventsBatch).Return(eventsBatch, nil).Once()
+				repo.On("DispatchEvents", mock.Anything, []string{url}, eventsBatch).Return(3, nil).Once()
+				repo.On("ClaimDeliveries", mock.Anything, now, lease, deliveriesBatch).Return([]models.Delivery{{ID: 1, URL: url, Event: models.Event{ID: 1, Data: "{}"}}}, nil)
+				repo.On("SaveDelivery", mock.Anything, mock.MatchedBy(func(d models.Delivery) bool {
+					return d.ID == 1 && d.Status == models.DeliveryDelivered && d.Attempts == 1
+				})).Return(nil)
+			},
+		},
+		"it does not claim deliveries if events cannot be dispatched": {
+			setupMock: func(repo *mocks.WebhooksStore, url string) {
+				repo.On("DispatchEvents", mock.Anything, []string{url}, eventsBatch).Return(0, errors.New("cannot dispatch"))
+			},
+		},
+		"it can handle errors": {
+			setupMock: func(repo *mocks.WebhooksStore, url string) {
+				repo.On("DispatchEvents", mock.Anything, []string{url}, eventsBatch).Return(0, nil)
+				repo.On("ClaimDeliveries", mock.Anything, now, lease, deliveriesBatch).Return([]models.Delivery{}, errors.New("cannot claim"))
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer srv.Close()
+
+			cfg := configs.Config{}
+			cfg.Webhooks.URLs = []string{srv.URL}
+			cfg.Webhooks.MaxAttempts = 3
+			cfg.Webhooks.TimeoutSeconds = 1
+
+			repo := new(mocks.WebhooksStore)
+			c.setupMock(repo, srv.URL)
+
+			d := NewDispatcher(&cfg, repo, srv.Client())
+			d.now = func() time.Time { return now }
+			d.Dispatch(context.Background())
+
+			repo.AssertExpectations(t)
+		})
+	}
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/brokeyourbike/xm-golang-exercise/api/handlers"
@@ -11,16 +14,23 @@ import (
 	"github.com/brokeyourbike/xm-golang-exercise/api/server"
 	"github.com/brokeyourbike/xm-golang-exercise/configs"
 	"github.com/brokeyourbike/xm-golang-exercise/db"
+	"github.com/brokeyourbike/xm-golang-exercise/db/cached"
+	"github.com/brokeyourbike/xm-golang-exercise/db/memory"
+	"github.com/brokeyourbike/xm-golang-exercise/db/published"
+	"github.com/brokeyourbike/xm-golang-exercise/jobs"
+	"github.com/brokeyourbike/xm-golang-exercise/migrations"
 	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/broker"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/jwt"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/ratelimit"
+	"github.com/brokeyourbike/xm-golang-exercise/pkg/rbac"
 	"github.com/brokeyourbike/xm-golang-exercise/pkg/validator"
 	"github.com/caarlos0/env/v6"
 	"github.com/coocood/freecache"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-redis/redis/v8"
 	"github.com/gorilla/schema"
-	gorm_logrus "github.com/onrik/gorm-logrus"
 	log "github.com/sirupsen/logrus"
-	"gorm.io/driver/mysql"
-	"gorm.io/gorm"
 )
 
 func main() {
@@ -38,24 +48,260 @@ func run() error {
 		return fmt.Errorf("cannot parse config: %v", err)
 	}
 
-	orm, err := gorm.Open(mysql.Open(cfg.Database.Dsn), &gorm.Config{Logger: gorm_logrus.New()})
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		return runMigrate(&cfg, os.Args[2:])
+	}
+
+	// unsigned deliveries cannot be told apart from forged ones by the receivers
+	if len(cfg.Webhooks.URLs) > 0 && cfg.Webhooks.Secret == "" {
+		return fmt.Errorf("WEBHOOK_SECRET is required when WEBHOOK_URLS is set")
+	}
+
+	store, err := newStorage(&cfg)
 	if err != nil {
-		return fmt.Errorf("cannot connect to DB: %v", err)
+		return fmt.Errorf("cannot open storage: %v", err)
 	}
 
+	publisher, err := newPublisher(&cfg)
+	if err != nil {
+		return fmt.Errorf("cannot connect to message broker: %v", err)
+	}
+	defer publisher.Close()
+
 	cache := freecache.NewCache(cfg.CacheSizeMb * 1024 * 1024)
+	var companiesRepo published.Storage = store
+	var cacheStats handlers.CacheStatsRepo
+	if cfg.Companies.CacheTTLSeconds > 0 {
+		cachedRepo := cached.NewCompaniesRepo(&cfg, companiesRepo, cache)
+		go jobs.NewCacheReporter(&cfg, cachedRepo).Run(context.Background())
+		companiesRepo, cacheStats = cachedRepo, cachedRepo
+	}
+	companiesRepo = published.NewCompaniesRepo(&cfg, companiesRepo, publisher)
 	httpClient := http.Client{Timeout: time.Second * time.Duration(cfg.Ipapi.TimeoutSeconds)}
 
-	orm.AutoMigrate(&models.Company{})
-	companiesRepo := db.NewCompaniesRepo(orm)
+	purger := jobs.NewPurger(&cfg, store)
+	go purger.Run(context.Background())
+
+	webhookClient := http.Client{Timeout: time.Second * time.Duration(cfg.Webhooks.TimeoutSeconds)}
+	dispatcher := jobs.NewDispatcher(&cfg, store, &webhookClient)
+	go dispatcher.Run(context.Background())
+
+	policy, err := newPolicy(&cfg)
+	if err != nil {
+		return fmt.Errorf("cannot load authorization policy: %v", err)
+	}
+
+	limits, err := ratelimit.ParseLimits(cfg.RateLimit.Default, cfg.RateLimit.Routes)
+	if err != nil {
+		return fmt.Errorf("cannot parse rate limits: %v", err)
+	}
+
+	ipLimits, err := ratelimit.ParseLimits(cfg.RateLimit.IP, nil)
+	if err != nil {
+		return fmt.Errorf("cannot parse IP rate limit: %v", err)
+	}
+
+	limitStore, err := newRateLimitStore(&cfg)
+	if err != nil {
+		return fmt.Errorf("cannot open rate limit store: %v", err)
+	}
 
 	c := handlers.NewCompanies(companiesRepo)
+	ct := handlers.NewContacts(store)
+	wh := handlers.NewWebhooks(store)
+	ak := handlers.NewAPIKeys(store)
+	st := handlers.NewStats(cacheStats)
 	cmw := middlewares.NewCompanyCtx(companiesRepo)
+	tmw := middlewares.NewTrashedCompanyCtx(companiesRepo)
+	imw := middlewares.NewIfMatch()
 	pmw := middlewares.NewCompanyPayloadCtx(validator.NewValidation(), schema.NewDecoder())
+	ptmw := middlewares.NewCompanyPatchCtx(validator.NewValidation())
+	smw := middlewares.NewSearchQueryCtx(validator.NewValidation(), schema.NewDecoder())
+	bmw := middlewares.NewBulkPayloadCtx(&cfg, validator.NewValidation())
+	impmw := middlewares.NewCompanyImportCtx(&cfg, validator.NewValidation())
+	expmw := middlewares.NewExportQueryCtx(&cfg, validator.NewValidation())
+	ctmw := middlewares.NewContactPayloadCtx(validator.NewValidation())
+	kmw := middlewares.NewAPIKeyPayloadCtx(validator.NewValidation())
 	ipmw := middlewares.NewIpapi(&cfg, &httpClient, cache)
+	amw := middlewares.NewAuditCtx()
+	akmw := middlewares.NewAPIKeyAuth(store)
+	jwtmw := middlewares.NewJWTAuth(&cfg, newTokenVerifier(&cfg))
+	rlmw := middlewares.NewRateLimit(limitStore, limits, cfg.RateLimit.FailOpen)
+	iprlmw := middlewares.NewIPRateLimit(limitStore, ipLimits, cfg.RateLimit.IPFailOpen)
+	azmw := middlewares.NewAuthorize(policy)
+	admmw := middlewares.NewRequireScope(models.ScopeAPIKeysManage)
+	tnmw := middlewares.NewTenantCtx(&cfg)
 
-	mux := server.NewServer(chi.NewRouter(), c, cmw, pmw, ipmw)
+	mux := server.NewServer(chi.NewRouter(), server.Handlers{Companies: c, Contacts: ct, Webhooks: wh, APIKeys: ak, Stats: st}, server.Middlewares{
+		Company: cmw,
+		Trashed: tmw,
+		IfMatch: imw,
+		Payload: pmw,
+		Patch:   ptmw,
+		Search:  smw,
+		Bulk:    bmw,
+		Import:  impmw,
+		Export:  expmw,
+		Contact: ctmw,
+		Key:     kmw,
+		Ip:      ipmw,
+		IpLimit: iprlmw,
+		APIKey:  akmw,
+		Auth:    jwtmw,
+		Limit:   rlmw,
+		Policy:  azmw,
+		Admin:   admmw,
+		Audit:   amw,
+		Tenant:  tnmw,
+	})
 	mux.ListenAndServe(&cfg)
 
 	return nil
 }
+
+// storage defines a repository used by the handlers and jobs.
+type storage interface {
+	handlers.CompaniesRepo
+	handlers.ContactsRepo
+	handlers.APIKeysRepo
+	handlers.WebhooksRepo
+	jobs.CompaniesPurger
+	jobs.WebhooksStore
+}
+
+// newStorage creates the repository for the configured database driver.
+func newStorage(cfg *configs.Config) (storage, error) {
+	if cfg.Database.Driver == db.DriverMemory {
+		return memory.NewCompaniesRepo(cfg), nil
+	}
+
+	orm, err := db.Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := migrations.NewMigrator(orm, cfg).Check(); err != nil {
+		return nil, fmt.Errorf("schema is not up to date: %w", err)
+	}
+
+	repo := db.NewCompaniesRepo(orm, cfg)
+	if len(cfg.Database.ReplicaDsns) == 0 {
+		return repo, nil
+	}
+
+	replicas, err := db.OpenReplicas(orm, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.WithReplicas(replicas), nil
+}
+
+// newPublisher creates the publisher of the company events,
+// which discards the events if no message broker is configured.
+func newPublisher(cfg *configs.Config) (broker.Publisher, error) {
+	if cfg.Events.NatsURL == "" {
+		return broker.NewNop(), nil
+	}
+	return broker.NewNATS(cfg.Events.NatsURL)
+}
+
+// newTokenVerifier creates the verifier of the JWT bearer tokens,
+// or returns nil to disable the authentication if the key set is not configured.
+func newTokenVerifier(cfg *configs.Config) middlewares.TokenVerifier {
+	if cfg.JWT.JWKS == "" {
+		if !cfg.Auth.Required {
+			log.Warn("JWT_JWKS and AUTH_REQUIRED are not set, anonymous requests are allowed")
+		}
+		return nil
+	}
+
+	client := http.Client{Timeout: time.Second * time.Duration(cfg.JWT.TimeoutSeconds)}
+	keys := jwt.NewJWKS(cfg.JWT.JWKS, &client, time.Second*time.Duration(cfg.JWT.JWKSTTLSeconds))
+
+	return jwt.NewVerifier(keys, cfg.JWT.Audience, cfg.JWT.Issuer, time.Second*time.Duration(cfg.JWT.LeewaySeconds))
+}
+
+// newRateLimitStore creates the store of the rate limit buckets for the configured store.
+func newRateLimitStore(cfg *configs.Config) (ratelimit.Store, error) {
+	switch cfg.RateLimit.Store {
+	case "memory":
+		return ratelimit.NewMemoryStore(), nil
+	case "redis":
+		opts, err := redis.ParseURL(cfg.RateLimit.RedisURL)
+		if err != nil {
+			return nil, err
+		}
+		return ratelimit.NewRedisStore(redis.NewClient(opts), cfg.RateLimit.RedisPrefix), nil
+	}
+	return nil, fmt.Errorf("unsupported store %q", cfg.RateLimit.Store)
+}
+
+// newPolicy loads the authorization policy from RBAC_POLICY, or the default policy if it is not set.
+func newPolicy(cfg *configs.Config) (*rbac.Policy, error) {
+	if cfg.RBAC.Policy == "" {
+		return rbac.Parse(configs.DefaultPolicy)
+	}
+	return rbac.Load(cfg.RBAC.Policy)
+}
+
+// runMigrate runs the `migrate up|down|status|to N` subcommand.
+func runMigrate(cfg *configs.Config, args []string) error {
+	if cfg.Database.Driver == db.DriverMemory {
+		return fmt.Errorf("%s driver does not use migrations", db.DriverMemory)
+	}
+
+	if len(args) == 0 {
+		return errors.New("usage: migrate up|down|status|to N")
+	}
+
+	orm, err := db.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("cannot connect to DB: %v", err)
+	}
+
+	m := migrations.NewMigrator(orm, cfg)
+
+	switch args[0] {
+	case "up":
+		err = m.Up()
+	case "down":
+		err = m.Down()
+	case "to":
+		if len(args) < 2 {
+			return errors.New("usage: migrate to N")
+		}
+		version, perr := strconv.ParseUint(args[1], 10, 64)
+		if perr != nil {
+			return fmt.Errorf("invalid version '%s'", args[1])
+		}
+		err = m.To(uint(version))
+	case "status":
+		statuses, serr := m.Status()
+		if serr != nil {
+			return serr
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%05d %-30s %s\n", s.Version, s.Name, state)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command '%s'", args[0])
+	}
+
+	if err != nil {
+		return err
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	log.Infof("schema is at version %d", version)
+	return nil
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"gorm.io/gorm"
+)
+
+// company00001 is the companies table at version 1.
+type company00001 struct {
+	ID      uint64 `gorm:"primary_key"`
+	Name    string `gorm:"type:varchar(255);index"`
+	Code    string `gorm:"type:varchar(255);index"`
+	Country string `gorm:"type:varchar(2);index"`
+	Website string `gorm:"type:varchar(255);index"`
+	Phone   string `gorm:"type:varchar(255);index"`
+}
+
+func (company00001) TableName() string {
+	return "companies"
+}
+
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "create_companies",
+		Up: func(tx *gorm.DB, _ *configs.Config) error {
+			// The table may already exist, if it was created before the migrations were introduced.
+			if tx.Migrator().HasTable(&company00001{}) {
+				return nil
+			}
+			return tx.Migrator().CreateTable(&company00001{})
+		},
+		Down: func(tx *gorm.DB, _ *configs.Config) error {
+			return tx.Migrator().DropTable(&company00001{})
+		},
+	})
+}
@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"gorm.io/gorm"
+)
+
+// company00002 is the companies table column added at version 2.
+type company00002 struct {
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (company00002) TableName() string {
+	return "companies"
+}
+
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "add_companies_deleted_at",
+		Up: func(tx *gorm.DB, _ *configs.Config) error {
+			if tx.Migrator().HasColumn(&company00002{}, "DeletedAt") {
+				return nil
+			}
+			if err := tx.Migrator().AddColumn(&company00002{}, "DeletedAt"); err != nil {
+				return err
+			}
+			return tx.Migrator().CreateIndex(&company00002{}, "DeletedAt")
+		},
+		Down: func(tx *gorm.DB, _ *configs.Config) error {
+			// SQLite rebuilds the table on column drop, which can remove the index first.
+			if tx.Migrator().HasIndex(&company00002{}, "DeletedAt") {
+				if err := tx.Migrator().DropIndex(&company00002{}, "DeletedAt"); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().DropColumn(&company00002{}, "DeletedAt")
+		},
+	})
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"gorm.io/gorm"
+)
+
+// company00003 is the companies table column added at version 3.
+type company00003 struct {
+	Version uint64 `gorm:"not null;default:1"`
+}
+
+func (company00003) TableName() string {
+	return "companies"
+}
+
+func init() {
+	register(Migration{
+		Version: 3,
+		Name:    "add_companies_version",
+		Up: func(tx *gorm.DB, _ *configs.Config) error {
+			if tx.Migrator().HasColumn(&company00003{}, "Version") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&company00003{}, "Version")
+		},
+		Down: func(tx *gorm.DB, _ *configs.Config) error {
+			return tx.Migrator().DropColumn(&company00003{}, "Version")
+		},
+	})
+}
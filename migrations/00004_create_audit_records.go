@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"gorm.io/gorm"
+)
+
+// auditRecord00004 is the audit_records table at version 4.
+type auditRecord00004 struct {
+	ID        uint64 `gorm:"primary_key"`
+	CompanyID uint64 `gorm:"index"`
+	Action    string `gorm:"type:varchar(16)"`
+	Changes   string `gorm:"type:text"`
+	Actor     string `gorm:"type:varchar(255)"`
+	RequestID string `gorm:"type:varchar(255)"`
+	ClientIP  string `gorm:"type:varchar(45)"`
+	CreatedAt time.Time
+	PrevHash  string `gorm:"type:varchar(64)"`
+	Hash      string `gorm:"type:varchar(64)"`
+}
+
+func (auditRecord00004) TableName() string {
+	return "audit_records"
+}
+
+func init() {
+	register(Migration{
+		Version: 4,
+		Name:    "create_audit_records",
+		Up: func(tx *gorm.DB, _ *configs.Config) error {
+			if tx.Migrator().HasTable(&auditRecord00004{}) {
+				return nil
+			}
+			return tx.Migrator().CreateTable(&auditRecord00004{})
+		},
+		Down: func(tx *gorm.DB, _ *configs.Config) error {
+			return tx.Migrator().DropTable(&auditRecord00004{})
+		},
+	})
+}
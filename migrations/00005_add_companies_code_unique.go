@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"gorm.io/gorm"
+)
+
+// company00005 is the companies table index added at version 5, when the code is unique globally.
+type company00005 struct {
+	Code string `gorm:"type:varchar(255);uniqueIndex:idx_companies_code_unique"`
+}
+
+func (company00005) TableName() string {
+	return "companies"
+}
+
+// companyPerCountry00005 is the companies table index added at version 5, when the code is unique per country.
+type companyPerCountry00005 struct {
+	Country string `gorm:"type:varchar(2);uniqueIndex:idx_companies_country_code_unique"`
+	Code    string `gorm:"type:varchar(255);uniqueIndex:idx_companies_country_code_unique"`
+}
+
+func (companyPerCountry00005) TableName() string {
+	return "companies"
+}
+
+func init() {
+	register(Migration{
+		Version: 5,
+		Name:    "add_companies_code_unique",
+		Up: func(tx *gorm.DB, cfg *configs.Config) error {
+			if cfg.Companies.UniqueCodePerCountry {
+				return tx.Migrator().CreateIndex(&companyPerCountry00005{}, "idx_companies_country_code_unique")
+			}
+			return tx.Migrator().CreateIndex(&company00005{}, "idx_companies_code_unique")
+		},
+		Down: func(tx *gorm.DB, _ *configs.Config) error {
+			// The index is dropped regardless of the current configuration,
+			// so the scope can be changed by rolling back and applying this migration again.
+			if tx.Migrator().HasIndex(&companyPerCountry00005{}, "idx_companies_country_code_unique") {
+				if err := tx.Migrator().DropIndex(&companyPerCountry00005{}, "idx_companies_country_code_unique"); err != nil {
+					return err
+				}
+			}
+			if tx.Migrator().HasIndex(&company00005{}, "idx_companies_code_unique") {
+				return tx.Migrator().DropIndex(&company00005{}, "idx_companies_code_unique")
+			}
+			return nil
+		},
+	})
+}
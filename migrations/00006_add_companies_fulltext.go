@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		Version: 6,
+		Name:    "add_companies_fulltext",
+		// Only MySQL has FULLTEXT indexes, the other databases use the built-in search index.
+		Up: func(tx *gorm.DB, _ *configs.Config) error {
+			if tx.Dialector.Name() != "mysql" {
+				return nil
+			}
+			return tx.Exec("CREATE FULLTEXT INDEX idx_companies_fulltext ON companies (name, code, website, phone)").Error
+		},
+		Down: func(tx *gorm.DB, _ *configs.Config) error {
+			if tx.Dialector.Name() != "mysql" {
+				return nil
+			}
+			return tx.Exec("DROP INDEX idx_companies_fulltext ON companies").Error
+		},
+	})
+}
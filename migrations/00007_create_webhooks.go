@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"gorm.io/gorm"
+)
+
+// event00007 is the outbox_events table at version 7.
+type event00007 struct {
+	ID           uint64 `gorm:"primary_key"`
+	Type         string `gorm:"type:varchar(32)"`
+	CompanyID    uint64 `gorm:"index"`
+	Data         string `gorm:"type:text"`
+	CreatedAt    time.Time
+	DispatchedAt *time.Time `gorm:"index"`
+}
+
+func (event00007) TableName() string {
+	return "outbox_events"
+}
+
+// delivery00007 is the webhook_deliveries table at version 7.
+type delivery00007 struct {
+	ID            uint64 `gorm:"primary_key"`
+	EventID       uint64 `gorm:"index"`
+	URL           string `gorm:"type:varchar(2048)"`
+	Status        string `gorm:"type:varchar(16);index:idx_webhook_deliveries_due"`
+	Attempts      uint
+	NextAttemptAt time.Time `gorm:"index:idx_webhook_deliveries_due"`
+	LastError     string    `gorm:"type:text"`
+	DeliveredAt   *time.Time
+	CreatedAt     time.Time
+}
+
+func (delivery00007) TableName() string {
+	return "webhook_deliveries"
+}
+
+func init() {
+	register(Migration{
+		Version: 7,
+		Name:    "create_webhooks",
+		Up: func(tx *gorm.DB, _ *configs.Config) error {
+			for _, table := range []interface{}{&event00007{}, &delivery00007{}} {
+				if tx.Migrator().HasTable(table) {
+					continue
+				}
+				if err := tx.Migrator().CreateTable(table); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB, _ *configs.Config) error {
+			return tx.Migrator().DropTable(&delivery00007{}, &event00007{})
+		},
+	})
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"gorm.io/gorm"
+)
+
+// company00008 is the companies table column added at version 8.
+type company00008 struct {
+	ParentID *uint64 `gorm:"index"`
+}
+
+func (company00008) TableName() string {
+	return "companies"
+}
+
+func init() {
+	register(Migration{
+		Version: 8,
+		Name:    "add_companies_parent_id",
+		Up: func(tx *gorm.DB, _ *configs.Config) error {
+			if tx.Migrator().HasColumn(&company00008{}, "ParentID") {
+				return nil
+			}
+			if err := tx.Migrator().AddColumn(&company00008{}, "ParentID"); err != nil {
+				return err
+			}
+			return tx.Migrator().CreateIndex(&company00008{}, "ParentID")
+		},
+		Down: func(tx *gorm.DB, _ *configs.Config) error {
+			if tx.Migrator().HasIndex(&company00008{}, "ParentID") {
+				if err := tx.Migrator().DropIndex(&company00008{}, "ParentID"); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().DropColumn(&company00008{}, "ParentID")
+		},
+	})
+}
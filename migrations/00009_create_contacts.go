@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"gorm.io/gorm"
+)
+
+// contact00009 is the contacts table at version 9.
+type contact00009 struct {
+	ID        uint64 `gorm:"primary_key"`
+	CompanyID uint64 `gorm:"index"`
+	Name      string `gorm:"type:varchar(255)"`
+	Role      string `gorm:"type:varchar(255)"`
+	Email     string `gorm:"type:varchar(255)"`
+	Phone     string `gorm:"type:varchar(255)"`
+}
+
+func (contact00009) TableName() string {
+	return "contacts"
+}
+
+func init() {
+	register(Migration{
+		Version: 9,
+		Name:    "create_contacts",
+		Up: func(tx *gorm.DB, _ *configs.Config) error {
+			if tx.Migrator().HasTable(&contact00009{}) {
+				return nil
+			}
+			return tx.Migrator().CreateTable(&contact00009{})
+		},
+		Down: func(tx *gorm.DB, _ *configs.Config) error {
+			return tx.Migrator().DropTable(&contact00009{})
+		},
+	})
+}
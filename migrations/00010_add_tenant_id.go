@@ -0,0 +1,109 @@
+package migrations
+
+import (
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"gorm.io/gorm"
+)
+
+// company00010 is the companies table column added at version 10.
+type company00010 struct {
+	TenantID string `gorm:"type:varchar(64);not null;default:''"`
+}
+
+func (company00010) TableName() string {
+	return "companies"
+}
+
+// contact00010 is the contacts table column added at version 10.
+type contact00010 struct {
+	TenantID string `gorm:"type:varchar(64);not null;default:''"`
+}
+
+func (contact00010) TableName() string {
+	return "contacts"
+}
+
+// companyTenant00010 is the companies table index added at version 10, when the code is unique in the tenant.
+type companyTenant00010 struct {
+	TenantID string `gorm:"type:varchar(64);uniqueIndex:idx_companies_tenant_code_unique"`
+	Code     string `gorm:"type:varchar(255);uniqueIndex:idx_companies_tenant_code_unique"`
+}
+
+func (companyTenant00010) TableName() string {
+	return "companies"
+}
+
+// companyTenantPerCountry00010 is the companies table index added at version 10,
+// when the code is unique per country in the tenant.
+type companyTenantPerCountry00010 struct {
+	TenantID string `gorm:"type:varchar(64);uniqueIndex:idx_companies_tenant_country_code_unique"`
+	Country  string `gorm:"type:varchar(2);uniqueIndex:idx_companies_tenant_country_code_unique"`
+	Code     string `gorm:"type:varchar(255);uniqueIndex:idx_companies_tenant_country_code_unique"`
+}
+
+func (companyTenantPerCountry00010) TableName() string {
+	return "companies"
+}
+
+func init() {
+	register(Migration{
+		Version: 10,
+		Name:    "add_tenant_id",
+		// Existing companies and contacts are moved to the default tenant,
+		// and the code becomes unique in the tenant instead of the whole table.
+		Up: func(tx *gorm.DB, cfg *configs.Config) error {
+			for _, table := range []interface{}{&company00010{}, &contact00010{}} {
+				if tx.Migrator().HasColumn(table, "TenantID") {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(table, "TenantID"); err != nil {
+					return err
+				}
+				if err := tx.Model(table).Where("1 = 1").Update("tenant_id", cfg.Tenants.Default).Error; err != nil {
+					return err
+				}
+			}
+
+			if err := dropCodeIndexes(tx, &companyPerCountry00005{}, "idx_companies_country_code_unique", &company00005{}, "idx_companies_code_unique"); err != nil {
+				return err
+			}
+
+			if cfg.Companies.UniqueCodePerCountry {
+				return tx.Migrator().CreateIndex(&companyTenantPerCountry00010{}, "idx_companies_tenant_country_code_unique")
+			}
+			return tx.Migrator().CreateIndex(&companyTenant00010{}, "idx_companies_tenant_code_unique")
+		},
+		// Rolling back fails, if the same code is used by different tenants.
+		Down: func(tx *gorm.DB, cfg *configs.Config) error {
+			if err := dropCodeIndexes(tx, &companyTenantPerCountry00010{}, "idx_companies_tenant_country_code_unique", &companyTenant00010{}, "idx_companies_tenant_code_unique"); err != nil {
+				return err
+			}
+
+			// SQLite rebuilds the table to drop the column, so the index is created afterwards
+			if err := tx.Migrator().DropColumn(&contact00010{}, "TenantID"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropColumn(&company00010{}, "TenantID"); err != nil {
+				return err
+			}
+
+			if cfg.Companies.UniqueCodePerCountry {
+				return tx.Migrator().CreateIndex(&companyPerCountry00005{}, "idx_companies_country_code_unique")
+			}
+			return tx.Migrator().CreateIndex(&company00005{}, "idx_companies_code_unique")
+		},
+	})
+}
+
+// dropCodeIndexes drops the unique code indexes, created for either of the code scopes.
+func dropCodeIndexes(tx *gorm.DB, perCountry interface{}, perCountryName string, global interface{}, globalName string) error {
+	if tx.Migrator().HasIndex(perCountry, perCountryName) {
+		if err := tx.Migrator().DropIndex(perCountry, perCountryName); err != nil {
+			return err
+		}
+	}
+	if tx.Migrator().HasIndex(global, globalName) {
+		return tx.Migrator().DropIndex(global, globalName)
+	}
+	return nil
+}
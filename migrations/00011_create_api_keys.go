@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"gorm.io/gorm"
+)
+
+// apiKey00011 is the api_keys table at version 11.
+type apiKey00011 struct {
+	ID         uint64 `gorm:"primary_key"`
+	Name       string `gorm:"type:varchar(255)"`
+	Prefix     string `gorm:"type:varchar(32);uniqueIndex"`
+	Hash       string `gorm:"type:varchar(64)"`
+	Scopes     string `gorm:"type:varchar(255)"`
+	TenantID   string `gorm:"type:varchar(64);index"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+func (apiKey00011) TableName() string {
+	return "api_keys"
+}
+
+func init() {
+	register(Migration{
+		Version: 11,
+		Name:    "create_api_keys",
+		Up: func(tx *gorm.DB, _ *configs.Config) error {
+			if tx.Migrator().HasTable(&apiKey00011{}) {
+				return nil
+			}
+			return tx.Migrator().CreateTable(&apiKey00011{})
+		},
+		Down: func(tx *gorm.DB, _ *configs.Config) error {
+			return tx.Migrator().DropTable(&apiKey00011{})
+		},
+	})
+}
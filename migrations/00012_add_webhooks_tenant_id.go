@@ -0,0 +1,62 @@
+package migrations
+
+import (
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"gorm.io/gorm"
+)
+
+// event00012 is the outbox_events table column added at version 12.
+type event00012 struct {
+	TenantID string `gorm:"type:varchar(64);index"`
+}
+
+func (event00012) TableName() string {
+	return "outbox_events"
+}
+
+// delivery00012 is the webhook_deliveries table column added at version 12.
+type delivery00012 struct {
+	TenantID string `gorm:"type:varchar(64);index"`
+}
+
+func (delivery00012) TableName() string {
+	return "webhook_deliveries"
+}
+
+func init() {
+	register(Migration{
+		Version: 12,
+		Name:    "add_webhooks_tenant_id",
+		// Existing events and deliveries are moved to the default tenant, like the companies at version 10.
+		Up: func(tx *gorm.DB, cfg *configs.Config) error {
+			for _, table := range []interface{}{&event00012{}, &delivery00012{}} {
+				if tx.Migrator().HasColumn(table, "TenantID") {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(table, "TenantID"); err != nil {
+					return err
+				}
+				if err := tx.Migrator().CreateIndex(table, "TenantID"); err != nil {
+					return err
+				}
+				if err := tx.Model(table).Where("1 = 1").Update("tenant_id", cfg.Tenants.Default).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB, _ *configs.Config) error {
+			for _, table := range []interface{}{&delivery00012{}, &event00012{}} {
+				if tx.Migrator().HasIndex(table, "TenantID") {
+					if err := tx.Migrator().DropIndex(table, "TenantID"); err != nil {
+						return err
+					}
+				}
+				if err := tx.Migrator().DropColumn(table, "TenantID"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,70 @@
+package migrations
+
+import (
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"gorm.io/gorm"
+)
+
+// auditHead00013 is the audit_heads table at version 13.
+type auditHead00013 struct {
+	ID       uint64 `gorm:"primary_key"`
+	RecordID uint64
+	Hash     string `gorm:"type:varchar(64)"`
+}
+
+func (auditHead00013) TableName() string {
+	return "audit_heads"
+}
+
+func init() {
+	register(Migration{
+		Version: 13,
+		Name:    "create_audit_heads",
+		// The records were chained per company, they are chained again into a single chain in the order of their ids.
+		// The head row is created here, so the writers only lock and update it.
+		Up: func(tx *gorm.DB, _ *configs.Config) error {
+			if !tx.Migrator().HasTable(&auditHead00013{}) {
+				if err := tx.Migrator().CreateTable(&auditHead00013{}); err != nil {
+					return err
+				}
+			}
+
+			records := []models.AuditRecord{}
+			if err := tx.Order("id").Find(&records).Error; err != nil {
+				return err
+			}
+
+			head := auditHead00013{ID: models.AuditHeadID}
+			for _, r := range records {
+				r.PrevHash = head.Hash
+				r.Hash = r.ComputeHash()
+				if err := tx.Model(&r).Updates(map[string]interface{}{"prev_hash": r.PrevHash, "hash": r.Hash}).Error; err != nil {
+					return err
+				}
+				head.RecordID, head.Hash = r.ID, r.Hash
+			}
+
+			return tx.Save(&head).Error
+		},
+		// The records are chained per company again, like they were at version 4.
+		Down: func(tx *gorm.DB, _ *configs.Config) error {
+			records := []models.AuditRecord{}
+			if err := tx.Order("id").Find(&records).Error; err != nil {
+				return err
+			}
+
+			heads := map[uint64]string{}
+			for _, r := range records {
+				r.PrevHash = heads[r.CompanyID]
+				r.Hash = r.ComputeHash()
+				if err := tx.Model(&r).Updates(map[string]interface{}{"prev_hash": r.PrevHash, "hash": r.Hash}).Error; err != nil {
+					return err
+				}
+				heads[r.CompanyID] = r.Hash
+			}
+
+			return tx.Migrator().DropTable(&auditHead00013{})
+		},
+	})
+}
@@ -0,0 +1,215 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"gorm.io/gorm"
+)
+
+// ErrUnknownVersion is an error raised when the schema version is not known to the migrator.
+var ErrUnknownVersion = errors.New("unknown schema version")
+
+// ErrPendingMigrations is an error raised when the schema is behind the latest version.
+var ErrPendingMigrations = errors.New("pending migrations")
+
+// Migration defines a single versioned schema change.
+type Migration struct {
+	Version uint
+	Name    string
+	Up      func(*gorm.DB, *configs.Config) error
+	Down    func(*gorm.DB, *configs.Config) error
+}
+
+// registered holds all known migrations, each migration file registers itself.
+var registered []Migration
+
+// register adds the migration to the list of known migrations.
+func register(m Migration) {
+	registered = append(registered, m)
+	sort.Slice(registered, func(i, j int) bool { return registered[i].Version < registered[j].Version })
+}
+
+// schemaMigration is a record of the applied migration.
+type schemaMigration struct {
+	Version   uint `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status defines the state of a single migration.
+type Status struct {
+	Version uint
+	Name    string
+	Applied bool
+}
+
+// Migrator applies and rolls back the migrations.
+type Migrator struct {
+	db         *gorm.DB
+	cfg        *configs.Config
+	migrations []Migration
+}
+
+// NewMigrator creates a new instance of the Migrator with all known migrations.
+// Some migrations depend on the configuration, e.g. the scope of unique indexes.
+func NewMigrator(db *gorm.DB, cfg *configs.Config) *Migrator {
+	return &Migrator{db: db, cfg: cfg, migrations: registered}
+}
+
+// Latest returns the latest known schema version.
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version returns the current schema version.
+func (m *Migrator) Version() (uint, error) {
+	if err := m.db.AutoMigrate(&schemaMigration{}); err != nil {
+		return 0, err
+	}
+
+	var version uint
+	err := m.db.Model(&schemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// Check verifies that the schema is at the latest known version.
+func (m *Migrator) Check() error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	if version > m.Latest() {
+		return fmt.Errorf("%w: %d, latest known version is %d", ErrUnknownVersion, version, m.Latest())
+	}
+
+	if version < m.Latest() {
+		return fmt.Errorf("%w: schema is at version %d, latest version is %d", ErrPendingMigrations, version, m.Latest())
+	}
+
+	return nil
+}
+
+// Status returns the state of all known migrations.
+func (m *Migrator) Status() ([]Status, error) {
+	version, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		statuses = append(statuses, Status{Version: mig.Version, Name: mig.Name, Applied: mig.Version <= version})
+	}
+
+	return statuses, nil
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up() error {
+	return m.To(m.Latest())
+}
+
+// Down rolls back the latest applied migration.
+func (m *Migrator) Down() error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	target := uint(0)
+	for _, mig := range m.migrations {
+		if mig.Version < version {
+			target = mig.Version
+		}
+	}
+
+	return m.To(target)
+}
+
+// To applies or rolls back the migrations, until the schema is at the given version.
+func (m *Migrator) To(target uint) error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	if version > m.Latest() {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	if target != 0 && !m.known(target) {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, target)
+	}
+
+	for _, mig := range m.migrations {
+		if mig.Version > version && mig.Version <= target {
+			if err := m.apply(mig); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		if mig := m.migrations[i]; mig.Version <= version && mig.Version > target {
+			if err := m.rollback(mig); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// known reports whether the migration with the given version exists.
+func (m *Migrator) known(version uint) bool {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// apply runs the migration and records it as applied.
+func (m *Migrator) apply(mig Migration) error {
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		if err := mig.Up(tx, m.cfg); err != nil {
+			return err
+		}
+		return tx.Create(&schemaMigration{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now()}).Error
+	})
+
+	if err != nil {
+		return fmt.Errorf("cannot apply migration %d %s: %w", mig.Version, mig.Name, err)
+	}
+
+	return nil
+}
+
+// rollback reverts the migration and removes its record.
+func (m *Migrator) rollback(mig Migration) error {
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		if err := mig.Down(tx, m.cfg); err != nil {
+			return err
+		}
+		return tx.Delete(&schemaMigration{Version: mig.Version}).Error
+	})
+
+	if err != nil {
+		return fmt.Errorf("cannot roll back migration %d %s: %w", mig.Version, mig.Name, err)
+	}
+
+	return nil
+}
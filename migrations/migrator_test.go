@@ -0,0 +1,164 @@
+package migrations
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/brokeyourbike/xm-golang-exercise/configs"
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openTestDatabase(t *testing.T) *gorm.DB {
+	orm, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	return orm
+}
+
+func TestMigratorUpAndDown(t *testing.T) {
+	orm := openTestDatabase(t)
+	m := NewMigrator(orm, &configs.Config{})
+
+	require.ErrorIs(t, m.Check(), ErrPendingMigrations)
+
+	require.NoError(t, m.Up())
+	require.NoError(t, m.Check())
+	assert.True(t, orm.Migrator().HasTable("companies"))
+	assert.True(t, orm.Migrator().HasTable("audit_records"))
+	assert.True(t, orm.Migrator().HasColumn(&company00003{}, "Version"))
+
+	require.NoError(t, m.Down())
+	version, err := m.Version()
+	require.NoError(t, err)
+	assert.Equal(t, m.Latest()-1, version)
+
+	require.NoError(t, m.To(3))
+	assert.False(t, orm.Migrator().HasTable("audit_records"))
+
+	require.NoError(t, m.To(0))
+	assert.False(t, orm.Migrator().HasTable("companies"))
+
+	require.NoError(t, m.To(2))
+	assert.True(t, orm.Migrator().HasColumn(&company00002{}, "DeletedAt"))
+	assert.False(t, orm.Migrator().HasColumn(&company00003{}, "Version"))
+}
+
+func TestMigratorStatus(t *testing.T) {
+	m := NewMigrator(openTestDatabase(t), &configs.Config{})
+	require.NoError(t, m.To(1))
+
+	statuses, err := m.Status()
+	require.NoError(t, err)
+	require.Len(t, statuses, len(registered))
+
+	assert.Equal(t, Status{Version: 1, Name: "create_companies", Applied: true}, statuses[0])
+	assert.False(t, statuses[1].Applied)
+}
+
+func TestMigratorUnknownVersion(t *testing.T) {
+	orm := openTestDatabase(t)
+	m := NewMigrator(orm, &configs.Config{})
+	require.NoError(t, m.Up())
+
+	require.NoError(t, orm.Create(&schemaMigration{Version: m.Latest() + 1, Name: "from_the_future"}).Error)
+
+	assert.ErrorIs(t, m.Check(), ErrUnknownVersion)
+	assert.ErrorIs(t, m.Up(), ErrUnknownVersion)
+	assert.ErrorIs(t, NewMigrator(orm, &configs.Config{}).To(42), ErrUnknownVersion)
+}
+
+func TestMigratorExistingSchema(t *testing.T) {
+	orm := openTestDatabase(t)
+	require.NoError(t, orm.Migrator().CreateTable(&company00001{}, &auditRecord00004{}))
+	require.NoError(t, orm.Migrator().AddColumn(&company00002{}, "DeletedAt"))
+	require.NoError(t, orm.Migrator().AddColumn(&company00003{}, "Version"))
+
+	m := NewMigrator(orm, &configs.Config{})
+	require.NoError(t, m.Up())
+	require.NoError(t, m.Check())
+}
+
+func TestMigratorUniqueCodeScope(t *testing.T) {
+	orm := openTestDatabase(t)
+
+	m := NewMigrator(orm, &configs.Config{})
+	require.NoError(t, m.To(5))
+	assert.True(t, orm.Migrator().HasIndex(&company00005{}, "idx_companies_code_unique"))
+
+	require.NoError(t, m.Up())
+	assert.False(t, orm.Migrator().HasIndex(&company00005{}, "idx_companies_code_unique"))
+	assert.True(t, orm.Migrator().HasIndex(&companyTenant00010{}, "idx_companies_tenant_code_unique"))
+
+	cfg := configs.Config{}
+	cfg.Companies.UniqueCodePerCountry = true
+
+	m = NewMigrator(orm, &cfg)
+	require.NoError(t, m.To(4))
+	require.NoError(t, m.To(5))
+	assert.False(t, orm.Migrator().HasIndex(&company00005{}, "idx_companies_code_unique"))
+	assert.True(t, orm.Migrator().HasIndex(&companyPerCountry00005{}, "idx_companies_country_code_unique"))
+
+	require.NoError(t, m.Up())
+	assert.False(t, orm.Migrator().HasIndex(&companyPerCountry00005{}, "idx_companies_country_code_unique"))
+	assert.True(t, orm.Migrator().HasIndex(&companyTenantPerCountry00010{}, "idx_companies_tenant_country_code_unique"))
+}
+
+func TestMigratorMovesCompaniesToDefaultTenant(t *testing.T) {
+	orm := openTestDatabase(t)
+
+	cfg := configs.Config{}
+	cfg.Tenants.Default = "default"
+
+	m := NewMigrator(orm, &cfg)
+	require.NoError(t, m.To(9))
+	require.NoError(t, orm.Create(&company00001{Name: "acme", Code: "c1"}).Error)
+
+	require.NoError(t, m.Up())
+
+	var tenants []string
+	require.NoError(t, orm.Table("companies").Pluck("tenant_id", &tenants).Error)
+	assert.Equal(t, []string{"default"}, tenants)
+
+	require.NoError(t, m.To(9))
+	assert.False(t, orm.Migrator().HasColumn(&company00010{}, "TenantID"))
+	assert.True(t, orm.Migrator().HasIndex(&company00005{}, "idx_companies_code_unique"))
+}
+
+func TestMigratorChainsAuditOfAllCompanies(t *testing.T) {
+	orm := openTestDatabase(t)
+
+	m := NewMigrator(orm, &configs.Config{})
+	require.NoError(t, m.To(12))
+
+	// the records are chained per company before version 13
+	for _, companyID := range []uint64{1, 2, 1} {
+		var prev models.AuditRecord
+		orm.Where("company_id = ?", companyID).Order("id DESC").Limit(1).Find(&prev)
+
+		record := models.AuditRecord{CompanyID: companyID, Action: models.AuditActionUpdated, Changes: models.Changes{}, PrevHash: prev.Hash}
+		record.Hash = record.ComputeHash()
+		require.NoError(t, orm.Create(&record).Error)
+	}
+
+	require.NoError(t, m.Up())
+
+	var (
+		records []models.AuditRecord
+		head    models.AuditHead
+	)
+	require.NoError(t, orm.Order("id").Find(&records).Error)
+	require.NoError(t, orm.First(&head, models.AuditHeadID).Error)
+	assert.True(t, models.VerifyAuditTrail(records, head))
+
+	require.NoError(t, m.To(12))
+	require.NoError(t, orm.Order("id").Find(&records).Error)
+	assert.Equal(t, "", records[1].PrevHash)
+	assert.Equal(t, records[0].Hash, records[2].PrevHash)
+	assert.False(t, orm.Migrator().HasTable("audit_heads"))
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrAPIKeyNotFound is an error raised when an API key can not be found
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// Scopes granted to the API keys.
+const (
+	ScopeCompaniesRead   = "companies:read"
+	ScopeCompaniesWrite  = "companies:write"
+	ScopeCompaniesDelete = "companies:delete"
+)
+
+// ScopeAPIKeysManage is the scope of the token required to manage the API keys,
+// it cannot be granted to the API keys.
+const ScopeAPIKeysManage = "api-keys:manage"
+
+// Scopes is a list of scopes, stored in the database as a space separated string.
+type Scopes []string
+
+// Value implements the driver.Valuer interface.
+func (s Scopes) Value() (driver.Value, error) {
+	return strings.Join(s, " "), nil
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *Scopes) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		*s = strings.Fields(string(v))
+		return nil
+	case string:
+		*s = strings.Fields(v)
+		return nil
+	case nil:
+		*s = Scopes{}
+		return nil
+	}
+	return errors.New("unsupported scopes type")
+}
+
+// Has reports whether the scope is in the list.
+func (s Scopes) Has(scope string) bool {
+	for _, v := range s {
+		if v == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey defines the credentials of a service caller.
+// Only the hash of the key is stored, the key itself is returned once, when it is created or rotated.
+// Prefix is the public part of the key, used to find the key when it is used.
+type APIKey struct {
+	ID         uint64     `json:"id" gorm:"primary_key"`
+	Name       string     `json:"name" gorm:"type:varchar(255)"`
+	Prefix     string     `json:"prefix" gorm:"type:varchar(32);uniqueIndex"`
+	Hash       string     `json:"-" gorm:"type:varchar(64)"`
+	Scopes     Scopes     `json:"scopes" gorm:"type:varchar(255)"`
+	TenantID   string     `json:"-" gorm:"type:varchar(64);index"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// Active reports whether the key is neither revoked nor expired at the given time.
+func (k APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
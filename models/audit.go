@@ -0,0 +1,143 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Audit actions recorded for the company changes.
+const (
+	AuditActionCreated  = "created"
+	AuditActionUpdated  = "updated"
+	AuditActionDeleted  = "deleted"
+	AuditActionRestored = "restored"
+)
+
+// FieldChange defines the value of the field before and after the change.
+type FieldChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Changes is a set of field changes, stored in the database as JSON.
+type Changes map[string]FieldChange
+
+// Value implements the driver.Valuer interface.
+func (c Changes) Value() (driver.Value, error) {
+	b, err := json.Marshal(c)
+	return string(b), err
+}
+
+// Scan implements the sql.Scanner interface.
+func (c *Changes) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	case nil:
+		*c = Changes{}
+		return nil
+	}
+	return errors.New("unsupported changes type")
+}
+
+// Diff returns the changes of the company fields.
+func Diff(before Company, after Company) Changes {
+	changes := Changes{}
+
+	for _, f := range []string{"name", "code", "country", "website", "phone", "parent_id"} {
+		if from, to := before.Field(f), after.Field(f); from != to {
+			changes[f] = FieldChange{From: from, To: to}
+		}
+	}
+
+	return changes
+}
+
+// AuditMeta defines who made the change and where the change came from.
+type AuditMeta struct {
+	Actor     string
+	RequestID string
+	ClientIP  string
+}
+
+// auditMetaCtxKey is a key used for the AuditMeta object in the context
+type auditMetaCtxKey struct{}
+
+// WithAuditMeta returns a copy of the context with the given AuditMeta.
+func WithAuditMeta(ctx context.Context, meta AuditMeta) context.Context {
+	return context.WithValue(ctx, auditMetaCtxKey{}, meta)
+}
+
+// AuditMetaFrom returns the AuditMeta stored in the context.
+func AuditMetaFrom(ctx context.Context) AuditMeta {
+	meta, _ := ctx.Value(auditMetaCtxKey{}).(AuditMeta)
+	return meta
+}
+
+// AuditRecord defines a single change of the company.
+// Records of all companies form a single chain ordered by the id, each record is chained
+// to the previous record by including the previous record hash into its own hash.
+// A deleted record breaks the chain of the records written after it, whichever company they belong to.
+type AuditRecord struct {
+	ID        uint64    `json:"id" gorm:"primary_key"`
+	CompanyID uint64    `json:"company_id" gorm:"index"`
+	Action    string    `json:"action" gorm:"type:varchar(16)"`
+	Changes   Changes   `json:"changes" gorm:"type:text"`
+	Actor     string    `json:"actor" gorm:"type:varchar(255)"`
+	RequestID string    `json:"request_id" gorm:"type:varchar(255)"`
+	ClientIP  string    `json:"client_ip" gorm:"type:varchar(45)"`
+	CreatedAt time.Time `json:"created_at"`
+	PrevHash  string    `json:"prev_hash" gorm:"type:varchar(64)"`
+	Hash      string    `json:"hash" gorm:"type:varchar(64)"`
+}
+
+// ComputeHash returns the SHA-256 hash of the record content.
+func (a AuditRecord) ComputeHash() string {
+	b, _ := json.Marshal([]interface{}{
+		a.PrevHash,
+		a.CompanyID,
+		a.Action,
+		a.Changes,
+		a.Actor,
+		a.RequestID,
+		a.ClientIP,
+		a.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditHeadID is the id of the only AuditHead row.
+const AuditHeadID = 1
+
+// AuditHead defines the latest record of the audit chain.
+// It is updated together with every new record, so the records deleted
+// from the end of the chain, which no later record refers to, are detected.
+type AuditHead struct {
+	ID       uint64 `gorm:"primary_key"`
+	RecordID uint64
+	Hash     string `gorm:"type:varchar(64)"`
+}
+
+// VerifyAuditTrail reports whether all records, ordered from the oldest,
+// form an unbroken chain ending at the head and none of them was tampered with.
+func VerifyAuditTrail(records []AuditRecord, head AuditHead) bool {
+	prev := AuditRecord{}
+
+	for _, r := range records {
+		if r.PrevHash != prev.Hash || r.Hash != r.ComputeHash() {
+			return false
+		}
+		prev = r
+	}
+
+	return prev.ID == head.RecordID && prev.Hash == head.Hash
+}
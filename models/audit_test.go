@@ -0,0 +1,46 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	before := Company{Name: "before", Code: "c1", Country: "CY"}
+	after := Company{Name: "after", Code: "c1", Country: "GR"}
+
+	assert.Equal(t, Changes{
+		"name":    {From: "before", To: "after"},
+		"country": {From: "CY", To: "GR"},
+	}, Diff(before, after))
+}
+
+func TestVerifyAuditTrail(t *testing.T) {
+	records := []AuditRecord{
+		{ID: 1, CompanyID: 1, Action: AuditActionCreated, Changes: Changes{"name": {To: "john"}}, CreatedAt: time.Now()},
+		{ID: 2, CompanyID: 2, Action: AuditActionCreated, Changes: Changes{"name": {To: "jane"}}, CreatedAt: time.Now()},
+		{ID: 3, CompanyID: 1, Action: AuditActionDeleted, Changes: Changes{}, CreatedAt: time.Now()},
+		{ID: 4, CompanyID: 2, Action: AuditActionDeleted, Changes: Changes{}, CreatedAt: time.Now()},
+	}
+	prev := ""
+	for i := range records {
+		records[i].PrevHash = prev
+		records[i].Hash = records[i].ComputeHash()
+		prev = records[i].Hash
+	}
+	head := AuditHead{ID: AuditHeadID, RecordID: 4, Hash: prev}
+
+	assert.True(t, VerifyAuditTrail(records, head))
+	assert.True(t, VerifyAuditTrail(nil, AuditHead{ID: AuditHeadID}), "chain is empty")
+
+	assert.False(t, VerifyAuditTrail(records[1:], head), "first record is deleted")
+	assert.False(t, VerifyAuditTrail([]AuditRecord{records[0], records[1], records[3]}, head), "latest record of the company is deleted")
+	assert.False(t, VerifyAuditTrail(records[:3], head), "tail is deleted")
+	assert.False(t, VerifyAuditTrail(nil, head), "all records are deleted")
+
+	tampered := append([]AuditRecord{}, records...)
+	tampered[0].Actor = "someone else"
+	assert.False(t, VerifyAuditTrail(tampered, head), "record is modified")
+}
@@ -0,0 +1,7 @@
+package models
+
+// CacheStats holds the counters of the cache.
+type CacheStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
@@ -2,11 +2,44 @@ package models
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm"
 )
 
 // ErrCompanyNotFound is an error raised when a product can not be found in the database
 var ErrCompanyNotFound = errors.New("company not found")
 
+// ErrCompanyModified is an error raised when a company was modified since it was loaded
+var ErrCompanyModified = errors.New("company modified")
+
+// ErrCompanyConflict is an error raised when a company conflicts with an existing one
+var ErrCompanyConflict = errors.New("company conflict")
+
+// ErrParentNotFound is an error raised when the parent company can not be found
+var ErrParentNotFound = errors.New("parent company not found")
+
+// ErrCompanyCycle is an error raised when a company would become its own ancestor
+var ErrCompanyCycle = errors.New("company cannot be its own ancestor")
+
+// ErrCompanyHasChildren is an error raised when a company with subsidiaries is deleted
+var ErrCompanyHasChildren = errors.New("company has subsidiaries")
+
+// ConflictError describes the unique field of a company, already taken by the existing company.
+type ConflictError struct {
+	Field      string
+	ExistingID uint64
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s is already used by company %d", e.Field, e.ExistingID)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrCompanyConflict
+}
+
 // Company defines the structure for an API company
 type Company struct {
 	ID      uint64 `json:"id" gorm:"primary_key"`
@@ -15,4 +48,88 @@ type Company struct {
 	Country string `json:"country" gorm:"type:varchar(2);index"`
 	Website string `json:"website" gorm:"type:varchar(255);index"`
 	Phone   string `json:"phone" gorm:"type:varchar(255);index"`
+
+	ParentID *uint64 `json:"parent_id,omitempty" gorm:"index"`
+
+	// TenantID is the business unit owning the company, it is set from the request context.
+	TenantID string `json:"-" gorm:"type:varchar(64)"`
+
+	Version   uint64         `json:"-" gorm:"not null;default:1"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// ETag returns the entity tag of the current company version.
+func (c Company) ETag() string {
+	return fmt.Sprintf(`"%d"`, c.Version)
+}
+
+// Field returns the value of the company field by its name.
+func (c Company) Field(name string) string {
+	switch name {
+	case "name":
+		return c.Name
+	case "code":
+		return c.Code
+	case "country":
+		return c.Country
+	case "website":
+		return c.Website
+	case "phone":
+		return c.Phone
+	case "parent_id":
+		if c.ParentID == nil {
+			return ""
+		}
+		return strconv.FormatUint(*c.ParentID, 10)
+	}
+	return ""
+}
+
+// CompanyTree defines a company with its subsidiaries
+type CompanyTree struct {
+	Company
+	Children []CompanyTree `json:"children"`
+}
+
+// NewCompanyTree creates the tree of the root company from all its descendants.
+// Descendants are ordered the same as the given ones.
+func NewCompanyTree(root Company, descendants []Company) CompanyTree {
+	children := make(map[uint64][]Company)
+	for _, company := range descendants {
+		if company.ParentID != nil {
+			children[*company.ParentID] = append(children[*company.ParentID], company)
+		}
+	}
+
+	var build func(Company) CompanyTree
+	build = func(company Company) CompanyTree {
+		tree := CompanyTree{Company: company, Children: []CompanyTree{}}
+		for _, child := range children[company.ID] {
+			tree.Children = append(tree.Children, build(child))
+		}
+		return tree
+	}
+
+	return build(root)
+}
+
+// CompaniesPage defines a single page of companies
+type CompaniesPage struct {
+	Companies  []Company
+	NextCursor string
+	HasMore    bool
+}
+
+// SearchResult defines a single company matching the search query
+type SearchResult struct {
+	Company    Company           `json:"company"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// SearchPage defines a single page of the search results
+type SearchPage struct {
+	Results    []SearchResult
+	NextCursor string
+	HasMore    bool
 }
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// CompanyEventSchemaVersion is the version of the CompanyEvent schema.
+// It is increased on every change, which is not backward compatible.
+const CompanyEventSchemaVersion = 1
+
+// CompanySnapshot defines the company, as it was right after the change.
+type CompanySnapshot struct {
+	ID      uint64 `json:"id"`
+	Name    string `json:"name"`
+	Code    string `json:"code"`
+	Country string `json:"country"`
+	Website string `json:"website"`
+	Phone   string `json:"phone"`
+	Version uint64 `json:"version"`
+	Deleted bool   `json:"deleted"`
+
+	ParentID *uint64 `json:"parent_id,omitempty"`
+	TenantID string  `json:"tenant_id"`
+}
+
+// CompanyEvent defines a company change, published to the message broker.
+// Changes hold the changed fields, they are empty for deletes and restores.
+type CompanyEvent struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	SchemaVersion int             `json:"schema_version"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Company       CompanySnapshot `json:"company"`
+	Changes       Changes         `json:"changes"`
+}
+
+// NewCompanyEvent creates a new CompanyEvent with a random id.
+func NewCompanyEvent(eventType string, company Company, changes Changes) CompanyEvent {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	if changes == nil {
+		changes = Changes{}
+	}
+
+	return CompanyEvent{
+		ID:            hex.EncodeToString(id),
+		Type:          eventType,
+		SchemaVersion: CompanyEventSchemaVersion,
+		OccurredAt:    time.Now().UTC().Truncate(time.Millisecond),
+		Company: CompanySnapshot{
+			ID:      company.ID,
+			Name:    company.Name,
+			Code:    company.Code,
+			Country: company.Country,
+			Website: company.Website,
+			Phone:   company.Phone,
+			Version: company.Version,
+			Deleted: company.DeletedAt.Valid,
+
+			ParentID: company.ParentID,
+			TenantID: company.TenantID,
+		},
+		Changes: changes,
+	}
+}
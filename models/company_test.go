@@ -0,0 +1,32 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCompanyTree(t *testing.T) {
+	parent := func(id uint64) *uint64 { return &id }
+
+	root := Company{ID: 1, Name: "group"}
+	descendants := []Company{
+		{ID: 2, Name: "first", ParentID: parent(1)},
+		{ID: 3, Name: "second", ParentID: parent(1)},
+		{ID: 4, Name: "nested", ParentID: parent(2)},
+	}
+
+	assert.Equal(t, CompanyTree{Company: root, Children: []CompanyTree{
+		{Company: descendants[0], Children: []CompanyTree{
+			{Company: descendants[2], Children: []CompanyTree{}},
+		}},
+		{Company: descendants[1], Children: []CompanyTree{}},
+	}}, NewCompanyTree(root, descendants))
+}
+
+func TestCompanyParentField(t *testing.T) {
+	id := uint64(5)
+
+	assert.Equal(t, "", Company{}.Field("parent_id"))
+	assert.Equal(t, "5", Company{ParentID: &id}.Field("parent_id"))
+}
@@ -0,0 +1,19 @@
+package models
+
+import "errors"
+
+// ErrContactNotFound is an error raised when a contact can not be found
+var ErrContactNotFound = errors.New("contact not found")
+
+// Contact defines a person to contact in the company.
+// Contacts belong to the company, they are hidden while the company is soft-deleted
+// and removed when the company is purged.
+type Contact struct {
+	ID        uint64 `json:"id" gorm:"primary_key"`
+	CompanyID uint64 `json:"company_id" gorm:"index"`
+	Name      string `json:"name" gorm:"type:varchar(255)"`
+	Role      string `json:"role" gorm:"type:varchar(255)"`
+	Email     string `json:"email" gorm:"type:varchar(255)"`
+	Phone     string `json:"phone" gorm:"type:varchar(255)"`
+	TenantID  string `json:"-" gorm:"type:varchar(64)"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "context"
+
+// principalCtxKey is a key used for the principal in the context
+type principalCtxKey struct{}
+
+// Principal is the authenticated caller of the request.
+type Principal struct {
+	Subject string
+	// Tenant is the tenant the caller belongs to, empty if the caller is not bound to a tenant.
+	Tenant string
+	// APIKey is the prefix of the API key the caller is authenticated with, empty for other credentials.
+	APIKey string
+	Scopes []string
+	// Roles are the roles of the caller, granting the permissions of the authorization policy.
+	Roles []string
+}
+
+// WithPrincipal returns a copy of the context with the given principal.
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalCtxKey{}, principal)
+}
+
+// PrincipalFrom returns the principal stored in the context,
+// the second value reports whether the request is authenticated.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalCtxKey{}).(Principal)
+	return principal, ok
+}
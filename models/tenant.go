@@ -0,0 +1,25 @@
+package models
+
+import "context"
+
+// tenantCtxKey is a key used for the tenant in the context
+type tenantCtxKey struct{}
+
+// WithTenant returns a copy of the context with the given tenant.
+// Repositories limit every query to the companies of the tenant from the context.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantCtxKey{}, tenant)
+}
+
+// TenantFrom returns the tenant stored in the context.
+// A context without the tenant belongs to the empty tenant.
+func TenantFrom(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantCtxKey{}).(string)
+	return tenant
+}
+
+// HasTenant reports whether the tenant is stored in the context.
+func HasTenant(ctx context.Context) bool {
+	_, ok := ctx.Value(tenantCtxKey{}).(string)
+	return ok
+}
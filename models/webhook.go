@@ -0,0 +1,90 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrDeliveryNotFound is an error raised when a webhook delivery can not be found
+var ErrDeliveryNotFound = errors.New("delivery not found")
+
+// Event types emitted for the company changes.
+const (
+	EventCompanyCreated  = "company.created"
+	EventCompanyUpdated  = "company.updated"
+	EventCompanyDeleted  = "company.deleted"
+	EventCompanyRestored = "company.restored"
+)
+
+// Delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// Event defines a single company change, stored in the outbox
+// in the same transaction as the change itself.
+// Data holds the company, as it was right after the change.
+type Event struct {
+	ID           uint64     `json:"id" gorm:"primary_key"`
+	Type         string     `json:"type" gorm:"type:varchar(32)"`
+	CompanyID    uint64     `json:"company_id" gorm:"index"`
+	TenantID     string     `json:"-" gorm:"type:varchar(64);index"`
+	Data         string     `json:"-" gorm:"type:text"`
+	CreatedAt    time.Time  `json:"created_at"`
+	DispatchedAt *time.Time `json:"-" gorm:"index"`
+}
+
+func (Event) TableName() string {
+	return "outbox_events"
+}
+
+// NewEvent creates a new Event of the company change.
+func NewEvent(eventType string, company Company) (Event, error) {
+	data, err := json.Marshal(company)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		Type:      eventType,
+		CompanyID: company.ID,
+		TenantID:  company.TenantID,
+		Data:      string(data),
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+	}, nil
+}
+
+// Body returns the JSON document sent to the webhooks.
+func (e Event) Body() ([]byte, error) {
+	return json.Marshal(struct {
+		ID        uint64          `json:"id"`
+		Type      string          `json:"type"`
+		TenantID  string          `json:"tenant_id"`
+		CreatedAt time.Time       `json:"created_at"`
+		Data      json.RawMessage `json:"data"`
+	}{e.ID, e.Type, e.TenantID, e.CreatedAt, json.RawMessage(e.Data)})
+}
+
+// Delivery defines a single attempt to deliver the event to the webhook URL,
+// which is retried until it succeeds or the attempts are exhausted.
+// It belongs to the tenant of the event, so the deliveries are scoped without joining the events.
+type Delivery struct {
+	ID            uint64     `json:"id" gorm:"primary_key"`
+	EventID       uint64     `json:"event_id" gorm:"index"`
+	Event         Event      `json:"event"`
+	TenantID      string     `json:"-" gorm:"type:varchar(64);index"`
+	URL           string     `json:"url" gorm:"type:varchar(2048)"`
+	Status        string     `json:"status" gorm:"type:varchar(16);index:idx_webhook_deliveries_due"`
+	Attempts      uint       `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"index:idx_webhook_deliveries_due"`
+	LastError     string     `json:"last_error,omitempty" gorm:"type:text"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func (Delivery) TableName() string {
+	return "webhook_deliveries"
+}
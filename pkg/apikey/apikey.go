@@ -0,0 +1,52 @@
+// Package apikey generates and verifies the API keys.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+// keyType is the first part of every key, so the keys are easy to recognize, e.g. by secret scanners.
+const keyType = "xm"
+
+// Lengths of the random parts of the key, in bytes.
+const (
+	prefixLength = 6
+	secretLength = 32
+)
+
+// Generate returns a new random key and its prefix.
+// The key has the form xm_<prefix>_<secret>, the prefix is used to find the key.
+func Generate() (key string, prefix string, err error) {
+	b := make([]byte, prefixLength+secretLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	prefix = hex.EncodeToString(b[:prefixLength])
+	return keyType + "_" + prefix + "_" + hex.EncodeToString(b[prefixLength:]), prefix, nil
+}
+
+// Prefix returns the prefix of the key, the second value reports whether the key is well-formed.
+func Prefix(key string) (string, bool) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != keyType || len(parts[1]) != 2*prefixLength || len(parts[2]) != 2*secretLength {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// Hash returns the hash of the key, which is stored instead of the key.
+// The keys are random with 256 bits of entropy, so a fast hash cannot be brute-forced.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify reports whether the key matches the hash, the hashes are compared in constant time.
+func Verify(key, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(Hash(key)), []byte(hash)) == 1
+}
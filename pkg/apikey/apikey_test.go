@@ -0,0 +1,65 @@
+package apikey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	key, prefix, err := Generate()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(key, "xm_"+prefix+"_"))
+	assert.Len(t, prefix, 12)
+	assert.Len(t, key, 80)
+
+	other, otherPrefix, err := Generate()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+	assert.NotEqual(t, prefix, otherPrefix)
+
+	p, ok := Prefix(key)
+	assert.True(t, ok)
+	assert.Equal(t, prefix, p)
+}
+
+func TestPrefix(t *testing.T) {
+	secret := strings.Repeat("a", 64)
+
+	cases := map[string]struct {
+		key    string
+		prefix string
+		valid  bool
+	}{
+		"valid":          {key: "xm_0123456789ab_" + secret, prefix: "0123456789ab", valid: true},
+		"empty":          {key: ""},
+		"other type":     {key: "sk_0123456789ab_" + secret},
+		"short prefix":   {key: "xm_0123_" + secret},
+		"short secret":   {key: "xm_0123456789ab_abc"},
+		"too many parts": {key: "xm_0123456789ab_" + secret + "_a"},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			prefix, ok := Prefix(c.key)
+			assert.Equal(t, c.valid, ok)
+			assert.Equal(t, c.prefix, prefix)
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	key, _, err := Generate()
+	require.NoError(t, err)
+
+	hash := Hash(key)
+	assert.Len(t, hash, 64)
+	assert.NotContains(t, hash, key)
+
+	assert.True(t, Verify(key, hash))
+	assert.False(t, Verify(key+"0", hash))
+	assert.False(t, Verify(key, ""))
+}
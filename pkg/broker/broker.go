@@ -0,0 +1,27 @@
+// Package broker provides the publishers of messages to the message broker.
+package broker
+
+import "context"
+
+// Publisher publishes messages to the subjects of the message broker.
+type Publisher interface {
+	Publish(ctx context.Context, subject string, data []byte) error
+	Close() error
+}
+
+// Nop is a publisher, which discards all messages.
+// It is used when no message broker is configured.
+type Nop struct{}
+
+// NewNop creates an instance of Nop publisher.
+func NewNop() *Nop {
+	return &Nop{}
+}
+
+func (Nop) Publish(context.Context, string, []byte) error {
+	return nil
+}
+
+func (Nop) Close() error {
+	return nil
+}
@@ -0,0 +1,37 @@
+package broker
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATS publishes messages to the NATS server.
+// Messages are buffered by the connection, so a published message
+// may still be lost if the connection fails before it is flushed.
+type NATS struct {
+	conn *nats.Conn
+}
+
+// NewNATS connects to the NATS server at the given URL.
+// The connection is re-established automatically, if it is lost.
+func NewNATS(url string, options ...nats.Option) (*NATS, error) {
+	conn, err := nats.Connect(url, append([]nats.Option{nats.Name("xm-golang-exercise"), nats.MaxReconnects(-1)}, options...)...)
+	if err != nil {
+		return nil, err
+	}
+	return &NATS{conn: conn}, nil
+}
+
+// Publish publishes the message to the subject.
+func (n *NATS) Publish(ctx context.Context, subject string, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return n.conn.Publish(subject, data)
+}
+
+// Close flushes the buffered messages and closes the connection.
+func (n *NATS) Close() error {
+	return n.conn.Drain()
+}
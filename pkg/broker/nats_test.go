@@ -0,0 +1,92 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/brokeyourbike/xm-golang-exercise/models"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runNATS starts the NATS server on a random port, it is shut down when the test is done.
+func runNATS(t *testing.T) *server.Server {
+	opts := test.DefaultTestOptions
+	opts.Port = -1
+
+	srv := test.RunServer(&opts)
+	t.Cleanup(srv.Shutdown)
+	return srv
+}
+
+func TestNATSPublishesMessages(t *testing.T) {
+	srv := runNATS(t)
+
+	subscriber, err := nats.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	defer subscriber.Close()
+
+	messages := make(chan *nats.Msg, 10)
+	_, err = subscriber.ChanSubscribe("events.>", messages)
+	require.NoError(t, err)
+	require.NoError(t, subscriber.Flush())
+
+	publisher, err := NewNATS(srv.ClientURL())
+	require.NoError(t, err)
+
+	event := models.NewCompanyEvent(models.EventCompanyCreated, models.Company{ID: 1, Name: "acme", Code: "c1", Country: "CY"}, models.Changes{})
+	data, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	require.NoError(t, publisher.Publish(context.Background(), "events.company.created", data))
+	require.NoError(t, publisher.Close())
+
+	select {
+	case m := <-messages:
+		assert.Equal(t, "events.company.created", m.Subject)
+
+		var received models.CompanyEvent
+		require.NoError(t, json.Unmarshal(m.Data, &received))
+		assert.Equal(t, event.ID, received.ID)
+		assert.Equal(t, models.EventCompanyCreated, received.Type)
+		assert.Equal(t, event.Company, received.Company)
+	case <-time.After(time.Second * 5):
+		t.Fatal("message was not published")
+	}
+}
+
+func TestNATSDoesNotPublishWithCancelledContext(t *testing.T) {
+	srv := runNATS(t)
+
+	publisher, err := NewNATS(srv.ClientURL())
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, publisher.Publish(ctx, "events.company.created", []byte(`{}`)), context.Canceled)
+}
+
+func TestNATSCannotConnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	url := "nats://" + listener.Addr().String()
+	listener.Close()
+
+	_, err = NewNATS(url)
+	assert.Error(t, err)
+}
+
+func TestNopDiscardsMessages(t *testing.T) {
+	publisher := NewNop()
+
+	assert.NoError(t, publisher.Publish(context.Background(), "events.company.created", []byte(`{}`)))
+	assert.NoError(t, publisher.Close())
+}
@@ -0,0 +1,219 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoKeys is an error raised when the key set cannot be loaded.
+var ErrNoKeys = errors.New("keys are not available")
+
+// defaultMinRefresh is how often the keys can be reloaded because of an unknown key.
+const defaultMinRefresh = 10 * time.Second
+
+// HTTPClient is used to fetch the remote key set.
+type HTTPClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// jwk is a JSON Web Key, only the members of the supported key types are decoded.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// ParseJWKS parses the JSON Web Key Set.
+// Keys of unsupported types and keys not used for signatures are skipped,
+// so a new key type in the set does not break the verification of other keys.
+func ParseJWKS(data []byte) ([]Key, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("cannot decode key set: %w", err)
+	}
+
+	keys := []Key{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		material, err := k.material()
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode key %q: %w", k.Kid, err)
+		}
+
+		if material != nil {
+			keys = append(keys, Key{ID: k.Kid, Algorithm: k.Alg, Material: material})
+		}
+	}
+
+	return keys, nil
+}
+
+// material returns the key material, or nil if the key type is not supported.
+func (k jwk) material() (interface{}, error) {
+	switch {
+	case k.Kty == "oct":
+		return decodeBytes(k.K)
+	case k.Kty == "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case k.Kty == "EC" && k.Crv == "P-256":
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+	return nil, nil
+}
+
+// JWKS is a key source loading the JSON Web Key Set from a local file or URL.
+// The keys are cached for the TTL, so the rotated keys are picked up without restart.
+// A token signed with an unknown key reloads the keys earlier.
+// The keys are loaded at most once per minRefresh, if they cannot be reloaded the previously loaded keys are used.
+type JWKS struct {
+	location   string
+	httpClient HTTPClient
+	ttl        time.Duration
+	minRefresh time.Duration
+
+	mu          sync.Mutex
+	keys        []Key
+	err         error
+	loadedAt    time.Time
+	attemptedAt time.Time
+	now         func() time.Time
+}
+
+// NewJWKS creates an instance of JWKS.
+// The location is a URL starting with http:// or https://, or a path of the local file.
+func NewJWKS(location string, httpClient HTTPClient, ttl time.Duration) *JWKS {
+	return &JWKS{location: location, httpClient: httpClient, ttl: ttl, minRefresh: defaultMinRefresh, now: time.Now}
+}
+
+// Keys returns the cached keys, reloading them if the cache expired.
+func (j *JWKS) Keys(ctx context.Context, refresh bool) ([]Key, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := j.now()
+
+	if j.keys != nil && now.Sub(j.loadedAt) < j.ttl && !refresh {
+		return j.keys, nil
+	}
+
+	// the location is not requested more often than minRefresh, even when it is not available
+	if now.Sub(j.attemptedAt) < j.minRefresh {
+		if j.keys != nil {
+			return j.keys, nil
+		}
+		return nil, j.err
+	}
+
+	j.attemptedAt = now
+
+	keys, err := j.load(ctx)
+	if err != nil {
+		j.err = fmt.Errorf("%w: %v", ErrNoKeys, err)
+		if j.keys != nil {
+			return j.keys, nil
+		}
+		return nil, j.err
+	}
+
+	j.keys, j.loadedAt, j.err = keys, now, nil
+	return j.keys, nil
+}
+
+// load reads the key set from its location.
+func (j *JWKS) load(ctx context.Context) ([]Key, error) {
+	if !strings.HasPrefix(j.location, "http://") && !strings.HasPrefix(j.location, "https://") {
+		data, err := os.ReadFile(j.location)
+		if err != nil {
+			return nil, err
+		}
+		return ParseJWKS(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.location, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := j.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseJWKS(data)
+}
+
+// decodeBytes decodes the base64url encoded value.
+func decodeBytes(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errors.New("missing key member")
+	}
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+// decodeInt decodes the base64url encoded big-endian integer.
+func decodeInt(s string) (*big.Int, error) {
+	b, err := decodeBytes(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,152 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	data := fmt.Sprintf(`{"keys":[
+		{"kty":"RSA","kid":"rs","alg":"RS256","use":"sig","n":"%s","e":"AQAB"},
+		{"kty":"EC","kid":"es","crv":"P-256","x":"%s","y":"%s"},
+		{"kty":"oct","kid":"hs","k":"c2VjcmV0"},
+		{"kty":"RSA","kid":"enc","use":"enc","n":"%s","e":"AQAB"},
+		{"kty":"OKP","kid":"ed","crv":"Ed25519","x":"AAAA"}
+	]}`, b64(rsaKey.N.Bytes()), b64(ecKey.X.Bytes()), b64(ecKey.Y.Bytes()), b64(rsaKey.N.Bytes()))
+
+	keys, err := ParseJWKS([]byte(data))
+	require.NoError(t, err)
+	require.Len(t, keys, 3)
+
+	assert.Equal(t, Key{ID: "rs", Algorithm: RS256, Material: &rsaKey.PublicKey}, keys[0])
+	assert.Equal(t, "es", keys[1].ID)
+	assert.True(t, ecKey.PublicKey.Equal(keys[1].Material))
+	assert.Equal(t, Key{ID: "hs", Material: []byte("secret")}, keys[2])
+}
+
+func TestParseJWKSFails(t *testing.T) {
+	cases := map[string]string{
+		"invalid JSON":         `{"keys":`,
+		"missing modulus":      `{"keys":[{"kty":"RSA","kid":"rs","e":"AQAB"}]}`,
+		"invalid exponent":     `{"keys":[{"kty":"RSA","kid":"rs","n":"AQAB","e":"AQ"}]}`,
+		"point not on curve":   `{"keys":[{"kty":"EC","kid":"es","crv":"P-256","x":"AQ","y":"AQ"}]}`,
+		"invalid secret":       `{"keys":[{"kty":"oct","kid":"hs","k":"!"}]}`,
+		"missing secret value": `{"keys":[{"kty":"oct","kid":"hs"}]}`,
+	}
+
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseJWKS([]byte(data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestJWKSCachesKeys(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	requests, status := 0, http.StatusOK
+	body := `{"keys":[{"kty":"oct","kid":"1","k":"b25l"}]}`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	jwks := NewJWKS(srv.URL, srv.Client(), time.Minute)
+	jwks.now = func() time.Time { return now }
+
+	keys, err := jwks.Keys(context.Background(), false)
+	require.NoError(t, err)
+	assert.Equal(t, []Key{{ID: "1", Material: []byte("one")}}, keys)
+	assert.Equal(t, 1, requests)
+
+	// the cached keys are used until the TTL expires
+	now = now.Add(30 * time.Second)
+	_, err = jwks.Keys(context.Background(), false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, requests)
+
+	// the keys are rotated
+	body = `{"keys":[{"kty":"oct","kid":"2","k":"dHdv"}]}`
+
+	now = now.Add(31 * time.Second)
+	keys, err = jwks.Keys(context.Background(), false)
+	require.NoError(t, err)
+	assert.Equal(t, []Key{{ID: "2", Material: []byte("two")}}, keys)
+	assert.Equal(t, 2, requests)
+
+	// the refresh is limited
+	now = now.Add(time.Second)
+	_, err = jwks.Keys(context.Background(), true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests)
+
+	now = now.Add(defaultMinRefresh)
+	_, err = jwks.Keys(context.Background(), true)
+	require.NoError(t, err)
+	assert.Equal(t, 3, requests)
+
+	// the previous keys are used, when the keys cannot be loaded
+	status = http.StatusInternalServerError
+
+	now = now.Add(time.Minute)
+	keys, err = jwks.Keys(context.Background(), false)
+	require.NoError(t, err)
+	assert.Equal(t, []Key{{ID: "2", Material: []byte("two")}}, keys)
+	assert.Equal(t, 4, requests)
+}
+
+func TestJWKSFailsWithoutKeys(t *testing.T) {
+	requests := 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	jwks := NewJWKS(srv.URL, srv.Client(), time.Minute)
+
+	_, err := jwks.Keys(context.Background(), false)
+	assert.ErrorIs(t, err, ErrNoKeys)
+
+	_, err = jwks.Keys(context.Background(), true)
+	assert.ErrorIs(t, err, ErrNoKeys)
+	assert.Equal(t, 1, requests)
+}
+
+func TestJWKSLoadsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"keys":[{"kty":"oct","kid":"1","k":"b25l"}]}`), 0600))
+
+	jwks := NewJWKS(path, nil, time.Minute)
+
+	keys, err := jwks.Keys(context.Background(), false)
+	require.NoError(t, err)
+	assert.Equal(t, []Key{{ID: "1", Material: []byte("one")}}, keys)
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
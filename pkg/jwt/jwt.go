@@ -0,0 +1,313 @@
+// Package jwt verifies JSON Web Tokens signed with HS256, RS256 or ES256.
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Supported signing algorithms.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+// Errors returned when the token cannot be verified.
+var (
+	ErrMalformed      = errors.New("token is malformed")
+	ErrUnsupportedAlg = errors.New("token algorithm is not supported")
+	ErrKeyNotFound    = errors.New("token key is not found")
+	ErrSignature      = errors.New("token signature is invalid")
+	ErrNoExpiry       = errors.New("token has no expiry")
+	ErrExpired        = errors.New("token is expired")
+	ErrNotValidYet    = errors.New("token is not valid yet")
+	ErrAudience       = errors.New("token audience is not accepted")
+	ErrIssuer         = errors.New("token issuer is not accepted")
+)
+
+// Key is a key used to verify the token signature.
+// Material is a []byte secret for HS256, a *rsa.PublicKey for RS256 and a *ecdsa.PublicKey for ES256.
+// A key with an empty algorithm can be used by any algorithm of its type.
+type Key struct {
+	ID        string
+	Algorithm string
+	Material  interface{}
+}
+
+// fits reports whether the key can verify the signature of the given algorithm.
+func (k Key) fits(alg string) bool {
+	if k.Algorithm != "" && k.Algorithm != alg {
+		return false
+	}
+
+	switch m := k.Material.(type) {
+	case []byte:
+		return alg == HS256
+	case *rsa.PublicKey:
+		return alg == RS256
+	case *ecdsa.PublicKey:
+		return alg == ES256 && m.Curve == elliptic.P256()
+	}
+	return false
+}
+
+// KeySource provides the keys used to verify the tokens.
+// When refresh is true the source should reload the keys, because the key of the token is not known.
+type KeySource interface {
+	Keys(ctx context.Context, refresh bool) ([]Key, error)
+}
+
+// NumericDate is the number of seconds since the epoch.
+type NumericDate float64
+
+// Time returns the date as time.
+func (d NumericDate) Time() time.Time {
+	sec, frac := math.Modf(float64(d))
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+// Audience is the audience of the token, a single string or an array of strings.
+type Audience []string
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+
+	*a = many
+	return nil
+}
+
+// Claims are the verified claims of the token.
+// All the claims, including the registered ones, are available in Values.
+type Claims struct {
+	Issuer    string       `json:"iss"`
+	Subject   string       `json:"sub"`
+	Audience  Audience     `json:"aud"`
+	ExpiresAt *NumericDate `json:"exp"`
+	NotBefore *NumericDate `json:"nbf"`
+	IssuedAt  *NumericDate `json:"iat"`
+
+	Values map[string]interface{} `json:"-"`
+}
+
+// String returns the claim as a string, or an empty string if the claim is not a string.
+func (c Claims) String(name string) string {
+	s, _ := c.Values[name].(string)
+	return s
+}
+
+// Strings returns the claim as a list of strings.
+// The claim can be an array of strings or a space separated string, as the OAuth scope claim.
+func (c Claims) Strings(name string) []string {
+	switch v := c.Values[name].(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		values := []string{}
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// header is the JOSE header of the token.
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verifier verifies the tokens with the keys of the key source.
+// If the audience is not empty, the token must be issued for one of its values.
+// If the issuer is not empty, the token must be issued by it.
+// Leeway is the allowed clock skew of the exp and nbf claims.
+type Verifier struct {
+	Keys     KeySource
+	Audience []string
+	Issuer   string
+	Leeway   time.Duration
+
+	now func() time.Time
+}
+
+// NewVerifier creates an instance of Verifier.
+func NewVerifier(keys KeySource, audience []string, issuer string, leeway time.Duration) *Verifier {
+	return &Verifier{Keys: keys, Audience: audience, Issuer: issuer, Leeway: leeway, now: time.Now}
+}
+
+// Verify checks the signature and the registered claims of the token, and returns its claims.
+// Tokens without the exp claim are rejected.
+func (v *Verifier) Verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrMalformed
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return Claims{}, ErrMalformed
+	}
+
+	if h.Alg != HS256 && h.Alg != RS256 && h.Alg != ES256 {
+		return Claims{}, ErrUnsupportedAlg
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, ErrMalformed
+	}
+
+	if err := v.verifySignature(ctx, h, parts[0]+"."+parts[1], signature); err != nil {
+		return Claims{}, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, ErrMalformed
+	}
+	if err := decodeSegment(parts[1], &claims.Values); err != nil {
+		return Claims{}, ErrMalformed
+	}
+
+	if err := v.verifyClaims(claims); err != nil {
+		return Claims{}, err
+	}
+
+	return claims, nil
+}
+
+// verifySignature verifies the signature with the keys matching the header,
+// the keys are reloaded once if none of them matches.
+func (v *Verifier) verifySignature(ctx context.Context, h header, signed string, signature []byte) error {
+	keys, err := v.Keys.Keys(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	candidates := matchKeys(keys, h)
+	if len(candidates) == 0 {
+		if keys, err = v.Keys.Keys(ctx, true); err != nil {
+			return err
+		}
+		candidates = matchKeys(keys, h)
+	}
+
+	if len(candidates) == 0 {
+		return ErrKeyNotFound
+	}
+
+	for _, key := range candidates {
+		if verify(h.Alg, key, signed, signature) {
+			return nil
+		}
+	}
+
+	return ErrSignature
+}
+
+// verifyClaims checks the time, audience and issuer claims.
+func (v *Verifier) verifyClaims(claims Claims) error {
+	now := v.now()
+
+	if claims.ExpiresAt == nil {
+		return ErrNoExpiry
+	}
+
+	if !now.Before(claims.ExpiresAt.Time().Add(v.Leeway)) {
+		return ErrExpired
+	}
+
+	if claims.NotBefore != nil && now.Add(v.Leeway).Before(claims.NotBefore.Time()) {
+		return ErrNotValidYet
+	}
+
+	if v.Issuer != "" && claims.Issuer != v.Issuer {
+		return ErrIssuer
+	}
+
+	if len(v.Audience) > 0 && !intersects(v.Audience, claims.Audience) {
+		return ErrAudience
+	}
+
+	return nil
+}
+
+// matchKeys returns the keys with the ID of the header, which can be used by the algorithm of the header.
+// If the header has no key ID, all keys of the algorithm are returned.
+func matchKeys(keys []Key, h header) []Key {
+	var matched []Key
+	for _, key := range keys {
+		if (h.Kid == "" || key.ID == h.Kid) && key.fits(h.Alg) {
+			matched = append(matched, key)
+		}
+	}
+	return matched
+}
+
+// verify reports whether the signature of the signed part of the token is valid.
+func verify(alg string, key Key, signed string, signature []byte) bool {
+	digest := sha256.Sum256([]byte(signed))
+
+	switch alg {
+	case HS256:
+		mac := hmac.New(sha256.New, key.Material.([]byte))
+		mac.Write([]byte(signed))
+		return hmac.Equal(signature, mac.Sum(nil))
+	case RS256:
+		return rsa.VerifyPKCS1v15(key.Material.(*rsa.PublicKey), crypto.SHA256, digest[:], signature) == nil
+	case ES256:
+		// the signature is the concatenation of the 32 bytes long r and s values
+		if len(signature) != 64 {
+			return false
+		}
+		r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(key.Material.(*ecdsa.PublicKey), digest[:], r, s)
+	}
+	return false
+}
+
+// decodeSegment decodes the base64url encoded JSON segment of the token.
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// intersects reports whether the lists have a common value.
+func intersects(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
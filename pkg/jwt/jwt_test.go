@@ -0,0 +1,149 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticKeys is a key source with the fixed keys, counting the refreshes.
+type staticKeys struct {
+	keys      []Key
+	refreshed []Key
+	refreshes int
+}
+
+func (s *staticKeys) Keys(ctx context.Context, refresh bool) ([]Key, error) {
+	if refresh {
+		s.refreshes++
+		if s.refreshed != nil {
+			s.keys = s.refreshed
+		}
+	}
+	return s.keys, nil
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	secret := []byte("secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	keys := &staticKeys{keys: []Key{
+		{ID: "hs", Algorithm: HS256, Material: secret},
+		{ID: "rs", Material: &rsaKey.PublicKey},
+		{ID: "es", Material: &ecKey.PublicKey},
+	}}
+
+	claims := func(extra map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{"sub": "john", "iss": "https://issuer", "aud": "companies", "exp": now.Add(time.Minute).Unix()}
+		for k, v := range extra {
+			c[k] = v
+		}
+		return c
+	}
+
+	sign := func(alg string, key interface{}, kid string, claims map[string]interface{}) string {
+		token, err := Sign(alg, key, kid, claims)
+		require.NoError(t, err)
+		return token
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"john"}`)) + "."
+
+	rsaPublic := []byte(base64.RawURLEncoding.EncodeToString(rsaKey.PublicKey.N.Bytes()))
+
+	cases := map[string]struct {
+		token   string
+		subject string
+		err     error
+	}{
+		"HS256":                   {token: sign(HS256, secret, "hs", claims(nil)), subject: "john"},
+		"RS256":                   {token: sign(RS256, rsaKey, "rs", claims(nil)), subject: "john"},
+		"ES256":                   {token: sign(ES256, ecKey, "es", claims(nil)), subject: "john"},
+		"without key ID":          {token: sign(ES256, ecKey, "", claims(nil)), subject: "john"},
+		"audience list":           {token: sign(HS256, secret, "hs", claims(map[string]interface{}{"aud": []string{"other", "companies"}})), subject: "john"},
+		"expired within leeway":   {token: sign(HS256, secret, "hs", claims(map[string]interface{}{"exp": now.Add(-time.Second).Unix()})), subject: "john"},
+		"valid since now":         {token: sign(HS256, secret, "hs", claims(map[string]interface{}{"nbf": now.Unix()})), subject: "john"},
+		"other secret":            {token: sign(HS256, []byte("other"), "hs", claims(nil)), err: ErrSignature},
+		"other RSA key":           {token: sign(RS256, mustRSAKey(t), "rs", claims(nil)), err: ErrSignature},
+		"unknown key":             {token: sign(HS256, secret, "unknown", claims(nil)), err: ErrKeyNotFound},
+		"algorithm of other key":  {token: sign(HS256, rsaPublic, "rs", claims(nil)), err: ErrKeyNotFound},
+		"algorithm none":          {token: unsigned, err: ErrUnsupportedAlg},
+		"malformed":               {token: "not.a.token.at.all", err: ErrMalformed},
+		"invalid signature":       {token: sign(HS256, secret, "hs", claims(nil)) + "!", err: ErrMalformed},
+		"expired":                 {token: sign(HS256, secret, "hs", claims(map[string]interface{}{"exp": now.Add(-time.Minute).Unix()})), err: ErrExpired},
+		"without expiry":          {token: sign(HS256, secret, "hs", map[string]interface{}{"sub": "john", "iss": "https://issuer", "aud": "companies"}), err: ErrNoExpiry},
+		"not valid yet":           {token: sign(HS256, secret, "hs", claims(map[string]interface{}{"nbf": now.Add(time.Minute).Unix()})), err: ErrNotValidYet},
+		"other audience":          {token: sign(HS256, secret, "hs", claims(map[string]interface{}{"aud": "other"})), err: ErrAudience},
+		"without audience":        {token: sign(HS256, secret, "hs", claims(map[string]interface{}{"aud": []string{}})), err: ErrAudience},
+		"other issuer":            {token: sign(HS256, secret, "hs", claims(map[string]interface{}{"iss": "https://other"})), err: ErrIssuer},
+		"fractional expiry":       {token: sign(HS256, secret, "hs", claims(map[string]interface{}{"exp": float64(now.Unix()) + 0.5})), subject: "john"},
+		"claims of invalid types": {token: sign(HS256, secret, "hs", claims(map[string]interface{}{"exp": "tomorrow"})), err: ErrMalformed},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			v := NewVerifier(keys, []string{"companies"}, "https://issuer", 5*time.Second)
+			v.now = func() time.Time { return now }
+
+			claims, err := v.Verify(context.Background(), c.token)
+			assert.ErrorIs(t, err, c.err)
+			assert.Equal(t, c.subject, claims.Subject)
+		})
+	}
+}
+
+func TestVerifyRefreshesUnknownKeys(t *testing.T) {
+	keys := &staticKeys{
+		keys:      []Key{{ID: "old", Material: []byte("old")}},
+		refreshed: []Key{{ID: "old", Material: []byte("old")}, {ID: "new", Material: []byte("new")}},
+	}
+
+	v := NewVerifier(keys, nil, "", 0)
+
+	token, err := Sign(HS256, []byte("old"), "old", map[string]interface{}{"exp": time.Now().Add(time.Minute).Unix()})
+	require.NoError(t, err)
+
+	_, err = v.Verify(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, 0, keys.refreshes)
+
+	token, err = Sign(HS256, []byte("new"), "new", map[string]interface{}{"exp": time.Now().Add(time.Minute).Unix()})
+	require.NoError(t, err)
+
+	_, err = v.Verify(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, 1, keys.refreshes)
+}
+
+func TestClaims(t *testing.T) {
+	claims := Claims{Values: map[string]interface{}{
+		"tenant": "acme",
+		"scope":  "companies:read companies:write",
+		"roles":  []interface{}{"admin", 1, "viewer"},
+	}}
+
+	assert.Equal(t, "acme", claims.String("tenant"))
+	assert.Equal(t, "", claims.String("roles"))
+	assert.Equal(t, []string{"companies:read", "companies:write"}, claims.Strings("scope"))
+	assert.Equal(t, []string{"admin", "viewer"}, claims.Strings("roles"))
+	assert.Nil(t, claims.Strings("missing"))
+}
+
+func mustRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
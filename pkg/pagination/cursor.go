@@ -0,0 +1,50 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidCursor is an error raised when a cursor can not be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor points to the last item of the previously returned page.
+// Values holds the sort keys of the item, while Sort holds the
+// sort expression the cursor was created with.
+// Results ranked by relevance can not be paginated by keys,
+// so their cursor holds the Offset of the next page instead of the ID.
+type Cursor struct {
+	ID     uint64   `json:"id,omitempty"`
+	Sort   string   `json:"s,omitempty"`
+	Values []string `json:"v,omitempty"`
+	Offset int      `json:"o,omitempty"`
+}
+
+// Encode converts the Cursor into an opaque string
+// that is safe to be used in the URL.
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Decode converts an opaque string back into a Cursor.
+// Empty string is decoded into an empty Cursor.
+func Decode(s string) (Cursor, error) {
+	var c Cursor
+
+	if s == "" {
+		return c, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(b, &c); err != nil || (c.ID == 0 && c.Offset <= 0) {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
@@ -0,0 +1,53 @@
+package pagination
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursorCanBeEncodedAndDecoded(t *testing.T) {
+	c, err := Decode(Cursor{ID: 42}.Encode())
+
+	assert.NoError(t, err)
+	assert.Equal(t, Cursor{ID: 42}, c)
+}
+
+func TestCursorWithValuesCanBeEncodedAndDecoded(t *testing.T) {
+	cursor := Cursor{ID: 42, Sort: "-name", Values: []string{"john"}}
+	c, err := Decode(cursor.Encode())
+
+	assert.NoError(t, err)
+	assert.Equal(t, cursor, c)
+}
+
+func TestOffsetCursorCanBeEncodedAndDecoded(t *testing.T) {
+	cursor := Cursor{Sort: "acme", Offset: 20}
+	c, err := Decode(cursor.Encode())
+
+	assert.NoError(t, err)
+	assert.Equal(t, cursor, c)
+}
+
+func TestEmptyCursorCanBeDecoded(t *testing.T) {
+	c, err := Decode("")
+
+	assert.NoError(t, err)
+	assert.Equal(t, Cursor{}, c)
+}
+
+func TestInvalidCursorCannotBeDecoded(t *testing.T) {
+	cases := map[string]string{
+		"not a base64":    "!!!",
+		"not a json":      "bm90LWEtanNvbg",
+		"empty id":        Cursor{}.Encode(),
+		"negative offset": Cursor{Offset: -1}.Encode(),
+	}
+
+	for name, s := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := Decode(s)
+			assert.ErrorIs(t, err, ErrInvalidCursor)
+		})
+	}
+}
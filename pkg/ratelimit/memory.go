@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the full buckets are removed from the memory store.
+const sweepInterval = time.Minute
+
+// bucket is the state of the bucket of a single client.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+// MemoryStore keeps the buckets in the memory of the process.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]bucket
+	swept   time.Time
+}
+
+// NewMemoryStore creates an empty memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]bucket)}
+}
+
+// Take takes a token from the bucket with the given key, new buckets are full.
+func (m *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = bucket{tokens: float64(limit.Burst), updated: now}
+	}
+
+	tokens, res := take(refill(b.tokens, now.Sub(b.updated), limit), limit)
+	m.buckets[key] = bucket{tokens: tokens, updated: now, limit: limit}
+
+	return res, nil
+}
+
+// sweep removes the buckets which are full again, so the idle clients do not use the memory.
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.swept) < sweepInterval {
+		return
+	}
+	m.swept = now
+
+	for key, b := range m.buckets {
+		if refill(b.tokens, now.Sub(b.updated), b.limit) >= float64(b.limit.Burst) {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Package ratelimit limits the rate of the requests with the token buckets.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Burst requests at once, and refills the bucket with Burst tokens every Period.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// ParseLimit parses the limit in the form <burst>/<period>, e.g. 30/1m.
+func ParseLimit(s string) (Limit, error) {
+	burst, period, found := strings.Cut(strings.TrimSpace(s), "/")
+	if !found {
+		return Limit{}, fmt.Errorf("limit %q must have the form <burst>/<period>", s)
+	}
+
+	n, err := strconv.Atoi(burst)
+	if err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("limit %q must allow at least 1 request", s)
+	}
+
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("limit %q must have a positive period", s)
+	}
+
+	return Limit{Burst: n, Period: d}, nil
+}
+
+// ParseRoutes parses the limits of the routes in the form <route>=<burst>/<period>, e.g. POST /companies=30/1m.
+func ParseRoutes(routes []string) (map[string]Limit, error) {
+	limits := make(map[string]Limit, len(routes))
+
+	for _, r := range routes {
+		route, limit, found := strings.Cut(r, "=")
+		if !found || strings.TrimSpace(route) == "" {
+			return nil, fmt.Errorf("route limit %q must have the form <route>=<burst>/<period>", r)
+		}
+
+		l, err := ParseLimit(limit)
+		if err != nil {
+			return nil, err
+		}
+		limits[strings.TrimSpace(route)] = l
+	}
+
+	return limits, nil
+}
+
+// Limits are the limits of the routes, the routes without a limit share the default limit.
+// Default is nil if these routes are not limited.
+type Limits struct {
+	Default *Limit
+	Routes  map[string]Limit
+}
+
+// ParseLimits parses the default limit, empty if the routes without a limit are not limited, and the limits of the routes.
+func ParseLimits(def string, routes []string) (Limits, error) {
+	limits := Limits{}
+
+	if strings.TrimSpace(def) != "" {
+		l, err := ParseLimit(def)
+		if err != nil {
+			return limits, err
+		}
+		limits.Default = &l
+	}
+
+	r, err := ParseRoutes(routes)
+	if err != nil {
+		return limits, err
+	}
+	limits.Routes = r
+
+	return limits, nil
+}
+
+// rate returns the number of tokens added to the bucket per second.
+func (l Limit) rate() float64 {
+	return float64(l.Burst) / l.Period.Seconds()
+}
+
+// Result is the state of the bucket after the request is taken.
+type Result struct {
+	Allowed bool
+	// Remaining is the number of requests allowed right now.
+	Remaining int
+	// RetryAfter is the time until the next request is allowed, zero if the request was allowed.
+	RetryAfter time.Duration
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+}
+
+// Store keeps the buckets of the clients.
+type Store interface {
+	// Take takes a token from the bucket with the given key.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// take takes a token from the bucket with the given number of tokens,
+// and returns the tokens left in the bucket with the result.
+func take(tokens float64, limit Limit) (float64, Result) {
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+	return tokens, result(tokens, allowed, limit)
+}
+
+// result returns the result of the request, given the tokens left in the bucket.
+func result(tokens float64, allowed bool, limit Limit) Result {
+	rate := limit.rate()
+
+	res := Result{Allowed: allowed, Remaining: int(math.Floor(tokens))}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	res.Reset = seconds((float64(limit.Burst) - tokens) / rate)
+
+	return res
+}
+
+// refill returns the number of tokens in the bucket after the elapsed time.
+func refill(tokens float64, elapsed time.Duration, limit Limit) float64 {
+	if elapsed <= 0 {
+		return tokens
+	}
+	return math.Min(float64(limit.Burst), tokens+elapsed.Seconds()*limit.rate())
+}
+
+// seconds converts the seconds to duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,197 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	cases := map[string]struct {
+		limit string
+		want  Limit
+		err   bool
+	}{
+		"per minute":         {limit: "30/1m", want: Limit{Burst: 30, Period: time.Minute}},
+		"spaces are trimmed": {limit: " 5/1s ", want: Limit{Burst: 5, Period: time.Second}},
+		"no period":          {limit: "30", err: true},
+		"invalid burst":      {limit: "many/1m", err: true},
+		"zero burst":         {limit: "0/1m", err: true},
+		"invalid period":     {limit: "30/minute", err: true},
+		"zero period":        {limit: "30/0s", err: true},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			limit, err := ParseLimit(c.limit)
+			if c.err {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, c.want, limit)
+		})
+	}
+}
+
+func TestParseRoutes(t *testing.T) {
+	limits, err := ParseRoutes([]string{"POST /companies=30/1m", " GET /companies/{id} =100/1s"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Limit{
+		"POST /companies":     {Burst: 30, Period: time.Minute},
+		"GET /companies/{id}": {Burst: 100, Period: time.Second},
+	}, limits)
+
+	_, err = ParseRoutes([]string{"POST /companies"})
+	assert.Error(t, err)
+
+	_, err = ParseRoutes([]string{"=30/1m"})
+	assert.Error(t, err)
+
+	_, err = ParseRoutes([]string{"POST /companies=30"})
+	assert.Error(t, err)
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits("600/1m", []string{"POST /companies=30/1m"})
+	require.NoError(t, err)
+	assert.Equal(t, &Limit{Burst: 600, Period: time.Minute}, limits.Default)
+	assert.Equal(t, map[string]Limit{"POST /companies": {Burst: 30, Period: time.Minute}}, limits.Routes)
+
+	limits, err = ParseLimits("", nil)
+	require.NoError(t, err)
+	assert.Nil(t, limits.Default)
+	assert.Empty(t, limits.Routes)
+
+	_, err = ParseLimits("600", nil)
+	assert.Error(t, err)
+
+	_, err = ParseLimits("600/1m", []string{"POST /companies"})
+	assert.Error(t, err)
+}
+
+// testStore runs the same checks against every store.
+// setNow sets the time of the stores, which do not use the time given to Take.
+func testStore(t *testing.T, store Store, setNow func(time.Time)) {
+	limit := Limit{Burst: 3, Period: 3 * time.Second}
+	now := time.Date(2022, 4, 1, 10, 0, 0, 0, time.UTC)
+
+	take := func(key string, at time.Time) (Result, error) {
+		setNow(at)
+		return store.Take(context.Background(), key, limit, at)
+	}
+
+	for i := 2; i >= 0; i-- {
+		res, err := take("client", now)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, i, res.Remaining)
+		assert.Zero(t, res.RetryAfter)
+		assert.Equal(t, time.Duration(3-i)*time.Second, res.Reset)
+	}
+
+	res, err := take("client", now)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 3*time.Second, res.Reset)
+
+	// other clients have their own buckets
+	res, err = take("other", now)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	// the bucket is refilled over time
+	res, err = take("client", now.Add(500*time.Millisecond))
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+
+	res, err = take("client", now.Add(time.Second))
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	// the bucket is never fuller than the burst
+	res, err = take("client", now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 2, res.Remaining)
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore(), func(time.Time) {})
+}
+
+func TestMemoryStoreRemovesFullBuckets(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Burst: 3, Period: 3 * time.Second}
+	now := time.Date(2022, 4, 1, 10, 0, 0, 0, time.UTC)
+
+	_, err := store.Take(context.Background(), "client", limit, now)
+	require.NoError(t, err)
+	assert.Len(t, store.buckets, 1)
+
+	_, err = store.Take(context.Background(), "other", limit, now.Add(sweepInterval))
+	require.NoError(t, err)
+	assert.Len(t, store.buckets, 1)
+	assert.Contains(t, store.buckets, "other")
+}
+
+func TestRedisStore(t *testing.T) {
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	defer client.Close()
+
+	testStore(t, NewRedisStore(client, "ratelimit"), srv.SetTime)
+
+	assert.True(t, srv.Exists("ratelimit:client"))
+	assert.True(t, srv.Exists("ratelimit:other"))
+
+	// the buckets expire when they are full again
+	srv.FastForward(3 * time.Second)
+	assert.False(t, srv.Exists("ratelimit:client"))
+}
+
+func TestRedisStoreUsesServerTime(t *testing.T) {
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	defer client.Close()
+
+	store := NewRedisStore(client, "ratelimit")
+	limit := Limit{Burst: 1, Period: time.Minute}
+	now := time.Date(2022, 4, 1, 10, 0, 0, 0, time.UTC)
+	srv.SetTime(now)
+
+	res, err := store.Take(context.Background(), "client", limit, now)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	// the clock of the instance is ahead, the bucket is not refilled until the server time passes
+	res, err = store.Take(context.Background(), "client", limit, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+
+	srv.SetTime(now.Add(time.Minute))
+	res, err = store.Take(context.Background(), "client", limit, now)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+}
+
+func TestRedisStoreFails(t *testing.T) {
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr(), MaxRetries: -1})
+	defer client.Close()
+
+	srv.Close()
+
+	_, err := NewRedisStore(client, "ratelimit").Take(context.Background(), "client", Limit{Burst: 1, Period: time.Second}, time.Now())
+	assert.Error(t, err)
+}
@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// takeScript takes a token from the bucket stored in the hash at KEYS[1].
+// ARGV are the burst and the period, in milliseconds. The current time is the time of the Redis server,
+// so the buckets are refilled the same whichever instance of the service takes the token, despite the clock skew.
+// The bucket expires when it is full again, the script returns whether the request
+// is allowed and the tokens left in the bucket.
+var takeScript = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local rate = burst / period
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(state[1])
+local updated = tonumber(state[2])
+if tokens == nil or updated == nil then
+	tokens = burst
+	updated = now
+end
+
+if now > updated then
+	tokens = math.min(burst, tokens + (now - updated) * rate)
+	updated = now
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "updated", tostring(updated))
+redis.call("PEXPIRE", KEYS[1], math.max(1, math.ceil((burst - tokens) / rate)))
+
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore keeps the buckets in Redis, so the limits are shared by all instances of the service.
+// The buckets are updated with a script, so concurrent requests of the same client are counted correctly.
+type RedisStore struct {
+	client redis.Scripter
+	prefix string
+}
+
+// NewRedisStore creates a store keeping the buckets in the keys with the given prefix.
+func NewRedisStore(client redis.Scripter, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// Take takes a token from the bucket with the given key, new buckets are full.
+// The given time is ignored, the buckets are refilled by the time of the Redis server.
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit, _ time.Time) (Result, error) {
+	args := []interface{}{limit.Burst, limit.Period.Milliseconds()}
+
+	values, err := takeScript.Run(ctx, s.client, []string{s.prefix + ":" + key}, args...).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	if len(values) != 2 {
+		return Result{}, fmt.Errorf("unexpected reply of the rate limit script: %v", values)
+	}
+
+	allowed, _ := values[0].(int64)
+	left, _ := values[1].(string)
+
+	tokens, err := strconv.ParseFloat(left, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("unexpected tokens of the rate limit script: %w", err)
+	}
+
+	return result(tokens, allowed == 1, limit), nil
+}
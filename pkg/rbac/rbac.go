@@ -0,0 +1,109 @@
+// Package rbac maps the routes to the permissions they require, and the roles to the permissions they grant.
+package rbac
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// methods are the methods allowed in the route rules.
+var methods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true, "OPTIONS": true,
+}
+
+// paramPattern matches the regular expressions of the route parameters, e.g. `:[0-9]+` in `{id:[0-9]+}`.
+var paramPattern = regexp.MustCompile(`:[^}]*}`)
+
+// Policy is the authorization policy.
+// Roles maps the role to the permissions it grants,
+// Routes maps the route, e.g. `GET /companies/{id}`, to the permission it requires.
+type Policy struct {
+	Roles  map[string][]string `json:"roles"`
+	Routes map[string]string   `json:"routes"`
+}
+
+// Parse parses and validates the JSON encoded policy.
+func Parse(data []byte) (*Policy, error) {
+	var raw Policy
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid policy: %w", err)
+	}
+
+	p := Policy{Roles: raw.Roles, Routes: make(map[string]string, len(raw.Routes))}
+	if p.Roles == nil {
+		p.Roles = map[string][]string{}
+	}
+
+	for route, permission := range raw.Routes {
+		method, pattern, found := strings.Cut(strings.TrimSpace(route), " ")
+		if !found || !methods[method] || !strings.HasPrefix(strings.TrimSpace(pattern), "/") {
+			return nil, fmt.Errorf("invalid policy: route %q must have the form `METHOD /path`", route)
+		}
+
+		if permission == "" {
+			return nil, fmt.Errorf("invalid policy: route %q has no permission", route)
+		}
+
+		key := method + " " + Pattern(strings.TrimSpace(pattern))
+		if _, ok := p.Routes[key]; ok {
+			return nil, fmt.Errorf("invalid policy: route %q is defined more than once", route)
+		}
+		p.Routes[key] = permission
+	}
+
+	for role, permissions := range p.Roles {
+		for _, permission := range permissions {
+			if permission == "" {
+				return nil, fmt.Errorf("invalid policy: role %q has an empty permission", role)
+			}
+		}
+	}
+
+	return &p, nil
+}
+
+// Load reads the policy from the JSON file.
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read policy: %w", err)
+	}
+	return Parse(data)
+}
+
+// Pattern returns the normalized route pattern,
+// without the regular expressions of the parameters and the trailing slash.
+func Pattern(route string) string {
+	route = paramPattern.ReplaceAllString(route, "}")
+	if len(route) > 1 {
+		route = strings.TrimSuffix(route, "/")
+	}
+	return route
+}
+
+// Permission returns the permission required by the route,
+// the second value reports whether the policy has a rule for the route.
+func (p *Policy) Permission(method string, route string) (string, bool) {
+	permission, ok := p.Routes[method+" "+Pattern(route)]
+	return permission, ok
+}
+
+// Permissions returns the permissions granted by the roles, unknown roles grant nothing.
+func (p *Policy) Permissions(roles []string) []string {
+	seen := map[string]bool{}
+	permissions := []string{}
+
+	for _, role := range roles {
+		for _, permission := range p.Roles[role] {
+			if !seen[permission] {
+				seen[permission] = true
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+
+	return permissions
+}
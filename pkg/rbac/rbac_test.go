@@ -0,0 +1,120 @@
+package rbac
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	cases := map[string]struct {
+		policy string
+		err    string
+	}{
+		"valid policy": {
+			policy: `{"roles":{"viewer":["companies:read"]},"routes":{"GET /companies":"companies:read","DELETE /companies/{id:[0-9]+}/":"companies:delete"}}`,
+		},
+		"empty policy": {
+			policy: `{}`,
+		},
+		"json should be valid": {
+			policy: `not-a-json`,
+			err:    "invalid policy: invalid character 'o' in literal null (expecting 'u')",
+		},
+		"route should have method": {
+			policy: `{"routes":{"/companies":"companies:read"}}`,
+			err:    "invalid policy: route \"/companies\" must have the form `METHOD /path`",
+		},
+		"method should be known": {
+			policy: `{"routes":{"FETCH /companies":"companies:read"}}`,
+			err:    "invalid policy: route \"FETCH /companies\" must have the form `METHOD /path`",
+		},
+		"path should be absolute": {
+			policy: `{"routes":{"GET companies":"companies:read"}}`,
+			err:    "invalid policy: route \"GET companies\" must have the form `METHOD /path`",
+		},
+		"route should have permission": {
+			policy: `{"routes":{"GET /companies":""}}`,
+			err:    "invalid policy: route \"GET /companies\" has no permission",
+		},
+		"route should be defined once": {
+			policy: `{"routes":{"GET /companies":"companies:read","GET /companies/":"companies:write"}}`,
+			err:    "is defined more than once",
+		},
+		"role should not have empty permission": {
+			policy: `{"roles":{"viewer":[""]}}`,
+			err:    "invalid policy: role \"viewer\" has an empty permission",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := Parse([]byte(c.policy))
+			if c.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), c.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NotNil(t, p.Roles)
+			assert.NotNil(t, p.Routes)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"routes":{"GET /companies":"companies:read"}}`), 0o600))
+
+	p, err := Load(path)
+	require.NoError(t, err)
+
+	permission, ok := p.Permission("GET", "/companies")
+	assert.True(t, ok)
+	assert.Equal(t, "companies:read", permission)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestPattern(t *testing.T) {
+	cases := map[string]string{
+		"/":                                 "/",
+		"/companies":                        "/companies",
+		"/companies/":                       "/companies",
+		"/companies/{id:[0-9]+}/":           "/companies/{id}",
+		"/companies/{id}/contacts/{cid:.*}": "/companies/{id}/contacts/{cid}",
+	}
+
+	for route, want := range cases {
+		assert.Equal(t, want, Pattern(route), route)
+	}
+}
+
+func TestPermission(t *testing.T) {
+	p, err := Parse([]byte(`{"routes":{"GET /companies/{id}":"companies:read","DELETE /companies/{id}":"companies:delete"}}`))
+	require.NoError(t, err)
+
+	permission, ok := p.Permission("GET", "/companies/{id:[0-9]+}/")
+	assert.True(t, ok)
+	assert.Equal(t, "companies:read", permission)
+
+	permission, ok = p.Permission("DELETE", "/companies/{id:[0-9]+}")
+	assert.True(t, ok)
+	assert.Equal(t, "companies:delete", permission)
+
+	_, ok = p.Permission("PUT", "/companies/{id:[0-9]+}")
+	assert.False(t, ok)
+}
+
+func TestPermissions(t *testing.T) {
+	p, err := Parse([]byte(`{"roles":{"viewer":["companies:read"],"editor":["companies:read","companies:write"]}}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"companies:read", "companies:write"}, p.Permissions([]string{"viewer", "editor", "unknown"}))
+	assert.Equal(t, []string{}, p.Permissions(nil))
+}
@@ -0,0 +1,135 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+const (
+	// HighlightPre is inserted before every matched term.
+	HighlightPre = "<em>"
+	// HighlightPost is inserted after every matched term.
+	HighlightPost = "</em>"
+
+	// snippetRunes is the maximum length of a snippet, excluding the ellipsis.
+	snippetRunes = 100
+)
+
+// Highlight returns the HTML snippet of the text, with the words matching the terms highlighted.
+// Long texts are cut around the first match. It returns false, if nothing matches.
+func Highlight(text string, terms []string) (string, bool) {
+	runes := []rune(text)
+
+	first := -1
+	words := splitWords(runes)
+	for _, w := range words {
+		if w.word && matchesAny(strings.ToLower(string(runes[w.start:w.end])), terms) {
+			first = w.start
+			break
+		}
+	}
+
+	if first == -1 {
+		return "", false
+	}
+
+	start, end := 0, len(runes)
+	if len(runes) > snippetRunes {
+		start = first - snippetRunes/4
+		if start < 0 {
+			start = 0
+		}
+		end = start + snippetRunes
+		if end > len(runes) {
+			end = len(runes)
+			start = end - snippetRunes
+		}
+	}
+
+	var b strings.Builder
+
+	if start > 0 {
+		b.WriteString("…")
+	}
+
+	for _, w := range words {
+		if w.end <= start || w.start >= end {
+			continue
+		}
+
+		s, e := w.start, w.end
+		if s < start {
+			s = start
+		}
+		if e > end {
+			e = end
+		}
+		part := html.EscapeString(string(runes[s:e]))
+
+		if w.word && matchesAny(strings.ToLower(string(runes[w.start:w.end])), terms) {
+			b.WriteString(HighlightPre + part + HighlightPost)
+		} else {
+			b.WriteString(part)
+		}
+	}
+
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+
+	return b.String(), true
+}
+
+// Highlights returns the highlighted snippets of the matching fields.
+func Highlights(fields map[string]string, terms []string) map[string]string {
+	highlights := make(map[string]string)
+
+	for field, text := range fields {
+		if h, ok := Highlight(text, terms); ok {
+			highlights[field] = h
+		}
+	}
+
+	return highlights
+}
+
+// span is a part of the text, either a word or a separator between words.
+type span struct {
+	start, end int
+	word       bool
+}
+
+// splitWords splits the text into words and separators, the same way Tokenize does.
+func splitWords(runes []rune) []span {
+	var spans []span
+
+	for i := 0; i < len(runes); {
+		word := isWordRune(runes[i])
+
+		j := i + 1
+		for j < len(runes) && isWordRune(runes[j]) == word {
+			j++
+		}
+
+		spans = append(spans, span{start: i, end: j, word: word})
+		i = j
+	}
+
+	return spans
+}
+
+// isWordRune reports whether the rune is a part of the word.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// matchesAny reports whether any of the terms is a prefix of the word.
+func matchesAny(word string, terms []string) bool {
+	for _, t := range terms {
+		if strings.HasPrefix(word, t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlight(t *testing.T) {
+	cases := map[string]struct {
+		text      string
+		terms     []string
+		highlight string
+		ok        bool
+	}{
+		"whole word":    {text: "Acme Holdings", terms: []string{"acme"}, highlight: "<em>Acme</em> Holdings", ok: true},
+		"prefix":        {text: "Acme Holdings", terms: []string{"hold"}, highlight: "Acme <em>Holdings</em>", ok: true},
+		"many terms":    {text: "acme.com", terms: []string{"acme", "com"}, highlight: "<em>acme</em>.<em>com</em>", ok: true},
+		"html escaping": {text: "<b>Acme</b> & co", terms: []string{"co"}, highlight: "&lt;b&gt;Acme&lt;/b&gt; &amp; <em>co</em>", ok: true},
+		"no match":      {text: "Beta", terms: []string{"acme"}},
+		"infix":         {text: "Megacme", terms: []string{"acme"}},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			h, ok := Highlight(c.text, c.terms)
+			assert.Equal(t, c.ok, ok)
+			assert.Equal(t, c.highlight, h)
+		})
+	}
+}
+
+func TestHighlightCutsLongText(t *testing.T) {
+	text := strings.Repeat("lorem ", 30) + "acme " + strings.Repeat("ipsum ", 30)
+
+	h, ok := Highlight(text, []string{"acme"})
+	assert.True(t, ok)
+	assert.True(t, strings.HasPrefix(h, "…"))
+	assert.True(t, strings.HasSuffix(h, "…"))
+	assert.Contains(t, h, "<em>acme</em>")
+	assert.Equal(t, snippetRunes+2, len([]rune(strings.ReplaceAll(strings.ReplaceAll(h, HighlightPre, ""), HighlightPost, ""))))
+}
+
+func TestHighlights(t *testing.T) {
+	highlights := Highlights(map[string]string{"name": "Acme", "code": "c1"}, []string{"acme"})
+	assert.Equal(t, map[string]string{"name": "<em>Acme</em>"}, highlights)
+}
@@ -0,0 +1,190 @@
+// Package search provides a small in-process inverted index,
+// used to rank companies when the database has no full-text search.
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// prefixWeight is a multiplier applied to the terms matched only by the prefix.
+const prefixWeight = 0.5
+
+// Hit is a single document matching the search terms.
+type Hit struct {
+	ID    uint64
+	Score float64
+}
+
+// Tokenize splits the text into lowercase terms, consisting of letters and digits.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Index is an inverted index of documents, consisting of the named fields.
+// Every query term matches the indexed terms it is a prefix of,
+// documents must match all the query terms.
+type Index struct {
+	weights  map[string]float64
+	mu       sync.RWMutex
+	docs     map[uint64][]string
+	postings map[string]map[uint64]map[string]int
+	vocab    []string
+}
+
+// NewIndex creates a new instance of the Index,
+// only the fields listed in weights are indexed.
+func NewIndex(weights map[string]float64) *Index {
+	return &Index{
+		weights:  weights,
+		docs:     make(map[uint64][]string),
+		postings: make(map[string]map[uint64]map[string]int),
+	}
+}
+
+// Put adds or replaces the document in the index.
+func (i *Index) Put(id uint64, fields map[string]string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.remove(id)
+
+	var terms []string
+	for field, text := range fields {
+		if _, ok := i.weights[field]; !ok {
+			continue
+		}
+
+		for _, term := range Tokenize(text) {
+			docs, ok := i.postings[term]
+			if !ok {
+				docs = make(map[uint64]map[string]int)
+				i.postings[term] = docs
+				i.addToVocab(term)
+			}
+
+			if docs[id] == nil {
+				docs[id] = make(map[string]int)
+				terms = append(terms, term)
+			}
+			docs[id][field]++
+		}
+	}
+
+	i.docs[id] = terms
+}
+
+// Remove removes the document from the index.
+func (i *Index) Remove(id uint64) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.remove(id)
+}
+
+// Len returns the number of indexed documents.
+func (i *Index) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return len(i.docs)
+}
+
+// Search returns the documents matching all the terms,
+// ordered by the score and then by the id.
+func (i *Index) Search(terms []string) []Hit {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	if len(terms) == 0 {
+		return []Hit{}
+	}
+
+	var scores map[uint64]float64
+
+	for _, term := range terms {
+		matched := i.match(term)
+
+		idf := 1 + math.Log(float64(len(i.docs))/math.Max(float64(len(matched)), 1))
+
+		next := make(map[uint64]float64, len(matched))
+		for id, score := range matched {
+			if scores == nil {
+				next[id] = score * idf
+			} else if prev, ok := scores[id]; ok {
+				next[id] = prev + score*idf
+			}
+		}
+		scores = next
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+
+	sort.Slice(hits, func(a, b int) bool {
+		if hits[a].Score != hits[b].Score {
+			return hits[a].Score > hits[b].Score
+		}
+		return hits[a].ID < hits[b].ID
+	})
+
+	return hits
+}
+
+// match returns the weighted term frequency of every document matching the term.
+func (i *Index) match(term string) map[uint64]float64 {
+	matched := make(map[uint64]float64)
+
+	for n := sort.SearchStrings(i.vocab, term); n < len(i.vocab) && strings.HasPrefix(i.vocab[n], term); n++ {
+		multiplier := 1.0
+		if i.vocab[n] != term {
+			multiplier = prefixWeight
+		}
+
+		for id, fields := range i.postings[i.vocab[n]] {
+			for field, tf := range fields {
+				matched[id] += i.weights[field] * float64(tf) * multiplier
+			}
+		}
+	}
+
+	return matched
+}
+
+// remove removes the document from the index.
+// It must be called while holding the write lock.
+func (i *Index) remove(id uint64) {
+	for _, term := range i.docs[id] {
+		delete(i.postings[term], id)
+
+		if len(i.postings[term]) == 0 {
+			delete(i.postings, term)
+			i.removeFromVocab(term)
+		}
+	}
+
+	delete(i.docs, id)
+}
+
+// addToVocab inserts the term into the sorted vocabulary.
+func (i *Index) addToVocab(term string) {
+	n := sort.SearchStrings(i.vocab, term)
+	i.vocab = append(i.vocab, "")
+	copy(i.vocab[n+1:], i.vocab[n:])
+	i.vocab[n] = term
+}
+
+// removeFromVocab removes the term from the sorted vocabulary.
+func (i *Index) removeFromVocab(term string) {
+	n := sort.SearchStrings(i.vocab, term)
+	if n < len(i.vocab) && i.vocab[n] == term {
+		i.vocab = append(i.vocab[:n], i.vocab[n+1:]...)
+	}
+}
@@ -0,0 +1,76 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var weights = map[string]float64{"name": 3, "code": 1}
+
+func ids(hits []Hit) []uint64 {
+	result := make([]uint64, 0, len(hits))
+	for _, h := range hits {
+		result = append(result, h.ID)
+	}
+	return result
+}
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"acme", "holdings", "ltd"}, Tokenize("ACME Holdings, Ltd."))
+	assert.Equal(t, []string{"acme", "com"}, Tokenize("acme.com"))
+	assert.Equal(t, []string{"35799123456"}, Tokenize("+35799123456"))
+	assert.Empty(t, Tokenize(" +-* "))
+}
+
+func TestIndexSearch(t *testing.T) {
+	i := NewIndex(weights)
+	i.Put(1, map[string]string{"name": "Acme Holdings", "code": "h1"})
+	i.Put(2, map[string]string{"name": "Beta", "code": "acme"})
+	i.Put(3, map[string]string{"name": "Acmeverse", "code": "a3"})
+	i.Put(4, map[string]string{"name": "Gamma", "code": "g4", "website": "acme.com"})
+
+	cases := map[string]struct {
+		terms []string
+		ids   []uint64
+	}{
+		"name is weighted above code": {terms: []string{"acme"}, ids: []uint64{1, 3, 2}},
+		"prefix match":                {terms: []string{"acmev"}, ids: []uint64{3}},
+		"all terms must match":        {terms: []string{"acme", "hold"}, ids: []uint64{1}},
+		"no match":                    {terms: []string{"delta"}, ids: []uint64{}},
+		"not indexed field":           {terms: []string{"com"}, ids: []uint64{}},
+		"no terms":                    {terms: nil, ids: []uint64{}},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, c.ids, ids(i.Search(c.terms)))
+		})
+	}
+}
+
+func TestIndexOrdersTiesByID(t *testing.T) {
+	i := NewIndex(weights)
+	i.Put(2, map[string]string{"name": "acme"})
+	i.Put(1, map[string]string{"name": "acme"})
+
+	hits := i.Search([]string{"acme"})
+	assert.Equal(t, []uint64{1, 2}, ids(hits))
+	assert.Equal(t, hits[0].Score, hits[1].Score)
+}
+
+func TestIndexPutAndRemove(t *testing.T) {
+	i := NewIndex(weights)
+	i.Put(1, map[string]string{"name": "acme"})
+	i.Put(2, map[string]string{"name": "acme beta"})
+
+	i.Put(1, map[string]string{"name": "gamma"})
+	assert.Equal(t, []uint64{2}, ids(i.Search([]string{"acme"})))
+	assert.Equal(t, []uint64{1}, ids(i.Search([]string{"gam"})))
+
+	i.Remove(2)
+	assert.Empty(t, i.Search([]string{"acme"}))
+	assert.Equal(t, 1, i.Len())
+	assert.NotContains(t, i.vocab, "acme")
+	assert.NotContains(t, i.vocab, "beta")
+}
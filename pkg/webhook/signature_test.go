@@ -0,0 +1,41 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	ts := time.Unix(1650000000, 0)
+
+	signature := Sign("secret", ts, []byte(`{"id":1}`))
+	assert.Equal(t, "sha256=62c006dae562371070aeb1007af0008c8a7fe5b9b5d671181e5ed190f92a8119", signature)
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	signature := Sign("secret", time.Unix(1650000000, 0), body)
+
+	cases := map[string]struct {
+		secret    string
+		signature string
+		timestamp string
+		body      []byte
+		valid     bool
+	}{
+		"valid":             {secret: "secret", signature: signature, timestamp: "1650000000", body: body, valid: true},
+		"other secret":      {secret: "other", signature: signature, timestamp: "1650000000", body: body},
+		"other timestamp":   {secret: "secret", signature: signature, timestamp: "1650000001", body: body},
+		"other body":        {secret: "secret", signature: signature, timestamp: "1650000000", body: []byte(`{"id":2}`)},
+		"invalid timestamp": {secret: "secret", signature: signature, timestamp: "moon", body: body},
+		"unknown algorithm": {secret: "secret", signature: "md5=abc", timestamp: "1650000000", body: body},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, c.valid, Verify(c.secret, c.signature, c.timestamp, c.body))
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterWritesWorkbook(t *testing.T) {
+	var buf bytes.Buffer
+
+	w, err := NewWriter(&buf, "Companies & Co")
+	require.NoError(t, err)
+	require.NoError(t, w.WriteRow([]string{"id", "name"}))
+	require.NoError(t, w.WriteRow([]string{"1", " <acme> "}))
+	require.NoError(t, w.Close())
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	files := map[string]string{}
+	for _, f := range r.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+
+	assert.Len(t, files, 5)
+	assert.Contains(t, files, "[Content_Types].xml")
+	assert.Contains(t, files, "_rels/.rels")
+	assert.Contains(t, files, "xl/_rels/workbook.xml.rels")
+	assert.Contains(t, files["xl/workbook.xml"], `<sheet name="Companies &amp; Co" sheetId="1" r:id="rId1"/>`)
+	assert.Contains(t, files["xl/worksheets/sheet1.xml"], `<sheetData>`+
+		`<row r="1"><c r="A1" t="inlineStr"><is><t xml:space="preserve">id</t></is></c><c r="B1" t="inlineStr"><is><t xml:space="preserve">name</t></is></c></row>`+
+		`<row r="2"><c r="A2" t="inlineStr"><is><t xml:space="preserve">1</t></is></c><c r="B2" t="inlineStr"><is><t xml:space="preserve"> &lt;acme&gt; </t></is></c></row>`+
+		`</sheetData></worksheet>`)
+}
+
+func TestWriterFlushesRows(t *testing.T) {
+	var buf bytes.Buffer
+
+	w, err := NewWriter(&buf, "Sheet")
+	require.NoError(t, err)
+
+	size := buf.Len()
+	require.NoError(t, w.WriteRow([]string{"1"}))
+	require.NoError(t, w.Flush())
+
+	assert.Greater(t, buf.Len(), size)
+}
+
+func TestColumn(t *testing.T) {
+	cases := map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"}
+
+	for i, name := range cases {
+		assert.Equal(t, name, column(i))
+	}
+}